@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"github.com/shubhbham/BankingApi_Golang/internal/config"
 	"github.com/shubhbham/BankingApi_Golang/internal/server"
@@ -14,6 +15,14 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	// Run the end-of-day batch instead of serving when asked to
+	if len(os.Args) > 1 && os.Args[1] == "eod" {
+		if err := server.RunEOD(cfg); err != nil {
+			log.Fatalf("End-of-day run failed: %v", err)
+		}
+		return
+	}
+
 	// Create and start server
 	srv, err := server.New(cfg)
 	if err != nil {
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/google/uuid"
@@ -29,7 +30,16 @@ func (h *AccountHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.service.CreateAccount(r.Context(), &account); err != nil {
-		respondError(w, http.StatusInternalServerError, err.Error())
+		switch {
+		case errors.Is(err, core.ErrNotFound):
+			respondError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, core.ErrNotEligible):
+			respondError(w, http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, core.ErrInvalidInput):
+			respondError(w, http.StatusBadRequest, err.Error())
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 
@@ -86,20 +96,86 @@ func (h *AccountHandler) ListAccountsByCustomer(w http.ResponseWriter, r *http.R
 	respondJSON(w, http.StatusOK, accounts)
 }
 
-func (h *AccountHandler) CloseAccount(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := uuid.Parse(vars["id"])
+type ChangeStatusRequest struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+func (h *AccountHandler) ChangeStatus(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	var req ChangeStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	change, err := h.service.ChangeStatus(r.Context(), id, req.Status, req.Reason, actorID(r))
 	if err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid account ID")
+		respondStatusChangeError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, change)
+}
+
+func (h *AccountHandler) StatusHistory(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseAccountID(w, r)
+	if !ok {
 		return
 	}
 
-	if err := h.service.CloseAccount(r.Context(), id); err != nil {
+	history, err := h.service.StatusHistory(r.Context(), id)
+	if err != nil {
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]string{
-		"message": "Account closed successfully",
-	})
+	respondJSON(w, http.StatusOK, history)
+}
+
+// Reactivate returns a dormant account to ACTIVE. The customer's KYC must
+// have been verified since the account went dormant.
+func (h *AccountHandler) Reactivate(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	change, err := h.service.ChangeStatus(r.Context(), id, core.AccountActive,
+		"Reactivated after KYC verification", actorID(r))
+	if err != nil {
+		respondStatusChangeError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, change)
+}
+
+func respondStatusChangeError(w http.ResponseWriter, err error) {
+	switch {
+	case err == core.ErrKYCRequired:
+		respondError(w, http.StatusUnprocessableEntity, "The customer's KYC must be verified again before the account is reactivated")
+	case err == core.ErrNotFound:
+		respondError(w, http.StatusNotFound, "Account not found")
+	case err == core.ErrInvalidInput:
+		respondError(w, http.StatusBadRequest, "A reason and the user in X-User-ID are required")
+	case errors.Is(err, core.ErrInvalidTransition):
+		respondError(w, http.StatusConflict, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// isAccountStatusError reports whether err refuses a posting because of the
+// status of an account.
+func isAccountStatusError(err error) bool {
+	return errors.Is(err, core.ErrAccountClosed) ||
+		errors.Is(err, core.ErrAccountSuspended) ||
+		errors.Is(err, core.ErrAccountFrozen) ||
+		errors.Is(err, core.ErrAccountDormant) ||
+		errors.Is(err, core.ErrAccountInactive)
 }
\ No newline at end of file
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+type ACHHandler struct {
+	service       *core.ACHService
+	beneficiaries *core.BeneficiaryService
+}
+
+func NewACHHandler(service *core.ACHService, beneficiaries *core.BeneficiaryService) *ACHHandler {
+	return &ACHHandler{service: service, beneficiaries: beneficiaries}
+}
+
+type ACHTransferRequest struct {
+	BeneficiaryID       *uuid.UUID `json:"beneficiary_id,omitempty"`
+	EntryType           string     `json:"entry_type"`
+	SECCode             string     `json:"sec_code"`
+	RoutingNumber       string     `json:"routing_number"`
+	ReceiverAccount     string     `json:"receiver_account"`
+	ReceiverAccountType string     `json:"receiver_account_type"`
+	ReceiverName        string     `json:"receiver_name"`
+	ReceiverID          *string    `json:"receiver_id,omitempty"`
+	Amount              float64    `json:"amount"`
+	Description         *string    `json:"description,omitempty"`
+	EffectiveDate       *string    `json:"effective_date,omitempty"`
+}
+
+func (h *ACHHandler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	var req ACHTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	effective, ok := parseOptionalDate(w, req.EffectiveDate)
+	if !ok {
+		return
+	}
+
+	t := core.ACHTransfer{
+		AccountID:           accountID,
+		BeneficiaryID:       req.BeneficiaryID,
+		EntryType:           req.EntryType,
+		SECCode:             req.SECCode,
+		RoutingNumber:       req.RoutingNumber,
+		ReceiverAccount:     req.ReceiverAccount,
+		ReceiverAccountType: req.ReceiverAccountType,
+		ReceiverName:        req.ReceiverName,
+		ReceiverID:          req.ReceiverID,
+		Amount:              req.Amount,
+		Description:         req.Description,
+	}
+	if effective != nil {
+		t.EffectiveDate = *effective
+	}
+
+	// A beneficiary's registered details take the place of the receiver fields.
+	if req.BeneficiaryID != nil {
+		b, ok := checkBeneficiary(w, r, h.beneficiaries, accountID, *req.BeneficiaryID, req.Amount)
+		if !ok {
+			return
+		}
+		if b.BankCode == nil {
+			respondError(w, http.StatusBadRequest, "Internal beneficiaries are paid by transfer, not ACH")
+			return
+		}
+		t.RoutingNumber, t.ReceiverAccount, t.ReceiverName = *b.BankCode, b.AccountNumber, b.PayeeName
+	}
+
+	if err := h.service.CreateTransfer(r.Context(), &t); err != nil {
+		switch {
+		case err == core.ErrNotFound:
+			respondError(w, http.StatusNotFound, "Account not found")
+		case isAccountStatusError(err), err == core.ErrInsufficientFunds, err == core.ErrCoolingLimit:
+			respondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, core.ErrChannelNotAllowed), errors.Is(err, core.ErrLimitExceeded):
+			respondError(w, http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, core.ErrApprovalRequired):
+			respondError(w, http.StatusForbidden, err.Error())
+		case errors.Is(err, core.ErrInvalidInput):
+			respondError(w, http.StatusBadRequest, "Entry type must be CREDIT or DEBIT, SEC code PPD or CCD, amount positive, "+
+				"and the routing number valid; the receiver needs an account number of up to 17 characters and a name, "+
+				"and the effective date must not be before the business date")
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, t)
+}
+
+func (h *ACHHandler) ListTransfers(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	transfers, err := h.service.ListTransfersByAccount(r.Context(), accountID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, transfers)
+}
+
+func (h *ACHHandler) GetTransfer(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid transfer ID")
+		return
+	}
+
+	t, err := h.service.GetTransfer(r.Context(), id)
+	if err != nil {
+		if err == core.ErrNotFound {
+			respondError(w, http.StatusNotFound, "ACH transfer not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, t)
+}
+
+// GenerateFile writes pending transfers to a NACHA file now, outside the
+// end-of-day run.
+func (h *ACHHandler) GenerateFile(w http.ResponseWriter, r *http.Request) {
+	f, err := h.service.GenerateFile(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if f == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, f)
+}
+
+// ProcessReturns applies the return files waiting in the inbound directory.
+func (h *ACHHandler) ProcessReturns(w http.ResponseWriter, r *http.Request) {
+	results, err := h.service.ProcessInbound(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if results == nil {
+		results = []*core.ACHReturnResult{}
+	}
+
+	respondJSON(w, http.StatusOK, results)
+}
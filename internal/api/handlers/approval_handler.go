@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+type ApprovalHandler struct {
+	service *core.ApprovalService
+}
+
+func NewApprovalHandler(service *core.ApprovalService) *ApprovalHandler {
+	return &ApprovalHandler{service: service}
+}
+
+func (h *ApprovalHandler) ListApprovals(w http.ResponseWriter, r *http.Request) {
+	approvals, err := h.service.ListApprovals(r.Context(), r.URL.Query().Get("status"))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, approvals)
+}
+
+// GetApproval returns a request with its decision trail.
+func (h *ApprovalHandler) GetApproval(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseApprovalID(w, r)
+	if !ok {
+		return
+	}
+
+	a, err := h.service.GetApproval(r.Context(), id)
+	if err != nil {
+		respondApprovalError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, a)
+}
+
+type ApprovalDecisionRequest struct {
+	Comment string `json:"comment"`
+}
+
+func (h *ApprovalHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, true)
+}
+
+func (h *ApprovalHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, false)
+}
+
+// decide records the decision of the user in X-User-ID. An approved request
+// is carried out before the response; its status says whether that worked.
+func (h *ApprovalHandler) decide(w http.ResponseWriter, r *http.Request, approve bool) {
+	id, ok := parseApprovalID(w, r)
+	if !ok {
+		return
+	}
+
+	var req ApprovalDecisionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	if actorID(r) == "" {
+		respondError(w, http.StatusBadRequest, "X-User-ID is required")
+		return
+	}
+
+	a, err := h.service.Decide(r.Context(), id, actorID(r), approve, req.Comment)
+	if err != nil {
+		respondApprovalError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, a)
+}
+
+func parseApprovalID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["approval_id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid approval ID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// submitApproval holds an operation for a second user's approval and
+// responds 202 Accepted with the pending request.
+func submitApproval(w http.ResponseWriter, r *http.Request, approvals *core.ApprovalService, op string,
+	subjectID uuid.UUID, amount *float64, payload any, comment string) {
+	if actorID(r) == "" {
+		respondError(w, http.StatusBadRequest, "X-User-ID is required")
+		return
+	}
+
+	a, err := approvals.Submit(r.Context(), op, subjectID, amount, payload, actorID(r), comment)
+	if err != nil {
+		respondApprovalError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusAccepted, a)
+}
+
+func respondApprovalError(w http.ResponseWriter, err error) {
+	switch {
+	case err == core.ErrNotFound:
+		respondError(w, http.StatusNotFound, "Not found")
+	case errors.Is(err, core.ErrNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+	case err == core.ErrDuplicateEntry:
+		respondError(w, http.StatusConflict, "A request for this is already awaiting approval")
+	case errors.Is(err, core.ErrUnauthorized):
+		respondError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, core.ErrInvalidTransition), errors.Is(err, core.ErrClosureBlocked),
+		err == core.ErrAlreadyReversed:
+		respondError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, core.ErrNotReversible), errors.Is(err, core.ErrChannelNotAllowed),
+		errors.Is(err, core.ErrLimitExceeded):
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+	case err == core.ErrInsufficientFunds, isAccountStatusError(err), err == core.ErrCoolingLimit:
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, core.ErrInvalidInput):
+		respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+type BeneficiaryHandler struct {
+	service *core.BeneficiaryService
+	stepUp  *core.StepUpService
+}
+
+func NewBeneficiaryHandler(service *core.BeneficiaryService, stepUp *core.StepUpService) *BeneficiaryHandler {
+	return &BeneficiaryHandler{service: service, stepUp: stepUp}
+}
+
+type StepUpRequest struct {
+	Purpose string `json:"purpose"`
+}
+
+func (h *BeneficiaryHandler) IssueStepUp(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	customerID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid customer ID")
+		return
+	}
+
+	var req StepUpRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	challenge, err := h.stepUp.IssueChallenge(r.Context(), customerID, req.Purpose)
+	if err != nil {
+		if err == core.ErrInvalidInput {
+			respondError(w, http.StatusBadRequest, "Unknown step-up purpose")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, challenge)
+}
+
+func (h *BeneficiaryHandler) AddBeneficiary(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	customerID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid customer ID")
+		return
+	}
+
+	challengeID, code, ok := stepUpCredentials(r)
+	if !ok {
+		respondError(w, http.StatusUnauthorized, core.ErrStepUpRequired.Error())
+		return
+	}
+
+	var b core.Beneficiary
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	b.CustomerID = customerID
+
+	if err := h.service.AddBeneficiary(r.Context(), &b, challengeID, code); err != nil {
+		respondBeneficiaryError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, b)
+}
+
+func (h *BeneficiaryHandler) ListBeneficiaries(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	customerID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid customer ID")
+		return
+	}
+
+	beneficiaries, err := h.service.ListBeneficiaries(r.Context(), customerID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, beneficiaries)
+}
+
+func (h *BeneficiaryHandler) DeleteBeneficiary(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	customerID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid customer ID")
+		return
+	}
+
+	beneficiaryID, err := uuid.Parse(vars["beneficiary_id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid beneficiary ID")
+		return
+	}
+
+	challengeID, code, ok := stepUpCredentials(r)
+	if !ok {
+		respondError(w, http.StatusUnauthorized, core.ErrStepUpRequired.Error())
+		return
+	}
+
+	if err := h.service.DeleteBeneficiary(r.Context(), customerID, beneficiaryID, challengeID, code); err != nil {
+		respondBeneficiaryError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{
+		"message": "Beneficiary deleted successfully",
+	})
+}
+
+// stepUpCredentials reads the redeemed challenge from the X-Step-Up-Challenge
+// and X-Step-Up-Code headers.
+func stepUpCredentials(r *http.Request) (uuid.UUID, string, bool) {
+	challengeID, err := uuid.Parse(r.Header.Get("X-Step-Up-Challenge"))
+	code := r.Header.Get("X-Step-Up-Code")
+	if err != nil || code == "" {
+		return uuid.Nil, "", false
+	}
+	return challengeID, code, true
+}
+
+func respondBeneficiaryError(w http.ResponseWriter, err error) {
+	switch {
+	case err == core.ErrStepUpRequired:
+		respondError(w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, core.ErrNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+	case isAccountStatusError(err):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case err == core.ErrDuplicateEntry:
+		respondError(w, http.StatusConflict, "Beneficiary already registered")
+	case err == core.ErrInvalidInput:
+		respondError(w, http.StatusBadRequest, "Nickname, account number, payee_name and type are required; external beneficiaries also need bank_code")
+	default:
+		respondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
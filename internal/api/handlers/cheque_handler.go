@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+type ChequeHandler struct {
+	service *core.ChequeService
+}
+
+func NewChequeHandler(service *core.ChequeService) *ChequeHandler {
+	return &ChequeHandler{service: service}
+}
+
+type IssueChequeBookRequest struct {
+	Leaves int `json:"leaves"`
+}
+
+func (h *ChequeHandler) IssueChequeBook(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	accountID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+
+	var req IssueChequeBookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	book, err := h.service.IssueChequeBook(r.Context(), accountID, req.Leaves)
+	if err != nil {
+		if err == core.ErrNotFound {
+			respondError(w, http.StatusNotFound, "Account not found")
+			return
+		}
+		if isAccountStatusError(err) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, core.ErrInvalidInput) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, book)
+}
+
+func (h *ChequeHandler) ListChequeBooks(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	accountID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+
+	books, err := h.service.ListChequeBooks(r.Context(), accountID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, books)
+}
+
+type StopPaymentRequest struct {
+	Reason *string `json:"reason"`
+}
+
+func (h *ChequeHandler) StopPayment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	accountID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+
+	chequeNumber, err := strconv.ParseInt(vars["number"], 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid cheque number")
+		return
+	}
+
+	var req StopPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	stop := core.ChequeStopPayment{
+		AccountID:    accountID,
+		ChequeNumber: chequeNumber,
+		Reason:       req.Reason,
+	}
+
+	if err := h.service.StopPayment(r.Context(), &stop); err != nil {
+		if err == core.ErrNotFound {
+			respondError(w, http.StatusNotFound, "Cheque leaf not issued to this account")
+			return
+		}
+		if err == core.ErrDuplicateEntry {
+			respondError(w, http.StatusConflict, "Stop payment already in place")
+			return
+		}
+		if errors.Is(err, core.ErrInvalidInput) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, stop)
+}
+
+type PresentChequeRequest struct {
+	AccountID         uuid.UUID `json:"account_id"`
+	ChequeNumber      int64     `json:"cheque_number"`
+	Amount            float64   `json:"amount"`
+	PayeeName         string    `json:"payee_name"`
+	ChequeDate        string    `json:"cheque_date"`
+	SignatureVerified bool      `json:"signature_verified"`
+}
+
+// PresentCheque handles inward clearing. A returned cheque is still a
+// successful clearing outcome, so both PAID and RETURNED respond with 200.
+func (h *ChequeHandler) PresentCheque(w http.ResponseWriter, r *http.Request) {
+	var req PresentChequeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	chequeDate, err := time.Parse("2006-01-02", req.ChequeDate)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid cheque date, expected YYYY-MM-DD")
+		return
+	}
+
+	p := core.ChequePresentment{
+		AccountID:         req.AccountID,
+		ChequeNumber:      req.ChequeNumber,
+		Amount:            req.Amount,
+		PayeeName:         req.PayeeName,
+		ChequeDate:        chequeDate,
+		SignatureVerified: req.SignatureVerified,
+	}
+
+	if err := h.service.PresentCheque(r.Context(), &p); err != nil {
+		if err == core.ErrNotFound {
+			respondError(w, http.StatusNotFound, "Cheque leaf not issued to this account")
+			return
+		}
+		if err == core.ErrDuplicateEntry {
+			respondError(w, http.StatusConflict, "Cheque already paid")
+			return
+		}
+		if err == core.ErrInvalidInput {
+			respondError(w, http.StatusBadRequest, "Amount must be positive")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, p)
+}
+
+func (h *ChequeHandler) ListPresentments(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	accountID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	if limit == 0 {
+		limit = 20
+	}
+
+	presentments, err := h.service.ListPresentments(r.Context(), accountID, limit, offset)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, presentments)
+}
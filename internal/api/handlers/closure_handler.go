@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+type ClosureHandler struct {
+	service   *core.ClosureService
+	approvals *core.ApprovalService
+}
+
+func NewClosureHandler(service *core.ClosureService, approvals *core.ApprovalService) *ClosureHandler {
+	return &ClosureHandler{service: service, approvals: approvals}
+}
+
+// CloseAccount closes an account, paying out its balance as the request
+// nominates. The body is optional for an account with nothing to pay out.
+// When closures need approval the account is closed once a second user
+// approves the request.
+func (h *ClosureHandler) CloseAccount(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	var req core.ClosureRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	if h.approvals.Required(core.OpAccountClosure, 0) {
+		submitApproval(w, r, h.approvals, core.OpAccountClosure, id, nil, req, req.Reason)
+		return
+	}
+
+	closure, err := h.service.CloseAccount(r.Context(), id, &req, actorID(r))
+	if err != nil {
+		switch {
+		case err == core.ErrInvalidInput:
+			respondError(w, http.StatusBadRequest, "X-User-ID is required")
+		case err == core.ErrNotFound:
+			respondError(w, http.StatusNotFound, "Account not found")
+		case errors.Is(err, core.ErrInvalidInput), errors.Is(err, core.ErrNotFound):
+			respondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, core.ErrUnauthorized):
+			respondError(w, http.StatusForbidden, "Teller session belongs to another teller")
+		case errors.Is(err, core.ErrInvalidTransition), errors.Is(err, core.ErrClosureBlocked):
+			respondError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, core.ErrInsufficientFunds), isAccountStatusError(err):
+			respondError(w, http.StatusUnprocessableEntity, err.Error())
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusOK, closure)
+}
+
+func (h *ClosureHandler) GetClosure(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	closure, err := h.service.GetClosure(r.Context(), id)
+	if err != nil {
+		if err == core.ErrNotFound {
+			respondError(w, http.StatusNotFound, "Account is not closed")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, closure)
+}
+
+func (h *ClosureHandler) GetCertificate(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := h.service.GetCertificate(r.Context(), id)
+	if err != nil {
+		if err == core.ErrNotFound {
+			respondError(w, http.StatusNotFound, "Account is not closed")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", "inline; filename=\"closure-certificate.pdf\"")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
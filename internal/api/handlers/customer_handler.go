@@ -11,11 +11,12 @@ import (
 )
 
 type CustomerHandler struct {
-	service *core.CustomerService
+	service   *core.CustomerService
+	approvals *core.ApprovalService
 }
 
-func NewCustomerHandler(service *core.CustomerService) *CustomerHandler {
-	return &CustomerHandler{service: service}
+func NewCustomerHandler(service *core.CustomerService, approvals *core.ApprovalService) *CustomerHandler {
+	return &CustomerHandler{service: service, approvals: approvals}
 }
 
 func (h *CustomerHandler) CreateCustomer(w http.ResponseWriter, r *http.Request) {
@@ -28,6 +29,9 @@ func (h *CustomerHandler) CreateCustomer(w http.ResponseWriter, r *http.Request)
 	if customer.KYCStatus == "" {
 		customer.KYCStatus = "PENDING"
 	}
+	if customer.Segment == "" {
+		customer.Segment = "STANDARD"
+	}
 
 	if err := h.service.CreateCustomer(r.Context(), &customer); err != nil {
 		respondError(w, http.StatusInternalServerError, err.Error())
@@ -94,3 +98,34 @@ func (h *CustomerHandler) ListCustomers(w http.ResponseWriter, r *http.Request)
 
 	respondJSON(w, http.StatusOK, customers)
 }
+
+// VerifyKYC records that the customer's KYC was verified by the user in
+// X-User-ID. As a manual override of KYC it may need a second user's
+// approval first.
+func (h *CustomerHandler) VerifyKYC(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid customer ID")
+		return
+	}
+
+	if h.approvals.Required(core.OpKYCOverride, 0) {
+		submitApproval(w, r, h.approvals, core.OpKYCOverride, id, nil, nil, "")
+		return
+	}
+
+	customer, err := h.service.VerifyKYC(r.Context(), id, actorID(r))
+	if err != nil {
+		switch err {
+		case core.ErrInvalidInput:
+			respondError(w, http.StatusBadRequest, "X-User-ID is required")
+		case core.ErrNotFound:
+			respondError(w, http.StatusNotFound, "Customer not found")
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusOK, customer)
+}
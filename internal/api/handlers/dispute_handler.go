@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+// maxAttachmentSize bounds uploaded dispute evidence.
+const maxAttachmentSize = 5 << 20
+
+type DisputeHandler struct {
+	service *core.DisputeService
+}
+
+func NewDisputeHandler(service *core.DisputeService) *DisputeHandler {
+	return &DisputeHandler{service: service}
+}
+
+type OpenDisputeRequest struct {
+	TxnID       uuid.UUID `json:"txn_id"`
+	ReasonCode  string    `json:"reason_code"`
+	Amount      float64   `json:"amount"`
+	Description *string   `json:"description,omitempty"`
+}
+
+func (h *DisputeHandler) OpenDispute(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	var req OpenDisputeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	d := core.Dispute{
+		AccountID:   accountID,
+		TxnID:       req.TxnID,
+		ReasonCode:  req.ReasonCode,
+		Amount:      req.Amount,
+		Description: req.Description,
+	}
+	if err := h.service.Open(r.Context(), &d); err != nil {
+		switch {
+		case err == core.ErrNotFound:
+			respondError(w, http.StatusNotFound, "Transaction not found on this account")
+		case err == core.ErrDuplicateEntry:
+			respondError(w, http.StatusConflict, "The transaction already has an open dispute")
+		case err == core.ErrAlreadyReversed, errors.Is(err, core.ErrDuplicateEntry):
+			respondError(w, http.StatusConflict, err.Error())
+		case err == core.ErrInvalidInput:
+			respondError(w, http.StatusBadRequest, "Reason code must be one of UNAUTHORISED, DUPLICATE, NOT_RECEIVED, "+
+				"INCORRECT_AMOUNT, CANCELLED or OTHER, and the amount must not be negative")
+		case errors.Is(err, core.ErrInvalidInput):
+			respondError(w, http.StatusBadRequest, err.Error())
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, d)
+}
+
+func (h *DisputeHandler) ListAccountDisputes(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	disputes, err := h.service.ListByAccount(r.Context(), accountID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, disputes)
+}
+
+func (h *DisputeHandler) GetDispute(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseDisputeID(w, r)
+	if !ok {
+		return
+	}
+
+	d, err := h.service.GetDispute(r.Context(), id)
+	if err != nil {
+		respondDisputeError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, d)
+}
+
+type DisputeNoteRequest struct {
+	Body string `json:"body"`
+}
+
+func (h *DisputeHandler) AddNote(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseDisputeID(w, r)
+	if !ok {
+		return
+	}
+
+	var req DisputeNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	n, err := h.service.AddNote(r.Context(), id, actorID(r), req.Body)
+	if err != nil {
+		if err == core.ErrInvalidInput {
+			respondError(w, http.StatusBadRequest, "A note needs a body and an author in X-User-ID")
+			return
+		}
+		respondDisputeError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, n)
+}
+
+// AddAttachment takes the evidence file as the raw request body, named by
+// the name query parameter.
+func (h *DisputeHandler) AddAttachment(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseDisputeID(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxAttachmentSize))
+	if err != nil {
+		respondError(w, http.StatusRequestEntityTooLarge, "Attachment is too large")
+		return
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mediaType
+	}
+	a := core.DisputeAttachment{
+		DisputeID:   id,
+		FileName:    r.URL.Query().Get("name"),
+		ContentType: contentType,
+		UploadedBy:  actorID(r),
+	}
+	if err := h.service.AddAttachment(r.Context(), &a, data); err != nil {
+		if err == core.ErrInvalidInput {
+			respondError(w, http.StatusBadRequest, "An attachment needs a non-empty body, a name query parameter "+
+				"and an uploader in X-User-ID")
+			return
+		}
+		respondDisputeError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, a)
+}
+
+func (h *DisputeHandler) GetAttachment(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseDisputeID(w, r)
+	if !ok {
+		return
+	}
+	attachmentID, err := uuid.Parse(mux.Vars(r)["attachment_id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid attachment ID")
+		return
+	}
+
+	a, data, err := h.service.GetAttachment(r.Context(), id, attachmentID)
+	if err != nil {
+		if err == core.ErrNotFound {
+			respondError(w, http.StatusNotFound, "Attachment not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.FileName}))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// ListDisputes is the operations queue, filtered by status and, with
+// overdue=true, to disputes past a deadline.
+func (h *DisputeHandler) ListDisputes(w http.ResponseWriter, r *http.Request) {
+	disputes, err := h.service.List(r.Context(), optionalQuery(r, "status"), r.URL.Query().Get("overdue") == "true")
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, disputes)
+}
+
+func (h *DisputeHandler) GrantProvisionalCredit(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseDisputeID(w, r)
+	if !ok {
+		return
+	}
+
+	d, err := h.service.GrantProvisionalCredit(r.Context(), id, actorID(r))
+	if err != nil {
+		respondDisputeError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, d)
+}
+
+func (h *DisputeHandler) StartInvestigation(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseDisputeID(w, r)
+	if !ok {
+		return
+	}
+
+	d, err := h.service.StartInvestigation(r.Context(), id, actorID(r))
+	if err != nil {
+		respondDisputeError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, d)
+}
+
+type ResolveDisputeRequest struct {
+	// Outcome is CUSTOMER or BANK: whose favour the dispute is resolved in.
+	Outcome    string `json:"outcome"`
+	Resolution string `json:"resolution"`
+}
+
+func (h *DisputeHandler) ResolveDispute(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseDisputeID(w, r)
+	if !ok {
+		return
+	}
+
+	var req ResolveDisputeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Outcome != "CUSTOMER" && req.Outcome != "BANK" {
+		respondError(w, http.StatusBadRequest, "Outcome must be CUSTOMER or BANK")
+		return
+	}
+
+	d, err := h.service.Resolve(r.Context(), id, req.Outcome == "CUSTOMER", req.Resolution, actorID(r))
+	if err != nil {
+		if err == core.ErrInvalidInput {
+			respondError(w, http.StatusBadRequest, "A resolution is required")
+			return
+		}
+		respondDisputeError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, d)
+}
+
+func parseDisputeID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["dispute_id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid dispute ID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func respondDisputeError(w http.ResponseWriter, err error) {
+	switch {
+	case err == core.ErrNotFound:
+		respondError(w, http.StatusNotFound, "Dispute not found")
+	case isAccountStatusError(err):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, core.ErrInvalidInput):
+		respondError(w, http.StatusConflict, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
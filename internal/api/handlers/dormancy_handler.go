@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+type DormancyHandler struct {
+	service *core.DormancyService
+}
+
+func NewDormancyHandler(service *core.DormancyService) *DormancyHandler {
+	return &DormancyHandler{service: service}
+}
+
+// DormantBalances returns the dormant accounts and balances of each branch.
+func (h *DormancyHandler) DormantBalances(w http.ResponseWriter, r *http.Request) {
+	report, err := h.service.DormantBalances(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, report)
+}
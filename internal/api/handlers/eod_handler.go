@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+type EODHandler struct {
+	service *core.EODService
+
+	mu      sync.Mutex
+	running bool
+}
+
+func NewEODHandler(service *core.EODService) *EODHandler {
+	return &EODHandler{service: service}
+}
+
+// TriggerRun starts the EOD run in the background; its progress is reported by
+// GetStatus. The advisory lock in the service still guards against runs from
+// other instances or the command line.
+func (h *EODHandler) TriggerRun(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.running {
+		respondError(w, http.StatusConflict, core.ErrEODRunning.Error())
+		return
+	}
+
+	businessDate, err := h.service.BusinessDate(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.running = true
+	go func() {
+		defer func() {
+			h.mu.Lock()
+			h.running = false
+			h.mu.Unlock()
+		}()
+		if _, err := h.service.Run(context.Background()); err != nil {
+			log.Printf("EOD run failed: %v", err)
+		}
+	}()
+
+	respondJSON(w, http.StatusAccepted, map[string]interface{}{
+		"message":       "End-of-day run started",
+		"business_date": businessDate.Format("2006-01-02"),
+	})
+}
+
+func (h *EODHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	businessDate, err := h.service.BusinessDate(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	run, err := h.service.LatestRun(r.Context())
+	if err != nil && err != core.ErrNotFound {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"business_date": businessDate.Format("2006-01-02"),
+		"last_run":      run,
+	})
+}
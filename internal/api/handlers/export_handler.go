@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+	"github.com/shubhbham/BankingApi_Golang/internal/export"
+)
+
+type ExportHandler struct {
+	service  *core.TransactionService
+	currency string
+}
+
+func NewExportHandler(service *core.TransactionService, currency string) *ExportHandler {
+	return &ExportHandler{service: service, currency: currency}
+}
+
+// WantsExport matches transaction list requests that ask for a file format,
+// either with format= or through the Accept header. Everything else gets the
+// JSON list.
+func WantsExport(r *http.Request, rm *mux.RouteMatch) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return !strings.EqualFold(format, "json")
+	}
+	_, ok := export.Negotiate(r.Header.Get("Accept"))
+	return ok
+}
+
+func (h *ExportHandler) ExportTransactions(w http.ResponseWriter, r *http.Request) {
+	accountID, err := uuid.Parse(mux.Vars(r)["account_id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+
+	format, ok := export.Negotiate(r.Header.Get("Accept"))
+	if name := r.URL.Query().Get("format"); name != "" {
+		format, ok = export.Lookup(name)
+	}
+	if !ok {
+		respondError(w, http.StatusBadRequest, "Format must be csv, ofx, qif, mt940 or json")
+		return
+	}
+
+	from, ok := parseOptionalDate(w, optionalQuery(r, "from"))
+	if !ok {
+		return
+	}
+	to, ok := parseOptionalDate(w, optionalQuery(r, "to"))
+	if !ok {
+		return
+	}
+	var fromDate, toDate time.Time
+	if from != nil {
+		fromDate = *from
+	}
+	if to != nil {
+		toDate = *to
+	}
+
+	// Long histories outlive the server's write timeout.
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	sw := &streamWriter{ResponseWriter: w}
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf("attachment; filename=\"transactions-%s.%s\"", accountID, format.Extension))
+
+	err = h.service.StreamStatement(r.Context(), accountID, fromDate, toDate, format.New(sw, h.currency))
+	if err == nil {
+		return
+	}
+	if sw.started {
+		// The status line has gone out; all we can do is cut the stream short.
+		log.Printf("export of account %s aborted: %v", accountID, err)
+		return
+	}
+
+	w.Header().Del("Content-Disposition")
+	switch err {
+	case core.ErrNotFound:
+		respondError(w, http.StatusNotFound, "Account not found")
+	case core.ErrInvalidInput:
+		respondError(w, http.StatusBadRequest, "The from date must not be after the to date")
+	default:
+		respondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func optionalQuery(r *http.Request, key string) *string {
+	if v := r.URL.Query().Get(key); v != "" {
+		return &v
+	}
+	return nil
+}
+
+// streamWriter records whether any of the body has been written, so errors
+// before the first byte can still be reported with a proper status.
+type streamWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	s.started = true
+	return s.ResponseWriter.Write(p)
+}
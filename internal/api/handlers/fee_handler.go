@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+type FeeHandler struct {
+	service *core.FeeService
+}
+
+func NewFeeHandler(service *core.FeeService) *FeeHandler {
+	return &FeeHandler{service: service}
+}
+
+// GetPriceBook returns the fee schedules and tax the bank charges from.
+func (h *FeeHandler) GetPriceBook(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, h.service.PriceBook())
+}
+
+// ListCharges returns the periodic fees charged to an account. Transaction
+// fees appear in the account's transactions with channel FEE.
+func (h *FeeHandler) ListCharges(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	charges, err := h.service.ListCharges(r.Context(), accountID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, charges)
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+type InterbankHandler struct {
+	service       *core.InterbankService
+	beneficiaries *core.BeneficiaryService
+}
+
+func NewInterbankHandler(service *core.InterbankService, beneficiaries *core.BeneficiaryService) *InterbankHandler {
+	return &InterbankHandler{service: service, beneficiaries: beneficiaries}
+}
+
+type InterbankPaymentRequest struct {
+	BeneficiaryID *uuid.UUID `json:"beneficiary_id,omitempty"`
+	BankCode      string     `json:"bank_code"`
+	AccountNumber string     `json:"account_number"`
+	PayeeName     string     `json:"payee_name"`
+	Amount        float64    `json:"amount"`
+	Urgency       string     `json:"urgency"`
+	Description   *string    `json:"description,omitempty"`
+}
+
+func (h *InterbankHandler) SubmitPayment(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	var req InterbankPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	p := core.InterbankPayment{
+		AccountID:     accountID,
+		BeneficiaryID: req.BeneficiaryID,
+		BankCode:      req.BankCode,
+		AccountNumber: req.AccountNumber,
+		PayeeName:     req.PayeeName,
+		Amount:        req.Amount,
+		Urgency:       req.Urgency,
+		Description:   req.Description,
+	}
+
+	if req.BeneficiaryID != nil {
+		b, ok := checkBeneficiary(w, r, h.beneficiaries, accountID, *req.BeneficiaryID, req.Amount)
+		if !ok {
+			return
+		}
+		if b.BankCode == nil {
+			respondError(w, http.StatusBadRequest, "Internal beneficiaries are paid by transfer, not interbank payment")
+			return
+		}
+		p.BankCode, p.AccountNumber, p.PayeeName = *b.BankCode, b.AccountNumber, b.PayeeName
+	}
+
+	if err := h.service.Submit(r.Context(), &p); err != nil {
+		switch {
+		case err == core.ErrNotFound:
+			respondError(w, http.StatusNotFound, "Account not found")
+		case isAccountStatusError(err), err == core.ErrInsufficientFunds, err == core.ErrCoolingLimit:
+			respondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, core.ErrChannelNotAllowed), errors.Is(err, core.ErrLimitExceeded):
+			respondError(w, http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, core.ErrApprovalRequired):
+			respondError(w, http.StatusForbidden, err.Error())
+		case err == core.ErrInvalidInput:
+			respondError(w, http.StatusBadRequest, "Amount must be positive and urgency NORMAL or URGENT; "+
+				"the payee needs a bank code, an account number of up to 34 characters and a name")
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, p)
+}
+
+func (h *InterbankHandler) ListPayments(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	payments, err := h.service.ListPaymentsByAccount(r.Context(), accountID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, payments)
+}
+
+func (h *InterbankHandler) GetPayment(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid payment ID")
+		return
+	}
+
+	p, err := h.service.GetPayment(r.Context(), id)
+	if err != nil {
+		if err == core.ErrNotFound {
+			respondError(w, http.StatusNotFound, "Interbank payment not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, p)
+}
+
+// SettleNow clears waiting payments without waiting for the next DNS cycle.
+func (h *InterbankHandler) SettleNow(w http.ResponseWriter, r *http.Request) {
+	b, err := h.service.SettleNow(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if b == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, b)
+}
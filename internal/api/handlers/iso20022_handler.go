@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+	"github.com/shubhbham/BankingApi_Golang/internal/iso20022"
+)
+
+type ISO20022Handler struct {
+	reporter *iso20022.Reporter
+}
+
+func NewISO20022Handler(reporter *iso20022.Reporter) *ISO20022Handler {
+	return &ISO20022Handler{reporter: reporter}
+}
+
+func (h *ISO20022Handler) GetCamt053(w http.ResponseWriter, r *http.Request) {
+	h.respondReport(w, r, h.reporter.Statement)
+}
+
+func (h *ISO20022Handler) GetCamt052(w http.ResponseWriter, r *http.Request) {
+	h.respondReport(w, r, h.reporter.IntradayReport)
+}
+
+func (h *ISO20022Handler) respondReport(w http.ResponseWriter, r *http.Request,
+	render func(ctx context.Context, accountID uuid.UUID, from, to time.Time) ([]byte, error)) {
+	accountID, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	from, err := time.Parse("2006-01-02", r.URL.Query().Get("from"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid from date, expected YYYY-MM-DD")
+		return
+	}
+	to, err := time.Parse("2006-01-02", r.URL.Query().Get("to"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid to date, expected YYYY-MM-DD")
+		return
+	}
+
+	doc, err := render(r.Context(), accountID, from, to)
+	if err != nil {
+		switch err {
+		case core.ErrNotFound:
+			respondError(w, http.StatusNotFound, "Account not found")
+		case core.ErrInvalidInput:
+			respondError(w, http.StatusBadRequest, "The from date must not be after the to date")
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	w.Write(doc)
+}
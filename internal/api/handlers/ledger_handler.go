@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+type LedgerHandler struct {
+	service *core.LedgerService
+}
+
+func NewLedgerHandler(service *core.LedgerService) *LedgerHandler {
+	return &LedgerHandler{service: service}
+}
+
+func (h *LedgerHandler) GetGLAccount(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	gl, err := h.service.GetGLAccount(r.Context(), vars["code"])
+	if err != nil {
+		if err == core.ErrNotFound {
+			respondError(w, http.StatusNotFound, "GL account not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, gl)
+}
+
+func (h *LedgerHandler) ListEntries(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	if limit == 0 {
+		limit = 50
+	}
+
+	entries, err := h.service.ListEntries(r.Context(), vars["code"], limit, offset)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, entries)
+}
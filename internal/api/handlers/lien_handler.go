@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+type LienHandler struct {
+	service   *core.LienService
+	approvals *core.ApprovalService
+}
+
+func NewLienHandler(service *core.LienService, approvals *core.ApprovalService) *LienHandler {
+	return &LienHandler{service: service, approvals: approvals}
+}
+
+type LienRequest struct {
+	LienType  string   `json:"lien_type"`
+	Amount    *float64 `json:"amount,omitempty"`
+	Reference string   `json:"reference"`
+	Authority string   `json:"authority"`
+	ExpiresOn *string  `json:"expires_on,omitempty"`
+}
+
+func (h *LienHandler) PlaceLien(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	var req LienRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	expiresOn, ok := parseOptionalDate(w, req.ExpiresOn)
+	if !ok {
+		return
+	}
+
+	l := core.Lien{
+		AccountID: accountID,
+		LienType:  req.LienType,
+		Amount:    req.Amount,
+		Reference: req.Reference,
+		Authority: req.Authority,
+		ExpiresOn: expiresOn,
+	}
+	if err := h.service.PlaceLien(r.Context(), &l, actorID(r)); err != nil {
+		respondLienError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, l)
+}
+
+func (h *LienHandler) ListLiens(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	liens, err := h.service.ListLiens(r.Context(), accountID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, liens)
+}
+
+// AvailableBalance returns the account balance with liened funds set apart.
+func (h *LienHandler) AvailableBalance(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	balance, err := h.service.AvailableBalance(r.Context(), accountID)
+	if err != nil {
+		respondLienError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, balance)
+}
+
+func (h *LienHandler) GetLien(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseLienID(w, r)
+	if !ok {
+		return
+	}
+
+	l, err := h.service.GetLien(r.Context(), id)
+	if err != nil {
+		respondLienError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, l)
+}
+
+type LienReleaseRequest struct {
+	Reason string `json:"reason"`
+}
+
+// RequestRelease asks for a lien to be released. The lien keeps holding
+// funds until a second user approves the request through the approvals
+// endpoints.
+func (h *LienHandler) RequestRelease(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseLienID(w, r)
+	if !ok {
+		return
+	}
+
+	var req LienReleaseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Reason == "" {
+		respondError(w, http.StatusBadRequest, "A reason is required")
+		return
+	}
+
+	submitApproval(w, r, h.approvals, core.OpLienRelease, id, nil,
+		core.LienReleaseApproval{Reason: req.Reason}, req.Reason)
+}
+
+func parseLienID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["lien_id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid lien ID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func respondLienError(w http.ResponseWriter, err error) {
+	switch {
+	case err == core.ErrNotFound:
+		respondError(w, http.StatusNotFound, "Not found")
+	case err == core.ErrInvalidInput:
+		respondError(w, http.StatusBadRequest, "X-User-ID is required")
+	case errors.Is(err, core.ErrInvalidInput):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case err == core.ErrAccountClosed:
+		respondError(w, http.StatusConflict, "Account is closed")
+	default:
+		respondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
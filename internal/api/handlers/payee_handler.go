@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+type PayeeHandler struct {
+	service *core.PayeeService
+}
+
+func NewPayeeHandler(service *core.PayeeService) *PayeeHandler {
+	return &PayeeHandler{service: service}
+}
+
+type PayeeCheckRequest struct {
+	AccountNumber string `json:"account_number"`
+	PayeeName     string `json:"payee_name"`
+}
+
+func (h *PayeeHandler) CheckPayee(w http.ResponseWriter, r *http.Request) {
+	var req PayeeCheckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.AccountNumber == "" {
+		respondError(w, http.StatusBadRequest, "Account number is required")
+		return
+	}
+
+	result, err := h.service.CheckByAccountNumber(r.Context(), req.AccountNumber, req.PayeeName)
+	if err != nil {
+		if err == core.ErrInvalidInput {
+			respondError(w, http.StatusBadRequest, "Payee name is required")
+			return
+		}
+		if err == core.ErrNotFound {
+			respondError(w, http.StatusNotFound, "Account not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+	"github.com/shubhbham/BankingApi_Golang/internal/iso20022"
+)
+
+// maxPaymentFileSize bounds uploaded payment files.
+const maxPaymentFileSize = 10 << 20
+
+type PaymentFileHandler struct {
+	service     *core.BulkPaymentService
+	defaultMode string
+}
+
+func NewPaymentFileHandler(service *core.BulkPaymentService, defaultMode string) *PaymentFileHandler {
+	return &PaymentFileHandler{service: service, defaultMode: defaultMode}
+}
+
+// UploadFile takes a pain.001 document as the request body, executes it and
+// answers with the pain.002 status report. The execution query parameter
+// overrides the configured mode.
+func (h *PaymentFileHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
+	customerID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid customer ID")
+		return
+	}
+
+	mode := h.defaultMode
+	if v := r.URL.Query().Get("execution"); v != "" {
+		mode = strings.ToUpper(v)
+	}
+	if mode != core.BulkExecutionAtomic && mode != core.BulkExecutionBestEffort {
+		respondError(w, http.StatusBadRequest, "Execution must be ATOMIC or BEST_EFFORT")
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPaymentFileSize))
+	if err != nil {
+		respondError(w, http.StatusRequestEntityTooLarge, "Payment file is too large")
+		return
+	}
+
+	f, err := iso20022.ParsePain001(data)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid pain.001 file: "+err.Error())
+		return
+	}
+	f.Mode = mode
+
+	if err := h.service.Process(r.Context(), customerID, f); err != nil {
+		respondPaymentFileError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", r.URL.Path+"/"+f.FileID.String())
+	h.respondStatusReport(w, http.StatusCreated, f)
+}
+
+func (h *PaymentFileHandler) GetFile(w http.ResponseWriter, r *http.Request) {
+	f, ok := h.getFile(w, r)
+	if !ok {
+		return
+	}
+	respondJSON(w, http.StatusOK, f)
+}
+
+func (h *PaymentFileHandler) GetStatusReport(w http.ResponseWriter, r *http.Request) {
+	f, ok := h.getFile(w, r)
+	if !ok {
+		return
+	}
+	h.respondStatusReport(w, http.StatusOK, f)
+}
+
+func (h *PaymentFileHandler) getFile(w http.ResponseWriter, r *http.Request) (*core.BulkPaymentFile, bool) {
+	vars := mux.Vars(r)
+	customerID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid customer ID")
+		return nil, false
+	}
+	fileID, err := uuid.Parse(vars["file_id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid payment file ID")
+		return nil, false
+	}
+
+	f, err := h.service.GetFile(r.Context(), customerID, fileID)
+	if err != nil {
+		respondPaymentFileError(w, err)
+		return nil, false
+	}
+	return f, true
+}
+
+func (h *PaymentFileHandler) respondStatusReport(w http.ResponseWriter, status int, f *core.BulkPaymentFile) {
+	doc, err := iso20022.StatusReport(f)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("X-Payment-File-ID", f.FileID.String())
+	w.WriteHeader(status)
+	w.Write(doc)
+}
+
+func respondPaymentFileError(w http.ResponseWriter, err error) {
+	switch err {
+	case core.ErrNotFound:
+		respondError(w, http.StatusNotFound, "Payment file not found")
+	case core.ErrDuplicateEntry:
+		respondError(w, http.StatusConflict, "A payment file with this message ID was already submitted")
+	case core.ErrInvalidInput:
+		respondError(w, http.StatusBadRequest, "Invalid payment file")
+	default:
+		respondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+type ProductHandler struct {
+	service *core.ProductService
+}
+
+func NewProductHandler(service *core.ProductService) *ProductHandler {
+	return &ProductHandler{service: service}
+}
+
+func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
+	var p core.Product
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.service.CreateProduct(r.Context(), &p, actorID(r)); err != nil {
+		respondProductError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, p)
+}
+
+// PublishVersion takes the complete new terms of a product and publishes
+// them as its next version.
+func (h *ProductHandler) PublishVersion(w http.ResponseWriter, r *http.Request) {
+	var p core.Product
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.service.PublishVersion(r.Context(), mux.Vars(r)["code"], &p, actorID(r)); err != nil {
+		respondProductError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, p)
+}
+
+func (h *ProductHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
+	products, err := h.service.ListProducts(r.Context(), r.URL.Query().Get("include_retired") == "true")
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, products)
+}
+
+func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
+	p, err := h.service.GetProduct(r.Context(), mux.Vars(r)["code"])
+	if err != nil {
+		respondProductError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, p)
+}
+
+func (h *ProductHandler) GetProductVersion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	version, err := strconv.Atoi(vars["version"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid product version")
+		return
+	}
+
+	p, err := h.service.GetProductVersion(r.Context(), vars["code"], version)
+	if err != nil {
+		respondProductError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, p)
+}
+
+func respondProductError(w http.ResponseWriter, err error) {
+	switch {
+	case err == core.ErrNotFound:
+		respondError(w, http.StatusNotFound, "Product not found")
+	case err == core.ErrDuplicateEntry:
+		respondError(w, http.StatusConflict, "Product already exists; publish a new version instead")
+	case errors.Is(err, core.ErrInvalidInput):
+		respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
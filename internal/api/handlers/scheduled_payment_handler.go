@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+type ScheduledPaymentHandler struct {
+	service       *core.ScheduledPaymentService
+	beneficiaries *core.BeneficiaryService
+}
+
+func NewScheduledPaymentHandler(service *core.ScheduledPaymentService, beneficiaries *core.BeneficiaryService) *ScheduledPaymentHandler {
+	return &ScheduledPaymentHandler{service: service, beneficiaries: beneficiaries}
+}
+
+type ScheduledPaymentRequest struct {
+	FromAccountID uuid.UUID  `json:"from_account_id"`
+	ToAccountID   uuid.UUID  `json:"to_account_id"`
+	BeneficiaryID *uuid.UUID `json:"beneficiary_id,omitempty"`
+	Amount        float64    `json:"amount"`
+	Description   string     `json:"description"`
+	ExecuteOn     string     `json:"execute_on"`
+}
+
+func (h *ScheduledPaymentHandler) CreatePayment(w http.ResponseWriter, r *http.Request) {
+	var req ScheduledPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	executeOn, err := time.Parse("2006-01-02", req.ExecuteOn)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid execution date, expected YYYY-MM-DD")
+		return
+	}
+
+	if req.BeneficiaryID != nil {
+		b, ok := resolveBeneficiary(w, r, h.beneficiaries, req.FromAccountID, *req.BeneficiaryID, req.Amount)
+		if !ok {
+			return
+		}
+		req.ToAccountID = *b.AccountID
+	}
+
+	p := core.ScheduledPayment{
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		BeneficiaryID: req.BeneficiaryID,
+		Amount:        req.Amount,
+		Description:   req.Description,
+		ExecuteOn:     executeOn,
+	}
+
+	if err := h.service.CreatePayment(r.Context(), &p); err != nil {
+		if err == core.ErrInvalidInput {
+			respondError(w, http.StatusBadRequest, "Amount must be positive, accounts must differ and the execution date must not be in the past")
+			return
+		}
+		if errors.Is(err, core.ErrApprovalRequired) {
+			respondError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, p)
+}
+
+func (h *ScheduledPaymentHandler) GetPayment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid payment ID")
+		return
+	}
+
+	payment, err := h.service.GetPayment(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Scheduled payment not found")
+		return
+	}
+
+	attempts, err := h.service.ListAttempts(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"payment":  payment,
+		"attempts": attempts,
+	})
+}
+
+func (h *ScheduledPaymentHandler) ListPaymentsByAccount(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	accountID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+
+	payments, err := h.service.ListPaymentsByAccount(r.Context(), accountID, r.URL.Query().Get("status"))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, payments)
+}
+
+type AmendPaymentRequest struct {
+	Amount      float64 `json:"amount"`
+	Description string  `json:"description"`
+	ExecuteOn   string  `json:"execute_on"`
+}
+
+func (h *ScheduledPaymentHandler) AmendPayment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid payment ID")
+		return
+	}
+
+	var req AmendPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	executeOn, err := time.Parse("2006-01-02", req.ExecuteOn)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid execution date, expected YYYY-MM-DD")
+		return
+	}
+
+	p := core.ScheduledPayment{
+		PaymentID:   id,
+		Amount:      req.Amount,
+		Description: req.Description,
+		ExecuteOn:   executeOn,
+	}
+
+	if err := h.service.AmendPayment(r.Context(), &p); err != nil {
+		if err == core.ErrInvalidInput {
+			respondError(w, http.StatusBadRequest, "Amount must be positive and the execution date must not be in the past")
+			return
+		}
+		if err == core.ErrNotFound {
+			respondError(w, http.StatusNotFound, "No pending, unattempted payment with this ID")
+			return
+		}
+		if err == core.ErrCoolingLimit {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, core.ErrApprovalRequired) {
+			respondError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	payment, err := h.service.GetPayment(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, payment)
+}
+
+func (h *ScheduledPaymentHandler) CancelPayment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid payment ID")
+		return
+	}
+
+	if err := h.service.CancelPayment(r.Context(), id); err != nil {
+		if err == core.ErrNotFound {
+			respondError(w, http.StatusNotFound, "No pending payment with this ID")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{
+		"message": "Scheduled payment cancelled",
+	})
+}
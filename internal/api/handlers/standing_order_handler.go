@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+type StandingOrderHandler struct {
+	service       *core.StandingOrderService
+	beneficiaries *core.BeneficiaryService
+}
+
+func NewStandingOrderHandler(service *core.StandingOrderService, beneficiaries *core.BeneficiaryService) *StandingOrderHandler {
+	return &StandingOrderHandler{service: service, beneficiaries: beneficiaries}
+}
+
+type StandingOrderRequest struct {
+	ToAccountID    uuid.UUID  `json:"to_account_id"`
+	BeneficiaryID  *uuid.UUID `json:"beneficiary_id,omitempty"`
+	Amount         float64    `json:"amount"`
+	Description    string     `json:"description"`
+	Frequency      string     `json:"frequency"`
+	IntervalDays   *int       `json:"interval_days,omitempty"`
+	NonBusinessDay string     `json:"non_business_day"`
+	StartDate      string     `json:"start_date"`
+	EndDate        *string    `json:"end_date,omitempty"`
+	MaxCount       *int       `json:"max_count,omitempty"`
+}
+
+func (h *StandingOrderHandler) CreateStandingOrder(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	var req StandingOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid start date, expected YYYY-MM-DD")
+		return
+	}
+
+	endDate, ok := parseOptionalDate(w, req.EndDate)
+	if !ok {
+		return
+	}
+
+	if req.BeneficiaryID != nil {
+		b, ok := resolveBeneficiary(w, r, h.beneficiaries, accountID, *req.BeneficiaryID, req.Amount)
+		if !ok {
+			return
+		}
+		req.ToAccountID = *b.AccountID
+	}
+
+	so := core.StandingOrder{
+		AccountID:      accountID,
+		ToAccountID:    req.ToAccountID,
+		BeneficiaryID:  req.BeneficiaryID,
+		Amount:         req.Amount,
+		Description:    req.Description,
+		Frequency:      req.Frequency,
+		IntervalDays:   req.IntervalDays,
+		NonBusinessDay: req.NonBusinessDay,
+		StartDate:      startDate,
+		EndDate:        endDate,
+		MaxCount:       req.MaxCount,
+	}
+
+	if err := h.service.CreateStandingOrder(r.Context(), &so); err != nil {
+		respondStandingOrderError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, so)
+}
+
+func (h *StandingOrderHandler) ListStandingOrders(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	orders, err := h.service.ListStandingOrders(r.Context(), accountID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, orders)
+}
+
+func (h *StandingOrderHandler) GetStandingOrder(w http.ResponseWriter, r *http.Request) {
+	accountID, id, ok := parseStandingOrderIDs(w, r)
+	if !ok {
+		return
+	}
+
+	so, err := h.service.GetStandingOrder(r.Context(), accountID, id)
+	if err != nil {
+		respondStandingOrderError(w, err)
+		return
+	}
+
+	executions, err := h.service.ListExecutions(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"standing_order": so,
+		"executions":     executions,
+	})
+}
+
+type UpdateStandingOrderRequest struct {
+	Amount      float64 `json:"amount"`
+	Description string  `json:"description"`
+	EndDate     *string `json:"end_date,omitempty"`
+	MaxCount    *int    `json:"max_count,omitempty"`
+}
+
+func (h *StandingOrderHandler) UpdateStandingOrder(w http.ResponseWriter, r *http.Request) {
+	accountID, id, ok := parseStandingOrderIDs(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateStandingOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	endDate, ok := parseOptionalDate(w, req.EndDate)
+	if !ok {
+		return
+	}
+
+	so, err := h.service.UpdateStandingOrder(r.Context(), &core.StandingOrder{
+		StandingOrderID: id,
+		AccountID:       accountID,
+		Amount:          req.Amount,
+		Description:     req.Description,
+		EndDate:         endDate,
+		MaxCount:        req.MaxCount,
+	})
+	if err != nil {
+		respondStandingOrderError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, so)
+}
+
+func (h *StandingOrderHandler) ResumeStandingOrder(w http.ResponseWriter, r *http.Request) {
+	accountID, id, ok := parseStandingOrderIDs(w, r)
+	if !ok {
+		return
+	}
+
+	so, err := h.service.ResumeStandingOrder(r.Context(), accountID, id)
+	if err != nil {
+		respondStandingOrderError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, so)
+}
+
+func (h *StandingOrderHandler) CancelStandingOrder(w http.ResponseWriter, r *http.Request) {
+	accountID, id, ok := parseStandingOrderIDs(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.CancelStandingOrder(r.Context(), accountID, id); err != nil {
+		respondStandingOrderError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{
+		"message": "Standing order cancelled",
+	})
+}
+
+func parseAccountID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid account ID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func parseStandingOrderIDs(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	accountID, ok := parseAccountID(w, r)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	id, err := uuid.Parse(mux.Vars(r)["standing_order_id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid standing order ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return accountID, id, true
+}
+
+func parseOptionalDate(w http.ResponseWriter, value *string) (*time.Time, bool) {
+	if value == nil || *value == "" {
+		return nil, true
+	}
+	t, err := time.Parse("2006-01-02", *value)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid date, expected YYYY-MM-DD")
+		return nil, false
+	}
+	return &t, true
+}
+
+func respondStandingOrderError(w http.ResponseWriter, err error) {
+	switch {
+	case err == core.ErrNotFound:
+		respondError(w, http.StatusNotFound, "Standing order not found")
+	case errors.Is(err, core.ErrInvalidInput):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, core.ErrApprovalRequired):
+		respondError(w, http.StatusForbidden, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
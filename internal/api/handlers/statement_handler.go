@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+type StatementHandler struct {
+	service *core.StatementService
+}
+
+func NewStatementHandler(service *core.StatementService) *StatementHandler {
+	return &StatementHandler{service: service}
+}
+
+type StatementCycleRequest struct {
+	Cycle string `json:"cycle"`
+}
+
+func (h *StatementHandler) SetCycle(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	var req StatementCycleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.service.SetCycle(r.Context(), accountID, req.Cycle); err != nil {
+		switch err {
+		case core.ErrInvalidInput:
+			respondError(w, http.StatusBadRequest, "Cycle must be CALENDAR_MONTH or ANNIVERSARY")
+		case core.ErrNotFound:
+			respondError(w, http.StatusNotFound, "Account not found")
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{
+		"message": "Statement cycle updated",
+	})
+}
+
+func (h *StatementHandler) GetStatementPDF(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+	period := mux.Vars(r)["period"]
+
+	data, err := h.service.GetPDF(r.Context(), accountID, period)
+	if err != nil {
+		if err == core.ErrNotFound {
+			respondError(w, http.StatusNotFound, "Statement not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"statement-%s.pdf\"", period))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
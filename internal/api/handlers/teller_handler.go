@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+type TellerHandler struct {
+	service *core.TellerService
+}
+
+func NewTellerHandler(service *core.TellerService) *TellerHandler {
+	return &TellerHandler{service: service}
+}
+
+type OpenSessionRequest struct {
+	BranchID             uuid.UUID          `json:"branch_id"`
+	OpeningDenominations core.Denominations `json:"opening_denominations"`
+}
+
+func (h *TellerHandler) OpenSession(w http.ResponseWriter, r *http.Request) {
+	var req OpenSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	session := core.TellerSession{
+		BranchID:             req.BranchID,
+		TellerID:             actorID(r),
+		OpeningDenominations: req.OpeningDenominations,
+	}
+
+	if err := h.service.OpenSession(r.Context(), &session); err != nil {
+		respondTellerError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, session)
+}
+
+func (h *TellerHandler) GetSession(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	session, err := h.service.GetSession(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Teller session not found")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, session)
+}
+
+type CashTransactionRequest struct {
+	AccountID   uuid.UUID `json:"account_id"`
+	TxnType     string    `json:"txn_type"`
+	Amount      float64   `json:"amount"`
+	Description *string   `json:"description"`
+}
+
+func (h *TellerHandler) PostCash(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	sessionID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	var req CashTransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	txn := core.AccountTransaction{
+		AccountID:   req.AccountID,
+		TxnType:     req.TxnType,
+		Amount:      req.Amount,
+		Description: req.Description,
+	}
+
+	if err := h.service.PostCash(r.Context(), sessionID, actorID(r), &txn); err != nil {
+		respondTellerError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, txn)
+}
+
+type VaultTransferRequest struct {
+	Direction     string             `json:"direction"`
+	Denominations core.Denominations `json:"denominations"`
+}
+
+func (h *TellerHandler) RequestVaultTransfer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	sessionID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	var req VaultTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	vt := core.VaultTransfer{
+		SessionID:     sessionID,
+		Direction:     req.Direction,
+		Denominations: req.Denominations,
+		InitiatedBy:   actorID(r),
+	}
+
+	if err := h.service.RequestVaultTransfer(r.Context(), &vt); err != nil {
+		respondTellerError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, vt)
+}
+
+func (h *TellerHandler) ApproveVaultTransfer(w http.ResponseWriter, r *http.Request) {
+	h.decideVaultTransfer(w, r, true)
+}
+
+func (h *TellerHandler) RejectVaultTransfer(w http.ResponseWriter, r *http.Request) {
+	h.decideVaultTransfer(w, r, false)
+}
+
+func (h *TellerHandler) decideVaultTransfer(w http.ResponseWriter, r *http.Request, approve bool) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid vault transfer ID")
+		return
+	}
+
+	vt, err := h.service.DecideVaultTransfer(r.Context(), id, actorID(r), approve)
+	if err != nil {
+		respondTellerError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, vt)
+}
+
+type CloseSessionRequest struct {
+	ClosingDenominations core.Denominations `json:"closing_denominations"`
+}
+
+func (h *TellerHandler) CloseSession(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	sessionID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	var req CloseSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	session, err := h.service.CloseSession(r.Context(), sessionID, actorID(r), req.ClosingDenominations)
+	if err != nil {
+		respondTellerError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, session)
+}
+
+func (h *TellerHandler) BalancingReport(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	branchID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid branch ID")
+		return
+	}
+
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+	if d := r.URL.Query().Get("date"); d != "" {
+		day, err = time.Parse("2006-01-02", d)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid date, expected YYYY-MM-DD")
+			return
+		}
+	}
+
+	report, err := h.service.BalancingReport(r.Context(), branchID, day)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, report)
+}
+
+func respondTellerError(w http.ResponseWriter, err error) {
+	switch {
+	case err == core.ErrNotFound:
+		respondError(w, http.StatusNotFound, "Not found")
+	case err == core.ErrDuplicateEntry:
+		respondError(w, http.StatusConflict, "Teller already has an open session")
+	case errors.Is(err, core.ErrUnauthorized), errors.Is(err, core.ErrApprovalRequired):
+		respondError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, core.ErrInsufficientFunds):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case isAccountStatusError(err):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, core.ErrChannelNotAllowed), errors.Is(err, core.ErrLimitExceeded):
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, core.ErrInvalidInput):
+		respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -11,11 +13,24 @@ import (
 )
 
 type TransactionHandler struct {
-	service *core.TransactionService
+	service       *core.TransactionService
+	beneficiaries *core.BeneficiaryService
+	payees        *core.PayeeService
+	approvals     *core.ApprovalService
+
+	// requirePayeeCheck makes confirmation-of-payee mandatory on transfers.
+	requirePayeeCheck bool
 }
 
-func NewTransactionHandler(service *core.TransactionService) *TransactionHandler {
-	return &TransactionHandler{service: service}
+func NewTransactionHandler(service *core.TransactionService, beneficiaries *core.BeneficiaryService,
+	payees *core.PayeeService, approvals *core.ApprovalService, requirePayeeCheck bool) *TransactionHandler {
+	return &TransactionHandler{
+		service:           service,
+		beneficiaries:     beneficiaries,
+		payees:            payees,
+		approvals:         approvals,
+		requirePayeeCheck: requirePayeeCheck,
+	}
 }
 
 func (h *TransactionHandler) CreateTransaction(w http.ResponseWriter, r *http.Request) {
@@ -30,8 +45,16 @@ func (h *TransactionHandler) CreateTransaction(w http.ResponseWriter, r *http.Re
 			respondError(w, http.StatusBadRequest, "Insufficient funds")
 			return
 		}
-		if err == core.ErrAccountClosed {
-			respondError(w, http.StatusBadRequest, "Account is closed")
+		if isAccountStatusError(err) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, core.ErrChannelNotAllowed) || errors.Is(err, core.ErrLimitExceeded) {
+			respondError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		if errors.Is(err, core.ErrApprovalRequired) {
+			respondError(w, http.StatusForbidden, err.Error())
 			return
 		}
 		respondError(w, http.StatusInternalServerError, err.Error())
@@ -58,6 +81,55 @@ func (h *TransactionHandler) GetTransaction(w http.ResponseWriter, r *http.Reque
 	respondJSON(w, http.StatusOK, txn)
 }
 
+type ReverseTransactionRequest struct {
+	ReasonCode string `json:"reason_code"`
+	Note       string `json:"note"`
+}
+
+// ReverseTransaction undoes a transaction, or both legs of a transfer, with
+// equal and opposite entries, once approved when reversals need approval.
+func (h *TransactionHandler) ReverseTransaction(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid transaction ID")
+		return
+	}
+
+	var req ReverseTransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if h.approvals.Required(core.OpReversal, 0) {
+		submitApproval(w, r, h.approvals, core.OpReversal, id, nil,
+			core.ReversalApproval{ReasonCode: req.ReasonCode, Note: req.Note}, req.Note)
+		return
+	}
+
+	reversals, err := h.service.ReverseTransaction(r.Context(), id, req.ReasonCode, req.Note, actorID(r))
+	if err != nil {
+		switch {
+		case err == core.ErrInvalidInput:
+			respondError(w, http.StatusBadRequest, "Reason code must be one of DUPLICATE, INCORRECT_AMOUNT, "+
+				"INCORRECT_ACCOUNT, UNAUTHORISED or CUSTOMER_REQUEST")
+		case err == core.ErrNotFound:
+			respondError(w, http.StatusNotFound, "Transaction not found")
+		case err == core.ErrAlreadyReversed:
+			respondError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, core.ErrNotReversible):
+			respondError(w, http.StatusUnprocessableEntity, err.Error())
+		case isAccountStatusError(err), err == core.ErrInsufficientFunds:
+			respondError(w, http.StatusBadRequest, err.Error())
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, reversals)
+}
+
 func (h *TransactionHandler) ListTransactionsByAccount(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	accountID, err := uuid.Parse(vars["account_id"])
@@ -82,11 +154,51 @@ func (h *TransactionHandler) ListTransactionsByAccount(w http.ResponseWriter, r
 	respondJSON(w, http.StatusOK, transactions)
 }
 
+func (h *TransactionHandler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	from, err := time.Parse("2006-01-02", r.URL.Query().Get("from"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid from date, expected YYYY-MM-DD")
+		return
+	}
+	to, err := time.Parse("2006-01-02", r.URL.Query().Get("to"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid to date, expected YYYY-MM-DD")
+		return
+	}
+
+	statement, err := h.service.Statement(r.Context(), accountID, from, to)
+	if err != nil {
+		switch err {
+		case core.ErrInvalidInput:
+			respondError(w, http.StatusBadRequest, "The from date must not be after the to date")
+		case core.ErrNotFound:
+			respondError(w, http.StatusNotFound, "Account not found")
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusOK, statement)
+}
+
+// TransferRequest names the payee either directly with ToAccountID or through
+// a registered BeneficiaryID, which also applies the beneficiary's limits.
+// When PayeeName is given it is checked against the destination holder's
+// name; a CLOSE_MATCH only proceeds with ConfirmCloseMatch set.
 type TransferRequest struct {
-	FromAccountID uuid.UUID `json:"from_account_id"`
-	ToAccountID   uuid.UUID `json:"to_account_id"`
-	Amount        float64   `json:"amount"`
-	Description   string    `json:"description"`
+	FromAccountID     uuid.UUID  `json:"from_account_id"`
+	ToAccountID       uuid.UUID  `json:"to_account_id"`
+	BeneficiaryID     *uuid.UUID `json:"beneficiary_id,omitempty"`
+	Amount            float64    `json:"amount"`
+	Description       string     `json:"description"`
+	PayeeName         string     `json:"payee_name,omitempty"`
+	ConfirmCloseMatch bool       `json:"confirm_close_match,omitempty"`
 }
 
 func (h *TransactionHandler) Transfer(w http.ResponseWriter, r *http.Request) {
@@ -101,12 +213,70 @@ func (h *TransactionHandler) Transfer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.BeneficiaryID != nil {
+		b, ok := resolveBeneficiary(w, r, h.beneficiaries, req.FromAccountID, *req.BeneficiaryID, req.Amount)
+		if !ok {
+			return
+		}
+		req.ToAccountID = *b.AccountID
+		if req.PayeeName == "" {
+			req.PayeeName = b.PayeeName
+		}
+	}
+
+	if req.PayeeName != "" || h.requirePayeeCheck {
+		check, err := h.payees.CheckByAccountID(r.Context(), req.ToAccountID, req.PayeeName)
+		if err != nil {
+			if err == core.ErrInvalidInput {
+				respondError(w, http.StatusBadRequest, "Payee name is required")
+				return
+			}
+			if err == core.ErrNotFound {
+				respondError(w, http.StatusNotFound, "Destination account not found")
+				return
+			}
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if check.Result == core.PayeeNoMatch ||
+			(check.Result == core.PayeeCloseMatch && !req.ConfirmCloseMatch) {
+			respondJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+				"error":       "Payee name does not match the account holder",
+				"payee_check": check,
+			})
+			return
+		}
+	}
+
+	// Large transfers wait for a second user; the checks above are not repeated.
+	if h.approvals.Required(core.OpTransfer, req.Amount) {
+		submitApproval(w, r, h.approvals, core.OpTransfer, req.FromAccountID, &req.Amount, core.TransferApproval{
+			FromAccountID: req.FromAccountID,
+			ToAccountID:   req.ToAccountID,
+			Amount:        req.Amount,
+			Description:   req.Description,
+		}, req.Description)
+		return
+	}
+
 	err := h.service.Transfer(r.Context(), req.FromAccountID, req.ToAccountID, req.Amount, req.Description)
 	if err != nil {
 		if err == core.ErrInsufficientFunds {
 			respondError(w, http.StatusBadRequest, "Insufficient funds")
 			return
 		}
+		if isAccountStatusError(err) || err == core.ErrCoolingLimit {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, core.ErrChannelNotAllowed) || errors.Is(err, core.ErrLimitExceeded) {
+			respondError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		if errors.Is(err, core.ErrApprovalRequired) {
+			respondError(w, http.StatusForbidden, err.Error())
+			return
+		}
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -116,6 +286,42 @@ func (h *TransactionHandler) Transfer(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// resolveBeneficiary applies the beneficiary's ownership and cooling checks
+// for an internal transfer, writing the error response when they fail.
+func resolveBeneficiary(w http.ResponseWriter, r *http.Request, beneficiaries *core.BeneficiaryService,
+	fromAccountID, beneficiaryID uuid.UUID, amount float64) (*core.Beneficiary, bool) {
+	b, ok := checkBeneficiary(w, r, beneficiaries, fromAccountID, beneficiaryID, amount)
+	if !ok {
+		return nil, false
+	}
+	if b.AccountID == nil {
+		respondError(w, http.StatusBadRequest, "External beneficiaries cannot be paid by internal transfer")
+		return nil, false
+	}
+	return b, true
+}
+
+// checkBeneficiary resolves a beneficiary of either type for a payment and
+// responds with the error when the payment is not allowed.
+func checkBeneficiary(w http.ResponseWriter, r *http.Request, beneficiaries *core.BeneficiaryService,
+	fromAccountID, beneficiaryID uuid.UUID, amount float64) (*core.Beneficiary, bool) {
+	b, err := beneficiaries.ResolveTransfer(r.Context(), fromAccountID, beneficiaryID, amount)
+	if err != nil {
+		switch {
+		case errors.Is(err, core.ErrNotFound):
+			respondError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, core.ErrUnauthorized):
+			respondError(w, http.StatusForbidden, err.Error())
+		case err == core.ErrCoolingLimit:
+			respondError(w, http.StatusBadRequest, err.Error())
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return nil, false
+	}
+	return b, true
+}
+
 // Helper functions
 func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -125,4 +331,9 @@ func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 
 func respondError(w http.ResponseWriter, status int, message string) {
 	respondJSON(w, status, map[string]string{"error": message})
+}
+
+// actorID identifies the staff member or user performing the request.
+func actorID(r *http.Request) string {
+	return r.Header.Get("X-User-ID")
 }
\ No newline at end of file
@@ -4,25 +4,87 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/shubhbham/BankingApi_Golang/internal/api/handlers"
 	"github.com/shubhbham/BankingApi_Golang/internal/api/middleware"
+	"github.com/shubhbham/BankingApi_Golang/internal/batch"
+	"github.com/shubhbham/BankingApi_Golang/internal/config"
 	"github.com/shubhbham/BankingApi_Golang/internal/core"
 	"github.com/shubhbham/BankingApi_Golang/internal/db"
+	"github.com/shubhbham/BankingApi_Golang/internal/iso20022"
 )
 
-func NewRouter(database *db.DB) *mux.Router {
+func NewRouter(database *db.DB, cfg *config.Config, calendar *core.Calendar, prices *core.PriceBook) *mux.Router {
 	router := mux.NewRouter()
 
 	// Initialize services
 	customerService := core.NewCustomerService(database.Pool)
 	accountService := core.NewAccountService(database.Pool)
-	transactionService := core.NewTransactionService(database.Pool)
+	productService := core.NewProductService(database.Pool, prices, cfg.Currency)
+	cooling := batch.NewCoolingPolicy(cfg)
+	transactionService := batch.NewTransactionService(database.Pool, cfg, calendar, prices)
 	branchService := core.NewBranchService(database.Pool)
+	chequeService := core.NewChequeService(database.Pool, transactionService, cfg.ChequeReturnFee)
+	ledgerService := core.NewLedgerService(database.Pool)
+	tellerService := core.NewTellerService(database.Pool, transactionService, cfg.TellerSupervisors)
+	stepUpService := core.NewStepUpService(database.Pool, core.LogNotifier{})
+	beneficiaryService := core.NewBeneficiaryService(database.Pool, stepUpService, cooling)
+	payeeService := core.NewPayeeService(database.Pool)
+	scheduledPaymentService := core.NewScheduledPaymentService(database.Pool, transactionService, core.RetryPolicy{
+		MaxRetries: cfg.ScheduledPaymentMaxRetries,
+		Interval:   cfg.ScheduledPaymentRetryInterval,
+	})
+
+	standingOrderService := core.NewStandingOrderService(database.Pool, transactionService,
+		calendar, core.LogNotifier{}, cfg.StandingOrderMaxFailures)
+
+	blobStore := core.NewLocalBlobStore(cfg.BlobStoreDir)
+	statementService := core.NewStatementService(database.Pool, transactionService, blobStore)
+	camtReporter := iso20022.NewReporter(transactionService, cfg.Currency)
+	bulkPaymentService := core.NewBulkPaymentService(database.Pool, transactionService, cfg.Currency)
+	achService := batch.NewACHService(database.Pool, cfg, transactionService)
+	interbankService := batch.NewInterbankService(database.Pool, cfg, transactionService)
+	disputeService := core.NewDisputeService(database.Pool, transactionService, calendar, blobStore, core.DisputePolicy{
+		FilingWindowDays:      cfg.DisputeFilingWindowDays,
+		ProvisionalCreditDays: cfg.DisputeProvisionalCreditDays,
+		ResolutionDays:        cfg.DisputeResolutionDays,
+	})
+	feeService := core.NewFeeService(database.Pool, transactionService)
+	lienService := core.NewLienService(database.Pool)
+	closureService := core.NewClosureService(database.Pool, transactionService, tellerService, blobStore)
+	approvalService := core.NewApprovalService(database.Pool, batch.NewApprovalPolicy(cfg),
+		core.ApprovalActions(closureService, transactionService, customerService, lienService))
+	dormancyService := core.NewDormancyService(database.Pool, core.LogNotifier{}, core.DormancyPolicy{
+		PeriodMonths: cfg.DormancyPeriodMonths,
+		NoticeDays:   cfg.DormancyNoticeDays,
+	})
+	eodService := batch.NewEOD(database.Pool, cfg, calendar, prices)
 
 	// Initialize handlers
 	healthHandler := handlers.NewHealthHandler(database)
-	customerHandler := handlers.NewCustomerHandler(customerService)
+	customerHandler := handlers.NewCustomerHandler(customerService, approvalService)
 	accountHandler := handlers.NewAccountHandler(accountService)
+	closureHandler := handlers.NewClosureHandler(closureService, approvalService)
+	lienHandler := handlers.NewLienHandler(lienService, approvalService)
+	productHandler := handlers.NewProductHandler(productService)
 	branchHandler := handlers.NewBranchHandler(branchService)
-	transactionHandler := handlers.NewTransactionHandler(transactionService)
+	transactionHandler := handlers.NewTransactionHandler(transactionService, beneficiaryService,
+		payeeService, approvalService, cfg.RequirePayeeCheck)
+	exportHandler := handlers.NewExportHandler(transactionService, cfg.Currency)
+	chequeHandler := handlers.NewChequeHandler(chequeService)
+	ledgerHandler := handlers.NewLedgerHandler(ledgerService)
+	tellerHandler := handlers.NewTellerHandler(tellerService)
+	beneficiaryHandler := handlers.NewBeneficiaryHandler(beneficiaryService, stepUpService)
+	payeeHandler := handlers.NewPayeeHandler(payeeService)
+	scheduledPaymentHandler := handlers.NewScheduledPaymentHandler(scheduledPaymentService, beneficiaryService)
+	standingOrderHandler := handlers.NewStandingOrderHandler(standingOrderService, beneficiaryService)
+	statementHandler := handlers.NewStatementHandler(statementService)
+	iso20022Handler := handlers.NewISO20022Handler(camtReporter)
+	paymentFileHandler := handlers.NewPaymentFileHandler(bulkPaymentService, cfg.BulkPaymentExecution)
+	achHandler := handlers.NewACHHandler(achService, beneficiaryService)
+	interbankHandler := handlers.NewInterbankHandler(interbankService, beneficiaryService)
+	disputeHandler := handlers.NewDisputeHandler(disputeService)
+	feeHandler := handlers.NewFeeHandler(feeService)
+	dormancyHandler := handlers.NewDormancyHandler(dormancyService)
+	approvalHandler := handlers.NewApprovalHandler(approvalService)
+	eodHandler := handlers.NewEODHandler(eodService)
 
 	// Apply global middleware
 	router.Use(middleware.Recovery)
@@ -40,24 +102,143 @@ func NewRouter(database *db.DB) *mux.Router {
 	api.HandleFunc("/customers", customerHandler.ListCustomers).Methods("GET")
 	api.HandleFunc("/customers/{id}", customerHandler.GetCustomer).Methods("GET")
 	api.HandleFunc("/customers/{id}", customerHandler.UpdateCustomer).Methods("PUT")
+	api.HandleFunc("/customers/{id}/kyc-verification", customerHandler.VerifyKYC).Methods("POST")
 
 	// Account routes
 	api.HandleFunc("/accounts", accountHandler.CreateAccount).Methods("POST")
 	api.HandleFunc("/accounts/{id}", accountHandler.GetAccount).Methods("GET")
 	api.HandleFunc("/accounts", accountHandler.GetAccountByNumber).Methods("GET").Queries("number", "{number}")
-	api.HandleFunc("/accounts/{id}/close", accountHandler.CloseAccount).Methods("POST")
+	api.HandleFunc("/accounts/{id}/close", closureHandler.CloseAccount).Methods("POST")
+	api.HandleFunc("/accounts/{id}/closure", closureHandler.GetClosure).Methods("GET")
+	api.HandleFunc("/accounts/{id}/closure-certificate.pdf", closureHandler.GetCertificate).Methods("GET")
+	api.HandleFunc("/accounts/{id}/status", accountHandler.ChangeStatus).Methods("POST")
+	api.HandleFunc("/accounts/{id}/status-history", accountHandler.StatusHistory).Methods("GET")
+	api.HandleFunc("/accounts/{id}/reactivate", accountHandler.Reactivate).Methods("POST")
 	api.HandleFunc("/customers/{customer_id}/accounts", accountHandler.ListAccountsByCustomer).Methods("GET")
 
+	// Product catalog routes
+	api.HandleFunc("/products", productHandler.CreateProduct).Methods("POST")
+	api.HandleFunc("/products", productHandler.ListProducts).Methods("GET")
+	api.HandleFunc("/products/{code}", productHandler.GetProduct).Methods("GET")
+	api.HandleFunc("/products/{code}", productHandler.PublishVersion).Methods("PUT")
+	api.HandleFunc("/products/{code}/versions/{version:[0-9]+}", productHandler.GetProductVersion).Methods("GET")
+
 	// Transaction routes
 	api.HandleFunc("/transactions", transactionHandler.CreateTransaction).Methods("POST")
 	api.HandleFunc("/transactions/{id}", transactionHandler.GetTransaction).Methods("GET")
+	api.HandleFunc("/transactions/{id}/reverse", transactionHandler.ReverseTransaction).Methods("POST")
 	api.HandleFunc("/transactions/transfer", transactionHandler.Transfer).Methods("POST")
+	api.HandleFunc("/accounts/{account_id}/transactions", exportHandler.ExportTransactions).Methods("GET").MatcherFunc(handlers.WantsExport)
 	api.HandleFunc("/accounts/{account_id}/transactions", transactionHandler.ListTransactionsByAccount).Methods("GET")
+	api.HandleFunc("/accounts/{id}/statements", transactionHandler.GetStatement).Methods("GET")
+	api.HandleFunc("/accounts/{id}/statements/{period:[0-9]{4}-[0-9]{2}}.pdf", statementHandler.GetStatementPDF).Methods("GET")
+	api.HandleFunc("/accounts/{id}/statement-cycle", statementHandler.SetCycle).Methods("PUT")
+	api.HandleFunc("/accounts/{id}/camt053", iso20022Handler.GetCamt053).Methods("GET")
+	api.HandleFunc("/accounts/{id}/camt052", iso20022Handler.GetCamt052).Methods("GET")
 
 	// Branch routes
 	api.HandleFunc("/branches", branchHandler.CreateBranch).Methods("POST")
 	api.HandleFunc("/branches/{id}", branchHandler.GetBranch).Methods("GET")
 	api.HandleFunc("/branches", branchHandler.ListBranches).Methods("GET")
 
+	// Cheque routes
+	api.HandleFunc("/accounts/{id}/cheque-books", chequeHandler.IssueChequeBook).Methods("POST")
+	api.HandleFunc("/accounts/{id}/cheque-books", chequeHandler.ListChequeBooks).Methods("GET")
+	api.HandleFunc("/accounts/{id}/cheques/{number}/stop", chequeHandler.StopPayment).Methods("POST")
+	api.HandleFunc("/accounts/{id}/cheques", chequeHandler.ListPresentments).Methods("GET")
+	api.HandleFunc("/cheques/clearing", chequeHandler.PresentCheque).Methods("POST")
+
+	// Teller and vault routes
+	api.HandleFunc("/teller-sessions", tellerHandler.OpenSession).Methods("POST")
+	api.HandleFunc("/teller-sessions/{id}", tellerHandler.GetSession).Methods("GET")
+	api.HandleFunc("/teller-sessions/{id}/cash", tellerHandler.PostCash).Methods("POST")
+	api.HandleFunc("/teller-sessions/{id}/vault-transfers", tellerHandler.RequestVaultTransfer).Methods("POST")
+	api.HandleFunc("/teller-sessions/{id}/close", tellerHandler.CloseSession).Methods("POST")
+	api.HandleFunc("/vault-transfers/{id}/approve", tellerHandler.ApproveVaultTransfer).Methods("POST")
+	api.HandleFunc("/vault-transfers/{id}/reject", tellerHandler.RejectVaultTransfer).Methods("POST")
+	api.HandleFunc("/branches/{id}/teller-balancing", tellerHandler.BalancingReport).Methods("GET")
+
+	// Beneficiary routes
+	api.HandleFunc("/customers/{id}/step-up", beneficiaryHandler.IssueStepUp).Methods("POST")
+	api.HandleFunc("/customers/{id}/beneficiaries", beneficiaryHandler.AddBeneficiary).Methods("POST")
+	api.HandleFunc("/customers/{id}/beneficiaries", beneficiaryHandler.ListBeneficiaries).Methods("GET")
+	api.HandleFunc("/customers/{id}/beneficiaries/{beneficiary_id}", beneficiaryHandler.DeleteBeneficiary).Methods("DELETE")
+
+	// Bulk payment file routes
+	api.HandleFunc("/customers/{id}/payment-files", paymentFileHandler.UploadFile).Methods("POST")
+	api.HandleFunc("/customers/{id}/payment-files/{file_id}", paymentFileHandler.GetFile).Methods("GET")
+	api.HandleFunc("/customers/{id}/payment-files/{file_id}/pain002", paymentFileHandler.GetStatusReport).Methods("GET")
+
+	// ACH routes
+	api.HandleFunc("/accounts/{id}/ach-transfers", achHandler.CreateTransfer).Methods("POST")
+	api.HandleFunc("/accounts/{id}/ach-transfers", achHandler.ListTransfers).Methods("GET")
+	api.HandleFunc("/ach-transfers/{id}", achHandler.GetTransfer).Methods("GET")
+	api.HandleFunc("/admin/ach/files", achHandler.GenerateFile).Methods("POST")
+	api.HandleFunc("/admin/ach/returns", achHandler.ProcessReturns).Methods("POST")
+
+	// Interbank payment routes
+	api.HandleFunc("/accounts/{id}/interbank-payments", interbankHandler.SubmitPayment).Methods("POST")
+	api.HandleFunc("/accounts/{id}/interbank-payments", interbankHandler.ListPayments).Methods("GET")
+	api.HandleFunc("/interbank-payments/{id}", interbankHandler.GetPayment).Methods("GET")
+	api.HandleFunc("/admin/interbank/settlement", interbankHandler.SettleNow).Methods("POST")
+
+	// Dispute routes
+	api.HandleFunc("/accounts/{id}/disputes", disputeHandler.OpenDispute).Methods("POST")
+	api.HandleFunc("/accounts/{id}/disputes", disputeHandler.ListAccountDisputes).Methods("GET")
+	api.HandleFunc("/disputes/{dispute_id}", disputeHandler.GetDispute).Methods("GET")
+	api.HandleFunc("/disputes/{dispute_id}/notes", disputeHandler.AddNote).Methods("POST")
+	api.HandleFunc("/disputes/{dispute_id}/attachments", disputeHandler.AddAttachment).Methods("POST")
+	api.HandleFunc("/disputes/{dispute_id}/attachments/{attachment_id}", disputeHandler.GetAttachment).Methods("GET")
+	api.HandleFunc("/admin/disputes", disputeHandler.ListDisputes).Methods("GET")
+	api.HandleFunc("/admin/disputes/{dispute_id}/provisional-credit", disputeHandler.GrantProvisionalCredit).Methods("POST")
+	api.HandleFunc("/admin/disputes/{dispute_id}/investigate", disputeHandler.StartInvestigation).Methods("POST")
+	api.HandleFunc("/admin/disputes/{dispute_id}/resolve", disputeHandler.ResolveDispute).Methods("POST")
+
+	// Approval routes
+	api.HandleFunc("/approvals", approvalHandler.ListApprovals).Methods("GET")
+	api.HandleFunc("/approvals/{approval_id}", approvalHandler.GetApproval).Methods("GET")
+	api.HandleFunc("/approvals/{approval_id}/approve", approvalHandler.Approve).Methods("POST")
+	api.HandleFunc("/approvals/{approval_id}/reject", approvalHandler.Reject).Methods("POST")
+
+	// Lien routes
+	api.HandleFunc("/accounts/{id}/liens", lienHandler.PlaceLien).Methods("POST")
+	api.HandleFunc("/accounts/{id}/liens", lienHandler.ListLiens).Methods("GET")
+	api.HandleFunc("/accounts/{id}/available-balance", lienHandler.AvailableBalance).Methods("GET")
+	api.HandleFunc("/liens/{lien_id}", lienHandler.GetLien).Methods("GET")
+	api.HandleFunc("/liens/{lien_id}/release", lienHandler.RequestRelease).Methods("POST")
+
+	// Fee routes
+	api.HandleFunc("/price-book", feeHandler.GetPriceBook).Methods("GET")
+	api.HandleFunc("/accounts/{id}/fee-charges", feeHandler.ListCharges).Methods("GET")
+
+	// Dormancy routes
+	api.HandleFunc("/admin/reports/dormant-balances", dormancyHandler.DormantBalances).Methods("GET")
+
+	// Confirmation-of-payee routes
+	api.HandleFunc("/payee-checks", payeeHandler.CheckPayee).Methods("POST")
+
+	// Scheduled payment routes
+	api.HandleFunc("/scheduled-payments", scheduledPaymentHandler.CreatePayment).Methods("POST")
+	api.HandleFunc("/scheduled-payments/{id}", scheduledPaymentHandler.GetPayment).Methods("GET")
+	api.HandleFunc("/scheduled-payments/{id}", scheduledPaymentHandler.AmendPayment).Methods("PUT")
+	api.HandleFunc("/scheduled-payments/{id}/cancel", scheduledPaymentHandler.CancelPayment).Methods("POST")
+	api.HandleFunc("/accounts/{id}/scheduled-payments", scheduledPaymentHandler.ListPaymentsByAccount).Methods("GET")
+
+	// Standing order routes
+	api.HandleFunc("/accounts/{id}/standing-orders", standingOrderHandler.CreateStandingOrder).Methods("POST")
+	api.HandleFunc("/accounts/{id}/standing-orders", standingOrderHandler.ListStandingOrders).Methods("GET")
+	api.HandleFunc("/accounts/{id}/standing-orders/{standing_order_id}", standingOrderHandler.GetStandingOrder).Methods("GET")
+	api.HandleFunc("/accounts/{id}/standing-orders/{standing_order_id}", standingOrderHandler.UpdateStandingOrder).Methods("PUT")
+	api.HandleFunc("/accounts/{id}/standing-orders/{standing_order_id}", standingOrderHandler.CancelStandingOrder).Methods("DELETE")
+	api.HandleFunc("/accounts/{id}/standing-orders/{standing_order_id}/resume", standingOrderHandler.ResumeStandingOrder).Methods("POST")
+
+	// General ledger routes
+	api.HandleFunc("/gl-accounts/{code}", ledgerHandler.GetGLAccount).Methods("GET")
+	api.HandleFunc("/gl-accounts/{code}/entries", ledgerHandler.ListEntries).Methods("GET")
+
+	// End-of-day routes
+	api.HandleFunc("/admin/eod", eodHandler.TriggerRun).Methods("POST")
+	api.HandleFunc("/admin/eod", eodHandler.GetStatus).Methods("GET")
+
 	return router
 }
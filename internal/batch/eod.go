@@ -0,0 +1,47 @@
+// Package batch assembles the end-of-day run from the services that take part
+// in it.
+package batch
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shubhbham/BankingApi_Golang/internal/config"
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+// NewEOD returns the EOD service with its steps in execution order. The
+// business date rollover is always appended last by core.NewEODService.
+func NewEOD(pool *pgxpool.Pool, cfg *config.Config, calendar *core.Calendar, prices *core.PriceBook) *core.EODService {
+	transactions := NewTransactionService(pool, cfg, calendar, prices)
+	tellers := core.NewTellerService(pool, transactions, cfg.TellerSupervisors)
+	scheduledPayments := core.NewScheduledPaymentService(pool, transactions, core.RetryPolicy{
+		MaxRetries: cfg.ScheduledPaymentMaxRetries,
+		Interval:   cfg.ScheduledPaymentRetryInterval,
+	})
+	standingOrders := core.NewStandingOrderService(pool, transactions,
+		calendar, core.LogNotifier{}, cfg.StandingOrderMaxFailures)
+	ach := NewACHService(pool, cfg, transactions)
+	interbank := NewInterbankService(pool, cfg, transactions)
+	interest := core.NewInterestService(pool, transactions, calendar)
+	fees := core.NewFeeService(pool, transactions)
+	liens := core.NewLienService(pool)
+	dormancy := core.NewDormancyService(pool, core.LogNotifier{}, core.DormancyPolicy{
+		PeriodMonths: cfg.DormancyPeriodMonths,
+		NoticeDays:   cfg.DormancyNoticeDays,
+	})
+	statements := core.NewStatementService(pool, transactions, core.NewLocalBlobStore(cfg.BlobStoreDir))
+
+	return core.NewEODService(pool, calendar,
+		core.Step("tills-closed", tellers.CheckTillsClosed),
+		core.Step("scheduled-payments", scheduledPayments.ExecuteDue),
+		core.Step("standing-orders", standingOrders.ExecuteDue),
+		core.Step("ach-returns", ach.ReceiveReturns),
+		core.Step("ach-settlement", ach.SettleDue),
+		core.Step("ach-file", ach.SendDue),
+		core.Step("interbank-settlement", interbank.CloseDay),
+		core.BatchStep{Name: "interest", Run: interest.PostDue},
+		core.BatchStep{Name: "fees", Run: fees.ChargeMonthly},
+		core.BatchStep{Name: "dormancy", Run: dormancy.MarkDormant},
+		core.BatchStep{Name: "lien-expiry", Run: liens.ExpireDue},
+		core.BatchStep{Name: "statements", Run: statements.GenerateDue},
+	)
+}
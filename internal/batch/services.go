@@ -0,0 +1,61 @@
+package batch
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shubhbham/BankingApi_Golang/internal/config"
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+// NewApprovalPolicy reads the approval policy from the configuration.
+func NewApprovalPolicy(cfg *config.Config) core.ApprovalPolicy {
+	return core.ApprovalPolicy{
+		Operations:        cfg.ApprovalOperations,
+		TransferThreshold: cfg.ApprovalTransferThreshold,
+		Expiry:            cfg.ApprovalExpiry,
+		Approvers:         cfg.ApprovalApprovers,
+	}
+}
+
+// NewCoolingPolicy reads the beneficiary cooling policy from the
+// configuration.
+func NewCoolingPolicy(cfg *config.Config) core.CoolingPolicy {
+	return core.CoolingPolicy{
+		Period:       cfg.BeneficiaryCoolingPeriod,
+		Limit:        cfg.BeneficiaryCoolingLimit,
+		Unregistered: cfg.BeneficiaryCoolingUnregistered,
+	}
+}
+
+// NewTransactionService builds the transaction service with the cooling and
+// approval policies from the configuration. The API, the EOD run and the
+// background jobs all use it, so they hold payments to the same rules.
+func NewTransactionService(pool *pgxpool.Pool, cfg *config.Config, calendar *core.Calendar, prices *core.PriceBook) *core.TransactionService {
+	return core.NewTransactionService(pool, calendar, prices, NewCoolingPolicy(cfg), NewApprovalPolicy(cfg))
+}
+
+// NewACHService builds the ACH service from the configuration. The API and
+// the EOD run both use it.
+func NewACHService(pool *pgxpool.Pool, cfg *config.Config, transactions *core.TransactionService) *core.ACHService {
+	return core.NewACHService(pool, transactions, core.ACHConfig{
+		ODFIRouting:        cfg.ACHODFIRouting,
+		OriginName:         cfg.ACHOriginName,
+		DestinationRouting: cfg.ACHDestinationRouting,
+		DestinationName:    cfg.ACHDestinationName,
+		CompanyName:        cfg.ACHCompanyName,
+		CompanyID:          cfg.ACHCompanyID,
+		OutboundDir:        cfg.ACHOutboundDir,
+		InboundDir:         cfg.ACHInboundDir,
+		ReturnWindowDays:   cfg.ACHReturnWindowDays,
+	})
+}
+
+// NewInterbankService builds the interbank service and its clearing house
+// from the configuration. The API, the EOD run and the settlement job all
+// use it.
+func NewInterbankService(pool *pgxpool.Pool, cfg *config.Config, transactions *core.TransactionService) *core.InterbankService {
+	clearingHouse := core.NewSimulatedClearingHouse(cfg.InterbankParticipants)
+	return core.NewInterbankService(pool, transactions, clearingHouse, core.InterbankConfig{
+		RTGSThreshold: cfg.InterbankRTGSThreshold,
+		DNSInterval:   cfg.InterbankDNSInterval,
+	})
+}
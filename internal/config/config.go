@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -11,6 +14,98 @@ type Config struct {
 	DatabaseURL string
 	ServerPort  string
 	Environment string
+
+	// CalendarDir holds calendar.json and the holiday files.
+	CalendarDir string
+
+	// PriceBookFile is the JSON tariff of fees and the tax on them.
+	PriceBookFile string
+
+	// Currency is the ISO 4217 code of account balances, used in exports.
+	Currency string
+
+	// BlobStoreDir is the root of the local blob store for generated documents.
+	BlobStoreDir string
+
+	// TellerSupervisors may approve vault transfers; empty allows any user
+	// other than the initiator and the till's teller.
+	TellerSupervisors []string
+
+	// ChequeReturnFee is charged to the drawer when a presented cheque is returned.
+	ChequeReturnFee float64
+
+	// New beneficiaries may only receive up to BeneficiaryCoolingLimit in
+	// total until BeneficiaryCoolingPeriod has passed. With
+	// BeneficiaryCoolingUnregistered, payees that are not beneficiaries are
+	// held to the same limit over any rolling period.
+	BeneficiaryCoolingPeriod       time.Duration
+	BeneficiaryCoolingLimit        float64
+	BeneficiaryCoolingUnregistered bool
+
+	// RequirePayeeCheck rejects transfers that do not pass confirmation-of-payee.
+	RequirePayeeCheck bool
+
+	// SchedulerInterval is how often background jobs look for due work.
+	SchedulerInterval time.Duration
+
+	// Scheduled payments failing for insufficient funds or an unexpected
+	// error are retried up to ScheduledPaymentMaxRetries times,
+	// ScheduledPaymentRetryInterval apart.
+	ScheduledPaymentMaxRetries    int
+	ScheduledPaymentRetryInterval time.Duration
+
+	// StandingOrderMaxFailures suspends a standing order after this many
+	// consecutive failed payments.
+	StandingOrderMaxFailures int
+
+	// BulkPaymentExecution is the default execution mode of uploaded payment
+	// files: ATOMIC rejects a whole batch when one instruction fails,
+	// BEST_EFFORT books every instruction that can be booked.
+	BulkPaymentExecution string
+
+	// ACH origination: the bank's routing number and name, the ACH operator's,
+	// the originating company in batch headers, and the directories NACHA
+	// files are written to and return files are read from. Sent entries
+	// settle ACHReturnWindowDays business days after their effective date.
+	ACHODFIRouting        string
+	ACHOriginName         string
+	ACHDestinationRouting string
+	ACHDestinationName    string
+	ACHCompanyName        string
+	ACHCompanyID          string
+	ACHOutboundDir        string
+	ACHInboundDir         string
+	ACHReturnWindowDays   int
+
+	// Interbank payments of InterbankRTGSThreshold or more settle gross in
+	// real time; smaller ones are netted in batches every InterbankDNSInterval.
+	// InterbankParticipants lists the bank code prefixes the simulated
+	// clearing house settles to; empty means every bank.
+	InterbankRTGSThreshold float64
+	InterbankDNSInterval   time.Duration
+	InterbankParticipants  []string
+
+	// Customers may dispute a debit up to DisputeFilingWindowDays after it
+	// was posted. A provisional credit is due within
+	// DisputeProvisionalCreditDays business days, and a resolution within
+	// DisputeResolutionDays calendar days.
+	DisputeFilingWindowDays      int
+	DisputeProvisionalCreditDays int
+	DisputeResolutionDays        int
+
+	// Accounts without customer-initiated activity for DormancyPeriodMonths
+	// become dormant. Notices are sent DormancyNoticeDays before that.
+	DormancyPeriodMonths int
+	DormancyNoticeDays   []int
+
+	// ApprovalOperations wait for a second user's approval before they run;
+	// transfers only from ApprovalTransferThreshold up. Requests lapse after
+	// ApprovalExpiry. Only ApprovalApprovers may decide them; empty means
+	// nobody can.
+	ApprovalOperations        []string
+	ApprovalTransferThreshold float64
+	ApprovalExpiry            time.Duration
+	ApprovalApprovers         []string
 }
 
 func Load() (*Config, error) {
@@ -21,6 +116,58 @@ func Load() (*Config, error) {
 		DatabaseURL: getEnv("DATABASE_URL", ""),
 		ServerPort:  getEnv("SERVER_PORT", "8080"),
 		Environment: getEnv("ENVIRONMENT", "development"),
+		CalendarDir: getEnv("CALENDAR_DIR", "config/calendar"),
+		Currency:    getEnv("CURRENCY", "INR"),
+
+		PriceBookFile: getEnv("PRICE_BOOK_FILE", "config/pricebook.json"),
+
+		BlobStoreDir: getEnv("BLOB_STORE_DIR", "data/blobs"),
+
+		TellerSupervisors: getEnvList("TELLER_SUPERVISORS"),
+
+		ChequeReturnFee: getEnvFloat("CHEQUE_RETURN_FEE", 250),
+
+		BeneficiaryCoolingPeriod:       getEnvDuration("BENEFICIARY_COOLING_PERIOD", 24*time.Hour),
+		BeneficiaryCoolingLimit:        getEnvFloat("BENEFICIARY_COOLING_LIMIT", 25000),
+		BeneficiaryCoolingUnregistered: getEnvBool("BENEFICIARY_COOLING_UNREGISTERED", false),
+
+		RequirePayeeCheck: getEnvBool("REQUIRE_PAYEE_CHECK", false),
+
+		SchedulerInterval: getEnvDuration("SCHEDULER_INTERVAL", time.Minute),
+
+		ScheduledPaymentMaxRetries:    getEnvInt("SCHEDULED_PAYMENT_MAX_RETRIES", 3),
+		ScheduledPaymentRetryInterval: getEnvDuration("SCHEDULED_PAYMENT_RETRY_INTERVAL", 4*time.Hour),
+
+		StandingOrderMaxFailures: getEnvInt("STANDING_ORDER_MAX_FAILURES", 3),
+
+		BulkPaymentExecution: getEnv("BULK_PAYMENT_EXECUTION", "ATOMIC"),
+
+		ACHODFIRouting:        getEnv("ACH_ODFI_ROUTING", ""),
+		ACHOriginName:         getEnv("ACH_ORIGIN_NAME", ""),
+		ACHDestinationRouting: getEnv("ACH_DESTINATION_ROUTING", ""),
+		ACHDestinationName:    getEnv("ACH_DESTINATION_NAME", ""),
+		ACHCompanyName:        getEnv("ACH_COMPANY_NAME", ""),
+		ACHCompanyID:          getEnv("ACH_COMPANY_ID", ""),
+		ACHOutboundDir:        getEnv("ACH_OUTBOUND_DIR", "data/ach/outbound"),
+		ACHInboundDir:         getEnv("ACH_INBOUND_DIR", "data/ach/inbound"),
+		ACHReturnWindowDays:   getEnvInt("ACH_RETURN_WINDOW_DAYS", 2),
+
+		InterbankRTGSThreshold: getEnvFloat("INTERBANK_RTGS_THRESHOLD", 200000),
+		InterbankDNSInterval:   getEnvDuration("INTERBANK_DNS_INTERVAL", 30*time.Minute),
+		InterbankParticipants:  getEnvList("INTERBANK_PARTICIPANTS"),
+
+		DisputeFilingWindowDays:      getEnvInt("DISPUTE_FILING_WINDOW_DAYS", 120),
+		DisputeProvisionalCreditDays: getEnvInt("DISPUTE_PROVISIONAL_CREDIT_DAYS", 10),
+		DisputeResolutionDays:        getEnvInt("DISPUTE_RESOLUTION_DAYS", 45),
+
+		DormancyPeriodMonths: getEnvInt("DORMANCY_PERIOD_MONTHS", 24),
+		DormancyNoticeDays:   getEnvIntList("DORMANCY_NOTICE_DAYS", []int{90, 30, 7}),
+
+		ApprovalOperations: getEnvStringList("APPROVAL_OPERATIONS",
+			[]string{"ACCOUNT_CLOSURE", "TRANSFER", "KYC_OVERRIDE", "LIEN_RELEASE", "REVERSAL"}),
+		ApprovalTransferThreshold: getEnvFloat("APPROVAL_TRANSFER_THRESHOLD", 500000),
+		ApprovalExpiry:            getEnvDuration("APPROVAL_EXPIRY", 24*time.Hour),
+		ApprovalApprovers:         getEnvList("APPROVAL_APPROVERS"),
 	}
 
 	if cfg.DatabaseURL == "" {
@@ -35,4 +182,78 @@ func getEnv(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if i, err := strconv.Atoi(value); err == nil {
+			return i
+		}
+	}
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
+
+// getEnvIntList parses a comma-separated list of integers, falling back to
+// defaultValue when the variable is unset or an item is not a number.
+func getEnvIntList(key string, defaultValue []int) []int {
+	items := getEnvList(key)
+	if len(items) == 0 {
+		return defaultValue
+	}
+	values := make([]int, 0, len(items))
+	for _, item := range items {
+		i, err := strconv.Atoi(item)
+		if err != nil {
+			return defaultValue
+		}
+		values = append(values, i)
+	}
+	return values
+}
+
+// getEnvStringList is getEnvList with defaultValue used only when the
+// variable is unset, so setting it empty clears the list.
+func getEnvStringList(key string, defaultValue []string) []string {
+	if _, ok := os.LookupEnv(key); !ok {
+		return defaultValue
+	}
+	return getEnvList(key)
+}
+
+// getEnvList splits a comma-separated variable, dropping empty items.
+func getEnvList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -2,9 +2,11 @@ package core
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -18,6 +20,10 @@ type Account struct {
 	Status        string     `json:"status"`
 	OpenedAt      time.Time  `json:"opened_at"`
 	ClosedAt      *time.Time `json:"closed_at,omitempty"`
+	// ProductCode and ProductVersion identify the product terms the account
+	// was opened on. Accounts opened before the product catalog have none.
+	ProductCode    *string `json:"product_code,omitempty"`
+	ProductVersion *int    `json:"product_version,omitempty"`
 }
 
 type AccountService struct {
@@ -28,22 +34,86 @@ func NewAccountService(db *pgxpool.Pool) *AccountService {
 	return &AccountService{db: db}
 }
 
+// CreateAccount opens an account on the latest version of a.ProductCode,
+// which the customer must be eligible for. The account type comes from the
+// product.
 func (s *AccountService) CreateAccount(ctx context.Context, a *Account) error {
+	if a.ProductCode == nil || *a.ProductCode == "" {
+		return fmt.Errorf("%w: product_code is required", ErrInvalidInput)
+	}
+	if a.Status != AccountActive && a.Status != AccountPendingActivation {
+		return fmt.Errorf("%w: accounts open as ACTIVE or PENDING_ACTIVATION", ErrInvalidInput)
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	product, err := scanProduct(tx.QueryRow(ctx, `
+		SELECT `+productColumns+`
+		FROM products
+		WHERE product_code = $1
+		ORDER BY version DESC
+		LIMIT 1`,
+		*a.ProductCode))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("product %s: %w", *a.ProductCode, ErrNotFound)
+		}
+		return err
+	}
+	if product.Status != ProductActive {
+		return fmt.Errorf("%w: product %s is retired", ErrNotEligible, product.ProductCode)
+	}
+
+	var dateOfBirth *time.Time
+	var kycStatus string
+	err = tx.QueryRow(ctx, `
+		SELECT date_of_birth, kyc_status FROM customers WHERE customer_id = $1`,
+		a.CustomerID,
+	).Scan(&dateOfBirth, &kycStatus)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("customer: %w", ErrNotFound)
+		}
+		return err
+	}
+
+	businessDate, err := businessDateTx(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if err := product.checkEligibility(dateOfBirth, kycStatus, businessDate); err != nil {
+		return err
+	}
+
+	a.AccountType = product.AccountType
+	a.ProductVersion = &product.Version
+
 	query := `
-		INSERT INTO accounts (customer_id, branch_id, account_type, account_number, balance, status)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO accounts (customer_id, branch_id, account_type, account_number, balance, status,
+		                      product_code, product_version)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING account_id, opened_at`
 
-	err := s.db.QueryRow(ctx, query, a.CustomerID, a.BranchID, a.AccountType,
-		a.AccountNumber, a.Balance, a.Status).Scan(&a.AccountID, &a.OpenedAt)
+	err = tx.QueryRow(ctx, query, a.CustomerID, a.BranchID, a.AccountType,
+		a.AccountNumber, a.Balance, a.Status, a.ProductCode, a.ProductVersion).Scan(&a.AccountID, &a.OpenedAt)
+	if err != nil {
+		return err
+	}
+	if _, err := recordStatusTx(ctx, tx, a.AccountID, nil, a.Status, "Account opened", nil); err != nil {
+		return err
+	}
 
-	return err
+	return tx.Commit(ctx)
 }
 
 func (s *AccountService) GetAccount(ctx context.Context, id uuid.UUID) (*Account, error) {
 	query := `
 		SELECT account_id, customer_id, branch_id, account_type, account_number, 
-		       balance, status, opened_at, closed_at
+		       balance, status, opened_at, closed_at, product_code, product_version
 		FROM accounts
 		WHERE account_id = $1`
 
@@ -51,6 +121,7 @@ func (s *AccountService) GetAccount(ctx context.Context, id uuid.UUID) (*Account
 	err := s.db.QueryRow(ctx, query, id).Scan(
 		&a.AccountID, &a.CustomerID, &a.BranchID, &a.AccountType,
 		&a.AccountNumber, &a.Balance, &a.Status, &a.OpenedAt, &a.ClosedAt,
+		&a.ProductCode, &a.ProductVersion,
 	)
 
 	if err != nil {
@@ -63,7 +134,7 @@ func (s *AccountService) GetAccount(ctx context.Context, id uuid.UUID) (*Account
 func (s *AccountService) GetAccountByNumber(ctx context.Context, accountNumber string) (*Account, error) {
 	query := `
 		SELECT account_id, customer_id, branch_id, account_type, account_number, 
-		       balance, status, opened_at, closed_at
+		       balance, status, opened_at, closed_at, product_code, product_version
 		FROM accounts
 		WHERE account_number = $1`
 
@@ -71,6 +142,7 @@ func (s *AccountService) GetAccountByNumber(ctx context.Context, accountNumber s
 	err := s.db.QueryRow(ctx, query, accountNumber).Scan(
 		&a.AccountID, &a.CustomerID, &a.BranchID, &a.AccountType,
 		&a.AccountNumber, &a.Balance, &a.Status, &a.OpenedAt, &a.ClosedAt,
+		&a.ProductCode, &a.ProductVersion,
 	)
 
 	if err != nil {
@@ -83,7 +155,7 @@ func (s *AccountService) GetAccountByNumber(ctx context.Context, accountNumber s
 func (s *AccountService) ListAccountsByCustomer(ctx context.Context, customerID uuid.UUID) ([]*Account, error) {
 	query := `
 		SELECT account_id, customer_id, branch_id, account_type, account_number, 
-		       balance, status, opened_at, closed_at
+		       balance, status, opened_at, closed_at, product_code, product_version
 		FROM accounts
 		WHERE customer_id = $1
 		ORDER BY opened_at DESC`
@@ -100,6 +172,7 @@ func (s *AccountService) ListAccountsByCustomer(ctx context.Context, customerID
 		err := rows.Scan(
 			&a.AccountID, &a.CustomerID, &a.BranchID, &a.AccountType,
 			&a.AccountNumber, &a.Balance, &a.Status, &a.OpenedAt, &a.ClosedAt,
+			&a.ProductCode, &a.ProductVersion,
 		)
 		if err != nil {
 			return nil, err
@@ -125,23 +198,5 @@ func (s *AccountService) UpdateBalance(ctx context.Context, accountID uuid.UUID,
 		return ErrAccountClosed
 	}
 
-	return nil
-}
-
-func (s *AccountService) CloseAccount(ctx context.Context, accountID uuid.UUID) error {
-	query := `
-		UPDATE accounts
-		SET status = 'CLOSED', closed_at = now()
-		WHERE account_id = $1 AND status = 'ACTIVE'`
-
-	result, err := s.db.Exec(ctx, query, accountID)
-	if err != nil {
-		return err
-	}
-
-	if result.RowsAffected() == 0 {
-		return ErrNotFound
-	}
-
 	return nil
 }
\ No newline at end of file
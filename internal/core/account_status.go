@@ -0,0 +1,221 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Account statuses.
+const (
+	AccountPendingActivation = "PENDING_ACTIVATION"
+	AccountActive            = "ACTIVE"
+	AccountDormant           = "DORMANT"
+	AccountFrozenDebit       = "FROZEN_DEBIT"
+	AccountFrozenTotal       = "FROZEN_TOTAL"
+	AccountSuspended         = "SUSPENDED"
+	AccountClosed            = "CLOSED"
+)
+
+// accountTransitions lists the statuses each status may move to. A frozen or
+// suspended account has to be released before it can be closed, and a
+// closed account stays closed.
+var accountTransitions = map[string][]string{
+	AccountPendingActivation: {AccountActive, AccountClosed},
+	AccountActive:            {AccountDormant, AccountFrozenDebit, AccountFrozenTotal, AccountSuspended, AccountClosed},
+	AccountDormant:           {AccountActive, AccountFrozenDebit, AccountFrozenTotal, AccountSuspended, AccountClosed},
+	AccountFrozenDebit:       {AccountActive, AccountFrozenTotal, AccountSuspended},
+	AccountFrozenTotal:       {AccountActive, AccountFrozenDebit, AccountSuspended},
+	AccountSuspended:         {AccountActive, AccountFrozenTotal, AccountClosed},
+}
+
+// AccountStatusChange is one entry of an account's status history. FromStatus
+// is nil for the status the account was opened with.
+type AccountStatusChange struct {
+	ChangeID   uuid.UUID `json:"change_id"`
+	AccountID  uuid.UUID `json:"account_id"`
+	FromStatus *string   `json:"from_status,omitempty"`
+	ToStatus   string    `json:"to_status"`
+	Reason     string    `json:"reason"`
+	Actor      *string   `json:"actor,omitempty"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
+
+func canTransition(from, to string) bool {
+	for _, s := range accountTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// postingBlocked returns why an account in status cannot take an entry of
+// txnType, or nil when it can. A debit freeze still takes credits, and so
+// does a dormant account.
+func postingBlocked(status, txnType string) error {
+	switch status {
+	case AccountActive:
+		return nil
+	case AccountDormant:
+		if txnType == "CREDIT" {
+			return nil
+		}
+		return ErrAccountDormant
+	case AccountFrozenDebit:
+		if txnType == "CREDIT" {
+			return nil
+		}
+		return ErrAccountFrozen
+	case AccountFrozenTotal:
+		return ErrAccountFrozen
+	case AccountSuspended:
+		return ErrAccountSuspended
+	case AccountPendingActivation:
+		return ErrAccountInactive
+	default:
+		return ErrAccountClosed
+	}
+}
+
+// isAccountStatusError reports whether err is postingBlocked refusing an
+// entry because of the account's status.
+func isAccountStatusError(err error) bool {
+	return errors.Is(err, ErrAccountClosed) ||
+		errors.Is(err, ErrAccountSuspended) ||
+		errors.Is(err, ErrAccountFrozen) ||
+		errors.Is(err, ErrAccountDormant) ||
+		errors.Is(err, ErrAccountInactive)
+}
+
+// ChangeStatus moves an account to a new status if the transition is
+// permitted, recording the reason and the actor in its status history. A
+// dormant account is only reactivated once the customer's KYC has been
+// verified again since it went dormant. Accounts are closed through
+// ClosureService, which settles them first.
+func (s *AccountService) ChangeStatus(ctx context.Context, accountID uuid.UUID, to, reason, actor string) (*AccountStatusChange, error) {
+	if reason == "" || actor == "" {
+		return nil, ErrInvalidInput
+	}
+	if to == AccountClosed {
+		return nil, fmt.Errorf("%w: accounts are closed through the closure process", ErrInvalidTransition)
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	change, err := changeStatusTx(ctx, tx, accountID, to, reason, &actor)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// changeStatusTx applies a status transition inside an open database
+// transaction so other services can combine it with their own writes.
+func changeStatusTx(ctx context.Context, tx pgx.Tx, accountID uuid.UUID, to, reason string, actor *string) (*AccountStatusChange, error) {
+	var from string
+	err := tx.QueryRow(ctx,
+		`SELECT status FROM accounts WHERE account_id = $1 FOR UPDATE`, accountID,
+	).Scan(&from)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if !canTransition(from, to) {
+		return nil, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, from, to)
+	}
+	if from == AccountDormant && to == AccountActive {
+		if err := checkFreshKYCTx(ctx, tx, accountID); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE accounts
+		SET status = $1, closed_at = CASE WHEN $1 = 'CLOSED' THEN now() ELSE closed_at END
+		WHERE account_id = $2`,
+		to, accountID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return recordStatusTx(ctx, tx, accountID, &from, to, reason, actor)
+}
+
+// checkFreshKYCTx returns ErrKYCRequired unless the account holder's KYC was
+// verified after the account last went dormant.
+func checkFreshKYCTx(ctx context.Context, tx pgx.Tx, accountID uuid.UUID) error {
+	var kycStatus string
+	var verifiedAt, dormantSince *time.Time
+	err := tx.QueryRow(ctx, `
+		SELECT c.kyc_status, c.kyc_verified_at,
+		       (SELECT max(h.changed_at) FROM account_status_history h
+		        WHERE h.account_id = a.account_id AND h.to_status = 'DORMANT')
+		FROM accounts a
+		JOIN customers c ON c.customer_id = a.customer_id
+		WHERE a.account_id = $1`,
+		accountID,
+	).Scan(&kycStatus, &verifiedAt, &dormantSince)
+	if err != nil {
+		return err
+	}
+
+	if kycStatus != "VERIFIED" || verifiedAt == nil ||
+		(dormantSince != nil && !verifiedAt.After(*dormantSince)) {
+		return ErrKYCRequired
+	}
+	return nil
+}
+
+func recordStatusTx(ctx context.Context, tx pgx.Tx, accountID uuid.UUID, from *string, to, reason string, actor *string) (*AccountStatusChange, error) {
+	c := &AccountStatusChange{AccountID: accountID, FromStatus: from, ToStatus: to, Reason: reason, Actor: actor}
+	err := tx.QueryRow(ctx, `
+		INSERT INTO account_status_history (account_id, from_status, to_status, reason, actor)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING change_id, changed_at`,
+		accountID, from, to, reason, actor,
+	).Scan(&c.ChangeID, &c.ChangedAt)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *AccountService) StatusHistory(ctx context.Context, accountID uuid.UUID) ([]*AccountStatusChange, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT change_id, account_id, from_status, to_status, reason, actor, changed_at
+		FROM account_status_history
+		WHERE account_id = $1
+		ORDER BY changed_at, change_id`,
+		accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []*AccountStatusChange
+	for rows.Next() {
+		c := &AccountStatusChange{}
+		err := rows.Scan(&c.ChangeID, &c.AccountID, &c.FromStatus, &c.ToStatus, &c.Reason, &c.Actor, &c.ChangedAt)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, c)
+	}
+	return history, rows.Err()
+}
@@ -0,0 +1,761 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shubhbham/BankingApi_Golang/internal/nacha"
+)
+
+// GLACHSettlement holds ACH entries between origination and settlement with
+// the ACH operator.
+const GLACHSettlement = "ACH_SETTLEMENT"
+
+// ACH entry types, from the receiver's point of view: a CREDIT pays the
+// receiver from our customer's account, a DEBIT collects from the receiver
+// into it.
+const (
+	ACHCredit = "CREDIT"
+	ACHDebit  = "DEBIT"
+)
+
+// ACHConfig identifies the bank and the ACH operator in generated files and
+// names the local directories files are exchanged through. ReturnWindowDays
+// is how many business days after its effective date a sent entry can still
+// be returned; it settles once they have passed.
+type ACHConfig struct {
+	ODFIRouting        string
+	OriginName         string
+	DestinationRouting string
+	DestinationName    string
+	CompanyName        string
+	CompanyID          string
+	OutboundDir        string
+	InboundDir         string
+	ReturnWindowDays   int
+}
+
+type ACHTransfer struct {
+	TransferID          uuid.UUID  `json:"transfer_id"`
+	AccountID           uuid.UUID  `json:"account_id"`
+	BeneficiaryID       *uuid.UUID `json:"beneficiary_id,omitempty"`
+	EntryType           string     `json:"entry_type"`
+	SECCode             string     `json:"sec_code"`
+	RoutingNumber       string     `json:"routing_number"`
+	ReceiverAccount     string     `json:"receiver_account"`
+	ReceiverAccountType string     `json:"receiver_account_type"`
+	ReceiverName        string     `json:"receiver_name"`
+	ReceiverID          *string    `json:"receiver_id,omitempty"`
+	Amount              float64    `json:"amount"`
+	Description         *string    `json:"description,omitempty"`
+	EffectiveDate       time.Time  `json:"effective_date"`
+	Status              string     `json:"status"`
+	TxnID               *uuid.UUID `json:"txn_id,omitempty"`
+	FileID              *uuid.UUID `json:"file_id,omitempty"`
+	TraceNumber         *string    `json:"trace_number,omitempty"`
+	ReturnCode          *string    `json:"return_code,omitempty"`
+	ReturnTxnID         *uuid.UUID `json:"return_txn_id,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	SentAt              *time.Time `json:"sent_at,omitempty"`
+	ReturnedAt          *time.Time `json:"returned_at,omitempty"`
+}
+
+type ACHFile struct {
+	FileID      uuid.UUID `json:"file_id"`
+	FileName    string    `json:"file_name"`
+	Direction   string    `json:"direction"`
+	FileDate    time.Time `json:"file_date"`
+	EntryCount  int       `json:"entry_count"`
+	TotalDebit  float64   `json:"total_debit"`
+	TotalCredit float64   `json:"total_credit"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ACHReturnResult reports what a return file did: the transfers it reversed
+// and the trace numbers that matched no sent transfer.
+type ACHReturnResult struct {
+	File      ACHFile     `json:"file"`
+	Returned  []ACHReturn `json:"returned"`
+	Unmatched []ACHReturn `json:"unmatched"`
+}
+
+type ACHReturn struct {
+	TransferID  *uuid.UUID `json:"transfer_id,omitempty"`
+	TraceNumber string     `json:"trace_number"`
+	ReturnCode  string     `json:"return_code"`
+}
+
+var achAccountNumber = regexp.MustCompile(`^[A-Za-z0-9]{1,17}$`)
+
+type ACHService struct {
+	db   *pgxpool.Pool
+	txns *TransactionService
+	cfg  ACHConfig
+}
+
+func NewACHService(db *pgxpool.Pool, txns *TransactionService, cfg ACHConfig) *ACHService {
+	return &ACHService{db: db, txns: txns, cfg: cfg}
+}
+
+// CreateTransfer originates an ACH entry; it goes out in the next generated
+// file. A CREDIT debits the customer's account at once, against the ACH
+// settlement GL. A DEBIT only credits the account when it settles, once it
+// can no longer be returned; see SettleDue. Transfers to a beneficiary must have been checked with
+// BeneficiaryService.ResolveTransfer and carry its account details; credits
+// to any receiver are held to the cooling limit.
+func (s *ACHService) CreateTransfer(ctx context.Context, t *ACHTransfer) error {
+	t.Amount = roundCents(t.Amount)
+	if t.ReceiverAccountType == "" {
+		t.ReceiverAccountType = "CHECKING"
+	}
+	if t.Amount <= 0 || (t.EntryType != ACHCredit && t.EntryType != ACHDebit) ||
+		(t.SECCode != nacha.SECPPD && t.SECCode != nacha.SECCCD) ||
+		(t.ReceiverAccountType != "CHECKING" && t.ReceiverAccountType != "SAVINGS") {
+		return ErrInvalidInput
+	}
+	if err := s.txns.checkApproval(t.Amount); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if !nacha.ValidRouting(t.RoutingNumber) {
+		return fmt.Errorf("routing number: %w", ErrInvalidInput)
+	}
+	if !achAccountNumber.MatchString(t.ReceiverAccount) || strings.TrimSpace(t.ReceiverName) == "" {
+		return ErrInvalidInput
+	}
+
+	businessDate, err := businessDateTx(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if t.EffectiveDate.IsZero() {
+		t.EffectiveDate = businessDate
+	} else if t.EffectiveDate.Before(businessDate) {
+		return fmt.Errorf("effective date: %w", ErrInvalidInput)
+	}
+
+	description := fmt.Sprintf("ACH credit to %s", t.ReceiverName)
+	if t.EntryType == ACHDebit {
+		description = fmt.Sprintf("ACH debit from %s", t.ReceiverName)
+	}
+	if t.Description != nil && *t.Description != "" {
+		description = *t.Description
+	}
+
+	if t.EntryType == ACHCredit {
+		payee := Payee{AccountNumber: t.ReceiverAccount, BankCode: t.RoutingNumber}
+		if err := checkCoolingTx(ctx, tx, s.txns.cooling, t.AccountID, payee, t.Amount); err != nil {
+			return err
+		}
+	}
+
+	if t.EntryType == ACHCredit {
+		channel := "ACH"
+		txn := &AccountTransaction{
+			AccountID:   t.AccountID,
+			TxnType:     "DEBIT",
+			Amount:      t.Amount,
+			Description: &description,
+			Channel:     &channel,
+		}
+		if err := s.txns.createTransactionTx(ctx, tx, txn); err != nil {
+			return err
+		}
+		err = postJournalTx(ctx, tx, &Journal{
+			DebitGL:   GLCustomerDeposits,
+			CreditGL:  GLACHSettlement,
+			Amount:    t.Amount,
+			Narrative: "ACH credit originated",
+			TxnID:     &txn.TxnID,
+		})
+		if err != nil {
+			return err
+		}
+		t.TxnID = &txn.TxnID
+	} else {
+		var status string
+		err = tx.QueryRow(ctx, `SELECT status FROM accounts WHERE account_id = $1`, t.AccountID).Scan(&status)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrNotFound
+			}
+			return err
+		}
+		if err := postingBlocked(status, "CREDIT"); err != nil {
+			return err
+		}
+	}
+
+	t.Status = "PENDING"
+	err = tx.QueryRow(ctx, `
+		INSERT INTO ach_transfers (account_id, beneficiary_id, entry_type, sec_code, routing_number,
+		    receiver_account, receiver_account_type, receiver_name, receiver_id, amount, description,
+		    effective_date, status, txn_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING transfer_id, created_at`,
+		t.AccountID, t.BeneficiaryID, t.EntryType, t.SECCode, t.RoutingNumber, t.ReceiverAccount,
+		t.ReceiverAccountType, t.ReceiverName, t.ReceiverID, t.Amount, t.Description,
+		t.EffectiveDate, t.Status, t.TxnID,
+	).Scan(&t.TransferID, &t.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+const achTransferColumns = `
+	transfer_id, account_id, beneficiary_id, entry_type, sec_code, routing_number, receiver_account,
+	receiver_account_type, receiver_name, receiver_id, amount, description, effective_date, status,
+	txn_id, file_id, trace_number, return_code, return_txn_id, created_at, sent_at, returned_at`
+
+func scanACHTransfer(row pgx.Row) (*ACHTransfer, error) {
+	t := &ACHTransfer{}
+	err := row.Scan(&t.TransferID, &t.AccountID, &t.BeneficiaryID, &t.EntryType, &t.SECCode,
+		&t.RoutingNumber, &t.ReceiverAccount, &t.ReceiverAccountType, &t.ReceiverName, &t.ReceiverID,
+		&t.Amount, &t.Description, &t.EffectiveDate, &t.Status, &t.TxnID, &t.FileID, &t.TraceNumber,
+		&t.ReturnCode, &t.ReturnTxnID, &t.CreatedAt, &t.SentAt, &t.ReturnedAt)
+	return t, err
+}
+
+func (s *ACHService) GetTransfer(ctx context.Context, id uuid.UUID) (*ACHTransfer, error) {
+	t, err := scanACHTransfer(s.db.QueryRow(ctx,
+		`SELECT `+achTransferColumns+` FROM ach_transfers WHERE transfer_id = $1`, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *ACHService) ListTransfersByAccount(ctx context.Context, accountID uuid.UUID) ([]*ACHTransfer, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT `+achTransferColumns+` FROM ach_transfers WHERE account_id = $1 ORDER BY created_at DESC`,
+		accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transfers := []*ACHTransfer{}
+	for rows.Next() {
+		t, err := scanACHTransfer(rows)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, t)
+	}
+	return transfers, rows.Err()
+}
+
+// GenerateFile writes every pending transfer due by the business date into
+// a NACHA file in the outbound directory, one batch per SEC code and
+// effective date. It returns nil when nothing is pending.
+//
+// The file is written under a temporary name and renamed once the transfers
+// are marked SENT, so the outbound directory only ever holds committed files.
+func (s *ACHService) GenerateFile(ctx context.Context) (*ACHFile, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT `+achTransferColumns+`
+		FROM ach_transfers
+		WHERE status = 'PENDING'
+		  AND effective_date <= (SELECT business_date FROM system_state WHERE id = 1)
+		ORDER BY sec_code, effective_date, created_at
+		FOR UPDATE`)
+	if err != nil {
+		return nil, err
+	}
+	var transfers []*ACHTransfer
+	for rows.Next() {
+		t, err := scanACHTransfer(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		transfers = append(transfers, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(transfers) == 0 {
+		return nil, nil
+	}
+	if !nacha.ValidRouting(s.cfg.ODFIRouting) || !nacha.ValidRouting(s.cfg.DestinationRouting) {
+		return nil, fmt.Errorf("ACH origin and destination routing numbers are not configured")
+	}
+
+	now := time.Now().UTC()
+	f := &ACHFile{Direction: "OUTBOUND", FileDate: civilDate(now)}
+
+	var used int
+	err = tx.QueryRow(ctx, `
+		SELECT count(*) FROM ach_files WHERE direction = 'OUTBOUND' AND file_date = $1`,
+		f.FileDate,
+	).Scan(&used)
+	if err != nil {
+		return nil, err
+	}
+	const modifiers = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	if used >= len(modifiers) {
+		return nil, fmt.Errorf("all %d ACH file ID modifiers for %s are used", len(modifiers), f.FileDate.Format("2006-01-02"))
+	}
+	modifier := modifiers[used : used+1]
+	f.FileName = fmt.Sprintf("ACH_%s_%s.ach", f.FileDate.Format("20060102"), modifier)
+
+	file := &nacha.File{
+		ImmediateDestination: s.cfg.DestinationRouting,
+		ImmediateOrigin:      s.cfg.ODFIRouting,
+		DestinationName:      s.cfg.DestinationName,
+		OriginName:           s.cfg.OriginName,
+		CreatedAt:            now,
+		IDModifier:           modifier,
+	}
+
+	odfi := s.cfg.ODFIRouting[:8]
+
+	var batch *nacha.Batch
+	for _, t := range transfers {
+		if batch == nil || batch.SECCode != t.SECCode || !batch.EffectiveDate.Equal(t.EffectiveDate) {
+			batch = &nacha.Batch{
+				CompanyName:      s.cfg.CompanyName,
+				CompanyID:        s.cfg.CompanyID,
+				SECCode:          t.SECCode,
+				EntryDescription: "PAYMENT",
+				EffectiveDate:    t.EffectiveDate,
+				ODFI:             odfi,
+				Number:           len(file.Batches) + 1,
+			}
+			file.Batches = append(file.Batches, batch)
+		}
+
+		var seq int64
+		if err := tx.QueryRow(ctx, `SELECT nextval('ach_trace_seq')`).Scan(&seq); err != nil {
+			return nil, err
+		}
+		trace := fmt.Sprintf("%s%07d", odfi, seq%10_000_000)
+		t.TraceNumber = &trace
+
+		e := &nacha.Entry{
+			TransactionCode: achTransactionCode(t),
+			RDFI:            t.RoutingNumber,
+			AccountNumber:   t.ReceiverAccount,
+			Amount:          int64(math.Round(t.Amount * 100)),
+			Name:            t.ReceiverName,
+			TraceNumber:     trace,
+		}
+		if t.ReceiverID != nil {
+			e.IndividualID = *t.ReceiverID
+		}
+		if t.Description != nil && *t.Description != "" {
+			e.Addenda = []*nacha.Addenda{{TypeCode: nacha.AddendaPayment, PaymentInfo: *t.Description}}
+		}
+		batch.Entries = append(batch.Entries, e)
+
+		f.EntryCount++
+		if t.EntryType == ACHCredit {
+			f.TotalCredit += t.Amount
+		} else {
+			f.TotalDebit += t.Amount
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := nacha.Write(&buf, file); err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO ach_files (file_name, direction, file_date, id_modifier, entry_count, total_debit, total_credit)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING file_id, created_at`,
+		f.FileName, f.Direction, f.FileDate, modifier, f.EntryCount, roundCents(f.TotalDebit), roundCents(f.TotalCredit),
+	).Scan(&f.FileID, &f.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range transfers {
+		_, err := tx.Exec(ctx, `
+			UPDATE ach_transfers
+			SET status = 'SENT', file_id = $1, trace_number = $2, sent_at = now()
+			WHERE transfer_id = $3`,
+			f.FileID, t.TraceNumber, t.TransferID,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.MkdirAll(s.cfg.OutboundDir, 0o755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(s.cfg.OutboundDir, f.FileName)
+	if err := os.WriteFile(path+".tmp", buf.Bytes(), 0o644); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		os.Remove(path + ".tmp")
+		return nil, err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// SendDue is the end-of-day step that generates the day's ACH file.
+func (s *ACHService) SendDue(ctx context.Context) error {
+	f, err := s.GenerateFile(ctx)
+	if f != nil {
+		log.Printf("ACH file %s written with %d entries", f.FileName, f.EntryCount)
+	}
+	return err
+}
+
+func achTransactionCode(t *ACHTransfer) int {
+	switch {
+	case t.ReceiverAccountType == "SAVINGS" && t.EntryType == ACHCredit:
+		return nacha.SavingsCredit
+	case t.ReceiverAccountType == "SAVINGS":
+		return nacha.SavingsDebit
+	case t.EntryType == ACHCredit:
+		return nacha.CheckingCredit
+	}
+	return nacha.CheckingDebit
+}
+
+// SettleDue settles every sent transfer whose return window has passed by
+// the business date. A DEBIT is credited to the customer's account now,
+// whatever the account's status, as the money has been collected; a CREDIT
+// was posted when it was originated.
+func (s *ACHService) SettleDue(ctx context.Context) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	businessDate, err := businessDateTx(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT `+achTransferColumns+`
+		FROM ach_transfers
+		WHERE status = 'SENT' AND effective_date <= $1
+		ORDER BY effective_date, created_at
+		FOR UPDATE`,
+		businessDate,
+	)
+	if err != nil {
+		return err
+	}
+	var due []*ACHTransfer
+	for rows.Next() {
+		t, err := scanACHTransfer(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		if t.settles(s.txns.calendar, s.cfg.ReturnWindowDays, businessDate) {
+			due = append(due, t)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, t := range due {
+		// A DEBIT that already carries its entry was credited when it was
+		// originated.
+		if t.EntryType == ACHDebit && t.TxnID == nil {
+			if err := s.creditCollectionTx(ctx, tx, t); err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec(ctx, `
+			UPDATE ach_transfers SET status = 'SETTLED', txn_id = $1, settled_at = now()
+			WHERE transfer_id = $2`,
+			t.TxnID, t.TransferID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	if len(due) > 0 {
+		log.Printf("ACH: %d transfers settled", len(due))
+	}
+	return nil
+}
+
+// settles reports whether a sent transfer is past its return window on the
+// business date: window business days after its effective date.
+func (t *ACHTransfer) settles(cal BusinessDays, window int, businessDate time.Time) bool {
+	return !AddBusinessDays(cal, t.EffectiveDate, window).After(businessDate)
+}
+
+// creditCollectionTx credits the customer with a collected ACH debit.
+func (s *ACHService) creditCollectionTx(ctx context.Context, tx pgx.Tx, t *ACHTransfer) error {
+	_, err := tx.Exec(ctx, `SELECT 1 FROM accounts WHERE account_id = $1 FOR UPDATE`, t.AccountID)
+	if err != nil {
+		return err
+	}
+
+	description := fmt.Sprintf("ACH debit from %s", t.ReceiverName)
+	if t.Description != nil && *t.Description != "" {
+		description = *t.Description
+	}
+	channel := "ACH"
+	txn := &AccountTransaction{
+		AccountID:   t.AccountID,
+		TxnType:     "CREDIT",
+		Amount:      t.Amount,
+		Description: &description,
+		Channel:     &channel,
+	}
+	if err := s.txns.postEntryTx(ctx, tx, txn); err != nil {
+		return err
+	}
+	err = postJournalTx(ctx, tx, &Journal{
+		DebitGL:   GLACHSettlement,
+		CreditGL:  GLCustomerDeposits,
+		Amount:    t.Amount,
+		Narrative: "ACH debit settled",
+		TxnID:     &txn.TxnID,
+	})
+	if err != nil {
+		return err
+	}
+	t.TxnID = &txn.TxnID
+	return nil
+}
+
+// ProcessReturnFile applies an ACH return file. Each returned entry is
+// matched to the sent transfer by its original trace number, and the
+// original posting is reversed with the return reason code. A file name can
+// only be processed once.
+func (s *ACHService) ProcessReturnFile(ctx context.Context, name string, data []byte) (*ACHReturnResult, error) {
+	file, err := nacha.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	res := &ACHReturnResult{
+		File:      ACHFile{FileName: name, Direction: "RETURN", FileDate: civilDate(file.CreatedAt)},
+		Returned:  []ACHReturn{},
+		Unmatched: []ACHReturn{},
+	}
+	for _, b := range file.Batches {
+		for _, e := range b.Entries {
+			res.File.EntryCount++
+			amount := float64(e.Amount) / 100
+			if nacha.IsCredit(e.TransactionCode) {
+				res.File.TotalCredit += amount
+			} else {
+				res.File.TotalDebit += amount
+			}
+		}
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO ach_files (file_name, direction, file_date, id_modifier, entry_count, total_debit, total_credit)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (file_name) DO NOTHING
+		RETURNING file_id, created_at`,
+		name, res.File.Direction, res.File.FileDate, file.IDModifier, res.File.EntryCount,
+		roundCents(res.File.TotalDebit), roundCents(res.File.TotalCredit),
+	).Scan(&res.File.FileID, &res.File.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrDuplicateEntry
+		}
+		return nil, err
+	}
+
+	for _, b := range file.Batches {
+		for _, e := range b.Entries {
+			for _, a := range e.Addenda {
+				if a.TypeCode != nacha.AddendaReturn {
+					continue
+				}
+				r := ACHReturn{TraceNumber: a.OriginalTrace, ReturnCode: a.ReturnCode}
+				id, err := s.returnTransferTx(ctx, tx, res.File.FileID, a, e.Amount)
+				if err != nil {
+					return nil, err
+				}
+				if id == nil {
+					res.Unmatched = append(res.Unmatched, r)
+					continue
+				}
+				r.TransferID = id
+				res.Returned = append(res.Returned, r)
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// returnTransferTx reverses the transfer a return addenda refers to. It
+// returns nil when no sent or settled transfer matches the trace number and
+// amount. A DEBIT returned before it settled was never credited, so only its
+// status changes.
+func (s *ACHService) returnTransferTx(ctx context.Context, tx pgx.Tx, fileID uuid.UUID, a *nacha.Addenda, cents int64) (*uuid.UUID, error) {
+	t, err := scanACHTransfer(tx.QueryRow(ctx,
+		`SELECT `+achTransferColumns+` FROM ach_transfers WHERE trace_number = $1 AND status IN ('SENT', 'SETTLED') FOR UPDATE`,
+		a.OriginalTrace))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if int64(math.Round(t.Amount*100)) != cents {
+		return nil, nil
+	}
+
+	var returnTxnID *uuid.UUID
+	if t.TxnID != nil {
+		// Returns are applied whatever the account's balance or status: the
+		// money has already come back, or already gone.
+		_, err = tx.Exec(ctx, `SELECT 1 FROM accounts WHERE account_id = $1 FOR UPDATE`, t.AccountID)
+		if err != nil {
+			return nil, err
+		}
+
+		description := fmt.Sprintf("ACH return %s of trace %s", a.ReturnCode, a.OriginalTrace)
+		channel := "ACH"
+		txn := &AccountTransaction{
+			AccountID:   t.AccountID,
+			TxnType:     "CREDIT",
+			Amount:      t.Amount,
+			Description: &description,
+			Channel:     &channel,
+		}
+		journal := &Journal{DebitGL: GLACHSettlement, CreditGL: GLCustomerDeposits, Amount: t.Amount, Narrative: description}
+		if t.EntryType == ACHDebit {
+			txn.TxnType = "DEBIT"
+			journal.DebitGL, journal.CreditGL = GLCustomerDeposits, GLACHSettlement
+		}
+		if err := s.txns.postEntryTx(ctx, tx, txn); err != nil {
+			return nil, err
+		}
+		journal.TxnID = &txn.TxnID
+		if err := postJournalTx(ctx, tx, journal); err != nil {
+			return nil, err
+		}
+		returnTxnID = &txn.TxnID
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE ach_transfers
+		SET status = 'RETURNED', return_code = $1, return_file_id = $2, return_txn_id = $3, returned_at = now()
+		WHERE transfer_id = $4`,
+		a.ReturnCode, fileID, returnTxnID, t.TransferID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &t.TransferID, nil
+}
+
+// ProcessInbound applies every return file in the inbound directory and
+// moves each one to processed/ once it has been applied. Files that cannot be
+// parsed are moved to rejected/ and left for operations to inspect.
+func (s *ACHService) ProcessInbound(ctx context.Context) ([]*ACHReturnResult, error) {
+	entries, err := os.ReadDir(s.cfg.InboundDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	processed := filepath.Join(s.cfg.InboundDir, "processed")
+	rejected := filepath.Join(s.cfg.InboundDir, "rejected")
+	var results []*ACHReturnResult
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), ".tmp") {
+			continue
+		}
+		path := filepath.Join(s.cfg.InboundDir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return results, err
+		}
+
+		dest := processed
+		res, err := s.ProcessReturnFile(ctx, entry.Name(), data)
+		switch {
+		case errors.Is(err, ErrInvalidInput):
+			log.Printf("ACH return file %s rejected: %v", entry.Name(), err)
+			dest = rejected
+		case err == ErrDuplicateEntry:
+			log.Printf("ACH return file %s was already processed", entry.Name())
+		case err != nil:
+			return results, fmt.Errorf("ACH return file %s: %w", entry.Name(), err)
+		default:
+			results = append(results, res)
+		}
+
+		if err := os.MkdirAll(dest, 0o755); err != nil {
+			return results, err
+		}
+		if err := os.Rename(path, filepath.Join(dest, entry.Name())); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// ReceiveReturns is the end-of-day step that applies waiting return files.
+func (s *ACHService) ReceiveReturns(ctx context.Context) error {
+	results, err := s.ProcessInbound(ctx)
+	for _, r := range results {
+		log.Printf("ACH return file %s: %d returned, %d unmatched", r.File.FileName, len(r.Returned), len(r.Unmatched))
+	}
+	return err
+}
@@ -0,0 +1,45 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+// weekdays treats Monday to Friday as business days, except the listed
+// holidays.
+type weekdays map[time.Time]bool
+
+func (h weekdays) IsBusinessDay(day time.Time) bool {
+	return day.Weekday() != time.Saturday && day.Weekday() != time.Sunday && !h[day]
+}
+
+func TestACHTransferSettles(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC) }
+	cal := weekdays{day(11): true} // a Wednesday holiday
+
+	tests := []struct {
+		name         string
+		effective    time.Time
+		window       int
+		businessDate time.Time
+		want         bool
+	}{
+		{"on the effective date", day(2), 2, day(2), false},
+		{"inside the window", day(2), 2, day(3), false},
+		{"window just passed", day(2), 2, day(4), true},
+		{"window spans a weekend", day(5), 2, day(6), false},
+		{"settles after the weekend", day(5), 2, day(9), true},
+		{"holiday extends the window", day(9), 2, day(11), false},
+		{"settles after the holiday", day(9), 2, day(12), true},
+		{"no window", day(2), 0, day(2), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &ACHTransfer{EffectiveDate: tt.effective}
+			if got := tr.settles(cal, tt.window, tt.businessDate); got != tt.want {
+				t.Errorf("settles on %s = %v, want %v", tt.businessDate.Format("2006-01-02"), got, tt.want)
+			}
+		})
+	}
+}
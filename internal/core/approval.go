@@ -0,0 +1,544 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Operations the approval engine can hold for a second user.
+const (
+	OpAccountClosure = "ACCOUNT_CLOSURE"
+	OpTransfer       = "TRANSFER"
+	OpKYCOverride    = "KYC_OVERRIDE"
+	OpLienRelease    = "LIEN_RELEASE"
+	OpReversal       = "REVERSAL"
+)
+
+// Approval statuses. An approved request is carried out in the database
+// transaction that approves it, so it is only ever seen APPROVED in its
+// decision trail: it ends EXECUTED or FAILED.
+const (
+	ApprovalPending  = "PENDING"
+	ApprovalApproved = "APPROVED"
+	ApprovalRejected = "REJECTED"
+	ApprovalExpired  = "EXPIRED"
+	ApprovalExecuted = "EXECUTED"
+	ApprovalFailed   = "FAILED"
+)
+
+// ApprovalPolicy says which operations need a second user. Transfers need
+// one from TransferThreshold up; lien releases always do. Requests lapse
+// after Expiry. Only the users in Approvers may decide requests, and never
+// their own.
+type ApprovalPolicy struct {
+	Operations        []string
+	TransferThreshold float64
+	Expiry            time.Duration
+	Approvers         []string
+}
+
+// Approval is a request to carry out an operation on SubjectID (an account,
+// customer, lien or transaction) once a different user approves it. Payload
+// holds what the operation needs and Result what it returned.
+type Approval struct {
+	ApprovalID  uuid.UUID        `json:"approval_id"`
+	Operation   string           `json:"operation"`
+	SubjectID   uuid.UUID        `json:"subject_id"`
+	Amount      *float64         `json:"amount,omitempty"`
+	Payload     json.RawMessage  `json:"payload"`
+	Status      string           `json:"status"`
+	RequestedBy string           `json:"requested_by"`
+	CreatedAt   time.Time        `json:"created_at"`
+	ExpiresAt   time.Time        `json:"expires_at"`
+	DecidedBy   *string          `json:"decided_by,omitempty"`
+	DecidedAt   *time.Time       `json:"decided_at,omitempty"`
+	Result      json.RawMessage  `json:"result,omitempty"`
+	Error       *string          `json:"error,omitempty"`
+	CompletedAt *time.Time       `json:"completed_at,omitempty"`
+	Events      []*ApprovalEvent `json:"events,omitempty"`
+}
+
+// ApprovalEvent is one step of an approval's decision trail.
+type ApprovalEvent struct {
+	Event     string    `json:"event"`
+	Actor     *string   `json:"actor,omitempty"`
+	Comment   *string   `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ApprovalAction carries out an approved operation inside the database
+// transaction that approves it. Validate, when set, runs on submission so
+// requests that cannot succeed are refused up front.
+type ApprovalAction struct {
+	Validate func(ctx context.Context, a *Approval) error
+	Execute  func(ctx context.Context, tx pgx.Tx, a *Approval) (any, error)
+}
+
+const approvalColumns = `approval_id, operation, subject_id, amount, payload, status, requested_by,
+	created_at, expires_at, decided_by, decided_at, result, error, completed_at`
+
+func scanApproval(row pgx.Row) (*Approval, error) {
+	a := &Approval{}
+	err := row.Scan(&a.ApprovalID, &a.Operation, &a.SubjectID, &a.Amount, &a.Payload, &a.Status,
+		&a.RequestedBy, &a.CreatedAt, &a.ExpiresAt, &a.DecidedBy, &a.DecidedAt, &a.Result, &a.Error,
+		&a.CompletedAt)
+	return a, err
+}
+
+type ApprovalService struct {
+	db      *pgxpool.Pool
+	policy  ApprovalPolicy
+	actions map[string]ApprovalAction
+}
+
+// NewApprovalService carries out approved requests with actions, keyed by
+// operation. Services that only expire requests may pass nil.
+func NewApprovalService(db *pgxpool.Pool, policy ApprovalPolicy, actions map[string]ApprovalAction) *ApprovalService {
+	if policy.Expiry <= 0 {
+		policy.Expiry = 24 * time.Hour
+	}
+	return &ApprovalService{db: db, policy: policy, actions: actions}
+}
+
+// Required reports whether op needs a second user's approval. amount is the
+// value of a transfer and is ignored for other operations.
+func (p ApprovalPolicy) Required(op string, amount float64) bool {
+	if op == OpLienRelease {
+		return true
+	}
+	for _, o := range p.Operations {
+		if strings.EqualFold(o, op) {
+			return op != OpTransfer || amount >= p.TransferThreshold
+		}
+	}
+	return false
+}
+
+// Required reports whether op needs a second user's approval under the
+// service's policy.
+func (s *ApprovalService) Required(op string, amount float64) bool {
+	return s.policy.Required(op, amount)
+}
+
+// Submit records a pending request for op on subjectID, to be carried out
+// with payload once another user approves it. Only one request at a time
+// may be pending for a subject, except for transfers.
+func (s *ApprovalService) Submit(ctx context.Context, op string, subjectID uuid.UUID, amount *float64, payload any, actor, comment string) (*Approval, error) {
+	if actor == "" {
+		return nil, ErrInvalidInput
+	}
+	action, ok := s.actions[op]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown operation %s", ErrInvalidInput, op)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	a := &Approval{
+		Operation:   op,
+		SubjectID:   subjectID,
+		Amount:      amount,
+		Payload:     data,
+		RequestedBy: actor,
+	}
+	if action.Validate != nil {
+		if err := action.Validate(ctx, a); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// A request that has lapsed but not yet been swept must not block a new one.
+	_, err = tx.Exec(ctx, `
+		WITH expired AS (
+			UPDATE approvals SET status = 'EXPIRED', completed_at = now()
+			WHERE operation = $1 AND subject_id = $2 AND status = 'PENDING' AND expires_at <= now()
+			RETURNING approval_id
+		)
+		INSERT INTO approval_events (approval_id, event)
+		SELECT approval_id, 'EXPIRED' FROM expired`,
+		op, subjectID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err = scanApproval(tx.QueryRow(ctx, `
+		INSERT INTO approvals (operation, subject_id, amount, payload, status, requested_by, expires_at)
+		VALUES ($1, $2, $3, $4, 'PENDING', $5, now() + $6 * interval '1 second')
+		ON CONFLICT (operation, subject_id) WHERE status = 'PENDING' AND operation <> 'TRANSFER' DO NOTHING
+		RETURNING `+approvalColumns,
+		op, subjectID, amount, json.RawMessage(data), actor, s.policy.Expiry.Seconds(),
+	))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrDuplicateEntry
+		}
+		return nil, err
+	}
+
+	if err := recordApprovalEventTx(ctx, tx, a.ApprovalID, "REQUESTED", &actor, comment); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetApproval(ctx, a.ApprovalID)
+}
+
+// Decide approves or rejects a pending request. The approver must differ
+// from the requester and be one of the policy's approvers. An approved
+// request is carried out in the same database transaction as the decision,
+// with the request locked, so it runs exactly once; if the operation fails
+// the request ends FAILED with the error, which is not returned.
+func (s *ApprovalService) Decide(ctx context.Context, approvalID uuid.UUID, approverID string, approve bool, comment string) (*Approval, error) {
+	if approverID == "" {
+		return nil, ErrInvalidInput
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var expired bool
+	a := &Approval{}
+	err = tx.QueryRow(ctx, `
+		SELECT `+approvalColumns+`, expires_at <= now()
+		FROM approvals
+		WHERE approval_id = $1
+		FOR UPDATE`,
+		approvalID,
+	).Scan(&a.ApprovalID, &a.Operation, &a.SubjectID, &a.Amount, &a.Payload, &a.Status,
+		&a.RequestedBy, &a.CreatedAt, &a.ExpiresAt, &a.DecidedBy, &a.DecidedAt, &a.Result, &a.Error,
+		&a.CompletedAt, &expired)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if a.Status != ApprovalPending {
+		return nil, fmt.Errorf("%w: approval request is %s", ErrInvalidInput, a.Status)
+	}
+
+	if expired {
+		_, err = tx.Exec(ctx, `
+			UPDATE approvals SET status = 'EXPIRED', completed_at = now() WHERE approval_id = $1`,
+			approvalID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if err := recordApprovalEventTx(ctx, tx, approvalID, "EXPIRED", nil, ""); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: approval request expired at %s", ErrInvalidInput, a.ExpiresAt.Format(time.RFC3339))
+	}
+
+	if a.RequestedBy == approverID {
+		return nil, fmt.Errorf("requester cannot decide own request: %w", ErrUnauthorized)
+	}
+	if !s.isApprover(approverID) {
+		return nil, fmt.Errorf("%s is not an approver: %w", approverID, ErrUnauthorized)
+	}
+
+	status, event := ApprovalRejected, "REJECTED"
+	if approve {
+		status, event = ApprovalApproved, "APPROVED"
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE approvals
+		SET status = $1, decided_by = $2, decided_at = now(),
+		    completed_at = CASE WHEN $1 = 'REJECTED' THEN now() END
+		WHERE approval_id = $3`,
+		status, approverID, approvalID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordApprovalEventTx(ctx, tx, approvalID, event, &approverID, comment); err != nil {
+		return nil, err
+	}
+
+	if approve {
+		a.Status = ApprovalApproved
+		a.DecidedBy = &approverID
+		if err := s.executeTx(ctx, tx, a); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetApproval(ctx, approvalID)
+}
+
+// executeTx carries out an approved request and records how it ended. The
+// operation runs in a savepoint, so a failed one leaves nothing behind but
+// the FAILED request.
+func (s *ApprovalService) executeTx(ctx context.Context, tx pgx.Tx, a *Approval) error {
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	var result any
+	var opErr error
+	if action, ok := s.actions[a.Operation]; ok {
+		result, opErr = action.Execute(ctx, sp, a)
+	} else {
+		opErr = fmt.Errorf("no action for operation %s", a.Operation)
+	}
+	if opErr != nil {
+		err = sp.Rollback(ctx)
+	} else {
+		err = sp.Commit(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	status, event, comment := approvalOutcome(opErr)
+	var data json.RawMessage
+	var errMsg *string
+	if opErr != nil {
+		errMsg = &comment
+	} else if result != nil {
+		if data, err = json.Marshal(result); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE approvals SET status = $1, result = $2, error = $3, completed_at = now()
+		WHERE approval_id = $4`,
+		status, data, errMsg, a.ApprovalID,
+	)
+	if err != nil {
+		return err
+	}
+	return recordApprovalEventTx(ctx, tx, a.ApprovalID, event, nil, comment)
+}
+
+// approvalOutcome returns the final status, trail event and comment of a
+// request whose operation returned err.
+func approvalOutcome(err error) (status, event, comment string) {
+	if err != nil {
+		return ApprovalFailed, "FAILED", err.Error()
+	}
+	return ApprovalExecuted, "EXECUTED", ""
+}
+
+// isApprover reports whether userID may decide requests. With no approvers
+// configured nobody may, so held operations wait until some are named.
+func (s *ApprovalService) isApprover(userID string) bool {
+	for _, a := range s.policy.Approvers {
+		if a == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func recordApprovalEventTx(ctx context.Context, tx pgx.Tx, approvalID uuid.UUID, event string, actor *string, comment string) error {
+	var note *string
+	if comment != "" {
+		note = &comment
+	}
+	// An approval and its execution share a transaction; the clock keeps
+	// their events in order.
+	_, err := tx.Exec(ctx, `
+		INSERT INTO approval_events (approval_id, event, actor, comment, created_at)
+		VALUES ($1, $2, $3, $4, clock_timestamp())`,
+		approvalID, event, actor, note,
+	)
+	return err
+}
+
+// GetApproval returns a request with its decision trail.
+func (s *ApprovalService) GetApproval(ctx context.Context, approvalID uuid.UUID) (*Approval, error) {
+	a, err := scanApproval(s.db.QueryRow(ctx,
+		`SELECT `+approvalColumns+` FROM approvals WHERE approval_id = $1`, approvalID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT event, actor, comment, created_at
+		FROM approval_events
+		WHERE approval_id = $1
+		ORDER BY created_at`,
+		approvalID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e := &ApprovalEvent{}
+		if err := rows.Scan(&e.Event, &e.Actor, &e.Comment, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		a.Events = append(a.Events, e)
+	}
+	return a, rows.Err()
+}
+
+// ListApprovals returns requests newest first, optionally only those with
+// status.
+func (s *ApprovalService) ListApprovals(ctx context.Context, status string) ([]*Approval, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT `+approvalColumns+`
+		FROM approvals
+		WHERE $1 = '' OR status = $1
+		ORDER BY created_at DESC`,
+		strings.ToUpper(status),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var approvals []*Approval
+	for rows.Next() {
+		a, err := scanApproval(rows)
+		if err != nil {
+			return nil, err
+		}
+		approvals = append(approvals, a)
+	}
+	return approvals, rows.Err()
+}
+
+// ExpireDue marks pending requests past their expiry as EXPIRED. It runs as
+// a background job; Decide also refuses a lapsed request on its own.
+func (s *ApprovalService) ExpireDue(ctx context.Context) error {
+	_, err := s.db.Exec(ctx, `
+		WITH expired AS (
+			UPDATE approvals SET status = 'EXPIRED', completed_at = now()
+			WHERE status = 'PENDING' AND expires_at <= now()
+			RETURNING approval_id
+		)
+		INSERT INTO approval_events (approval_id, event)
+		SELECT approval_id, 'EXPIRED' FROM expired`)
+	return err
+}
+
+// TransferApproval is the payload of a TRANSFER request.
+type TransferApproval struct {
+	FromAccountID uuid.UUID `json:"from_account_id"`
+	ToAccountID   uuid.UUID `json:"to_account_id"`
+	Amount        float64   `json:"amount"`
+	Description   string    `json:"description"`
+}
+
+// ReversalApproval is the payload of a REVERSAL request.
+type ReversalApproval struct {
+	ReasonCode string `json:"reason_code"`
+	Note       string `json:"note"`
+}
+
+// LienReleaseApproval is the payload of a LIEN_RELEASE request.
+type LienReleaseApproval struct {
+	Reason string `json:"reason"`
+}
+
+// ApprovalActions returns the actions of the operations the engine covers.
+// Each operation runs as the user who requested it; the approval records who
+// authorised it.
+func ApprovalActions(closures *ClosureService, txns *TransactionService, customers *CustomerService, liens *LienService) map[string]ApprovalAction {
+	return map[string]ApprovalAction{
+		OpAccountClosure: {
+			Validate: func(ctx context.Context, a *Approval) error {
+				return closures.checkClosable(ctx, a.SubjectID)
+			},
+			Execute: func(ctx context.Context, tx pgx.Tx, a *Approval) (any, error) {
+				var req ClosureRequest
+				if err := json.Unmarshal(a.Payload, &req); err != nil {
+					return nil, err
+				}
+				return closures.closeAccountTx(ctx, tx, a.SubjectID, &req, a.RequestedBy)
+			},
+		},
+		OpTransfer: {
+			Validate: func(ctx context.Context, a *Approval) error {
+				var t TransferApproval
+				if err := json.Unmarshal(a.Payload, &t); err != nil {
+					return err
+				}
+				return txns.checkTransferable(ctx, t.FromAccountID, t.ToAccountID, t.Amount)
+			},
+			Execute: func(ctx context.Context, tx pgx.Tx, a *Approval) (any, error) {
+				var t TransferApproval
+				if err := json.Unmarshal(a.Payload, &t); err != nil {
+					return nil, err
+				}
+				_, err := txns.approvedTransferTx(ctx, tx, t.FromAccountID, t.ToAccountID, t.Amount, t.Description)
+				return nil, err
+			},
+		},
+		OpKYCOverride: {
+			Validate: func(ctx context.Context, a *Approval) error {
+				_, err := customers.GetCustomer(ctx, a.SubjectID)
+				if err == pgx.ErrNoRows {
+					return ErrNotFound
+				}
+				return err
+			},
+			Execute: func(ctx context.Context, tx pgx.Tx, a *Approval) (any, error) {
+				return verifyKYCTx(ctx, tx, a.SubjectID, a.RequestedBy)
+			},
+		},
+		OpLienRelease: {
+			Validate: func(ctx context.Context, a *Approval) error {
+				return liens.checkReleasable(ctx, a.SubjectID)
+			},
+			Execute: func(ctx context.Context, tx pgx.Tx, a *Approval) (any, error) {
+				var r LienReleaseApproval
+				if err := json.Unmarshal(a.Payload, &r); err != nil {
+					return nil, err
+				}
+				return liens.releaseLienTx(ctx, tx, a.SubjectID, r.Reason, a)
+			},
+		},
+		OpReversal: {
+			Validate: func(ctx context.Context, a *Approval) error {
+				var r ReversalApproval
+				if err := json.Unmarshal(a.Payload, &r); err != nil {
+					return err
+				}
+				return txns.checkReversible(ctx, a.SubjectID, r.ReasonCode)
+			},
+			Execute: func(ctx context.Context, tx pgx.Tx, a *Approval) (any, error) {
+				var r ReversalApproval
+				if err := json.Unmarshal(a.Payload, &r); err != nil {
+					return nil, err
+				}
+				return txns.reverseTransactionTx(ctx, tx, a.SubjectID, r.ReasonCode, r.Note, a.RequestedBy)
+			},
+		},
+	}
+}
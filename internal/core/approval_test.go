@@ -0,0 +1,104 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApprovalPolicyRequired(t *testing.T) {
+	policy := ApprovalPolicy{
+		Operations:        []string{OpTransfer, "reversal"},
+		TransferThreshold: 1000,
+	}
+
+	tests := []struct {
+		name   string
+		op     string
+		amount float64
+		want   bool
+	}{
+		{"transfer below the threshold", OpTransfer, 999.99, false},
+		{"transfer at the threshold", OpTransfer, 1000, true},
+		{"transfer above the threshold", OpTransfer, 5000, true},
+		{"listed in another case", OpReversal, 0, true},
+		{"not listed", OpAccountClosure, 0, false},
+		{"lien release always", OpLienRelease, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Required(tt.op, tt.amount); got != tt.want {
+				t.Errorf("Required(%s, %.2f) = %v, want %v", tt.op, tt.amount, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckApproval(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  ApprovalPolicy
+		amount  float64
+		wantErr bool
+	}{
+		{"below the threshold", ApprovalPolicy{Operations: []string{OpTransfer}, TransferThreshold: 1000}, 999.99, false},
+		{"at the threshold", ApprovalPolicy{Operations: []string{OpTransfer}, TransferThreshold: 1000}, 1000, true},
+		{"transfers not held", ApprovalPolicy{Operations: []string{OpReversal}, TransferThreshold: 1000}, 5000, false},
+		{"no policy", ApprovalPolicy{}, 5000, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &TransactionService{approvals: tt.policy}
+			err := s.checkApproval(tt.amount)
+			if tt.wantErr != errors.Is(err, ErrApprovalRequired) {
+				t.Errorf("checkApproval(%.2f) = %v, want approval required %v", tt.amount, err, tt.wantErr)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("checkApproval(%.2f) = %v, want nil", tt.amount, err)
+			}
+		})
+	}
+}
+
+func TestApprovalOutcome(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  string
+		wantEvent   string
+		wantComment string
+	}{
+		{"executed", nil, ApprovalExecuted, "EXECUTED", ""},
+		{"failed", ErrInsufficientFunds, ApprovalFailed, "FAILED", "insufficient funds"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, event, comment := approvalOutcome(tt.err)
+			if status != tt.wantStatus || event != tt.wantEvent || comment != tt.wantComment {
+				t.Errorf("approvalOutcome = (%s, %s, %q), want (%s, %s, %q)",
+					status, event, comment, tt.wantStatus, tt.wantEvent, tt.wantComment)
+			}
+		})
+	}
+}
+
+func TestIsApprover(t *testing.T) {
+	tests := []struct {
+		approvers []string
+		user      string
+		want      bool
+	}{
+		{nil, "anyone", false},
+		{[]string{"checker-1", "checker-2"}, "checker-2", true},
+		{[]string{"checker-1"}, "maker-9", false},
+	}
+
+	for _, tt := range tests {
+		s := &ApprovalService{policy: ApprovalPolicy{Approvers: tt.approvers}}
+		if got := s.isApprover(tt.user); got != tt.want {
+			t.Errorf("isApprover(%q) with %v = %v, want %v", tt.user, tt.approvers, got, tt.want)
+		}
+	}
+}
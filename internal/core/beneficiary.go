@@ -0,0 +1,387 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	BeneficiaryInternal = "INTERNAL"
+	BeneficiaryExternal = "EXTERNAL"
+)
+
+// Beneficiary is a payee registered by a customer. Internal beneficiaries are
+// resolved to an account in this bank; external ones carry the other bank's
+// identifier (IFSC, sort code, routing number) in BankCode.
+type Beneficiary struct {
+	BeneficiaryID   uuid.UUID  `json:"beneficiary_id"`
+	CustomerID      uuid.UUID  `json:"customer_id"`
+	Nickname        string     `json:"nickname"`
+	BeneficiaryType string     `json:"beneficiary_type"`
+	AccountNumber   string     `json:"account_number"`
+	BankCode        *string    `json:"bank_code,omitempty"`
+	AccountID       *uuid.UUID `json:"account_id,omitempty"`
+	PayeeName       string     `json:"payee_name"`
+	Status          string     `json:"status"`
+	CoolingEndsAt   time.Time  `json:"cooling_ends_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// InCooling reports whether the beneficiary is still within its cooling period.
+func (b *Beneficiary) InCooling(now time.Time) bool {
+	return now.Before(b.CoolingEndsAt)
+}
+
+// CoolingPolicy caps what a customer may pay to someone they have only
+// recently started paying. Everything paid to a registered beneficiary since
+// it was added counts towards Limit until its cooling period ends. Payees
+// that are not registered beneficiaries are only limited when Unregistered is
+// set; they then never leave cooling and payments to them within the last
+// Period count instead. A zero Period turns the check off.
+type CoolingPolicy struct {
+	Period       time.Duration
+	Limit        float64
+	Unregistered bool
+}
+
+// Payee is where a payment goes: an account in this bank by AccountID, or
+// an account at another bank by AccountNumber and BankCode.
+type Payee struct {
+	AccountID     *uuid.UUID
+	AccountNumber string
+	BankCode      string
+}
+
+type BeneficiaryService struct {
+	db      *pgxpool.Pool
+	stepUp  *StepUpService
+	cooling CoolingPolicy
+}
+
+func NewBeneficiaryService(db *pgxpool.Pool, stepUp *StepUpService, cooling CoolingPolicy) *BeneficiaryService {
+	return &BeneficiaryService{
+		db:      db,
+		stepUp:  stepUp,
+		cooling: cooling,
+	}
+}
+
+// AddBeneficiary registers a payee after redeeming a BENEFICIARY_ADD step-up
+// challenge. The new beneficiary starts in its cooling period. The payee name
+// is the customer's own; the holder of an internal account is never revealed.
+func (s *BeneficiaryService) AddBeneficiary(ctx context.Context, b *Beneficiary, challengeID uuid.UUID, code string) error {
+	if b.Nickname == "" || b.AccountNumber == "" || b.PayeeName == "" {
+		return ErrInvalidInput
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.stepUp.verifyTx(ctx, tx, challengeID, b.CustomerID, StepUpBeneficiaryAdd, code); err != nil {
+		return err
+	}
+
+	switch b.BeneficiaryType {
+	case BeneficiaryInternal:
+		var accountID uuid.UUID
+		var status string
+		err = tx.QueryRow(ctx, `
+			SELECT account_id, status FROM accounts WHERE account_number = $1`,
+			b.AccountNumber,
+		).Scan(&accountID, &status)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return fmt.Errorf("beneficiary account: %w", ErrNotFound)
+			}
+			return err
+		}
+		if err := postingBlocked(status, "CREDIT"); err != nil {
+			return fmt.Errorf("beneficiary account: %w", err)
+		}
+		b.AccountID = &accountID
+		b.BankCode = nil
+	case BeneficiaryExternal:
+		if b.BankCode == nil || *b.BankCode == "" {
+			return ErrInvalidInput
+		}
+		b.AccountID = nil
+	default:
+		return ErrInvalidInput
+	}
+
+	b.Status = "ACTIVE"
+	b.CoolingEndsAt = time.Now().Add(s.cooling.Period)
+
+	query := `
+		INSERT INTO beneficiaries (customer_id, nickname, beneficiary_type, account_number, bank_code,
+		                           account_id, payee_name, status, cooling_ends_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT DO NOTHING
+		RETURNING beneficiary_id, created_at`
+
+	err = tx.QueryRow(ctx, query, b.CustomerID, b.Nickname, b.BeneficiaryType, b.AccountNumber,
+		b.BankCode, b.AccountID, b.PayeeName, b.Status, b.CoolingEndsAt).Scan(&b.BeneficiaryID, &b.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrDuplicateEntry
+		}
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// DeleteBeneficiary removes a payee after redeeming a BENEFICIARY_DELETE
+// step-up challenge. The row is kept for audit with status DELETED.
+func (s *BeneficiaryService) DeleteBeneficiary(ctx context.Context, customerID, beneficiaryID, challengeID uuid.UUID, code string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.stepUp.verifyTx(ctx, tx, challengeID, customerID, StepUpBeneficiaryDelete, code); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(ctx, `
+		UPDATE beneficiaries
+		SET status = 'DELETED', deleted_at = now()
+		WHERE beneficiary_id = $1 AND customer_id = $2 AND status = 'ACTIVE'`,
+		beneficiaryID, customerID,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *BeneficiaryService) GetBeneficiary(ctx context.Context, id uuid.UUID) (*Beneficiary, error) {
+	query := `
+		SELECT beneficiary_id, customer_id, nickname, beneficiary_type, account_number, bank_code,
+		       account_id, payee_name, status, cooling_ends_at, created_at
+		FROM beneficiaries
+		WHERE beneficiary_id = $1`
+
+	b := &Beneficiary{}
+	err := s.db.QueryRow(ctx, query, id).Scan(
+		&b.BeneficiaryID, &b.CustomerID, &b.Nickname, &b.BeneficiaryType, &b.AccountNumber, &b.BankCode,
+		&b.AccountID, &b.PayeeName, &b.Status, &b.CoolingEndsAt, &b.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (s *BeneficiaryService) ListBeneficiaries(ctx context.Context, customerID uuid.UUID) ([]*Beneficiary, error) {
+	query := `
+		SELECT beneficiary_id, customer_id, nickname, beneficiary_type, account_number, bank_code,
+		       account_id, payee_name, status, cooling_ends_at, created_at
+		FROM beneficiaries
+		WHERE customer_id = $1 AND status = 'ACTIVE'
+		ORDER BY nickname`
+
+	rows, err := s.db.Query(ctx, query, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var beneficiaries []*Beneficiary
+	for rows.Next() {
+		b := &Beneficiary{}
+		err := rows.Scan(
+			&b.BeneficiaryID, &b.CustomerID, &b.Nickname, &b.BeneficiaryType, &b.AccountNumber, &b.BankCode,
+			&b.AccountID, &b.PayeeName, &b.Status, &b.CoolingEndsAt, &b.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		beneficiaries = append(beneficiaries, b)
+	}
+
+	return beneficiaries, rows.Err()
+}
+
+// ResolveTransfer checks that a payment of amount from fromAccountID to the
+// given beneficiary is allowed and returns the beneficiary. The beneficiary
+// must belong to the source account's owner, and the payment must fit in its
+// cooling limit. The posting services apply the cooling limit again when the
+// payment is made; checking here refuses standing and scheduled payments
+// that could never run.
+func (s *BeneficiaryService) ResolveTransfer(ctx context.Context, fromAccountID, beneficiaryID uuid.UUID, amount float64) (*Beneficiary, error) {
+	b, err := s.GetBeneficiary(ctx, beneficiaryID)
+	if err != nil {
+		return nil, fmt.Errorf("beneficiary: %w", err)
+	}
+	if b.Status != "ACTIVE" {
+		return nil, fmt.Errorf("beneficiary: %w", ErrNotFound)
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var owner uuid.UUID
+	err = tx.QueryRow(ctx, `SELECT customer_id FROM accounts WHERE account_id = $1`, fromAccountID).Scan(&owner)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("source account: %w", ErrNotFound)
+		}
+		return nil, err
+	}
+	if owner != b.CustomerID {
+		return nil, fmt.Errorf("beneficiary belongs to another customer: %w", ErrUnauthorized)
+	}
+
+	if err := checkCoolingTx(ctx, tx, s.cooling, fromAccountID, b.payee(), amount); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (b *Beneficiary) payee() Payee {
+	p := Payee{AccountID: b.AccountID, AccountNumber: b.AccountNumber}
+	if b.BankCode != nil {
+		p.BankCode = *b.BankCode
+	}
+	return p
+}
+
+// checkCoolingTx refuses with ErrCoolingLimit a payment of amount from
+// fromAccountID that would take what the account's owner has paid payee
+// during its cooling window over the policy's limit. Payments between a
+// customer's own accounts are not limited. The source account is locked so
+// that concurrent payments from it are counted one after the other.
+func checkCoolingTx(ctx context.Context, tx pgx.Tx, policy CoolingPolicy, fromAccountID uuid.UUID, payee Payee, amount float64) error {
+	if policy.Period <= 0 {
+		return nil
+	}
+
+	var owner uuid.UUID
+	err := tx.QueryRow(ctx, `
+		SELECT customer_id FROM accounts WHERE account_id = $1 FOR UPDATE`,
+		fromAccountID,
+	).Scan(&owner)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("source account: %w", ErrNotFound)
+		}
+		return err
+	}
+
+	// Earliest registration of the payee among the owner's beneficiaries;
+	// both are NULL when it is not registered.
+	var registeredAt, coolingEndsAt *time.Time
+	if payee.AccountID != nil {
+		var payeeOwner uuid.UUID
+		err = tx.QueryRow(ctx, `SELECT customer_id FROM accounts WHERE account_id = $1`, *payee.AccountID).Scan(&payeeOwner)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return fmt.Errorf("destination account: %w", ErrNotFound)
+			}
+			return err
+		}
+		if payeeOwner == owner {
+			return nil
+		}
+		err = tx.QueryRow(ctx, `
+			SELECT MIN(created_at), MIN(cooling_ends_at)
+			FROM beneficiaries
+			WHERE customer_id = $1 AND account_id = $2 AND status = 'ACTIVE'`,
+			owner, *payee.AccountID,
+		).Scan(&registeredAt, &coolingEndsAt)
+	} else {
+		err = tx.QueryRow(ctx, `
+			SELECT MIN(created_at), MIN(cooling_ends_at)
+			FROM beneficiaries
+			WHERE customer_id = $1 AND beneficiary_type = 'EXTERNAL' AND account_number = $2
+			  AND upper(bank_code) = upper($3) AND status = 'ACTIVE'`,
+			owner, payee.AccountNumber, payee.BankCode,
+		).Scan(&registeredAt, &coolingEndsAt)
+	}
+	if err != nil {
+		return err
+	}
+
+	since, cooling := coolingWindow(time.Now(), policy, registeredAt, coolingEndsAt)
+	if !cooling {
+		return nil
+	}
+
+	var paid float64
+	if payee.AccountID != nil {
+		err = tx.QueryRow(ctx, `
+			SELECT COALESCE(SUM(d.amount), 0)
+			FROM account_transactions d
+			JOIN accounts a ON a.account_id = d.account_id
+			JOIN account_transactions c ON c.transfer_id = d.transfer_id AND c.txn_type = 'CREDIT'
+			WHERE a.customer_id = $1 AND c.account_id = $2 AND d.txn_type = 'DEBIT'
+			  AND d.status = 'POSTED' AND d.reversal_of IS NULL AND d.created_at >= $3`,
+			owner, *payee.AccountID, since,
+		).Scan(&paid)
+	} else {
+		err = tx.QueryRow(ctx, `
+			SELECT
+			    (SELECT COALESCE(SUM(t.amount), 0)
+			     FROM ach_transfers t
+			     JOIN accounts a ON a.account_id = t.account_id
+			     WHERE a.customer_id = $1 AND t.entry_type = 'CREDIT' AND t.receiver_account = $2
+			       AND t.routing_number = $3 AND t.status <> 'RETURNED' AND t.created_at >= $4)
+			  + (SELECT COALESCE(SUM(p.amount), 0)
+			     FROM interbank_payments p
+			     JOIN accounts a ON a.account_id = p.account_id
+			     WHERE a.customer_id = $1 AND p.account_number = $2 AND p.bank_code = upper($3)
+			       AND p.status <> 'RETURNED' AND p.submitted_at >= $4)`,
+			owner, payee.AccountNumber, payee.BankCode, since,
+		).Scan(&paid)
+	}
+	if err != nil {
+		return err
+	}
+
+	if coolingExceeded(paid, amount, policy.Limit) {
+		return ErrCoolingLimit
+	}
+	return nil
+}
+
+// coolingWindow returns when the cooling window of a payee began and whether
+// it is still open at now. registeredAt and coolingEndsAt are nil for a payee
+// that is not a registered beneficiary, whose window is the last period when
+// the policy limits unregistered payees.
+func coolingWindow(now time.Time, policy CoolingPolicy, registeredAt, coolingEndsAt *time.Time) (time.Time, bool) {
+	if registeredAt == nil || coolingEndsAt == nil {
+		if !policy.Unregistered {
+			return time.Time{}, false
+		}
+		return now.Add(-policy.Period), true
+	}
+	return *registeredAt, now.Before(*coolingEndsAt)
+}
+
+// coolingExceeded reports whether paying amount on top of what was already
+// paid in the cooling window goes over limit.
+func coolingExceeded(paid, amount, limit float64) bool {
+	return roundCents(paid+amount) > roundCents(limit)
+}
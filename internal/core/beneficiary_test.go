@@ -0,0 +1,66 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCoolingWindow(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	period := 24 * time.Hour
+	policy := CoolingPolicy{Period: period, Limit: 25000}
+	at := func(d time.Duration) *time.Time {
+		v := now.Add(d)
+		return &v
+	}
+
+	tests := []struct {
+		name          string
+		unregistered  bool
+		registeredAt  *time.Time
+		coolingEndsAt *time.Time
+		wantSince     time.Time
+		wantCooling   bool
+	}{
+		{"unregistered payee", false, nil, nil, time.Time{}, false},
+		{"unregistered payee limited", true, nil, nil, now.Add(-period), true},
+		{"new beneficiary", false, at(-time.Hour), at(23 * time.Hour), now.Add(-time.Hour), true},
+		{"cooling just ended", false, at(-period), at(0), now.Add(-period), false},
+		{"old beneficiary", false, at(-30 * period), at(-29 * period), now.Add(-30 * period), false},
+		{"old beneficiary, unregistered limited", true, at(-30 * period), at(-29 * period), now.Add(-30 * period), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy.Unregistered = tt.unregistered
+			since, cooling := coolingWindow(now, policy, tt.registeredAt, tt.coolingEndsAt)
+			if !since.Equal(tt.wantSince) || cooling != tt.wantCooling {
+				t.Errorf("coolingWindow = %v, %v; want %v, %v", since, cooling, tt.wantSince, tt.wantCooling)
+			}
+		})
+	}
+}
+
+func TestCoolingExceeded(t *testing.T) {
+	tests := []struct {
+		name   string
+		paid   float64
+		amount float64
+		want   bool
+	}{
+		{"first payment within limit", 0, 25000, false},
+		{"first payment over limit", 0, 25000.01, true},
+		{"split payments within limit", 20000, 5000, false},
+		{"split payments over limit", 20000, 5000.01, true},
+		{"many small payments", 24999.99, 0.02, true},
+		{"float rounding at the limit", 0.1 + 0.2, 24999.7, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := coolingExceeded(tt.paid, tt.amount, 25000); got != tt.want {
+				t.Errorf("coolingExceeded(%v, %v) = %v, want %v", tt.paid, tt.amount, got, tt.want)
+			}
+		})
+	}
+}
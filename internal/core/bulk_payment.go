@@ -0,0 +1,505 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Bulk payment execution modes.
+const (
+	// BulkExecutionAtomic books each batch (payment information block) as a
+	// whole: one rejected instruction rejects the batch.
+	BulkExecutionAtomic = "ATOMIC"
+	// BulkExecutionBestEffort books every instruction on its own.
+	BulkExecutionBestEffort = "BEST_EFFORT"
+)
+
+// Payment status and reason codes, as used in pain.002 reports.
+const (
+	PaymentStatusPending  = "PDNG"
+	PaymentStatusSettled  = "ACSC"
+	PaymentStatusRejected = "RJCT"
+	PaymentStatusPartial  = "PART"
+
+	ReasonIncorrectAccount  = "AC01"
+	ReasonClosedAccount     = "AC04"
+	ReasonBlockedAccount    = "AC06"
+	ReasonForbidden         = "AG01"
+	ReasonZeroAmount        = "AM01"
+	ReasonCurrency          = "AM03"
+	ReasonInsufficientFunds = "AM04"
+	ReasonControlSum        = "AM10"
+	ReasonNumberOfTxs       = "AM18"
+	ReasonNarrative         = "NARR"
+)
+
+// BulkPaymentFile is an uploaded credit-transfer initiation file. Declared
+// counts and sums are the values stated in the file, checked against the
+// instructions it contains.
+type BulkPaymentFile struct {
+	FileID        uuid.UUID           `json:"file_id"`
+	CustomerID    uuid.UUID           `json:"customer_id"`
+	MessageID     string              `json:"message_id"`
+	MessageName   string              `json:"message_name"`
+	Mode          string              `json:"mode"`
+	DeclaredCount int                 `json:"declared_count"`
+	DeclaredSum   *float64            `json:"declared_sum,omitempty"`
+	Status        string              `json:"status"`
+	Reason        *string             `json:"reason,omitempty"`
+	CreatedAt     time.Time           `json:"created_at"`
+	Batches       []*BulkPaymentBatch `json:"batches"`
+}
+
+type BulkPaymentBatch struct {
+	BatchID       string                    `json:"batch_id"`
+	DebtorAccount string                    `json:"debtor_account"`
+	DeclaredCount *int                      `json:"declared_count,omitempty"`
+	DeclaredSum   *float64                  `json:"declared_sum,omitempty"`
+	Status        string                    `json:"status"`
+	Instructions  []*BulkPaymentInstruction `json:"instructions"`
+
+	seq             int
+	debtorAccountID uuid.UUID
+}
+
+type BulkPaymentInstruction struct {
+	InstructionID   *string    `json:"instruction_id,omitempty"`
+	EndToEndID      string     `json:"end_to_end_id"`
+	CreditorAccount string     `json:"creditor_account"`
+	CreditorName    *string    `json:"creditor_name,omitempty"`
+	Amount          float64    `json:"amount"`
+	Currency        string     `json:"currency"`
+	Remittance      *string    `json:"remittance,omitempty"`
+	Status          string     `json:"status"`
+	Reason          *string    `json:"reason,omitempty"`
+	ReasonText      *string    `json:"reason_text,omitempty"`
+	TxnID           *uuid.UUID `json:"txn_id,omitempty"`
+}
+
+func (i *BulkPaymentInstruction) reject(code, text string) {
+	i.Status = PaymentStatusRejected
+	i.Reason = &code
+	if text != "" {
+		i.ReasonText = &text
+	}
+}
+
+// BulkPaymentService executes bulk payment files through the transfer engine
+// and records the outcome of every instruction.
+type BulkPaymentService struct {
+	db       *pgxpool.Pool
+	txns     *TransactionService
+	currency string
+}
+
+func NewBulkPaymentService(db *pgxpool.Pool, txns *TransactionService, currency string) *BulkPaymentService {
+	return &BulkPaymentService{db: db, txns: txns, currency: currency}
+}
+
+// Process validates and executes a file for customerID. Files failing
+// validation are recorded as rejected rather than returned as errors, so the
+// customer still receives a status report. A message ID the customer has
+// already used returns ErrDuplicateEntry. The file is recorded and executed
+// in one database transaction, so a file that fails part way leaves nothing
+// behind and can be submitted again.
+func (s *BulkPaymentService) Process(ctx context.Context, customerID uuid.UUID, f *BulkPaymentFile) error {
+	if f.Mode != BulkExecutionAtomic && f.Mode != BulkExecutionBestEffort {
+		return ErrInvalidInput
+	}
+	f.CustomerID = customerID
+	f.Status = PaymentStatusPending
+
+	code, text, err := s.validate(ctx, f)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.insertFileTx(ctx, tx, f); err != nil {
+		return err
+	}
+
+	if code != "" {
+		f.reject(code, text)
+		for _, b := range f.Batches {
+			if err := s.saveBatchTx(ctx, tx, f, b); err != nil {
+				return err
+			}
+		}
+	} else {
+		for _, b := range f.Batches {
+			if f.Mode == BulkExecutionAtomic {
+				err = s.executeAtomicTx(ctx, tx, f, b)
+			} else {
+				err = s.executeBestEffortTx(ctx, tx, f, b)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		f.settle()
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE payment_files SET status = $1, reason = $2 WHERE file_id = $3`,
+		f.Status, f.Reason, f.FileID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// validate applies the file-level checks: currency, declared totals and
+// ownership of every debtor account. It returns the reason code of the first
+// failure.
+func (s *BulkPaymentService) validate(ctx context.Context, f *BulkPaymentFile) (string, string, error) {
+	count, sum := 0, 0.0
+	for _, b := range f.Batches {
+		batchSum := 0.0
+		for _, in := range b.Instructions {
+			if in.Currency != s.currency {
+				return ReasonCurrency, fmt.Sprintf("instruction %s is in %s, only %s is supported", in.EndToEndID, in.Currency, s.currency), nil
+			}
+			batchSum += in.Amount
+		}
+		if b.DeclaredCount != nil && *b.DeclaredCount != len(b.Instructions) {
+			return ReasonNumberOfTxs, fmt.Sprintf("batch %s declares %d transactions but contains %d", b.BatchID, *b.DeclaredCount, len(b.Instructions)), nil
+		}
+		if b.DeclaredSum != nil && !sameAmount(*b.DeclaredSum, batchSum) {
+			return ReasonControlSum, fmt.Sprintf("batch %s control sum does not match its instructions", b.BatchID), nil
+		}
+		count += len(b.Instructions)
+		sum += batchSum
+	}
+	if f.DeclaredCount != count {
+		return ReasonNumberOfTxs, fmt.Sprintf("file declares %d transactions but contains %d", f.DeclaredCount, count), nil
+	}
+	if f.DeclaredSum != nil && !sameAmount(*f.DeclaredSum, sum) {
+		return ReasonControlSum, "file control sum does not match its instructions", nil
+	}
+
+	for _, b := range f.Batches {
+		var owner uuid.UUID
+		err := s.db.QueryRow(ctx, `
+			SELECT account_id, customer_id FROM accounts WHERE account_number = $1`,
+			b.DebtorAccount,
+		).Scan(&b.debtorAccountID, &owner)
+		if err != nil && err != pgx.ErrNoRows {
+			return "", "", err
+		}
+		if err == pgx.ErrNoRows || owner != f.CustomerID {
+			return ReasonForbidden, fmt.Sprintf("debtor account %s is not held by the customer", b.DebtorAccount), nil
+		}
+	}
+
+	return "", "", nil
+}
+
+func (s *BulkPaymentService) insertFileTx(ctx context.Context, tx pgx.Tx, f *BulkPaymentFile) error {
+	err := tx.QueryRow(ctx, `
+		INSERT INTO payment_files (customer_id, message_id, message_name, mode, declared_count, declared_sum, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (customer_id, message_id) DO NOTHING
+		RETURNING file_id, created_at`,
+		f.CustomerID, f.MessageID, f.MessageName, f.Mode, f.DeclaredCount, f.DeclaredSum, f.Status,
+	).Scan(&f.FileID, &f.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrDuplicateEntry
+		}
+		return err
+	}
+
+	for bi, b := range f.Batches {
+		b.seq = bi + 1
+		b.Status = PaymentStatusPending
+		for ii, in := range b.Instructions {
+			in.Status = PaymentStatusPending
+			_, err = tx.Exec(ctx, `
+				INSERT INTO payment_file_instructions (file_id, batch_seq, batch_id, debtor_account,
+				    batch_declared_count, batch_declared_sum, seq, instruction_id, end_to_end_id,
+				    creditor_account, creditor_name, amount, currency, remittance, status)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
+				f.FileID, b.seq, b.BatchID, b.DebtorAccount, b.DeclaredCount, b.DeclaredSum, ii+1,
+				in.InstructionID, in.EndToEndID, in.CreditorAccount, in.CreditorName, in.Amount,
+				in.Currency, in.Remittance, in.Status,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// executeAtomicTx books a batch under one savepoint. The first rejected
+// instruction rolls back the batch and rejects the rest with it.
+func (s *BulkPaymentService) executeAtomicTx(ctx context.Context, tx pgx.Tx, f *BulkPaymentFile, b *BulkPaymentBatch) error {
+	batch, err := tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer batch.Rollback(ctx)
+
+	var failed *BulkPaymentInstruction
+	for _, in := range b.Instructions {
+		if err := s.executeTx(ctx, batch, b, in); err != nil {
+			return err
+		}
+		if in.Status == PaymentStatusRejected {
+			failed = in
+			break
+		}
+	}
+
+	if failed == nil {
+		if err := s.saveBatchTx(ctx, batch, f, b); err != nil {
+			return err
+		}
+		return batch.Commit(ctx)
+	}
+
+	if err := batch.Rollback(ctx); err != nil {
+		return err
+	}
+	for _, in := range b.Instructions {
+		if in != failed {
+			in.TxnID = nil
+			in.reject(ReasonNarrative, fmt.Sprintf("batch rejected because instruction %s failed", failed.EndToEndID))
+		}
+	}
+	return s.saveBatchTx(ctx, tx, f, b)
+}
+
+// executeBestEffortTx books every instruction of a batch under its own
+// savepoint.
+func (s *BulkPaymentService) executeBestEffortTx(ctx context.Context, tx pgx.Tx, f *BulkPaymentFile, b *BulkPaymentBatch) error {
+	for i, in := range b.Instructions {
+		instruction, err := tx.Begin(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = s.executeTx(ctx, instruction, b, in)
+		if err == nil {
+			err = s.saveInstructionTx(ctx, instruction, f, i, b, in)
+		}
+		if err == nil {
+			err = instruction.Commit(ctx)
+		}
+		instruction.Rollback(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	b.Status = batchStatus(b)
+	return nil
+}
+
+// executeTx books one instruction. Business rejections are recorded on the
+// instruction and leave the transaction usable; other errors are returned.
+func (s *BulkPaymentService) executeTx(ctx context.Context, tx pgx.Tx, b *BulkPaymentBatch, in *BulkPaymentInstruction) error {
+	if in.Amount <= 0 {
+		in.reject(ReasonZeroAmount, "")
+		return nil
+	}
+
+	var creditorID uuid.UUID
+	err := tx.QueryRow(ctx, `
+		SELECT account_id FROM accounts WHERE account_number = $1`,
+		in.CreditorAccount,
+	).Scan(&creditorID)
+	if err == pgx.ErrNoRows || creditorID == b.debtorAccountID {
+		in.reject(ReasonIncorrectAccount, "")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	description := "Bulk payment " + in.EndToEndID
+	if in.Remittance != nil && *in.Remittance != "" {
+		description = *in.Remittance
+	}
+
+	txnID, err := s.txns.transferTx(ctx, tx, b.debtorAccountID, creditorID, in.Amount, description)
+	return in.settle(txnID, err)
+}
+
+// settle records the outcome of the instruction's transfer: the debit entry
+// on success, or the reason code of a business rejection. Other errors are
+// returned.
+func (in *BulkPaymentInstruction) settle(debitTxnID uuid.UUID, err error) error {
+	switch {
+	case err == nil:
+		in.Status = PaymentStatusSettled
+		in.TxnID = &debitTxnID
+	case errors.Is(err, ErrInsufficientFunds):
+		in.reject(ReasonInsufficientFunds, "")
+	case errors.Is(err, ErrAccountClosed):
+		in.reject(ReasonClosedAccount, err.Error())
+	case errors.Is(err, ErrAccountSuspended), errors.Is(err, ErrAccountFrozen),
+		errors.Is(err, ErrAccountDormant), errors.Is(err, ErrAccountInactive):
+		in.reject(ReasonBlockedAccount, err.Error())
+	case errors.Is(err, ErrNotFound):
+		in.reject(ReasonIncorrectAccount, err.Error())
+	case errors.Is(err, ErrChannelNotAllowed), errors.Is(err, ErrLimitExceeded), errors.Is(err, ErrCoolingLimit),
+		errors.Is(err, ErrApprovalRequired):
+		in.reject(ReasonForbidden, err.Error())
+	default:
+		return err
+	}
+	return nil
+}
+
+func (s *BulkPaymentService) saveBatchTx(ctx context.Context, tx pgx.Tx, f *BulkPaymentFile, b *BulkPaymentBatch) error {
+	for i, in := range b.Instructions {
+		if err := s.saveInstructionTx(ctx, tx, f, i, b, in); err != nil {
+			return err
+		}
+	}
+	b.Status = batchStatus(b)
+	return nil
+}
+
+func (s *BulkPaymentService) saveInstructionTx(ctx context.Context, tx pgx.Tx, f *BulkPaymentFile, i int, b *BulkPaymentBatch, in *BulkPaymentInstruction) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE payment_file_instructions
+		SET status = $1, reason = $2, reason_text = $3, txn_id = $4
+		WHERE file_id = $5 AND batch_seq = $6 AND seq = $7`,
+		in.Status, in.Reason, in.ReasonText, in.TxnID, f.FileID, b.seq, i+1,
+	)
+	return err
+}
+
+// GetFile returns a customer's file with the status of every instruction.
+func (s *BulkPaymentService) GetFile(ctx context.Context, customerID, fileID uuid.UUID) (*BulkPaymentFile, error) {
+	f := &BulkPaymentFile{}
+	err := s.db.QueryRow(ctx, `
+		SELECT file_id, customer_id, message_id, message_name, mode, declared_count, declared_sum, status, reason, created_at
+		FROM payment_files
+		WHERE file_id = $1 AND customer_id = $2`,
+		fileID, customerID,
+	).Scan(&f.FileID, &f.CustomerID, &f.MessageID, &f.MessageName, &f.Mode, &f.DeclaredCount, &f.DeclaredSum,
+		&f.Status, &f.Reason, &f.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT batch_seq, batch_id, debtor_account, batch_declared_count, batch_declared_sum,
+		       instruction_id, end_to_end_id, creditor_account, creditor_name, amount, currency,
+		       remittance, status, reason, reason_text, txn_id
+		FROM payment_file_instructions
+		WHERE file_id = $1
+		ORDER BY batch_seq, seq`,
+		fileID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var b *BulkPaymentBatch
+	for rows.Next() {
+		var batchSeq int
+		var batchID, debtor string
+		var declaredCount *int
+		var declaredSum *float64
+		in := &BulkPaymentInstruction{}
+		err := rows.Scan(&batchSeq, &batchID, &debtor, &declaredCount, &declaredSum,
+			&in.InstructionID, &in.EndToEndID, &in.CreditorAccount, &in.CreditorName, &in.Amount, &in.Currency,
+			&in.Remittance, &in.Status, &in.Reason, &in.ReasonText, &in.TxnID)
+		if err != nil {
+			return nil, err
+		}
+		if b == nil || b.seq != batchSeq {
+			b = &BulkPaymentBatch{seq: batchSeq, BatchID: batchID, DebtorAccount: debtor, DeclaredCount: declaredCount, DeclaredSum: declaredSum}
+			f.Batches = append(f.Batches, b)
+		}
+		b.Instructions = append(b.Instructions, in)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, b := range f.Batches {
+		b.Status = batchStatus(b)
+	}
+	return f, nil
+}
+
+// reject marks the whole file, and so every instruction, as rejected.
+func (f *BulkPaymentFile) reject(code, text string) {
+	f.Status = PaymentStatusRejected
+	f.Reason = &code
+	for _, b := range f.Batches {
+		for _, in := range b.Instructions {
+			in.reject(code, text)
+		}
+	}
+}
+
+// settle derives the file status from its batches.
+func (f *BulkPaymentFile) settle() {
+	settled, rejected := 0, 0
+	for _, b := range f.Batches {
+		for _, in := range b.Instructions {
+			switch in.Status {
+			case PaymentStatusSettled:
+				settled++
+			case PaymentStatusRejected:
+				rejected++
+			}
+		}
+	}
+	f.Status = aggregateStatus(settled, rejected)
+}
+
+func batchStatus(b *BulkPaymentBatch) string {
+	settled, rejected := 0, 0
+	for _, in := range b.Instructions {
+		switch in.Status {
+		case PaymentStatusSettled:
+			settled++
+		case PaymentStatusRejected:
+			rejected++
+		}
+	}
+	return aggregateStatus(settled, rejected)
+}
+
+func aggregateStatus(settled, rejected int) string {
+	switch {
+	case settled > 0 && rejected == 0:
+		return PaymentStatusSettled
+	case settled == 0 && rejected > 0:
+		return PaymentStatusRejected
+	case settled > 0:
+		return PaymentStatusPartial
+	}
+	return PaymentStatusPending
+}
+
+func sameAmount(a, b float64) bool {
+	return math.Abs(a-b) < 0.005
+}
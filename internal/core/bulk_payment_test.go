@@ -0,0 +1,62 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestInstructionSettle(t *testing.T) {
+	debit := uuid.New()
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus string
+		wantReason string
+		wantErr    bool
+	}{
+		{"booked", nil, PaymentStatusSettled, "", false},
+		{"insufficient funds", ErrInsufficientFunds, PaymentStatusRejected, ReasonInsufficientFunds, false},
+		{"closed creditor", fmt.Errorf("destination account: %w", ErrAccountClosed), PaymentStatusRejected, ReasonClosedAccount, false},
+		{"frozen debtor", fmt.Errorf("source account: %w", ErrAccountFrozen), PaymentStatusRejected, ReasonBlockedAccount, false},
+		{"unknown account", fmt.Errorf("destination account: %w", ErrNotFound), PaymentStatusRejected, ReasonIncorrectAccount, false},
+		{"limit", fmt.Errorf("%w: daily debit", ErrLimitExceeded), PaymentStatusRejected, ReasonForbidden, false},
+		{"cooling payee", ErrCoolingLimit, PaymentStatusRejected, ReasonForbidden, false},
+		{"needs approval", fmt.Errorf("%w: over the threshold", ErrApprovalRequired), PaymentStatusRejected, ReasonForbidden, false},
+		{"database failure", errors.New("connection reset"), PaymentStatusPending, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := &BulkPaymentInstruction{Status: PaymentStatusPending}
+			txnID := uuid.Nil
+			if tt.err == nil {
+				txnID = debit
+			}
+
+			err := in.settle(txnID, tt.err)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("settle error = %v, want error %v", err, tt.wantErr)
+			}
+			if in.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", in.Status, tt.wantStatus)
+			}
+			if tt.wantReason != "" && (in.Reason == nil || *in.Reason != tt.wantReason) {
+				t.Errorf("reason = %v, want %s", in.Reason, tt.wantReason)
+			}
+
+			// A settled instruction points at the transfer's debit entry,
+			// never at a fee or tax entry posted after it.
+			if tt.wantStatus == PaymentStatusSettled {
+				if in.TxnID == nil || *in.TxnID != debit {
+					t.Errorf("txn_id = %v, want debit %s", in.TxnID, debit)
+				}
+			} else if in.TxnID != nil {
+				t.Errorf("txn_id = %v, want none", in.TxnID)
+			}
+		})
+	}
+}
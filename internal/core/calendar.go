@@ -0,0 +1,308 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BusinessDays decides which dates are working days for payment execution.
+type BusinessDays interface {
+	IsBusinessDay(day time.Time) bool
+}
+
+// Non-business day handling for dated payments.
+const (
+	NonBusinessDayNext     = "NEXT"
+	NonBusinessDayPrevious = "PREVIOUS"
+	NonBusinessDayNone     = "NONE"
+)
+
+// maxBusinessDaySearch bounds how many days AdjustToBusinessDay looks through,
+// so that a calendar without business days fails instead of hanging.
+const maxBusinessDaySearch = 366
+
+// AdjustToBusinessDay moves day forwards or backwards to the nearest business
+// day according to rule. NONE leaves the date untouched. It fails when there
+// is no business day within a year.
+func AdjustToBusinessDay(cal BusinessDays, day time.Time, rule string) (time.Time, error) {
+	step := 0
+	switch rule {
+	case NonBusinessDayNext:
+		step = 1
+	case NonBusinessDayPrevious:
+		step = -1
+	default:
+		return day, nil
+	}
+
+	for i := 0; i <= maxBusinessDaySearch; i++ {
+		adjusted := day.AddDate(0, 0, i*step)
+		if cal.IsBusinessDay(adjusted) {
+			return adjusted, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("no business day within %d days of %s", maxBusinessDaySearch, day.Format("2006-01-02"))
+}
+
+// AddBusinessDays returns the date n business days after day.
+func AddBusinessDays(cal BusinessDays, day time.Time, n int) time.Time {
+	for n > 0 {
+		day = day.AddDate(0, 0, 1)
+		if cal.IsBusinessDay(day) {
+			n--
+		}
+	}
+	return day
+}
+
+// LastBusinessDayOfMonth returns the last business day of the month that
+// contains day.
+func LastBusinessDayOfMonth(cal BusinessDays, day time.Time) (time.Time, error) {
+	last := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC)
+	return AdjustToBusinessDay(cal, last, NonBusinessDayPrevious)
+}
+
+// Holiday file scopes.
+const (
+	HolidayScopeCountry = "COUNTRY"
+	HolidayScopeBranch  = "BRANCH"
+)
+
+// calendarFile is the layout of calendar.json in the calendar directory.
+// Cutoffs maps a channel (or DEFAULT) to its "15:04" posting cut-off.
+type calendarFile struct {
+	Timezone       string            `json:"timezone"`
+	DefaultCountry string            `json:"default_country"`
+	Weekend        []string          `json:"weekend"`
+	Cutoffs        map[string]string `json:"cutoffs"`
+}
+
+// holidayFile is the layout of every other *.json file in the calendar
+// directory. Code matches branches.country or branches.branch_code depending
+// on Scope.
+type holidayFile struct {
+	Scope    string `json:"scope"`
+	Code     string `json:"code"`
+	Holidays []struct {
+		Date string `json:"date"`
+		Name string `json:"name"`
+	} `json:"holidays"`
+}
+
+// Calendar knows the weekend, country and branch holidays, and the posting
+// cut-off time of each channel. Dates are civil dates at UTC midnight;
+// wall-clock times are interpreted in the calendar's time zone.
+type Calendar struct {
+	location       *time.Location
+	defaultCountry string
+	weekend        map[time.Weekday]bool
+	countries      map[string]map[string]string
+	branches       map[string]map[string]string
+	cutoffs        map[string]time.Duration
+}
+
+// DefaultCalendar has a Saturday/Sunday weekend, no holidays and no cut-offs.
+func DefaultCalendar() *Calendar {
+	return &Calendar{
+		location:  time.UTC,
+		weekend:   map[time.Weekday]bool{time.Saturday: true, time.Sunday: true},
+		countries: map[string]map[string]string{},
+		branches:  map[string]map[string]string{},
+		cutoffs:   map[string]time.Duration{},
+	}
+}
+
+// LoadCalendar reads calendar.json and the holiday files in dir. A missing
+// directory yields the default calendar.
+func LoadCalendar(dir string) (*Calendar, error) {
+	cal := DefaultCalendar()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cal, nil
+		}
+		return nil, err
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if e.Name() == "calendar.json" {
+			err = cal.applySettings(data)
+		} else {
+			err = cal.addHolidays(data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	return cal, nil
+}
+
+func (c *Calendar) applySettings(data []byte) error {
+	var f calendarFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+
+	if f.Timezone != "" {
+		loc, err := time.LoadLocation(f.Timezone)
+		if err != nil {
+			return err
+		}
+		c.location = loc
+	}
+	c.defaultCountry = f.DefaultCountry
+
+	if len(f.Weekend) > 0 {
+		c.weekend = map[time.Weekday]bool{}
+		for _, name := range f.Weekend {
+			wd, ok := parseWeekday(name)
+			if !ok {
+				return fmt.Errorf("unknown weekday %q", name)
+			}
+			c.weekend[wd] = true
+		}
+		if len(c.weekend) == 7 {
+			return fmt.Errorf("weekend covers every day of the week")
+		}
+	}
+
+	for channel, hhmm := range f.Cutoffs {
+		t, err := time.Parse("15:04", hhmm)
+		if err != nil {
+			return fmt.Errorf("cut-off for %s: %w", channel, err)
+		}
+		c.cutoffs[strings.ToUpper(channel)] = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+
+	return nil
+}
+
+func (c *Calendar) addHolidays(data []byte) error {
+	var f holidayFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+
+	var target map[string]map[string]string
+	switch f.Scope {
+	case HolidayScopeCountry:
+		target = c.countries
+	case HolidayScopeBranch:
+		target = c.branches
+	default:
+		return fmt.Errorf("unknown holiday scope %q", f.Scope)
+	}
+	if f.Code == "" {
+		return fmt.Errorf("holiday file without code")
+	}
+
+	days := target[f.Code]
+	if days == nil {
+		days = map[string]string{}
+		target[f.Code] = days
+	}
+	for _, h := range f.Holidays {
+		if _, err := time.Parse("2006-01-02", h.Date); err != nil {
+			return fmt.Errorf("holiday %q: %w", h.Date, err)
+		}
+		days[h.Date] = h.Name
+	}
+
+	return nil
+}
+
+// IsBusinessDay applies the weekend and the default country's holidays.
+func (c *Calendar) IsBusinessDay(day time.Time) bool {
+	return c.isBusinessDay(day, c.defaultCountry, "")
+}
+
+// ForBranch returns the business days of a branch: the weekend, the branch's
+// country holidays and its own local holidays.
+func (c *Calendar) ForBranch(country, branchCode string) BusinessDays {
+	return branchDays{cal: c, country: country, branchCode: branchCode}
+}
+
+func (c *Calendar) isBusinessDay(day time.Time, country, branchCode string) bool {
+	if c.weekend[day.Weekday()] {
+		return false
+	}
+	key := day.Format("2006-01-02")
+	if _, ok := c.countries[country][key]; ok {
+		return false
+	}
+	if _, ok := c.branches[branchCode][key]; ok {
+		return false
+	}
+	return true
+}
+
+// Today returns the current civil date in the calendar's time zone.
+func (c *Calendar) Today() time.Time {
+	return civilDate(time.Now().In(c.location))
+}
+
+// ValueDate returns the value date for an entry posted at now on the system
+// business date through channel at a branch. Entries after the channel's
+// cut-off, or made after midnight before the business date has rolled over,
+// are value-dated to the next business day; non-business days also roll
+// forward.
+func (c *Calendar) ValueDate(now, businessDate time.Time, channel, country, branchCode string) (time.Time, error) {
+	local := now.In(c.location)
+	valueDate := businessDate
+
+	switch today := civilDate(local); {
+	case today.After(businessDate):
+		valueDate = valueDate.AddDate(0, 0, 1)
+	case today.Equal(businessDate):
+		cutoff, ok := c.cutoffs[strings.ToUpper(channel)]
+		if !ok {
+			cutoff, ok = c.cutoffs["DEFAULT"]
+		}
+		if ok {
+			sinceMidnight := local.Sub(time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, c.location))
+			if sinceMidnight >= cutoff {
+				valueDate = valueDate.AddDate(0, 0, 1)
+			}
+		}
+	}
+
+	return AdjustToBusinessDay(c.ForBranch(country, branchCode), valueDate, NonBusinessDayNext)
+}
+
+type branchDays struct {
+	cal        *Calendar
+	country    string
+	branchCode string
+}
+
+func (b branchDays) IsBusinessDay(day time.Time) bool {
+	return b.cal.isBusinessDay(day, b.country, b.branchCode)
+}
+
+func civilDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func parseWeekday(name string) (time.Weekday, bool) {
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		if strings.EqualFold(wd.String(), name) {
+			return wd, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,45 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+// noBusinessDays is a calendar on which every day is a holiday.
+type noBusinessDays struct{}
+
+func (noBusinessDays) IsBusinessDay(time.Time) bool { return false }
+
+func TestAdjustToBusinessDay(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, time.March, d, 0, 0, 0, 0, time.UTC) }
+	cal := weekdays{day(9): true} // Monday 9 March is a holiday
+
+	tests := []struct {
+		name string
+		day  time.Time
+		rule string
+		want time.Time
+	}{
+		{"business day", day(10), NonBusinessDayNext, day(10)},
+		{"saturday, next", day(7), NonBusinessDayNext, day(10)},
+		{"saturday, previous", day(7), NonBusinessDayPrevious, day(6)},
+		{"holiday, previous", day(9), NonBusinessDayPrevious, day(6)},
+		{"saturday, none", day(7), NonBusinessDayNone, day(7)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AdjustToBusinessDay(cal, tt.day, tt.rule)
+			if err != nil || !got.Equal(tt.want) {
+				t.Errorf("AdjustToBusinessDay(%s, %s) = %s, %v; want %s",
+					tt.day.Format("2006-01-02"), tt.rule, got.Format("2006-01-02"), err, tt.want.Format("2006-01-02"))
+			}
+		})
+	}
+
+	for _, rule := range []string{NonBusinessDayNext, NonBusinessDayPrevious} {
+		if _, err := AdjustToBusinessDay(noBusinessDays{}, day(7), rule); err == nil {
+			t.Errorf("AdjustToBusinessDay(%s) on a calendar without business days: want error", rule)
+		}
+	}
+}
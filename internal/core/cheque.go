@@ -0,0 +1,427 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Cheque return reason codes recorded on a returned presentment.
+const (
+	ChequeReturnInsufficientFunds = "INSUFFICIENT_FUNDS"
+	ChequeReturnStopped           = "PAYMENT_STOPPED"
+	ChequeReturnStale             = "STALE_CHEQUE"
+	ChequeReturnSignatureMismatch = "SIGNATURE_MISMATCH"
+	ChequeReturnPostDated         = "POST_DATED"
+	ChequeReturnAccountBlocked    = "ACCOUNT_BLOCKED"
+	ChequeReturnExceedsLimit      = "EXCEEDS_LIMIT"
+)
+
+// chequeReturnFeeCode is the fee code the return charge is posted under.
+const chequeReturnFeeCode = "CHEQUE_RETURN"
+
+const (
+	defaultChequeLeaves = 25
+	maxChequeLeaves     = 100
+	firstChequeLeaf     = 100001
+)
+
+type ChequeBook struct {
+	ChequeBookID uuid.UUID `json:"cheque_book_id"`
+	AccountID    uuid.UUID `json:"account_id"`
+	StartLeaf    int64     `json:"start_leaf"`
+	EndLeaf      int64     `json:"end_leaf"`
+	Status       string    `json:"status"`
+	IssuedAt     time.Time `json:"issued_at"`
+}
+
+type ChequeStopPayment struct {
+	StopID       uuid.UUID `json:"stop_id"`
+	AccountID    uuid.UUID `json:"account_id"`
+	ChequeNumber int64     `json:"cheque_number"`
+	Reason       *string   `json:"reason,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type ChequePresentment struct {
+	PresentmentID     uuid.UUID  `json:"presentment_id"`
+	AccountID         uuid.UUID  `json:"account_id"`
+	ChequeNumber      int64      `json:"cheque_number"`
+	Amount            float64    `json:"amount"`
+	PayeeName         string     `json:"payee_name"`
+	ChequeDate        time.Time  `json:"cheque_date"`
+	SignatureVerified bool       `json:"signature_verified"`
+	Status            string     `json:"status"`
+	ReturnReason      *string    `json:"return_reason,omitempty"`
+	TxnID             *uuid.UUID `json:"txn_id,omitempty"`
+	FeeTxnID          *uuid.UUID `json:"fee_txn_id,omitempty"`
+	PresentedAt       time.Time  `json:"presented_at"`
+}
+
+type ChequeService struct {
+	db        *pgxpool.Pool
+	txns      *TransactionService
+	returnFee float64
+}
+
+func NewChequeService(db *pgxpool.Pool, txns *TransactionService, returnFee float64) *ChequeService {
+	return &ChequeService{db: db, txns: txns, returnFee: returnFee}
+}
+
+// IssueChequeBook allocates the next contiguous range of leaf numbers for
+// the account. Only ACTIVE current and savings accounts may hold cheque books.
+func (s *ChequeService) IssueChequeBook(ctx context.Context, accountID uuid.UUID, leaves int) (*ChequeBook, error) {
+	if leaves == 0 {
+		leaves = defaultChequeLeaves
+	}
+	if leaves < 0 || leaves > maxChequeLeaves {
+		return nil, ErrInvalidInput
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var accountType, status string
+	err = tx.QueryRow(ctx, `
+		SELECT account_type, status FROM accounts WHERE account_id = $1 FOR UPDATE`,
+		accountID,
+	).Scan(&accountType, &status)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if err := postingBlocked(status, "DEBIT"); err != nil {
+		return nil, err
+	}
+
+	// Accounts on a product may have cheques if the product allows the
+	// channel; older accounts go by their type.
+	terms, err := productTermsTx(ctx, tx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if terms != nil && !terms.allows("CHEQUE") {
+		return nil, fmt.Errorf("cheque books are not available on this account's product: %w", ErrInvalidInput)
+	}
+	if terms == nil && accountType != "CURRENT" && accountType != "SAVINGS" {
+		return nil, fmt.Errorf("cheque books are not available for %s accounts: %w", accountType, ErrInvalidInput)
+	}
+
+	// The account row lock above serialises issuance, so MAX is safe here.
+	var startLeaf int64
+	err = tx.QueryRow(ctx, `
+		SELECT COALESCE(MAX(end_leaf) + 1, $2) FROM cheque_books WHERE account_id = $1`,
+		accountID, firstChequeLeaf,
+	).Scan(&startLeaf)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &ChequeBook{
+		AccountID: accountID,
+		StartLeaf: startLeaf,
+		EndLeaf:   startLeaf + int64(leaves) - 1,
+		Status:    "ISSUED",
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO cheque_books (account_id, start_leaf, end_leaf, status)
+		VALUES ($1, $2, $3, $4)
+		RETURNING cheque_book_id, issued_at`,
+		b.AccountID, b.StartLeaf, b.EndLeaf, b.Status,
+	).Scan(&b.ChequeBookID, &b.IssuedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, tx.Commit(ctx)
+}
+
+func (s *ChequeService) ListChequeBooks(ctx context.Context, accountID uuid.UUID) ([]*ChequeBook, error) {
+	query := `
+		SELECT cheque_book_id, account_id, start_leaf, end_leaf, status, issued_at
+		FROM cheque_books
+		WHERE account_id = $1
+		ORDER BY start_leaf`
+
+	rows, err := s.db.Query(ctx, query, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var books []*ChequeBook
+	for rows.Next() {
+		b := &ChequeBook{}
+		err := rows.Scan(&b.ChequeBookID, &b.AccountID, &b.StartLeaf, &b.EndLeaf, &b.Status, &b.IssuedAt)
+		if err != nil {
+			return nil, err
+		}
+		books = append(books, b)
+	}
+
+	return books, rows.Err()
+}
+
+// StopPayment places a stop instruction on a single issued leaf that has not
+// yet been paid.
+func (s *ChequeService) StopPayment(ctx context.Context, sp *ChequeStopPayment) error {
+	issued, err := leafIssued(ctx, s.db, sp.AccountID, sp.ChequeNumber)
+	if err != nil {
+		return err
+	}
+	if !issued {
+		return ErrNotFound
+	}
+
+	var paid bool
+	err = s.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM cheque_presentments
+			WHERE account_id = $1 AND cheque_number = $2 AND status = 'PAID'
+		)`,
+		sp.AccountID, sp.ChequeNumber,
+	).Scan(&paid)
+	if err != nil {
+		return err
+	}
+	if paid {
+		return fmt.Errorf("cheque %d already paid: %w", sp.ChequeNumber, ErrInvalidInput)
+	}
+
+	query := `
+		INSERT INTO cheque_stop_payments (account_id, cheque_number, reason)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (account_id, cheque_number) DO NOTHING
+		RETURNING stop_id, created_at`
+
+	err = s.db.QueryRow(ctx, query, sp.AccountID, sp.ChequeNumber, sp.Reason).
+		Scan(&sp.StopID, &sp.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return ErrDuplicateEntry
+	}
+
+	return err
+}
+
+// PresentCheque runs inward clearing for a single cheque. The cheque is either
+// paid by debiting the drawer's account or returned with a reason code, in
+// which case the return fee is charged when the balance allows it. Cheques
+// are dated against the business date, so a stale or post-dated cheque is
+// returned. Inward clearing is not held for approval: the drawer has already
+// authorised the payment and the cheque must be paid or returned in the
+// clearing cycle, so large cheques are not checked against the approval
+// threshold.
+func (s *ChequeService) PresentCheque(ctx context.Context, p *ChequePresentment) error {
+	if p.Amount <= 0 {
+		return ErrInvalidInput
+	}
+
+	issued, err := leafIssued(ctx, s.db, p.AccountID, p.ChequeNumber)
+	if err != nil {
+		return err
+	}
+	if !issued {
+		return ErrNotFound
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var alreadyPaid, stopped bool
+	err = tx.QueryRow(ctx, `
+		SELECT
+			EXISTS (SELECT 1 FROM cheque_presentments
+			        WHERE account_id = $1 AND cheque_number = $2 AND status = 'PAID'),
+			EXISTS (SELECT 1 FROM cheque_stop_payments
+			        WHERE account_id = $1 AND cheque_number = $2)`,
+		p.AccountID, p.ChequeNumber,
+	).Scan(&alreadyPaid, &stopped)
+	if err != nil {
+		return err
+	}
+	if alreadyPaid {
+		return ErrDuplicateEntry
+	}
+
+	today, err := businessDateTx(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	reason := ChequeReturnStopped
+	if !stopped {
+		reason = chequeDateReturn(p.ChequeDate, today)
+	}
+	if reason == "" && !p.SignatureVerified {
+		reason = ChequeReturnSignatureMismatch
+	}
+
+	if reason == "" {
+		desc := fmt.Sprintf("Cheque %06d to %s", p.ChequeNumber, p.PayeeName)
+		channel := "CHEQUE"
+		debit := &AccountTransaction{
+			AccountID:   p.AccountID,
+			TxnType:     "DEBIT",
+			Amount:      p.Amount,
+			Description: &desc,
+			Channel:     &channel,
+		}
+		err = s.txns.createTransactionTx(ctx, tx, debit)
+		switch {
+		case err == nil:
+			p.TxnID = &debit.TxnID
+		case errors.Is(err, ErrInsufficientFunds):
+			reason = ChequeReturnInsufficientFunds
+		case isAccountStatusError(err):
+			reason = ChequeReturnAccountBlocked
+		case errors.Is(err, ErrChannelNotAllowed), errors.Is(err, ErrLimitExceeded):
+			reason = ChequeReturnExceedsLimit
+		default:
+			return err
+		}
+	}
+
+	if reason == "" {
+		p.Status = "PAID"
+	} else {
+		p.Status = "RETURNED"
+		p.ReturnReason = &reason
+
+		p.FeeTxnID, err = s.chargeReturnFeeTx(ctx, tx, p.AccountID,
+			fmt.Sprintf("Cheque %06d return charge (%s)", p.ChequeNumber, reason))
+		if err != nil {
+			return err
+		}
+	}
+
+	query := `
+		INSERT INTO cheque_presentments (account_id, cheque_number, amount, payee_name, cheque_date,
+		                                 signature_verified, status, return_reason, txn_id, fee_txn_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING presentment_id, presented_at`
+
+	err = tx.QueryRow(ctx, query, p.AccountID, p.ChequeNumber, p.Amount, p.PayeeName, p.ChequeDate,
+		p.SignatureVerified, p.Status, p.ReturnReason, p.TxnID, p.FeeTxnID).
+		Scan(&p.PresentmentID, &p.PresentedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *ChequeService) ListPresentments(ctx context.Context, accountID uuid.UUID, limit, offset int) ([]*ChequePresentment, error) {
+	query := `
+		SELECT presentment_id, account_id, cheque_number, amount, payee_name, cheque_date,
+		       signature_verified, status, return_reason, txn_id, fee_txn_id, presented_at
+		FROM cheque_presentments
+		WHERE account_id = $1
+		ORDER BY presented_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := s.db.Query(ctx, query, accountID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var presentments []*ChequePresentment
+	for rows.Next() {
+		p := &ChequePresentment{}
+		err := rows.Scan(
+			&p.PresentmentID, &p.AccountID, &p.ChequeNumber, &p.Amount, &p.PayeeName, &p.ChequeDate,
+			&p.SignatureVerified, &p.Status, &p.ReturnReason, &p.TxnID, &p.FeeTxnID, &p.PresentedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		presentments = append(presentments, p)
+	}
+
+	return presentments, rows.Err()
+}
+
+func leafIssued(ctx context.Context, db *pgxpool.Pool, accountID uuid.UUID, chequeNumber int64) (bool, error) {
+	var issued bool
+	err := db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM cheque_books
+			WHERE account_id = $1 AND $2 BETWEEN start_leaf AND end_leaf
+		)`,
+		accountID, chequeNumber,
+	).Scan(&issued)
+
+	return issued, err
+}
+
+// chargeReturnFeeTx posts the return fee, and the tax on it, as a fee. The
+// fee is left uncollected rather than overdrawing the account or posting to
+// one that takes no debits. It returns the fee entry, or nil when nothing was
+// charged.
+func (s *ChequeService) chargeReturnFeeTx(ctx context.Context, tx pgx.Tx, accountID uuid.UUID, description string) (*uuid.UUID, error) {
+	if s.returnFee <= 0 {
+		return nil, nil
+	}
+
+	var balance float64
+	var status string
+	err := tx.QueryRow(ctx, `
+		SELECT balance, status FROM accounts WHERE account_id = $1 FOR UPDATE`,
+		accountID,
+	).Scan(&balance, &status)
+	if err != nil {
+		return nil, err
+	}
+	if postingBlocked(status, "DEBIT") != nil {
+		return nil, nil
+	}
+
+	terms, err := productTermsTx(ctx, tx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	available, err := availableTx(ctx, tx, accountID, terms, balance)
+	if err != nil {
+		return nil, err
+	}
+	tax := s.txns.prices.TaxOn(s.returnFee)
+	if available < s.returnFee+tax {
+		return nil, nil
+	}
+
+	feeTxn, _, err := s.txns.postFeeTx(ctx, tx, accountID, chequeReturnFeeCode, s.returnFee, tax, description, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &feeTxn.TxnID, nil
+}
+
+// chequeDateReturn returns the reason a cheque dated on chequeDate is
+// returned on the business date today, or "" when its date is good: a
+// cheque is valid from its date for three months.
+func chequeDateReturn(chequeDate, today time.Time) string {
+	chequeDate, today = civilDate(chequeDate), civilDate(today)
+	switch {
+	case chequeDate.After(today):
+		return ChequeReturnPostDated
+	case today.After(chequeDate.AddDate(0, 3, 0)):
+		return ChequeReturnStale
+	}
+	return ""
+}
@@ -0,0 +1,31 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChequeDateReturn(t *testing.T) {
+	today := time.Date(2024, time.May, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		chequeDate time.Time
+		want       string
+	}{
+		{"dated today", today, ""},
+		{"dated last month", today.AddDate(0, -1, 0), ""},
+		{"last day of validity", today.AddDate(0, -3, 0), ""},
+		{"one day past validity", today.AddDate(0, -3, -1), ChequeReturnStale},
+		{"dated tomorrow", today.AddDate(0, 0, 1), ChequeReturnPostDated},
+		{"time of day ignored", today.Add(23 * time.Hour), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chequeDateReturn(tt.chequeDate, today); got != tt.want {
+				t.Errorf("chequeDateReturn(%s) = %q, want %q", tt.chequeDate.Format(time.DateOnly), got, tt.want)
+			}
+		})
+	}
+}
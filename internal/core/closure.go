@@ -0,0 +1,577 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shubhbham/BankingApi_Golang/internal/pdf"
+)
+
+// GLInterestExpense carries the interest the bank pays on deposits.
+const GLInterestExpense = "INTEREST_EXPENSE"
+
+// Closure payout methods.
+const (
+	PayoutTransfer = "TRANSFER"
+	PayoutCash     = "CASH"
+)
+
+// ClosureRequest asks for an account to be closed. Payout says how a
+// remaining balance leaves the bank's books: a transfer to another account,
+// nominated by number, or cash through the teller session of the actor.
+type ClosureRequest struct {
+	Reason string         `json:"reason"`
+	Payout *ClosurePayout `json:"payout,omitempty"`
+}
+
+type ClosurePayout struct {
+	Method        string     `json:"method"`
+	AccountNumber string     `json:"account_number,omitempty"`
+	SessionID     *uuid.UUID `json:"session_id,omitempty"`
+}
+
+// AccountClosure records how an account was closed. It is what the closure
+// certificate is printed from.
+type AccountClosure struct {
+	ClosureID       uuid.UUID  `json:"closure_id"`
+	AccountID       uuid.UUID  `json:"account_id"`
+	ClosedOn        time.Time  `json:"closed_on"`
+	Reason          string     `json:"reason"`
+	Actor           string     `json:"actor"`
+	FinalInterest   float64    `json:"final_interest"`
+	ClosureFee      float64    `json:"closure_fee"`
+	ClosureFeeTax   float64    `json:"closure_fee_tax"`
+	PayoutMethod    *string    `json:"payout_method,omitempty"`
+	PayoutAmount    float64    `json:"payout_amount"`
+	PayoutAccountID *uuid.UUID `json:"payout_account_id,omitempty"`
+	PayoutTxnID     *uuid.UUID `json:"payout_txn_id,omitempty"`
+	CertificateKey  string     `json:"certificate_key"`
+	ClosedAt        time.Time  `json:"closed_at"`
+}
+
+// ClosureService closes accounts: it checks nothing is left running against
+// the account, posts its final interest and any closure fee, pays out what
+// remains and issues a closure certificate.
+type ClosureService struct {
+	db      *pgxpool.Pool
+	txns    *TransactionService
+	fees    *FeeService
+	tellers *TellerService
+	blobs   BlobStore
+}
+
+func NewClosureService(db *pgxpool.Pool, txns *TransactionService, tellers *TellerService, blobs BlobStore) *ClosureService {
+	return &ClosureService{db: db, txns: txns, fees: NewFeeService(db, txns), tellers: tellers, blobs: blobs}
+}
+
+// closingAccount holds the details of the account being closed, including
+// those printed on the certificate.
+type closingAccount struct {
+	status        string
+	openedAt      time.Time
+	scheduleName  string
+	segment       string
+	interest      InterestScheme
+	accountNumber string
+	accountType   string
+	customerName  string
+	branch        Branch
+}
+
+// CloseAccount closes an account once nothing is left running against it.
+// Final interest is credited and the closure fee charged before the balance
+// is settled; a positive balance must be paid out and an overdrawn account
+// cannot be closed. Everything is posted in one database transaction, so a
+// refused closure leaves the account untouched.
+func (s *ClosureService) CloseAccount(ctx context.Context, accountID uuid.UUID, req *ClosureRequest, actor string) (*AccountClosure, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	closure, err := s.closeAccountTx(ctx, tx, accountID, req, actor)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return closure, nil
+}
+
+// closeAccountTx closes an account inside an open database transaction.
+func (s *ClosureService) closeAccountTx(ctx context.Context, tx pgx.Tx, accountID uuid.UUID, req *ClosureRequest, actor string) (*AccountClosure, error) {
+	if actor == "" {
+		return nil, ErrInvalidInput
+	}
+	if req.Reason == "" {
+		req.Reason = "Closed on request"
+	}
+
+	a := &closingAccount{}
+	err := tx.QueryRow(ctx, `
+		SELECT a.status, a.opened_at, `+feeScheduleName+`, c.segment,
+		       COALESCE(p.interest_method, 'NONE'), COALESCE(p.interest_rate, 0), p.interest_posting,
+		       a.account_number, a.account_type, c.name,
+		       b.branch_code, b.name, b.address, b.city, b.state, b.country
+		FROM accounts a
+		JOIN customers c ON c.customer_id = a.customer_id
+		JOIN branches b ON b.branch_id = a.branch_id
+		LEFT JOIN products p ON p.product_code = a.product_code AND p.version = a.product_version
+		WHERE a.account_id = $1
+		FOR UPDATE OF a`,
+		accountID,
+	).Scan(&a.status, &a.openedAt, &a.scheduleName, &a.segment,
+		&a.interest.Method, &a.interest.AnnualRate, &a.interest.Posting,
+		&a.accountNumber, &a.accountType, &a.customerName,
+		&a.branch.BranchCode, &a.branch.Name, &a.branch.Address, &a.branch.City, &a.branch.State, &a.branch.Country)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if !canTransition(a.status, AccountClosed) {
+		return nil, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, a.status, AccountClosed)
+	}
+
+	blockers, err := closureBlockersTx(ctx, tx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if len(blockers) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrClosureBlocked, strings.Join(blockers, "; "))
+	}
+
+	businessDate, err := businessDateTx(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	closure := &AccountClosure{
+		ClosureID: uuid.New(),
+		AccountID: accountID,
+		ClosedOn:  businessDate,
+		Reason:    req.Reason,
+		Actor:     actor,
+	}
+
+	closure.FinalInterest, err = s.postFinalInterestTx(ctx, tx, accountID, a, businessDate)
+	if err != nil {
+		return nil, err
+	}
+	closure.ClosureFee, closure.ClosureFeeTax, err = s.chargeClosureFeeTx(ctx, tx, accountID, a, businessDate)
+	if err != nil {
+		return nil, err
+	}
+
+	var balance float64
+	if err := tx.QueryRow(ctx, `SELECT balance FROM accounts WHERE account_id = $1`, accountID).Scan(&balance); err != nil {
+		return nil, err
+	}
+	if balance < 0 {
+		return nil, fmt.Errorf("%w: the account is overdrawn by %.2f", ErrClosureBlocked, -balance)
+	}
+	if balance > 0 {
+		if err := s.payOutTx(ctx, tx, accountID, a, balance, req.Payout, actor, closure); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := changeStatusTx(ctx, tx, accountID, AccountClosed, req.Reason, &actor); err != nil {
+		return nil, err
+	}
+
+	closure.CertificateKey = fmt.Sprintf("closures/%s.pdf", accountID)
+	err = tx.QueryRow(ctx, `
+		INSERT INTO account_closures (closure_id, account_id, closed_on, reason, actor, final_interest,
+		                              closure_fee, closure_fee_tax, payout_method, payout_amount,
+		                              payout_account_id, payout_txn_id, certificate_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING closed_at`,
+		closure.ClosureID, closure.AccountID, closure.ClosedOn, closure.Reason, closure.Actor,
+		closure.FinalInterest, closure.ClosureFee, closure.ClosureFeeTax, closure.PayoutMethod,
+		closure.PayoutAmount, closure.PayoutAccountID, closure.PayoutTxnID, closure.CertificateKey,
+	).Scan(&closure.ClosedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.blobs.Put(ctx, closure.CertificateKey, renderClosureCertificate(a, closure)); err != nil {
+		return nil, err
+	}
+	return closure, nil
+}
+
+// checkClosable refuses early a closure that would fail now: the account's
+// status does not allow it or something is still running against it.
+func (s *ClosureService) checkClosable(ctx context.Context, accountID uuid.UUID) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var status string
+	err = tx.QueryRow(ctx, `SELECT status FROM accounts WHERE account_id = $1`, accountID).Scan(&status)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+	if !canTransition(status, AccountClosed) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, status, AccountClosed)
+	}
+
+	blockers, err := closureBlockersTx(ctx, tx, accountID)
+	if err != nil {
+		return err
+	}
+	if len(blockers) > 0 {
+		return fmt.Errorf("%w: %s", ErrClosureBlocked, strings.Join(blockers, "; "))
+	}
+	return nil
+}
+
+// closureBlockersTx lists what is still running against an account and has
+// to be cancelled or finished before it can be closed.
+func closureBlockersTx(ctx context.Context, tx pgx.Tx, accountID uuid.UUID) ([]string, error) {
+	checks := []struct {
+		query string
+		what  string
+	}{
+		{`SELECT count(*) FROM standing_orders WHERE account_id = $1 AND status IN ('ACTIVE', 'SUSPENDED')`,
+			"standing orders to cancel"},
+		{`SELECT count(*) FROM scheduled_payments WHERE from_account_id = $1 AND status = 'PENDING'`,
+			"scheduled payments to cancel"},
+		{`SELECT count(*) FROM ach_transfers WHERE account_id = $1 AND status = 'PENDING'`,
+			"ACH transfers awaiting submission"},
+		{`SELECT count(*) FROM ach_transfers WHERE account_id = $1 AND status = 'SENT'`,
+			"ACH transfers inside their return window"},
+		{`SELECT count(*) FROM interbank_payments WHERE account_id = $1 AND status = 'SUBMITTED'`,
+			"interbank payments awaiting settlement"},
+		{`SELECT count(*) FROM disputes WHERE account_id = $1 AND status NOT IN ('RESOLVED_CUSTOMER', 'RESOLVED_BANK')`,
+			"open disputes"},
+		{`SELECT count(*) FROM liens WHERE account_id = $1 AND status = 'ACTIVE'`,
+			"liens or garnishment orders in force"},
+	}
+
+	var blockers []string
+	for _, c := range checks {
+		var n int
+		if err := tx.QueryRow(ctx, c.query, accountID).Scan(&n); err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			blockers = append(blockers, fmt.Sprintf("%d %s", n, c.what))
+		}
+	}
+	return blockers, nil
+}
+
+// postFinalInterestTx credits the interest the account earned in the current
+// posting period up to the day before closure. Earlier periods are settled by
+// the EOD interest step, so the period runs from the later of its first
+// day, the last interest posting and the day the account was opened. Interest
+// is worked out on the balances by value date, by the product's method.
+func (s *ClosureService) postFinalInterestTx(ctx context.Context, tx pgx.Tx, accountID uuid.UUID, a *closingAccount, businessDate time.Time) (float64, error) {
+	if a.interest.Method == InterestNone || a.interest.AnnualRate <= 0 {
+		return 0, nil
+	}
+
+	to := businessDate.AddDate(0, 0, -1)
+	from := interestPeriodStart(a.interest.Posting, to)
+	if opened := civilDate(a.openedAt); opened.After(from) {
+		from = opened
+	}
+	var lastPosted *time.Time
+	err := tx.QueryRow(ctx, `
+		SELECT max(posting_date) FROM account_transactions
+		WHERE account_id = $1 AND channel = 'INTEREST' AND reversal_of IS NULL`,
+		accountID,
+	).Scan(&lastPosted)
+	if err != nil {
+		return 0, err
+	}
+	if lastPosted != nil && lastPosted.After(from) {
+		from = civilDate(*lastPosted)
+	}
+	if to.Before(from) {
+		return 0, nil
+	}
+
+	balances, err := valueDatedBalancesTx(ctx, tx, accountID, from, to)
+	if err != nil {
+		return 0, err
+	}
+	interest := accruedInterest(a.interest.Method, a.interest.AnnualRate, from, balances)
+	if interest <= 0 {
+		return 0, nil
+	}
+
+	description := fmt.Sprintf("Final interest %s to %s", from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if _, err := s.txns.postInterestTx(ctx, tx, accountID, interest, description); err != nil {
+		return 0, err
+	}
+	return interest, nil
+}
+
+// interestPeriodStart returns the first day of the interest posting period
+// holding day: its month, or its calendar quarter for quarterly posting.
+func interestPeriodStart(posting *string, day time.Time) time.Time {
+	start := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if posting != nil && *posting == InterestPostingQuarterly {
+		start = start.AddDate(0, -int(day.Month()-1)%3, 0)
+	}
+	return start
+}
+
+// accruedInterest works out the interest earned at an annual rate on the
+// balances of consecutive days starting from. DAILY_BALANCE accrues on each
+// day's balance, AVERAGE_MONTHLY_BALANCE on each calendar month's average
+// balance over its days in range. A day, or month, in overdraft earns
+// nothing.
+func accruedInterest(method string, annualRate float64, from time.Time, balances []float64) float64 {
+	var balanceDays float64
+	switch method {
+	case InterestDailyBalance:
+		for _, b := range balances {
+			if b > 0 {
+				balanceDays += b
+			}
+		}
+	case InterestAverageMonthlyBalance:
+		var sum float64
+		for i, b := range balances {
+			sum += b
+			if next := from.AddDate(0, 0, i+1); i == len(balances)-1 || next.Day() == 1 {
+				if sum > 0 {
+					balanceDays += sum // the month's average times its days
+				}
+				sum = 0
+			}
+		}
+	}
+	return roundCents(balanceDays * annualRate / 100 / 365)
+}
+
+// chargeClosureFeeTx charges the schedule's closure fee when the account is
+// closed within the fee's window after opening. The fee and its tax are
+// capped at the balance left after final interest, so charging them never
+// overdraws the account; nothing is charged on an empty account.
+func (s *ClosureService) chargeClosureFeeTx(ctx context.Context, tx pgx.Tx, accountID uuid.UUID, a *closingAccount, businessDate time.Time) (float64, float64, error) {
+	schedule := s.fees.prices.Schedule(a.scheduleName)
+	if schedule == nil || schedule.ClosureFee == nil {
+		return 0, 0, nil
+	}
+	f := schedule.ClosureFee
+	if segmentWaived(f.WaivedSegments, a.segment) ||
+		!businessDate.Before(civilDate(a.openedAt).AddDate(0, f.WithinMonths, 0)) {
+		return 0, 0, nil
+	}
+
+	var balance float64
+	if err := tx.QueryRow(ctx, `SELECT balance FROM accounts WHERE account_id = $1`, accountID).Scan(&balance); err != nil {
+		return 0, 0, err
+	}
+	fee := capFee(f.Charge.Apply(0), balance, s.fees.prices.TaxOn)
+	if fee <= 0 {
+		return 0, 0, nil
+	}
+	description := fmt.Sprintf("Closure fee (closed within %d months of opening)", f.WithinMonths)
+	err := s.fees.chargeTx(ctx, tx, accountID, f.Code, businessDate.Format("2006-01-02"), fee, description)
+	if err != nil {
+		return 0, 0, err
+	}
+	return fee, s.fees.prices.TaxOn(fee), nil
+}
+
+// capFee lowers a fee so that it and the tax on it fit in the balance.
+func capFee(fee, balance float64, taxOn func(float64) float64) float64 {
+	if fee <= 0 || balance <= 0 {
+		return 0
+	}
+	if roundCents(fee+taxOn(fee)) <= balance {
+		return fee
+	}
+	capped := roundCents(fee * balance / (fee + taxOn(fee)))
+	for capped > 0 && roundCents(capped+taxOn(capped)) > balance {
+		capped = roundCents(capped - 0.01)
+	}
+	return capped
+}
+
+// payOutTx moves the remaining balance out of the account as the request
+// nominates. Only an active or dormant account may pay out.
+func (s *ClosureService) payOutTx(ctx context.Context, tx pgx.Tx, accountID uuid.UUID, a *closingAccount, balance float64, payout *ClosurePayout, actor string, closure *AccountClosure) error {
+	if payout == nil {
+		return fmt.Errorf("%w: a balance of %.2f must be paid out to a nominated account or in cash", ErrClosureBlocked, balance)
+	}
+	if a.status != AccountActive && a.status != AccountDormant {
+		return fmt.Errorf("%w: a %s account cannot pay out its balance", ErrClosureBlocked, a.status)
+	}
+
+	description := "Closing balance of account " + a.accountNumber
+	debit := &AccountTransaction{
+		AccountID:   accountID,
+		Amount:      balance,
+		Description: &description,
+	}
+
+	switch payout.Method {
+	case PayoutTransfer:
+		var toAccountID uuid.UUID
+		var toStatus string
+		err := tx.QueryRow(ctx, `
+			SELECT account_id, status FROM accounts WHERE account_number = $1 FOR UPDATE`,
+			payout.AccountNumber,
+		).Scan(&toAccountID, &toStatus)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return fmt.Errorf("payout account: %w", ErrNotFound)
+			}
+			return err
+		}
+		if toAccountID == accountID {
+			return fmt.Errorf("%w: the payout account is the account being closed", ErrInvalidInput)
+		}
+		if err := postingBlocked(toStatus, "CREDIT"); err != nil {
+			return fmt.Errorf("payout account: %w", err)
+		}
+
+		channel := "TRANSFER"
+		transferID := uuid.New()
+		debit.TxnType = "DEBIT"
+		debit.Channel = &channel
+		debit.TransferID = &transferID
+		if err := s.txns.postEntryTx(ctx, tx, debit); err != nil {
+			return err
+		}
+		err = s.txns.postEntryTx(ctx, tx, &AccountTransaction{
+			AccountID:   toAccountID,
+			TxnType:     "CREDIT",
+			Amount:      balance,
+			Description: &description,
+			Channel:     &channel,
+			TransferID:  &transferID,
+		})
+		if err != nil {
+			return err
+		}
+		closure.PayoutAccountID = &toAccountID
+
+	case PayoutCash:
+		if payout.SessionID == nil {
+			return fmt.Errorf("%w: a cash payout needs the teller session", ErrInvalidInput)
+		}
+		if err := s.tellers.payOutCashTx(ctx, tx, *payout.SessionID, actor, debit); err != nil {
+			if err == ErrNotFound {
+				return fmt.Errorf("teller session: %w", err)
+			}
+			return err
+		}
+
+	default:
+		return fmt.Errorf("%w: payout method must be TRANSFER or CASH", ErrInvalidInput)
+	}
+
+	closure.PayoutMethod = &payout.Method
+	closure.PayoutAmount = balance
+	closure.PayoutTxnID = &debit.TxnID
+	return nil
+}
+
+// GetClosure returns the closure record of an account.
+func (s *ClosureService) GetClosure(ctx context.Context, accountID uuid.UUID) (*AccountClosure, error) {
+	c := &AccountClosure{}
+	err := s.db.QueryRow(ctx, `
+		SELECT closure_id, account_id, closed_on, reason, actor, final_interest, closure_fee,
+		       closure_fee_tax, payout_method, payout_amount, payout_account_id, payout_txn_id,
+		       certificate_key, closed_at
+		FROM account_closures
+		WHERE account_id = $1`,
+		accountID,
+	).Scan(&c.ClosureID, &c.AccountID, &c.ClosedOn, &c.Reason, &c.Actor, &c.FinalInterest, &c.ClosureFee,
+		&c.ClosureFeeTax, &c.PayoutMethod, &c.PayoutAmount, &c.PayoutAccountID, &c.PayoutTxnID,
+		&c.CertificateKey, &c.ClosedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// GetCertificate returns the closure certificate PDF of an account.
+func (s *ClosureService) GetCertificate(ctx context.Context, accountID uuid.UUID) ([]byte, error) {
+	c, err := s.GetClosure(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return s.blobs.Get(ctx, c.CertificateKey)
+}
+
+func renderClosureCertificate(a *closingAccount, c *AccountClosure) []byte {
+	doc := pdf.New()
+	doc.AddPage()
+	right := pdf.PageWidth - pdfMargin
+
+	y := 50.0
+	doc.Text(pdfMargin, y, 16, true, a.branch.Name)
+	y += 16
+	doc.Text(pdfMargin, y, 9, false, "Branch code "+a.branch.BranchCode)
+	for _, line := range branchAddressLines(a.branch) {
+		y += 12
+		doc.Text(pdfMargin, y, 9, false, line)
+	}
+	doc.TextRight(right, 50, 14, true, "Account Closure Certificate")
+	doc.TextRight(right, 66, 9, false, "Certificate "+c.ClosureID.String())
+
+	y += 40
+	doc.Text(pdfMargin, y, 10, false, fmt.Sprintf("This is to certify that account %s (%s) held by %s",
+		a.accountNumber, a.accountType, a.customerName))
+	y += 14
+	doc.Text(pdfMargin, y, 10, false, fmt.Sprintf("opened on %s was closed on %s.",
+		a.openedAt.Format("02 Jan 2006"), c.ClosedOn.Format("02 Jan 2006")))
+
+	rows := [][2]string{
+		{"Reason", c.Reason},
+		{"Final interest credited", formatAmount(c.FinalInterest)},
+		{"Closure fee", formatAmount(c.ClosureFee)},
+		{"Tax on closure fee", formatAmount(c.ClosureFeeTax)},
+		{"Balance paid out", formatAmount(c.PayoutAmount)},
+	}
+	if c.PayoutMethod != nil {
+		method := "Cash"
+		if *c.PayoutMethod == PayoutTransfer {
+			method = "Transfer to a nominated account"
+		}
+		rows = append(rows, [2]string{"Paid out by", method})
+	}
+	rows = append(rows, [2]string{"Closing balance", formatAmount(0)})
+
+	y += 30
+	doc.Line(pdfMargin, y-10, right, y-10)
+	for _, row := range rows {
+		doc.Text(pdfMargin, y, 9, true, row[0])
+		doc.TextRight(right, y, 9, false, row[1])
+		y += pdfRowHeight
+	}
+	doc.Line(pdfMargin, y-4, right, y-4)
+
+	y += 20
+	doc.Text(pdfMargin, y, 9, false, "No further liability exists between the bank and the account holder on this account.")
+
+	y = pdf.PageHeight - 30
+	doc.Line(pdfMargin, y-12, right, y-12)
+	doc.Text(pdfMargin, y, 7, false, "This is a computer-generated certificate and does not require a signature.")
+
+	return doc.Bytes()
+}
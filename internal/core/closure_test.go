@@ -0,0 +1,102 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInterestPeriodStart(t *testing.T) {
+	monthly, quarterly := InterestPostingMonthly, InterestPostingQuarterly
+	day := func(m time.Month, d int) time.Time { return time.Date(2026, m, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name    string
+		posting *string
+		day     time.Time
+		want    time.Time
+	}{
+		{"monthly", &monthly, day(5, 17), day(5, 1)},
+		{"monthly on the first", &monthly, day(5, 1), day(5, 1)},
+		{"quarterly first month", &quarterly, day(4, 9), day(4, 1)},
+		{"quarterly last month", &quarterly, day(6, 30), day(4, 1)},
+		{"quarterly year end", &quarterly, day(12, 31), day(10, 1)},
+		{"no posting set", nil, day(2, 28), day(2, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := interestPeriodStart(tt.posting, tt.day); !got.Equal(tt.want) {
+				t.Errorf("interestPeriodStart = %s, want %s", got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestAccruedInterest(t *testing.T) {
+	jan30 := time.Date(2026, 1, 30, 0, 0, 0, 0, time.UTC)
+	repeat := func(b float64, n int) []float64 {
+		out := make([]float64, n)
+		for i := range out {
+			out[i] = b
+		}
+		return out
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		from     time.Time
+		balances []float64
+		want     float64
+	}{
+		{"daily flat balance", InterestDailyBalance, jan30, repeat(36500, 10), 100},
+		{"daily overdrawn days earn nothing", InterestDailyBalance, jan30, []float64{36500, -36500}, 10},
+		{"daily ignores an earlier surplus", InterestDailyBalance, jan30, []float64{73000, -36500}, 20},
+		{"monthly flat balance", InterestAverageMonthlyBalance, jan30, repeat(36500, 10), 100},
+		// January averages below zero and earns nothing; February still does.
+		{"monthly overdrawn month earns nothing", InterestAverageMonthlyBalance, jan30, []float64{-36500, -36500, 36500}, 10},
+		// Within one month a negative day is offset by the month's average.
+		{"monthly averages within the month", InterestAverageMonthlyBalance, jan30, []float64{73000, -36500}, 10},
+		{"no interest method", InterestNone, jan30, repeat(36500, 10), 0},
+		{"no days", InterestDailyBalance, jan30, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := accruedInterest(tt.method, 10, tt.from, tt.balances); got != tt.want {
+				t.Errorf("accruedInterest = %.2f, want %.2f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapFee(t *testing.T) {
+	taxOn := func(fee float64) float64 { return roundCents(fee * 18 / 100) }
+
+	tests := []struct {
+		name    string
+		fee     float64
+		balance float64
+		want    float64
+	}{
+		{"fits", 100, 500, 100},
+		{"fits exactly", 100, 118, 100},
+		{"capped", 100, 59, 50},
+		{"capped to the cent", 100, 10, 8.47},
+		{"empty account", 100, 0, 0},
+		{"overdrawn account", 100, -20, 0},
+		{"no fee", 0, 500, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := capFee(tt.fee, tt.balance, taxOn)
+			if got != tt.want {
+				t.Errorf("capFee(%.2f, %.2f) = %.2f, want %.2f", tt.fee, tt.balance, got, tt.want)
+			}
+			if got+taxOn(got) > tt.balance && got > 0 {
+				t.Errorf("fee %.2f and tax %.2f exceed the balance %.2f", got, taxOn(got), tt.balance)
+			}
+		})
+	}
+}
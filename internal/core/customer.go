@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -14,9 +15,14 @@ type Customer struct {
 	Email       string     `json:"email"`
 	Mobile      string     `json:"mobile"`
 	DateOfBirth *time.Time `json:"date_of_birth,omitempty"`
+	Address     *string    `json:"address,omitempty"`
 	KYCStatus   string     `json:"kyc_status"`
+	Segment     string     `json:"segment"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
+	// KYCVerifiedAt and KYCVerifiedBy record the last KYC verification.
+	KYCVerifiedAt *time.Time `json:"kyc_verified_at,omitempty"`
+	KYCVerifiedBy *string    `json:"kyc_verified_by,omitempty"`
 }
 
 type CustomerService struct {
@@ -29,11 +35,11 @@ func NewCustomerService(db *pgxpool.Pool) *CustomerService {
 
 func (s *CustomerService) CreateCustomer(ctx context.Context, c *Customer) error {
 	query := `
-		INSERT INTO customers (name, email, mobile, date_of_birth, kyc_status)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO customers (name, email, mobile, date_of_birth, address, kyc_status, segment)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING customer_id, created_at, updated_at`
 
-	err := s.db.QueryRow(ctx, query, c.Name, c.Email, c.Mobile, c.DateOfBirth, c.KYCStatus).
+	err := s.db.QueryRow(ctx, query, c.Name, c.Email, c.Mobile, c.DateOfBirth, c.Address, c.KYCStatus, c.Segment).
 		Scan(&c.CustomerID, &c.CreatedAt, &c.UpdatedAt)
 
 	return err
@@ -41,14 +47,15 @@ func (s *CustomerService) CreateCustomer(ctx context.Context, c *Customer) error
 
 func (s *CustomerService) GetCustomer(ctx context.Context, id uuid.UUID) (*Customer, error) {
 	query := `
-		SELECT customer_id, name, email, mobile, date_of_birth, kyc_status, created_at, updated_at
+		SELECT customer_id, name, email, mobile, date_of_birth, address, kyc_status, segment,
+		       created_at, updated_at, kyc_verified_at, kyc_verified_by
 		FROM customers
 		WHERE customer_id = $1`
 
 	c := &Customer{}
 	err := s.db.QueryRow(ctx, query, id).Scan(
-		&c.CustomerID, &c.Name, &c.Email, &c.Mobile, &c.DateOfBirth,
-		&c.KYCStatus, &c.CreatedAt, &c.UpdatedAt,
+		&c.CustomerID, &c.Name, &c.Email, &c.Mobile, &c.DateOfBirth, &c.Address,
+		&c.KYCStatus, &c.Segment, &c.CreatedAt, &c.UpdatedAt, &c.KYCVerifiedAt, &c.KYCVerifiedBy,
 	)
 
 	if err != nil {
@@ -58,23 +65,73 @@ func (s *CustomerService) GetCustomer(ctx context.Context, id uuid.UUID) (*Custo
 	return c, nil
 }
 
+// UpdateCustomer changes a customer's details. The KYC status is left as it
+// is: it only changes through a KYC verification, which may need approval.
 func (s *CustomerService) UpdateCustomer(ctx context.Context, c *Customer) error {
 	query := `
 		UPDATE customers
 		SET name = $1, email = $2, mobile = $3, date_of_birth = $4, 
-		    kyc_status = $5, updated_at = now()
-		WHERE customer_id = $6
-		RETURNING updated_at`
+		    address = $5, segment = COALESCE(NULLIF($6, ''), segment),
+		    updated_at = now()
+		WHERE customer_id = $7
+		RETURNING kyc_status, segment, updated_at`
 
 	err := s.db.QueryRow(ctx, query, c.Name, c.Email, c.Mobile, c.DateOfBirth,
-		c.KYCStatus, c.CustomerID).Scan(&c.UpdatedAt)
+		c.Address, c.Segment, c.CustomerID).Scan(&c.KYCStatus, &c.Segment, &c.UpdatedAt)
 
 	return err
 }
 
+// VerifyKYC records a completed KYC verification of the customer by actor.
+// It is the verification a dormant account's reactivation relies on.
+func (s *CustomerService) VerifyKYC(ctx context.Context, customerID uuid.UUID, actor string) (*Customer, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	c, err := verifyKYCTx(ctx, tx, customerID, actor)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// verifyKYCTx records a KYC verification inside an open database transaction.
+func verifyKYCTx(ctx context.Context, tx pgx.Tx, customerID uuid.UUID, actor string) (*Customer, error) {
+	if actor == "" {
+		return nil, ErrInvalidInput
+	}
+
+	c := &Customer{}
+	err := tx.QueryRow(ctx, `
+		UPDATE customers
+		SET kyc_status = 'VERIFIED', kyc_verified_at = now(), kyc_verified_by = $1, updated_at = now()
+		WHERE customer_id = $2
+		RETURNING customer_id, name, email, mobile, date_of_birth, address, kyc_status, segment,
+		          created_at, updated_at, kyc_verified_at, kyc_verified_by`,
+		actor, customerID,
+	).Scan(
+		&c.CustomerID, &c.Name, &c.Email, &c.Mobile, &c.DateOfBirth, &c.Address,
+		&c.KYCStatus, &c.Segment, &c.CreatedAt, &c.UpdatedAt, &c.KYCVerifiedAt, &c.KYCVerifiedBy,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
 func (s *CustomerService) ListCustomers(ctx context.Context, limit, offset int) ([]*Customer, error) {
 	query := `
-		SELECT customer_id, name, email, mobile, date_of_birth, kyc_status, created_at, updated_at
+		SELECT customer_id, name, email, mobile, date_of_birth, address, kyc_status, segment,
+		       created_at, updated_at, kyc_verified_at, kyc_verified_by
 		FROM customers
 		ORDER BY created_at DESC
 		LIMIT $1 OFFSET $2`
@@ -89,8 +146,8 @@ func (s *CustomerService) ListCustomers(ctx context.Context, limit, offset int)
 	for rows.Next() {
 		c := &Customer{}
 		err := rows.Scan(
-			&c.CustomerID, &c.Name, &c.Email, &c.Mobile, &c.DateOfBirth,
-			&c.KYCStatus, &c.CreatedAt, &c.UpdatedAt,
+			&c.CustomerID, &c.Name, &c.Email, &c.Mobile, &c.DateOfBirth, &c.Address,
+			&c.KYCStatus, &c.Segment, &c.CreatedAt, &c.UpdatedAt, &c.KYCVerifiedAt, &c.KYCVerifiedBy,
 		)
 		if err != nil {
 			return nil, err
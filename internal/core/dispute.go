@@ -0,0 +1,621 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// GLDisputeSettlement carries credits given to customers on disputes until
+// they are recovered by chargeback or written off.
+const GLDisputeSettlement = "DISPUTE_SETTLEMENT"
+
+// Dispute statuses. A dispute is open until it is resolved in favour of the
+// customer or of the bank.
+const (
+	DisputeOpened             = "OPENED"
+	DisputeProvisionalCredit  = "PROVISIONAL_CREDIT"
+	DisputeUnderInvestigation = "UNDER_INVESTIGATION"
+	DisputeResolvedCustomer   = "RESOLVED_CUSTOMER"
+	DisputeResolvedBank       = "RESOLVED_BANK"
+)
+
+// Dispute reason codes.
+const (
+	DisputeUnauthorised    = "UNAUTHORISED"
+	DisputeDuplicate       = "DUPLICATE"
+	DisputeNotReceived     = "NOT_RECEIVED"
+	DisputeIncorrectAmount = "INCORRECT_AMOUNT"
+	DisputeCancelled       = "CANCELLED"
+	DisputeOther           = "OTHER"
+)
+
+var disputeReasons = map[string]bool{
+	DisputeUnauthorised:    true,
+	DisputeDuplicate:       true,
+	DisputeNotReceived:     true,
+	DisputeIncorrectAmount: true,
+	DisputeCancelled:       true,
+	DisputeOther:           true,
+}
+
+// DisputePolicy sets the dispute deadlines. Customers may dispute a debit
+// up to FilingWindowDays after it was posted. The bank owes a provisional
+// credit within ProvisionalCreditDays business days and a resolution within
+// ResolutionDays calendar days of the dispute being opened.
+type DisputePolicy struct {
+	FilingWindowDays      int
+	ProvisionalCreditDays int
+	ResolutionDays        int
+}
+
+type Dispute struct {
+	DisputeID            uuid.UUID            `json:"dispute_id"`
+	AccountID            uuid.UUID            `json:"account_id"`
+	TxnID                uuid.UUID            `json:"txn_id"`
+	ReasonCode           string               `json:"reason_code"`
+	Description          *string              `json:"description,omitempty"`
+	Amount               float64              `json:"amount"`
+	Status               string               `json:"status"`
+	ProvisionalCreditDue time.Time            `json:"provisional_credit_due"`
+	ResolutionDue        time.Time            `json:"resolution_due"`
+	CreditTxnID          *uuid.UUID           `json:"credit_txn_id,omitempty"`
+	CreditReversalTxnID  *uuid.UUID           `json:"credit_reversal_txn_id,omitempty"`
+	Resolution           *string              `json:"resolution,omitempty"`
+	ResolvedBy           *string              `json:"resolved_by,omitempty"`
+	OpenedAt             time.Time            `json:"opened_at"`
+	ResolvedAt           *time.Time           `json:"resolved_at,omitempty"`
+	Notes                []*DisputeNote       `json:"notes,omitempty"`
+	Attachments          []*DisputeAttachment `json:"attachments,omitempty"`
+}
+
+type DisputeNote struct {
+	NoteID    uuid.UUID `json:"note_id"`
+	DisputeID uuid.UUID `json:"dispute_id"`
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type DisputeAttachment struct {
+	AttachmentID uuid.UUID `json:"attachment_id"`
+	DisputeID    uuid.UUID `json:"dispute_id"`
+	FileName     string    `json:"file_name"`
+	ContentType  string    `json:"content_type"`
+	SizeBytes    int       `json:"size_bytes"`
+	BlobKey      string    `json:"-"`
+	UploadedBy   string    `json:"uploaded_by"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type DisputeService struct {
+	db       *pgxpool.Pool
+	txns     *TransactionService
+	calendar BusinessDays
+	blobs    BlobStore
+	policy   DisputePolicy
+}
+
+func NewDisputeService(db *pgxpool.Pool, txns *TransactionService, calendar BusinessDays, blobs BlobStore, policy DisputePolicy) *DisputeService {
+	return &DisputeService{db: db, txns: txns, calendar: calendar, blobs: blobs, policy: policy}
+}
+
+// Open raises a dispute against a debit on the account. Amount defaults to
+// the whole transaction; a partial amount may be disputed.
+func (s *DisputeService) Open(ctx context.Context, d *Dispute) error {
+	d.Amount = roundCents(d.Amount)
+	if !disputeReasons[d.ReasonCode] || d.Amount < 0 {
+		return ErrInvalidInput
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// The entry is locked so a reversal cannot run alongside.
+	txn, err := scanTransaction(tx.QueryRow(ctx,
+		`SELECT `+transactionColumns+` FROM account_transactions WHERE txn_id = $1 AND account_id = $2 FOR UPDATE`,
+		d.TxnID, d.AccountID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+	if txn.TxnType != "DEBIT" || txn.ReversalOf != nil {
+		return fmt.Errorf("%w: only debits can be disputed", ErrInvalidInput)
+	}
+	disputes, err := disputeStatusesTx(ctx, tx, []uuid.UUID{d.TxnID})
+	if err != nil {
+		return err
+	}
+	if err := checkDisputable(txn.Status, disputes); err != nil {
+		return err
+	}
+	if d.Amount == 0 {
+		d.Amount = txn.Amount
+	}
+	if d.Amount > txn.Amount {
+		return fmt.Errorf("%w: amount exceeds the transaction", ErrInvalidInput)
+	}
+
+	businessDate, err := businessDateTx(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if businessDate.After(txn.PostingDate.AddDate(0, 0, s.policy.FilingWindowDays)) {
+		return fmt.Errorf("%w: the %d day filing window has passed", ErrInvalidInput, s.policy.FilingWindowDays)
+	}
+
+	d.Status = DisputeOpened
+	d.ProvisionalCreditDue = AddBusinessDays(s.calendar, businessDate, s.policy.ProvisionalCreditDays)
+	d.ResolutionDue = businessDate.AddDate(0, 0, s.policy.ResolutionDays)
+	err = tx.QueryRow(ctx, `
+		INSERT INTO disputes (account_id, txn_id, reason_code, description, amount, status,
+		    provisional_credit_due, resolution_due)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (txn_id) WHERE status <> 'RESOLVED_BANK' DO NOTHING
+		RETURNING dispute_id, opened_at`,
+		d.AccountID, d.TxnID, d.ReasonCode, d.Description, d.Amount, d.Status,
+		d.ProvisionalCreditDue, d.ResolutionDue,
+	).Scan(&d.DisputeID, &d.OpenedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrDuplicateEntry
+		}
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// disputeHolds reports whether a dispute in this status stops its
+// transaction from being disputed again or reversed. An open dispute holds it
+// until resolved, and one resolved for the customer has refunded it for good.
+// Only a dispute resolved for the bank lets it go.
+func disputeHolds(status string) bool {
+	return status != DisputeResolvedBank
+}
+
+// checkDisputable refuses a dispute of a transaction that was reversed, is
+// under dispute, or was already refunded on a dispute.
+func checkDisputable(txnStatus string, disputes []string) error {
+	if txnStatus == "REVERSED" {
+		return ErrAlreadyReversed
+	}
+	for _, status := range disputes {
+		switch {
+		case status == DisputeResolvedCustomer:
+			return fmt.Errorf("%w: the transaction was already refunded on a dispute", ErrDuplicateEntry)
+		case disputeHolds(status):
+			return ErrDuplicateEntry
+		}
+	}
+	return nil
+}
+
+// disputeStatusesTx returns the statuses of the disputes raised against the
+// transactions.
+func disputeStatusesTx(ctx context.Context, tx pgx.Tx, txnIDs []uuid.UUID) ([]string, error) {
+	rows, err := tx.Query(ctx, `SELECT status FROM disputes WHERE txn_id = ANY($1)`, txnIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statuses []string
+	for rows.Next() {
+		var status string
+		if err := rows.Scan(&status); err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, rows.Err()
+}
+
+const disputeColumns = `
+	dispute_id, account_id, txn_id, reason_code, description, amount, status, provisional_credit_due,
+	resolution_due, credit_txn_id, credit_reversal_txn_id, resolution, resolved_by, opened_at, resolved_at`
+
+func scanDispute(row pgx.Row) (*Dispute, error) {
+	d := &Dispute{}
+	err := row.Scan(&d.DisputeID, &d.AccountID, &d.TxnID, &d.ReasonCode, &d.Description, &d.Amount,
+		&d.Status, &d.ProvisionalCreditDue, &d.ResolutionDue, &d.CreditTxnID, &d.CreditReversalTxnID,
+		&d.Resolution, &d.ResolvedBy, &d.OpenedAt, &d.ResolvedAt)
+	return d, err
+}
+
+// GetDispute returns a dispute with its notes and attachments.
+func (s *DisputeService) GetDispute(ctx context.Context, id uuid.UUID) (*Dispute, error) {
+	d, err := scanDispute(s.db.QueryRow(ctx, `SELECT `+disputeColumns+` FROM disputes WHERE dispute_id = $1`, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT note_id, dispute_id, author, body, created_at
+		FROM dispute_notes WHERE dispute_id = $1 ORDER BY created_at`, id)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		n := &DisputeNote{}
+		if err := rows.Scan(&n.NoteID, &n.DisputeID, &n.Author, &n.Body, &n.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		d.Notes = append(d.Notes, n)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.Query(ctx, `
+		SELECT attachment_id, dispute_id, file_name, content_type, size_bytes, blob_key, uploaded_by, created_at
+		FROM dispute_attachments WHERE dispute_id = $1 ORDER BY created_at`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		a := &DisputeAttachment{}
+		err := rows.Scan(&a.AttachmentID, &a.DisputeID, &a.FileName, &a.ContentType, &a.SizeBytes,
+			&a.BlobKey, &a.UploadedBy, &a.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		d.Attachments = append(d.Attachments, a)
+	}
+	return d, rows.Err()
+}
+
+func (s *DisputeService) ListByAccount(ctx context.Context, accountID uuid.UUID) ([]*Dispute, error) {
+	return s.list(ctx, `WHERE account_id = $1 ORDER BY opened_at DESC`, accountID)
+}
+
+// List returns disputes for the operations queue, oldest deadline first.
+// Overdue keeps only open disputes past their provisional credit deadline
+// without a credit, or past their resolution deadline.
+func (s *DisputeService) List(ctx context.Context, status *string, overdue bool) ([]*Dispute, error) {
+	return s.list(ctx, `
+		WHERE ($1::text IS NULL OR status = $1)
+		  AND (NOT $2 OR (status NOT IN ('RESOLVED_CUSTOMER', 'RESOLVED_BANK') AND (
+		      resolution_due < (SELECT business_date FROM system_state WHERE id = 1)
+		      OR (credit_txn_id IS NULL AND provisional_credit_due < (SELECT business_date FROM system_state WHERE id = 1)))))
+		ORDER BY resolution_due, opened_at`,
+		status, overdue)
+}
+
+func (s *DisputeService) list(ctx context.Context, where string, args ...any) ([]*Dispute, error) {
+	rows, err := s.db.Query(ctx, `SELECT `+disputeColumns+` FROM disputes `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	disputes := []*Dispute{}
+	for rows.Next() {
+		d, err := scanDispute(rows)
+		if err != nil {
+			return nil, err
+		}
+		disputes = append(disputes, d)
+	}
+	return disputes, rows.Err()
+}
+
+// lockOpenTx locks a dispute and checks it is still open.
+func (s *DisputeService) lockOpenTx(ctx context.Context, tx pgx.Tx, id uuid.UUID) (*Dispute, error) {
+	d, err := scanDispute(tx.QueryRow(ctx,
+		`SELECT `+disputeColumns+` FROM disputes WHERE dispute_id = $1 FOR UPDATE`, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if d.Status == DisputeResolvedCustomer || d.Status == DisputeResolvedBank {
+		return nil, fmt.Errorf("%w: dispute is resolved", ErrInvalidInput)
+	}
+	return d, nil
+}
+
+// GrantProvisionalCredit credits the disputed amount to the customer while
+// the dispute is investigated. It is granted on an OPENED dispute, before the
+// investigation starts.
+func (s *DisputeService) GrantProvisionalCredit(ctx context.Context, id uuid.UUID, actor string) (*Dispute, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	d, err := s.lockOpenTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.CreditTxnID != nil {
+		return nil, fmt.Errorf("%w: provisional credit already granted", ErrInvalidInput)
+	}
+	if d.Status != DisputeOpened {
+		return nil, fmt.Errorf("%w: provisional credit can only be granted on an OPENED dispute", ErrInvalidInput)
+	}
+
+	txnID, err := s.creditTx(ctx, tx, d, "Provisional credit for dispute")
+	if err != nil {
+		return nil, err
+	}
+	d.CreditTxnID = &txnID
+	d.Status = DisputeProvisionalCredit
+
+	_, err = tx.Exec(ctx, `
+		UPDATE disputes SET status = $1, credit_txn_id = $2 WHERE dispute_id = $3`,
+		d.Status, d.CreditTxnID, d.DisputeID)
+	if err != nil {
+		return nil, err
+	}
+	if err := addNoteTx(ctx, tx, d.DisputeID, actor, fmt.Sprintf("Provisional credit of %.2f granted", d.Amount)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// StartInvestigation moves a dispute under investigation.
+func (s *DisputeService) StartInvestigation(ctx context.Context, id uuid.UUID, actor string) (*Dispute, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	d, err := s.lockOpenTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.Status == DisputeUnderInvestigation {
+		return nil, fmt.Errorf("%w: dispute is already under investigation", ErrInvalidInput)
+	}
+
+	d.Status = DisputeUnderInvestigation
+	if _, err := tx.Exec(ctx, `UPDATE disputes SET status = $1 WHERE dispute_id = $2`, d.Status, d.DisputeID); err != nil {
+		return nil, err
+	}
+	if err := addNoteTx(ctx, tx, d.DisputeID, actor, "Investigation started"); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Resolve closes a dispute. In the customer's favour, a provisional credit
+// becomes final, or the amount is credited now if none was given. In the
+// bank's favour, a provisional credit is taken back, even if that overdraws
+// the account.
+func (s *DisputeService) Resolve(ctx context.Context, id uuid.UUID, inFavourOfCustomer bool, resolution, actor string) (*Dispute, error) {
+	if strings.TrimSpace(resolution) == "" {
+		return nil, ErrInvalidInput
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	d, err := s.lockOpenTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case inFavourOfCustomer:
+		d.Status = DisputeResolvedCustomer
+		if d.CreditTxnID == nil {
+			txnID, err := s.creditTx(ctx, tx, d, "Refund for dispute")
+			if err != nil {
+				return nil, err
+			}
+			d.CreditTxnID = &txnID
+		}
+	default:
+		d.Status = DisputeResolvedBank
+		if d.CreditTxnID != nil {
+			txnID, err := s.reverseCreditTx(ctx, tx, d)
+			if err != nil {
+				return nil, err
+			}
+			d.CreditReversalTxnID = &txnID
+		}
+	}
+	d.Resolution = &resolution
+	d.ResolvedBy = &actor
+
+	err = tx.QueryRow(ctx, `
+		UPDATE disputes
+		SET status = $1, credit_txn_id = $2, credit_reversal_txn_id = $3, resolution = $4,
+		    resolved_by = $5, resolved_at = now()
+		WHERE dispute_id = $6
+		RETURNING resolved_at`,
+		d.Status, d.CreditTxnID, d.CreditReversalTxnID, d.Resolution, d.ResolvedBy, d.DisputeID,
+	).Scan(&d.ResolvedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := addNoteTx(ctx, tx, d.DisputeID, actor, "Resolved: "+resolution); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// creditTx credits the disputed amount to the account out of the dispute
+// settlement GL. It is a correction by the bank, so no fee is charged on it.
+func (s *DisputeService) creditTx(ctx context.Context, tx pgx.Tx, d *Dispute, label string) (uuid.UUID, error) {
+	_, err := tx.Exec(ctx, `SELECT 1 FROM accounts WHERE account_id = $1 FOR UPDATE`, d.AccountID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	description := fmt.Sprintf("%s %s", label, d.DisputeID)
+	channel := "DISPUTE"
+	txn := &AccountTransaction{
+		AccountID:   d.AccountID,
+		TxnType:     "CREDIT",
+		Amount:      d.Amount,
+		Description: &description,
+		Channel:     &channel,
+	}
+	if err := s.txns.postEntryTx(ctx, tx, txn); err != nil {
+		return uuid.Nil, err
+	}
+	err = postJournalTx(ctx, tx, &Journal{
+		DebitGL:   GLDisputeSettlement,
+		CreditGL:  GLCustomerDeposits,
+		Amount:    d.Amount,
+		Narrative: description,
+		TxnID:     &txn.TxnID,
+	})
+	return txn.TxnID, err
+}
+
+func (s *DisputeService) reverseCreditTx(ctx context.Context, tx pgx.Tx, d *Dispute) (uuid.UUID, error) {
+	_, err := tx.Exec(ctx, `SELECT 1 FROM accounts WHERE account_id = $1 FOR UPDATE`, d.AccountID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	description := fmt.Sprintf("Provisional credit reversed, dispute %s", d.DisputeID)
+	channel := "DISPUTE"
+	txn := &AccountTransaction{
+		AccountID:   d.AccountID,
+		TxnType:     "DEBIT",
+		Amount:      d.Amount,
+		Description: &description,
+		Channel:     &channel,
+		ReversalOf:  d.CreditTxnID,
+	}
+	if err := s.txns.postEntryTx(ctx, tx, txn); err != nil {
+		return uuid.Nil, err
+	}
+	_, err = tx.Exec(ctx, `UPDATE account_transactions SET status = 'REVERSED' WHERE txn_id = $1`, d.CreditTxnID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	err = postJournalTx(ctx, tx, &Journal{
+		DebitGL:   GLCustomerDeposits,
+		CreditGL:  GLDisputeSettlement,
+		Amount:    d.Amount,
+		Narrative: description,
+		TxnID:     &txn.TxnID,
+	})
+	return txn.TxnID, err
+}
+
+func (s *DisputeService) AddNote(ctx context.Context, id uuid.UUID, author, body string) (*DisputeNote, error) {
+	if strings.TrimSpace(author) == "" || strings.TrimSpace(body) == "" {
+		return nil, ErrInvalidInput
+	}
+
+	n := &DisputeNote{DisputeID: id, Author: author, Body: body}
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO dispute_notes (dispute_id, author, body)
+		SELECT dispute_id, $2, $3 FROM disputes WHERE dispute_id = $1
+		RETURNING note_id, created_at`,
+		id, author, body,
+	).Scan(&n.NoteID, &n.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return n, nil
+}
+
+func addNoteTx(ctx context.Context, tx pgx.Tx, id uuid.UUID, author, body string) error {
+	if author == "" {
+		author = "system"
+	}
+	_, err := tx.Exec(ctx, `INSERT INTO dispute_notes (dispute_id, author, body) VALUES ($1, $2, $3)`, id, author, body)
+	return err
+}
+
+// AddAttachment stores a piece of evidence in the blob store and records it
+// on the dispute.
+func (s *DisputeService) AddAttachment(ctx context.Context, a *DisputeAttachment, data []byte) error {
+	if a.FileName == "" || a.UploadedBy == "" || len(data) == 0 {
+		return ErrInvalidInput
+	}
+	if a.ContentType == "" {
+		a.ContentType = "application/octet-stream"
+	}
+
+	var exists bool
+	err := s.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM disputes WHERE dispute_id = $1)`, a.DisputeID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+
+	a.AttachmentID = uuid.New()
+	a.SizeBytes = len(data)
+	a.BlobKey = fmt.Sprintf("disputes/%s/%s", a.DisputeID, a.AttachmentID)
+	if err := s.blobs.Put(ctx, a.BlobKey, data); err != nil {
+		return err
+	}
+
+	return s.db.QueryRow(ctx, `
+		INSERT INTO dispute_attachments (attachment_id, dispute_id, file_name, content_type, size_bytes, blob_key, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at`,
+		a.AttachmentID, a.DisputeID, a.FileName, a.ContentType, a.SizeBytes, a.BlobKey, a.UploadedBy,
+	).Scan(&a.CreatedAt)
+}
+
+// GetAttachment returns an attachment of a dispute and its content.
+func (s *DisputeService) GetAttachment(ctx context.Context, disputeID, attachmentID uuid.UUID) (*DisputeAttachment, []byte, error) {
+	a := &DisputeAttachment{}
+	err := s.db.QueryRow(ctx, `
+		SELECT attachment_id, dispute_id, file_name, content_type, size_bytes, blob_key, uploaded_by, created_at
+		FROM dispute_attachments
+		WHERE dispute_id = $1 AND attachment_id = $2`,
+		disputeID, attachmentID,
+	).Scan(&a.AttachmentID, &a.DisputeID, &a.FileName, &a.ContentType, &a.SizeBytes,
+		&a.BlobKey, &a.UploadedBy, &a.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
+	}
+
+	data, err := s.blobs.Get(ctx, a.BlobKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return a, data, nil
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckDisputable(t *testing.T) {
+	tests := []struct {
+		name      string
+		txnStatus string
+		disputes  []string
+		want      error
+	}{
+		{"never disputed", "POSTED", nil, nil},
+		{"reversed", "REVERSED", nil, ErrAlreadyReversed},
+		{"open dispute", "POSTED", []string{DisputeOpened}, ErrDuplicateEntry},
+		{"provisional credit given", "POSTED", []string{DisputeProvisionalCredit}, ErrDuplicateEntry},
+		{"under investigation", "POSTED", []string{DisputeUnderInvestigation}, ErrDuplicateEntry},
+		{"already refunded", "POSTED", []string{DisputeResolvedCustomer}, ErrDuplicateEntry},
+		{"refunded after a rejected dispute", "POSTED", []string{DisputeResolvedBank, DisputeResolvedCustomer}, ErrDuplicateEntry},
+		{"rejected before", "POSTED", []string{DisputeResolvedBank}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkDisputable(tt.txnStatus, tt.disputes)
+			if tt.want == nil && err != nil {
+				t.Fatalf("checkDisputable = %v, want nil", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("checkDisputable = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDisputeHolds(t *testing.T) {
+	tests := []struct {
+		status string
+		want   bool
+	}{
+		{DisputeOpened, true},
+		{DisputeProvisionalCredit, true},
+		{DisputeUnderInvestigation, true},
+		{DisputeResolvedCustomer, true},
+		{DisputeResolvedBank, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			if got := disputeHolds(tt.status); got != tt.want {
+				t.Errorf("disputeHolds(%s) = %v, want %v", tt.status, got, tt.want)
+			}
+		})
+	}
+}
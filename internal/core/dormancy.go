@@ -0,0 +1,232 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// nonCustomerChannels are the channels of entries the bank posts on its own
+// initiative. They do not count as activity for dormancy, and neither do
+// reversals.
+var nonCustomerChannels = []string{"FEE", "INTEREST", "REVERSAL", "DISPUTE", "GARNISHMENT"}
+
+// DormancyPolicy sets how long an account may go without customer-initiated
+// activity before it becomes dormant, and how many days beforehand the
+// customer is sent notices.
+type DormancyPolicy struct {
+	PeriodMonths int
+	NoticeDays   []int
+}
+
+// DormantBalance is the number and total balance of a branch's dormant
+// accounts.
+type DormantBalance struct {
+	BranchID   uuid.UUID `json:"branch_id"`
+	BranchCode string    `json:"branch_code"`
+	BranchName string    `json:"branch_name"`
+	Accounts   int       `json:"accounts"`
+	Balance    float64   `json:"balance"`
+}
+
+type DormancyService struct {
+	db       *pgxpool.Pool
+	notifier Notifier
+	policy   DormancyPolicy
+}
+
+// NewDormancyService sends the notices of policy in order of escalation, the
+// one with the most days before dormancy first.
+func NewDormancyService(db *pgxpool.Pool, notifier Notifier, policy DormancyPolicy) *DormancyService {
+	policy.NoticeDays = append([]int(nil), policy.NoticeDays...)
+	sort.Sort(sort.Reverse(sort.IntSlice(policy.NoticeDays)))
+	return &DormancyService{db: db, notifier: notifier, policy: policy}
+}
+
+// MarkDormant moves active accounts without customer-initiated activity for
+// the dormancy period to DORMANT, and sends the notices due to accounts that
+// are approaching it. It runs as an end-of-day step and checkpoints the last
+// account processed; each notice is sent once for an expected dormancy date.
+func (s *DormancyService) MarkDormant(ctx context.Context, businessDate time.Time, cp *Checkpoint) error {
+	if s.policy.PeriodMonths <= 0 {
+		return nil
+	}
+
+	after := uuid.Nil
+	if cp.Value != "" {
+		id, err := uuid.Parse(cp.Value)
+		if err != nil {
+			return err
+		}
+		after = id
+	}
+
+	for {
+		var accountID uuid.UUID
+		err := s.db.QueryRow(ctx, `
+			SELECT account_id
+			FROM accounts
+			WHERE account_id > $1 AND status = 'ACTIVE'
+			ORDER BY account_id
+			LIMIT 1`,
+			after,
+		).Scan(&accountID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return nil
+			}
+			return err
+		}
+
+		if err := s.checkAccount(ctx, accountID, businessDate); err != nil {
+			return fmt.Errorf("account %s: %w", accountID, err)
+		}
+
+		after = accountID
+		if err := cp.Save(ctx, after.String()); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *DormancyService) checkAccount(ctx context.Context, accountID uuid.UUID, businessDate time.Time) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var customerID uuid.UUID
+	var accountNumber, status string
+	err = tx.QueryRow(ctx, `
+		SELECT customer_id, account_number, status FROM accounts WHERE account_id = $1 FOR UPDATE`,
+		accountID,
+	).Scan(&customerID, &accountNumber, &status)
+	if err != nil {
+		return err
+	}
+	if status != AccountActive {
+		return nil
+	}
+
+	lastActivity, err := lastActivityTx(ctx, tx, accountID)
+	if err != nil {
+		return err
+	}
+	dormantOn := lastActivity.AddDate(0, s.policy.PeriodMonths, 0)
+
+	if !businessDate.Before(dormantOn) {
+		reason := fmt.Sprintf("No customer-initiated activity since %s", lastActivity.Format("2006-01-02"))
+		if _, err := changeStatusTx(ctx, tx, accountID, AccountDormant, reason, nil); err != nil {
+			return err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return err
+		}
+
+		log.Printf("account %s: dormant, last activity %s", accountID, lastActivity.Format("2006-01-02"))
+		s.notify(ctx, customerID, "Account dormant", fmt.Sprintf(
+			"Your account %s has become dormant because it has not been used since %s. "+
+				"To use it again, visit a branch to verify your identity.",
+			accountNumber, lastActivity.Format("2006-01-02")))
+		return nil
+	}
+
+	// Only the most escalated notice due is sent, so an account that enters
+	// the notice period late skips the earlier ones.
+	stage := -1
+	for i, days := range s.policy.NoticeDays {
+		if !businessDate.Before(dormantOn.AddDate(0, 0, -days)) {
+			stage = i
+		}
+	}
+	if stage < 0 {
+		return nil
+	}
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO dormancy_notices (account_id, dormant_on, days_before)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (account_id, dormant_on, days_before) DO NOTHING`,
+		accountID, dormantOn, s.policy.NoticeDays[stage],
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	subject := "Account inactivity notice"
+	switch {
+	case stage == len(s.policy.NoticeDays)-1 && stage > 0:
+		subject = "Final notice: account becoming dormant"
+	case stage > 0:
+		subject = "Account inactivity reminder"
+	}
+	s.notify(ctx, customerID, subject, fmt.Sprintf(
+		"Your account %s has not been used since %s and will become dormant on %s unless you make a transaction.",
+		accountNumber, lastActivity.Format("2006-01-02"), dormantOn.Format("2006-01-02")))
+	return nil
+}
+
+func (s *DormancyService) notify(ctx context.Context, customerID uuid.UUID, subject, message string) {
+	if err := s.notifier.Notify(ctx, customerID, subject, message); err != nil {
+		log.Printf("dormancy: notification to customer %s failed: %v", customerID, err)
+	}
+}
+
+// lastActivityTx returns the date of the account's last customer-initiated
+// activity: its latest entry outside nonCustomerChannels, or the date it was
+// opened or last made active if that is later.
+func lastActivityTx(ctx context.Context, tx pgx.Tx, accountID uuid.UUID) (time.Time, error) {
+	var day time.Time
+	err := tx.QueryRow(ctx, `
+		SELECT GREATEST(
+		    a.opened_at::date,
+		    (SELECT max(t.posting_date) FROM account_transactions t
+		     WHERE t.account_id = a.account_id AND t.reversal_of IS NULL
+		       AND COALESCE(t.channel, '') <> ALL($2)),
+		    (SELECT max(h.changed_at)::date FROM account_status_history h
+		     WHERE h.account_id = a.account_id AND h.to_status = 'ACTIVE'))
+		FROM accounts a
+		WHERE a.account_id = $1`,
+		accountID, nonCustomerChannels,
+	).Scan(&day)
+	return day, err
+}
+
+// DormantBalances reports the dormant accounts of each branch and their total
+// balance, for regulatory returns.
+func (s *DormancyService) DormantBalances(ctx context.Context) ([]*DormantBalance, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT b.branch_id, b.branch_code, b.name, count(*), COALESCE(sum(a.balance), 0)
+		FROM accounts a
+		JOIN branches b ON b.branch_id = a.branch_id
+		WHERE a.status = 'DORMANT'
+		GROUP BY b.branch_id, b.branch_code, b.name
+		ORDER BY b.branch_code`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var report []*DormantBalance
+	for rows.Next() {
+		d := &DormantBalance{}
+		if err := rows.Scan(&d.BranchID, &d.BranchCode, &d.BranchName, &d.Accounts, &d.Balance); err != nil {
+			return nil, err
+		}
+		report = append(report, d)
+	}
+	return report, rows.Err()
+}
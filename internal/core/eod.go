@@ -0,0 +1,319 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// eodLockKey is the Postgres advisory lock key held by the single EOD leader.
+const eodLockKey int64 = 0x454f44 // "EOD"
+
+// BatchStep is one ordered step of the end-of-day run. Steps must be
+// idempotent for the business date: a failed run is restarted from the failed
+// step, which receives the checkpoint it last saved.
+type BatchStep struct {
+	Name string
+	Run  func(ctx context.Context, businessDate time.Time, cp *Checkpoint) error
+}
+
+// Step adapts a function that needs neither the business date nor a
+// checkpoint, such as a due-item sweep, into a batch step.
+func Step(name string, fn func(ctx context.Context) error) BatchStep {
+	return BatchStep{Name: name, Run: func(ctx context.Context, _ time.Time, _ *Checkpoint) error {
+		return fn(ctx)
+	}}
+}
+
+// Checkpoint lets a step record its progress so a restart can skip work that
+// already committed.
+type Checkpoint struct {
+	Value string
+
+	db    *pgxpool.Pool
+	runID uuid.UUID
+	step  string
+}
+
+func (c *Checkpoint) Save(ctx context.Context, value string) error {
+	_, err := c.db.Exec(ctx, `
+		UPDATE eod_step_runs SET checkpoint = $1, updated_at = now()
+		WHERE run_id = $2 AND step_name = $3`,
+		value, c.runID, c.step,
+	)
+	if err == nil {
+		c.Value = value
+	}
+	return err
+}
+
+type EODRun struct {
+	RunID        uuid.UUID     `json:"run_id"`
+	BusinessDate time.Time     `json:"business_date"`
+	Status       string        `json:"status"`
+	StartedAt    time.Time     `json:"started_at"`
+	FinishedAt   *time.Time    `json:"finished_at,omitempty"`
+	Steps        []*EODStepRun `json:"steps"`
+}
+
+type EODStepRun struct {
+	StepName   string     `json:"step_name"`
+	Seq        int        `json:"seq"`
+	Status     string     `json:"status"`
+	Checkpoint *string    `json:"checkpoint,omitempty"`
+	Error      *string    `json:"error,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// EODService orchestrates the end-of-day batch and owns the system business
+// date. The final step of every run rolls the business date over to the next
+// business day.
+type EODService struct {
+	db       *pgxpool.Pool
+	calendar *Calendar
+	steps    []BatchStep
+}
+
+func NewEODService(db *pgxpool.Pool, calendar *Calendar, steps ...BatchStep) *EODService {
+	s := &EODService{db: db, calendar: calendar}
+	s.steps = append(steps, BatchStep{Name: "business-date-rollover", Run: s.rollover})
+	return s
+}
+
+func (s *EODService) BusinessDate(ctx context.Context) (time.Time, error) {
+	return businessDate(ctx, s.db)
+}
+
+// businessDate reads the system business date. Dates customers choose for
+// future payments are checked against it rather than the wall clock.
+func businessDate(ctx context.Context, db *pgxpool.Pool) (time.Time, error) {
+	var day time.Time
+	err := db.QueryRow(ctx, `SELECT business_date FROM system_state WHERE id = 1`).Scan(&day)
+	return day, err
+}
+
+// Run executes, or resumes, the EOD run for the current business date. Only
+// one process may run it at a time.
+func (s *EODService) Run(ctx context.Context) (*EODRun, error) {
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var locked bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, eodLockKey).Scan(&locked); err != nil {
+		return nil, err
+	}
+	if !locked {
+		return nil, ErrEODRunning
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, eodLockKey)
+
+	businessDate, err := s.BusinessDate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	runID, err := s.startRun(ctx, businessDate)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("EOD run %s started for business date %s", runID, businessDate.Format("2006-01-02"))
+	runErr := s.runSteps(ctx, runID, businessDate)
+
+	status := "COMPLETED"
+	if runErr != nil {
+		status = "FAILED"
+	}
+	_, err = s.db.Exec(ctx, `
+		UPDATE eod_runs SET status = $1, finished_at = now() WHERE run_id = $2`,
+		status, runID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("EOD run %s %s", runID, status)
+
+	run, err := s.getRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	return run, runErr
+}
+
+// startRun returns the run for the business date, creating it and its step
+// rows on the first attempt. A failed run is reopened for restart.
+func (s *EODService) startRun(ctx context.Context, businessDate time.Time) (uuid.UUID, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var runID uuid.UUID
+	err = tx.QueryRow(ctx, `
+		INSERT INTO eod_runs (business_date, status)
+		VALUES ($1, 'RUNNING')
+		ON CONFLICT (business_date) DO UPDATE SET status = 'RUNNING', finished_at = NULL
+		RETURNING run_id`,
+		businessDate,
+	).Scan(&runID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	for i, step := range s.steps {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO eod_step_runs (run_id, step_name, seq, status)
+			VALUES ($1, $2, $3, 'PENDING')
+			ON CONFLICT (run_id, step_name) DO NOTHING`,
+			runID, step.Name, i+1,
+		)
+		if err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	return runID, tx.Commit(ctx)
+}
+
+func (s *EODService) runSteps(ctx context.Context, runID uuid.UUID, businessDate time.Time) error {
+	for _, step := range s.steps {
+		var status string
+		var checkpoint *string
+		err := s.db.QueryRow(ctx, `
+			SELECT status, checkpoint FROM eod_step_runs WHERE run_id = $1 AND step_name = $2`,
+			runID, step.Name,
+		).Scan(&status, &checkpoint)
+		if err != nil {
+			return err
+		}
+		if status == "COMPLETED" {
+			continue
+		}
+
+		_, err = s.db.Exec(ctx, `
+			UPDATE eod_step_runs
+			SET status = 'RUNNING', error = NULL, started_at = now(), updated_at = now()
+			WHERE run_id = $1 AND step_name = $2`,
+			runID, step.Name,
+		)
+		if err != nil {
+			return err
+		}
+
+		cp := &Checkpoint{db: s.db, runID: runID, step: step.Name}
+		if checkpoint != nil {
+			cp.Value = *checkpoint
+		}
+
+		stepErr := step.Run(ctx, businessDate, cp)
+
+		status = "COMPLETED"
+		var errMsg *string
+		if stepErr != nil {
+			status = "FAILED"
+			msg := stepErr.Error()
+			errMsg = &msg
+		}
+		_, err = s.db.Exec(ctx, `
+			UPDATE eod_step_runs
+			SET status = $1, error = $2, finished_at = now(), updated_at = now()
+			WHERE run_id = $3 AND step_name = $4`,
+			status, errMsg, runID, step.Name,
+		)
+		if err != nil {
+			return err
+		}
+
+		if stepErr != nil {
+			return fmt.Errorf("step %s: %w", step.Name, stepErr)
+		}
+		log.Printf("EOD step %s completed", step.Name)
+	}
+
+	return nil
+}
+
+// rollover moves the system business date to the next business day. It takes
+// the system_state row lock, so it waits for in-flight postings, and postings
+// that start afterwards see the new date.
+func (s *EODService) rollover(ctx context.Context, businessDate time.Time, cp *Checkpoint) error {
+	next, err := AdjustToBusinessDay(s.calendar, businessDate.AddDate(0, 0, 1), NonBusinessDayNext)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(ctx, `
+		UPDATE system_state SET business_date = $1, updated_at = now()
+		WHERE id = 1 AND business_date = $2`,
+		next, businessDate,
+	)
+	return err
+}
+
+// LatestRun returns the most recent EOD run with its steps.
+func (s *EODService) LatestRun(ctx context.Context) (*EODRun, error) {
+	var runID uuid.UUID
+	err := s.db.QueryRow(ctx, `SELECT run_id FROM eod_runs ORDER BY business_date DESC LIMIT 1`).Scan(&runID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return s.getRun(ctx, runID)
+}
+
+func (s *EODService) getRun(ctx context.Context, runID uuid.UUID) (*EODRun, error) {
+	run := &EODRun{}
+	err := s.db.QueryRow(ctx, `
+		SELECT run_id, business_date, status, started_at, finished_at
+		FROM eod_runs
+		WHERE run_id = $1`,
+		runID,
+	).Scan(&run.RunID, &run.BusinessDate, &run.Status, &run.StartedAt, &run.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT step_name, seq, status, checkpoint, error, started_at, finished_at
+		FROM eod_step_runs
+		WHERE run_id = $1
+		ORDER BY seq`,
+		runID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		st := &EODStepRun{}
+		err := rows.Scan(&st.StepName, &st.Seq, &st.Status, &st.Checkpoint, &st.Error, &st.StartedAt, &st.FinishedAt)
+		if err != nil {
+			return nil, err
+		}
+		run.Steps = append(run.Steps, st)
+	}
+
+	return run, rows.Err()
+}
+
+// businessDateTx reads the system business date and holds a share lock on it
+// until the transaction ends, so the EOD rollover cannot slip in between.
+func businessDateTx(ctx context.Context, tx pgx.Tx) (time.Time, error) {
+	var day time.Time
+	err := tx.QueryRow(ctx, `SELECT business_date FROM system_state WHERE id = 1 FOR SHARE`).Scan(&day)
+	return day, err
+}
@@ -8,7 +8,22 @@ var (
 	ErrInsufficientFunds = errors.New("insufficient funds")
 	ErrAccountClosed     = errors.New("account is closed")
 	ErrAccountSuspended  = errors.New("account is suspended")
+	ErrAccountFrozen     = errors.New("account is frozen")
+	ErrAccountDormant    = errors.New("account is dormant")
+	ErrAccountInactive   = errors.New("account is pending activation")
+	ErrInvalidTransition = errors.New("account status change not permitted")
+	ErrKYCRequired       = errors.New("fresh KYC verification required")
+	ErrClosureBlocked    = errors.New("account cannot be closed")
 	ErrDuplicateEntry    = errors.New("duplicate entry")
 	ErrUnauthorized      = errors.New("unauthorized")
 	ErrInternal          = errors.New("internal server error")
+	ErrStepUpRequired    = errors.New("step-up verification required")
+	ErrCoolingLimit      = errors.New("payments to a new payee exceed the cooling period limit")
+	ErrEODRunning        = errors.New("end-of-day run already in progress")
+	ErrAlreadyReversed   = errors.New("transaction is already reversed")
+	ErrNotReversible     = errors.New("transaction cannot be reversed")
+	ErrNotEligible       = errors.New("customer is not eligible for the product")
+	ErrChannelNotAllowed = errors.New("channel is not allowed for the account's product")
+	ErrLimitExceeded     = errors.New("amount exceeds the account's transaction limit")
+	ErrApprovalRequired  = errors.New("payment needs a second user's approval")
 )
\ No newline at end of file
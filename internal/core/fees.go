@@ -0,0 +1,401 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// GL codes of fee income and of the tax collected on fees.
+const (
+	GLFeeIncome  = "FEE_INCOME"
+	GLTaxPayable = "TAX_PAYABLE"
+)
+
+// FeeCharge records a periodic fee charged to an account for a period, so
+// each fee is charged at most once per period.
+type FeeCharge struct {
+	ChargeID  uuid.UUID  `json:"charge_id"`
+	AccountID uuid.UUID  `json:"account_id"`
+	FeeCode   string     `json:"fee_code"`
+	Period    string     `json:"period"`
+	Amount    float64    `json:"amount"`
+	Tax       float64    `json:"tax"`
+	TxnID     *uuid.UUID `json:"txn_id,omitempty"`
+	TaxTxnID  *uuid.UUID `json:"tax_txn_id,omitempty"`
+	ChargedAt time.Time  `json:"charged_at"`
+}
+
+// feeScheduleName selects an account's fee schedule in queries joining
+// accounts a to their product p: the product's schedule or, for accounts
+// without one, the account type.
+const feeScheduleName = `COALESCE(p.fee_schedule, a.account_type)`
+
+// feeQuote is a transaction fee priced before its triggering entry is posted.
+type feeQuote struct {
+	code string
+	fee  float64
+	tax  float64
+}
+
+func (q *feeQuote) total() float64 {
+	if q == nil {
+		return 0
+	}
+	return q.fee + q.tax
+}
+
+// transactionFeeTx prices the fee on an entry from the account's fee
+// schedule and the customer's segment. It returns nil when the entry is free.
+func (s *TransactionService) transactionFeeTx(ctx context.Context, tx pgx.Tx, accountID uuid.UUID, channel, txnType string, amount float64) (*feeQuote, error) {
+	if channel == "" || !s.prices.hasTransactionFees() {
+		return nil, nil
+	}
+
+	var scheduleName, segment string
+	err := tx.QueryRow(ctx, `
+		SELECT `+feeScheduleName+`, c.segment
+		FROM accounts a
+		JOIN customers c ON c.customer_id = a.customer_id
+		LEFT JOIN products p ON p.product_code = a.product_code AND p.version = a.product_version
+		WHERE a.account_id = $1`,
+		accountID,
+	).Scan(&scheduleName, &segment)
+	if err != nil {
+		return nil, err
+	}
+
+	f := s.prices.Schedule(scheduleName).TransactionFee(channel, txnType)
+	if f == nil || segmentWaived(f.WaivedSegments, segment) {
+		return nil, nil
+	}
+	fee := f.Charge.Apply(amount)
+	if fee <= 0 {
+		return nil, nil
+	}
+	return &feeQuote{code: f.Code, fee: fee, tax: s.prices.TaxOn(fee)}, nil
+}
+
+// postFeeTx debits a fee and the tax on it as two entries, each journaled to
+// its GL. The fee entry points at the entry that triggered it, if any, and
+// the tax entry at the fee. Callers must already hold the account lock.
+func (s *TransactionService) postFeeTx(ctx context.Context, tx pgx.Tx, accountID uuid.UUID, code string, fee, tax float64, description string, feeFor *uuid.UUID) (*AccountTransaction, *AccountTransaction, error) {
+	channel := "FEE"
+	feeTxn := &AccountTransaction{
+		AccountID:   accountID,
+		TxnType:     "DEBIT",
+		Amount:      fee,
+		Description: &description,
+		Channel:     &channel,
+		FeeFor:      feeFor,
+	}
+	if err := s.postEntryTx(ctx, tx, feeTxn); err != nil {
+		return nil, nil, err
+	}
+	err := postJournalTx(ctx, tx, &Journal{
+		DebitGL:   GLCustomerDeposits,
+		CreditGL:  GLFeeIncome,
+		Amount:    fee,
+		Narrative: fmt.Sprintf("Fee %s", code),
+		TxnID:     &feeTxn.TxnID,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if tax <= 0 {
+		return feeTxn, nil, nil
+	}
+
+	taxName := s.prices.Tax.Name
+	if taxName == "" {
+		taxName = "Tax"
+	}
+	taxDescription := fmt.Sprintf("%s on %s", taxName, description)
+	taxTxn := &AccountTransaction{
+		AccountID:   accountID,
+		TxnType:     "DEBIT",
+		Amount:      tax,
+		Description: &taxDescription,
+		Channel:     &channel,
+		FeeFor:      &feeTxn.TxnID,
+	}
+	if err := s.postEntryTx(ctx, tx, taxTxn); err != nil {
+		return nil, nil, err
+	}
+	err = postJournalTx(ctx, tx, &Journal{
+		DebitGL:   GLCustomerDeposits,
+		CreditGL:  GLTaxPayable,
+		Amount:    tax,
+		Narrative: fmt.Sprintf("%s on fee %s", taxName, code),
+		TxnID:     &taxTxn.TxnID,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return feeTxn, taxTxn, nil
+}
+
+type FeeService struct {
+	db     *pgxpool.Pool
+	txns   *TransactionService
+	prices *PriceBook
+}
+
+// NewFeeService charges the periodic fees of the price book the transaction
+// service was built with.
+func NewFeeService(db *pgxpool.Pool, txns *TransactionService) *FeeService {
+	return &FeeService{db: db, txns: txns, prices: txns.prices}
+}
+
+// PriceBook returns the tariff fees are charged from.
+func (s *FeeService) PriceBook() *PriceBook {
+	return s.prices
+}
+
+// ChargeMonthly charges the maintenance and minimum balance fees of the month
+// before the business date to every active account. It runs as an end-of-day
+// step and checkpoints the last account processed; fees already charged for
+// the month are skipped, so running it every day charges each fee once.
+// Periodic fees are charged even when they overdraw the account.
+func (s *FeeService) ChargeMonthly(ctx context.Context, businessDate time.Time, cp *Checkpoint) error {
+	if !s.hasPeriodicFees() {
+		return nil
+	}
+
+	monthStart := time.Date(businessDate.Year(), businessDate.Month(), 1, 0, 0, 0, 0, time.UTC)
+	start := monthStart.AddDate(0, -1, 0)
+	end := monthStart.AddDate(0, 0, -1)
+	period := start.Format("2006-01")
+
+	after := uuid.Nil
+	if cp.Value != "" {
+		id, err := uuid.Parse(cp.Value)
+		if err != nil {
+			return err
+		}
+		after = id
+	}
+
+	for {
+		var accountID uuid.UUID
+		err := s.db.QueryRow(ctx, `
+			SELECT account_id
+			FROM accounts
+			WHERE account_id > $1 AND status = 'ACTIVE' AND opened_at < $2
+			ORDER BY account_id
+			LIMIT 1`,
+			after, monthStart,
+		).Scan(&accountID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return nil
+			}
+			return err
+		}
+
+		if err := s.chargeAccount(ctx, accountID, period, start, end); err != nil {
+			return fmt.Errorf("account %s: %w", accountID, err)
+		}
+
+		after = accountID
+		if err := cp.Save(ctx, after.String()); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *FeeService) hasPeriodicFees() bool {
+	if s.prices == nil {
+		return false
+	}
+	for _, sched := range s.prices.Schedules {
+		if sched.MaintenanceFee != nil || sched.MinimumBalance != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *FeeService) chargeAccount(ctx context.Context, accountID uuid.UUID, period string, start, end time.Time) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var scheduleName, status, segment string
+	var openedAt time.Time
+	var productMinimum *float64
+	err = tx.QueryRow(ctx, `
+		SELECT `+feeScheduleName+`, a.status, a.opened_at, c.segment, NULLIF(p.min_balance, 0)
+		FROM accounts a
+		JOIN customers c ON c.customer_id = a.customer_id
+		LEFT JOIN products p ON p.product_code = a.product_code AND p.version = a.product_version
+		WHERE a.account_id = $1
+		FOR UPDATE OF a`,
+		accountID,
+	).Scan(&scheduleName, &status, &openedAt, &segment, &productMinimum)
+	if err != nil {
+		return err
+	}
+	schedule := s.prices.Schedule(scheduleName)
+	if status != "ACTIVE" || schedule == nil {
+		return nil
+	}
+
+	if f := schedule.MaintenanceFee; f != nil && !segmentWaived(f.WaivedSegments, segment) {
+		description := fmt.Sprintf("Maintenance fee %s", period)
+		if err := s.chargeTx(ctx, tx, accountID, f.Code, period, f.Charge.Apply(0), description); err != nil {
+			return err
+		}
+	}
+
+	if f := schedule.MinimumBalance; f != nil && !segmentWaived(f.WaivedSegments, segment) {
+		charged, err := chargedTx(ctx, tx, accountID, f.Code, period)
+		if err != nil {
+			return err
+		}
+		if !charged {
+			from := start
+			if opened := civilDate(openedAt); opened.After(from) {
+				from = opened
+			}
+			// The account's product sets its own minimum where it has one.
+			minimum := f.Minimum
+			if productMinimum != nil {
+				minimum = *productMinimum
+			}
+			average, err := averageDailyBalanceTx(ctx, tx, accountID, from, end)
+			if err != nil {
+				return err
+			}
+			if average < minimum {
+				description := fmt.Sprintf("Minimum balance charge %s (average %.2f, minimum %.2f)",
+					period, average, minimum)
+				err := s.chargeTx(ctx, tx, accountID, f.Code, period, f.Charge.Apply(minimum-average), description)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// chargeTx posts a periodic fee and records it against the period, unless it
+// was already charged.
+func (s *FeeService) chargeTx(ctx context.Context, tx pgx.Tx, accountID uuid.UUID, code, period string, fee float64, description string) error {
+	if fee <= 0 {
+		return nil
+	}
+	charged, err := chargedTx(ctx, tx, accountID, code, period)
+	if err != nil || charged {
+		return err
+	}
+
+	tax := s.prices.TaxOn(fee)
+	feeTxn, taxTxn, err := s.txns.postFeeTx(ctx, tx, accountID, code, fee, tax, description, nil)
+	if err != nil {
+		return err
+	}
+	var taxTxnID *uuid.UUID
+	if taxTxn != nil {
+		taxTxnID = &taxTxn.TxnID
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO fee_charges (account_id, fee_code, period, amount, tax, txn_id, tax_txn_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		accountID, code, period, fee, tax, feeTxn.TxnID, taxTxnID,
+	)
+	return err
+}
+
+func chargedTx(ctx context.Context, tx pgx.Tx, accountID uuid.UUID, code, period string) (bool, error) {
+	var charged bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM fee_charges WHERE account_id = $1 AND fee_code = $2 AND period = $3
+		)`,
+		accountID, code, period,
+	).Scan(&charged)
+	return charged, err
+}
+
+// averageDailyBalanceTx averages the closing balance of every day from one
+// date to another, both inclusive.
+func averageDailyBalanceTx(ctx context.Context, tx pgx.Tx, accountID uuid.UUID, from, to time.Time) (float64, error) {
+	balance, err := openingBalanceTx(ctx, tx, accountID, from)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT posting_date, balance_after
+		FROM account_transactions
+		WHERE account_id = $1 AND posting_date BETWEEN $2 AND $3
+		ORDER BY entry_seq`,
+		accountID, from, to,
+	)
+	if err != nil {
+		return 0, err
+	}
+	closing := map[time.Time]float64{}
+	for rows.Next() {
+		var day time.Time
+		var after float64
+		if err := rows.Scan(&day, &after); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		closing[civilDate(day)] = after
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var sum float64
+	days := 0
+	for day := civilDate(from); !day.After(to); day = day.AddDate(0, 0, 1) {
+		if b, ok := closing[day]; ok {
+			balance = b
+		}
+		sum += balance
+		days++
+	}
+	if days == 0 {
+		return balance, nil
+	}
+	return roundCents(sum / float64(days)), nil
+}
+
+func (s *FeeService) ListCharges(ctx context.Context, accountID uuid.UUID) ([]*FeeCharge, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT charge_id, account_id, fee_code, period, amount, tax, txn_id, tax_txn_id, charged_at
+		FROM fee_charges
+		WHERE account_id = $1
+		ORDER BY charged_at DESC`,
+		accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var charges []*FeeCharge
+	for rows.Next() {
+		c := &FeeCharge{}
+		err := rows.Scan(&c.ChargeID, &c.AccountID, &c.FeeCode, &c.Period, &c.Amount, &c.Tax,
+			&c.TxnID, &c.TaxTxnID, &c.ChargedAt)
+		if err != nil {
+			return nil, err
+		}
+		charges = append(charges, c)
+	}
+	return charges, rows.Err()
+}
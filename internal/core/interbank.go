@@ -0,0 +1,530 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// GL codes of interbank payments. Payments sit in the clearing GL from
+// submission until the clearing house settles them against the bank's
+// settlement (nostro) account at the central bank.
+const (
+	GLInterbankClearing = "INTERBANK_CLEARING"
+	GLNostroSettlement  = "NOSTRO_SETTLEMENT"
+)
+
+// Interbank rails: deferred net settlement in periodic batches, and real-time
+// gross settlement of each payment on its own.
+const (
+	RailDNS  = "DNS"
+	RailRTGS = "RTGS"
+)
+
+const (
+	UrgencyNormal = "NORMAL"
+	UrgencyUrgent = "URGENT"
+)
+
+// InterbankConfig routes payments between the rails. Payments of
+// RTGSThreshold or more, and urgent payments, go gross; the rest are batched
+// every DNSInterval.
+type InterbankConfig struct {
+	RTGSThreshold float64
+	DNSInterval   time.Duration
+}
+
+type InterbankPayment struct {
+	PaymentID     uuid.UUID  `json:"payment_id"`
+	AccountID     uuid.UUID  `json:"account_id"`
+	BeneficiaryID *uuid.UUID `json:"beneficiary_id,omitempty"`
+	Rail          string     `json:"rail"`
+	Urgency       string     `json:"urgency"`
+	BankCode      string     `json:"bank_code"`
+	AccountNumber string     `json:"account_number"`
+	PayeeName     string     `json:"payee_name"`
+	Amount        float64    `json:"amount"`
+	Description   *string    `json:"description,omitempty"`
+	Status        string     `json:"status"`
+	TxnID         uuid.UUID  `json:"txn_id"`
+	BatchID       *uuid.UUID `json:"batch_id,omitempty"`
+	ClearingRef   *string    `json:"clearing_ref,omitempty"`
+	ReturnReason  *string    `json:"return_reason,omitempty"`
+	ReturnTxnID   *uuid.UUID `json:"return_txn_id,omitempty"`
+	SubmittedAt   time.Time  `json:"submitted_at"`
+	SettledAt     *time.Time `json:"settled_at,omitempty"`
+	ReturnedAt    *time.Time `json:"returned_at,omitempty"`
+}
+
+// InterbankBatch is one deferred net settlement cycle: the payments submitted
+// before Cutoff, and the net amount settled for them.
+type InterbankBatch struct {
+	BatchID       uuid.UUID `json:"batch_id"`
+	Rail          string    `json:"rail"`
+	Cutoff        time.Time `json:"cutoff"`
+	PaymentCount  int       `json:"payment_count"`
+	SettledCount  int       `json:"settled_count"`
+	ReturnedCount int       `json:"returned_count"`
+	NetAmount     float64   `json:"net_amount"`
+	Reference     string    `json:"reference"`
+	SettledAt     time.Time `json:"settled_at"`
+}
+
+var interbankBankCode = regexp.MustCompile(`^[A-Za-z0-9]{4,15}$`)
+
+type InterbankService struct {
+	db       *pgxpool.Pool
+	txns     *TransactionService
+	clearing ClearingHouse
+	cfg      InterbankConfig
+}
+
+func NewInterbankService(db *pgxpool.Pool, txns *TransactionService, clearing ClearingHouse, cfg InterbankConfig) *InterbankService {
+	return &InterbankService{db: db, txns: txns, clearing: clearing, cfg: cfg}
+}
+
+// Route picks the rail for a payment of amount with the given urgency.
+func (s *InterbankService) Route(amount float64, urgency string) string {
+	if urgency == UrgencyUrgent || amount >= s.cfg.RTGSThreshold {
+		return RailRTGS
+	}
+	return RailDNS
+}
+
+// Submit debits the customer's account against the clearing GL and hands
+// the payment to its rail. RTGS payments are settled before Submit returns;
+// if the clearing house cannot be reached the payment stays SUBMITTED and is
+// retried by SettleDue. DNS payments wait for the next batch. Payments are
+// held to the cooling limit whether or not the payee is a beneficiary.
+func (s *InterbankService) Submit(ctx context.Context, p *InterbankPayment) error {
+	p.Amount = roundCents(p.Amount)
+	if p.Urgency == "" {
+		p.Urgency = UrgencyNormal
+	}
+	p.BankCode = strings.ToUpper(strings.TrimSpace(p.BankCode))
+	if p.Amount <= 0 || (p.Urgency != UrgencyNormal && p.Urgency != UrgencyUrgent) ||
+		!interbankBankCode.MatchString(p.BankCode) || p.AccountNumber == "" ||
+		len(p.AccountNumber) > 34 || strings.TrimSpace(p.PayeeName) == "" {
+		return ErrInvalidInput
+	}
+	if err := s.txns.checkApproval(p.Amount); err != nil {
+		return err
+	}
+	p.Rail = s.Route(p.Amount, p.Urgency)
+
+	description := fmt.Sprintf("%s payment to %s", p.Rail, p.PayeeName)
+	if p.Description != nil && *p.Description != "" {
+		description = *p.Description
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	payee := Payee{AccountNumber: p.AccountNumber, BankCode: p.BankCode}
+	if err := checkCoolingTx(ctx, tx, s.txns.cooling, p.AccountID, payee, p.Amount); err != nil {
+		return err
+	}
+
+	channel := "INTERBANK"
+	txn := &AccountTransaction{
+		AccountID:   p.AccountID,
+		TxnType:     "DEBIT",
+		Amount:      p.Amount,
+		Description: &description,
+		Channel:     &channel,
+	}
+	if err := s.txns.createTransactionTx(ctx, tx, txn); err != nil {
+		return err
+	}
+	err = postJournalTx(ctx, tx, &Journal{
+		DebitGL:   GLCustomerDeposits,
+		CreditGL:  GLInterbankClearing,
+		Amount:    p.Amount,
+		Narrative: fmt.Sprintf("%s payment submitted", p.Rail),
+		TxnID:     &txn.TxnID,
+	})
+	if err != nil {
+		return err
+	}
+
+	p.Status = "SUBMITTED"
+	p.TxnID = txn.TxnID
+	err = tx.QueryRow(ctx, `
+		INSERT INTO interbank_payments (account_id, beneficiary_id, rail, urgency, bank_code, account_number,
+		    payee_name, amount, description, status, txn_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING payment_id, submitted_at`,
+		p.AccountID, p.BeneficiaryID, p.Rail, p.Urgency, p.BankCode, p.AccountNumber,
+		p.PayeeName, p.Amount, p.Description, p.Status, p.TxnID,
+	).Scan(&p.PaymentID, &p.SubmittedAt)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	if p.Rail == RailRTGS {
+		if err := s.settleGross(ctx, p); err != nil {
+			log.Printf("RTGS payment %s left for retry: %v", p.PaymentID, err)
+		}
+	}
+	return nil
+}
+
+const interbankPaymentColumns = `
+	payment_id, account_id, beneficiary_id, rail, urgency, bank_code, account_number, payee_name,
+	amount, description, status, txn_id, batch_id, clearing_ref, return_reason, return_txn_id,
+	submitted_at, settled_at, returned_at`
+
+func scanInterbankPayment(row pgx.Row) (*InterbankPayment, error) {
+	p := &InterbankPayment{}
+	err := row.Scan(&p.PaymentID, &p.AccountID, &p.BeneficiaryID, &p.Rail, &p.Urgency, &p.BankCode,
+		&p.AccountNumber, &p.PayeeName, &p.Amount, &p.Description, &p.Status, &p.TxnID, &p.BatchID,
+		&p.ClearingRef, &p.ReturnReason, &p.ReturnTxnID, &p.SubmittedAt, &p.SettledAt, &p.ReturnedAt)
+	return p, err
+}
+
+func (s *InterbankService) GetPayment(ctx context.Context, id uuid.UUID) (*InterbankPayment, error) {
+	p, err := scanInterbankPayment(s.db.QueryRow(ctx,
+		`SELECT `+interbankPaymentColumns+` FROM interbank_payments WHERE payment_id = $1`, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *InterbankService) ListPaymentsByAccount(ctx context.Context, accountID uuid.UUID) ([]*InterbankPayment, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT `+interbankPaymentColumns+` FROM interbank_payments WHERE account_id = $1 ORDER BY submitted_at DESC`,
+		accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := []*InterbankPayment{}
+	for rows.Next() {
+		p, err := scanInterbankPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+	return payments, rows.Err()
+}
+
+// grossClaimTimeout is how long a claimed RTGS payment is left to the process
+// that claimed it before it may be sent again.
+const grossClaimTimeout = 5 * time.Minute
+
+// settleGross settles one RTGS payment with the clearing house and books the
+// result. The payment is claimed first: its row is locked and the attempt
+// recorded before the clearing house is called, so only one process sends
+// it. A payment another process has claimed is left alone until its claim
+// times out.
+func (s *InterbankService) settleGross(ctx context.Context, p *InterbankPayment) error {
+	claimed, err := s.claimGross(ctx, p.PaymentID)
+	if err != nil || !claimed {
+		return err
+	}
+
+	res, err := s.clearing.SettleGross(ctx, p)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var status string
+	err = tx.QueryRow(ctx,
+		`SELECT status FROM interbank_payments WHERE payment_id = $1 FOR UPDATE`, p.PaymentID,
+	).Scan(&status)
+	if err != nil {
+		return err
+	}
+	if status != "SUBMITTED" {
+		return nil
+	}
+
+	if err := s.applyResultTx(ctx, tx, p, res); err != nil {
+		return err
+	}
+	if res.Settled {
+		err := postJournalTx(ctx, tx, &Journal{
+			DebitGL:   GLInterbankClearing,
+			CreditGL:  GLNostroSettlement,
+			Amount:    p.Amount,
+			Narrative: "RTGS gross settlement " + res.Reference,
+			TxnID:     &p.TxnID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// claimGross marks a submitted RTGS payment as being sent to the clearing
+// house. It reports false when the payment is no longer SUBMITTED or another
+// process claimed it less than grossClaimTimeout ago.
+func (s *InterbankService) claimGross(ctx context.Context, paymentID uuid.UUID) (bool, error) {
+	tag, err := s.db.Exec(ctx, `
+		UPDATE interbank_payments
+		SET clearing_attempted_at = now()
+		WHERE payment_id = $1 AND status = 'SUBMITTED'
+		  AND (clearing_attempted_at IS NULL OR clearing_attempted_at < now() - $2 * interval '1 second')`,
+		paymentID, grossClaimTimeout.Seconds(),
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// applyResultTx records the clearing outcome of a payment. A returned
+// payment is credited back to the customer's account out of the clearing GL.
+func (s *InterbankService) applyResultTx(ctx context.Context, tx pgx.Tx, p *InterbankPayment, res *ClearingResult) error {
+	if res.Settled {
+		p.Status = "SETTLED"
+		p.ClearingRef = &res.Reference
+		return tx.QueryRow(ctx, `
+			UPDATE interbank_payments
+			SET status = 'SETTLED', clearing_ref = $1, settled_at = now()
+			WHERE payment_id = $2
+			RETURNING settled_at`,
+			res.Reference, p.PaymentID,
+		).Scan(&p.SettledAt)
+	}
+
+	// Returns are applied whatever the account's status: the money never
+	// left the bank.
+	_, err := tx.Exec(ctx, `SELECT 1 FROM accounts WHERE account_id = $1 FOR UPDATE`, p.AccountID)
+	if err != nil {
+		return err
+	}
+
+	description := fmt.Sprintf("%s payment to %s returned (%s)", p.Rail, p.PayeeName, res.ReturnReason)
+	channel := "INTERBANK"
+	txn := &AccountTransaction{
+		AccountID:   p.AccountID,
+		TxnType:     "CREDIT",
+		Amount:      p.Amount,
+		Description: &description,
+		Channel:     &channel,
+	}
+	if err := s.txns.postEntryTx(ctx, tx, txn); err != nil {
+		return err
+	}
+	err = postJournalTx(ctx, tx, &Journal{
+		DebitGL:   GLInterbankClearing,
+		CreditGL:  GLCustomerDeposits,
+		Amount:    p.Amount,
+		Narrative: description,
+		TxnID:     &txn.TxnID,
+	})
+	if err != nil {
+		return err
+	}
+
+	p.Status = "RETURNED"
+	p.ReturnReason = &res.ReturnReason
+	p.ReturnTxnID = &txn.TxnID
+	return tx.QueryRow(ctx, `
+		UPDATE interbank_payments
+		SET status = 'RETURNED', return_reason = $1, return_txn_id = $2, returned_at = now()
+		WHERE payment_id = $3
+		RETURNING returned_at`,
+		res.ReturnReason, txn.TxnID, p.PaymentID,
+	).Scan(&p.ReturnedAt)
+}
+
+// SettleDue is the background job of the interbank rails. It retries RTGS
+// payments the clearing house did not answer, and settles the DNS batch of
+// the last completed interval.
+func (s *InterbankService) SettleDue(ctx context.Context) error {
+	if err := s.retryGross(ctx); err != nil {
+		return err
+	}
+	b, err := s.settleNet(ctx, time.Now().Truncate(s.cfg.DNSInterval))
+	if b != nil {
+		log.Printf("DNS batch %s settled: %d payments, %d returned, net %.2f",
+			b.Reference, b.PaymentCount, b.ReturnedCount, b.NetAmount)
+	}
+	return err
+}
+
+// SettleNow settles every submitted payment without waiting for the end of
+// the DNS interval. It returns the DNS batch, or nil when no DNS payment was
+// waiting.
+func (s *InterbankService) SettleNow(ctx context.Context) (*InterbankBatch, error) {
+	if err := s.retryGross(ctx); err != nil {
+		return nil, err
+	}
+	return s.settleNet(ctx, time.Now())
+}
+
+// CloseDay is the end-of-day step that clears the last DNS batch of the day.
+func (s *InterbankService) CloseDay(ctx context.Context) error {
+	b, err := s.SettleNow(ctx)
+	if b != nil {
+		log.Printf("DNS batch %s settled: %d payments, %d returned, net %.2f",
+			b.Reference, b.PaymentCount, b.ReturnedCount, b.NetAmount)
+	}
+	return err
+}
+
+// retryGross sends the RTGS payments that are not being sent already: those
+// never claimed, and those whose claim has timed out.
+func (s *InterbankService) retryGross(ctx context.Context) error {
+	rows, err := s.db.Query(ctx, `
+		SELECT `+interbankPaymentColumns+`
+		FROM interbank_payments
+		WHERE rail = 'RTGS' AND status = 'SUBMITTED'
+		  AND (clearing_attempted_at IS NULL OR clearing_attempted_at < now() - $1 * interval '1 second')
+		ORDER BY submitted_at`,
+		grossClaimTimeout.Seconds())
+	if err != nil {
+		return err
+	}
+	var payments []*InterbankPayment
+	for rows.Next() {
+		p, err := scanInterbankPayment(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		payments = append(payments, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, p := range payments {
+		if err := s.settleGross(ctx, p); err != nil {
+			return fmt.Errorf("RTGS payment %s: %w", p.PaymentID, err)
+		}
+	}
+	return nil
+}
+
+// settleNet clears every DNS payment submitted before cutoff in one batch.
+// The clearing house settles the batch as a whole, so the nostro account
+// moves by the net amount of the settled payments.
+func (s *InterbankService) settleNet(ctx context.Context, cutoff time.Time) (*InterbankBatch, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT `+interbankPaymentColumns+`
+		FROM interbank_payments
+		WHERE rail = 'DNS' AND status = 'SUBMITTED' AND batch_id IS NULL AND submitted_at < $1
+		ORDER BY submitted_at
+		FOR UPDATE SKIP LOCKED`,
+		cutoff)
+	if err != nil {
+		return nil, err
+	}
+	var payments []*InterbankPayment
+	for rows.Next() {
+		p, err := scanInterbankPayment(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(payments) == 0 {
+		return nil, nil
+	}
+
+	results, err := s.clearing.SettleNet(ctx, RailDNS, payments)
+	if err != nil {
+		return nil, err
+	}
+	byPayment := make(map[uuid.UUID]*ClearingResult, len(results))
+	for _, r := range results {
+		byPayment[r.PaymentID] = r
+	}
+
+	b := &InterbankBatch{
+		Rail:         RailDNS,
+		Cutoff:       cutoff,
+		PaymentCount: len(payments),
+		Reference:    RailDNS + cutoff.UTC().Format("200601021504"),
+	}
+	ids := make([]uuid.UUID, 0, len(payments))
+	for _, p := range payments {
+		res, ok := byPayment[p.PaymentID]
+		if !ok {
+			return nil, fmt.Errorf("clearing house returned no result for payment %s", p.PaymentID)
+		}
+		if err := s.applyResultTx(ctx, tx, p, res); err != nil {
+			return nil, err
+		}
+		if res.Settled {
+			b.SettledCount++
+			b.NetAmount += p.Amount
+		} else {
+			b.ReturnedCount++
+		}
+		ids = append(ids, p.PaymentID)
+	}
+	b.NetAmount = roundCents(b.NetAmount)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO interbank_batches (rail, cutoff, payment_count, settled_count, returned_count, net_amount, reference)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING batch_id, settled_at`,
+		b.Rail, b.Cutoff, b.PaymentCount, b.SettledCount, b.ReturnedCount, b.NetAmount, b.Reference,
+	).Scan(&b.BatchID, &b.SettledAt)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, `UPDATE interbank_payments SET batch_id = $1 WHERE payment_id = ANY($2)`, b.BatchID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.NetAmount > 0 {
+		err := postJournalTx(ctx, tx, &Journal{
+			DebitGL:   GLInterbankClearing,
+			CreditGL:  GLNostroSettlement,
+			Amount:    b.NetAmount,
+			Narrative: "DNS net settlement " + b.Reference,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,182 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// InterestService credits the interest deposit accounts earn under their
+// product's interest terms at the end of each posting period.
+type InterestService struct {
+	db       *pgxpool.Pool
+	txns     *TransactionService
+	calendar *Calendar
+}
+
+func NewInterestService(db *pgxpool.Pool, txns *TransactionService, calendar *Calendar) *InterestService {
+	return &InterestService{db: db, txns: txns, calendar: calendar}
+}
+
+// PostDue credits the interest of every posting period that ends before the
+// next business date, so a period is settled by the EOD run of its last
+// business day. Interest is worked out on the value-dated balances of each
+// day of the period, by the product's method, as at closure. It runs as an
+// end-of-day step and checkpoints the last account processed; an account
+// already credited for the period is skipped, so a restarted run posts once.
+func (s *InterestService) PostDue(ctx context.Context, businessDate time.Time, cp *Checkpoint) error {
+	next, err := AdjustToBusinessDay(s.calendar, businessDate.AddDate(0, 0, 1), NonBusinessDayNext)
+	if err != nil {
+		return err
+	}
+
+	after := uuid.Nil
+	if cp.Value != "" {
+		id, err := uuid.Parse(cp.Value)
+		if err != nil {
+			return err
+		}
+		after = id
+	}
+
+	for {
+		var accountID uuid.UUID
+		err := s.db.QueryRow(ctx, `
+			SELECT a.account_id
+			FROM accounts a
+			JOIN products p ON p.product_code = a.product_code AND p.version = a.product_version
+			WHERE a.account_id > $1 AND a.status NOT IN ('CLOSED', 'PENDING_ACTIVATION')
+			  AND p.interest_method <> 'NONE' AND p.interest_rate > 0
+			ORDER BY a.account_id
+			LIMIT 1`,
+			after,
+		).Scan(&accountID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return nil
+			}
+			return err
+		}
+
+		if err := s.postAccount(ctx, accountID, businessDate, next); err != nil {
+			return fmt.Errorf("account %s: %w", accountID, err)
+		}
+
+		after = accountID
+		if err := cp.Save(ctx, after.String()); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *InterestService) postAccount(ctx context.Context, accountID uuid.UUID, businessDate, next time.Time) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var status string
+	var openedAt time.Time
+	var interest InterestScheme
+	err = tx.QueryRow(ctx, `
+		SELECT a.status, a.opened_at, COALESCE(p.interest_method, 'NONE'), COALESCE(p.interest_rate, 0),
+		       p.interest_posting
+		FROM accounts a
+		JOIN products p ON p.product_code = a.product_code AND p.version = a.product_version
+		WHERE a.account_id = $1
+		FOR UPDATE OF a`,
+		accountID,
+	).Scan(&status, &openedAt, &interest.Method, &interest.AnnualRate, &interest.Posting)
+	if err != nil {
+		return err
+	}
+	if status == AccountClosed || interest.Method == InterestNone || interest.AnnualRate <= 0 {
+		return nil
+	}
+
+	from, to, ok := interestPeriodEnding(interest.Posting, businessDate, next)
+	if !ok {
+		return nil
+	}
+	if opened := civilDate(openedAt); opened.After(from) {
+		from = opened
+	}
+	var lastPosted *time.Time
+	err = tx.QueryRow(ctx, `
+		SELECT max(posting_date) FROM account_transactions
+		WHERE account_id = $1 AND channel = 'INTEREST' AND reversal_of IS NULL`,
+		accountID,
+	).Scan(&lastPosted)
+	if err != nil {
+		return err
+	}
+	if lastPosted != nil && !civilDate(*lastPosted).Before(from) {
+		return nil
+	}
+	if to.Before(from) {
+		return nil
+	}
+
+	balances, err := valueDatedBalancesTx(ctx, tx, accountID, from, to)
+	if err != nil {
+		return err
+	}
+	amount := accruedInterest(interest.Method, interest.AnnualRate, from, balances)
+	if amount <= 0 {
+		return nil
+	}
+
+	description := fmt.Sprintf("Interest %s to %s", from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if _, err := s.txns.postInterestTx(ctx, tx, accountID, amount, description); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// interestPeriodEnding returns the first and last day of the posting period
+// holding businessDate, and whether that period ends before the next business
+// date. Non-business days at the end of the period are part of it.
+func interestPeriodEnding(posting *string, businessDate, next time.Time) (time.Time, time.Time, bool) {
+	start := interestPeriodStart(posting, businessDate)
+	nextStart := interestPeriodStart(posting, next)
+	if !nextStart.After(start) {
+		return start, time.Time{}, false
+	}
+	months := 1
+	if posting != nil && *posting == InterestPostingQuarterly {
+		months = 3
+	}
+	return start, start.AddDate(0, months, -1), true
+}
+
+// postInterestTx credits interest to an account and journals it against
+// interest expense. Callers must already hold the account lock.
+func (s *TransactionService) postInterestTx(ctx context.Context, tx pgx.Tx, accountID uuid.UUID, amount float64, description string) (*AccountTransaction, error) {
+	channel := "INTEREST"
+	txn := &AccountTransaction{
+		AccountID:   accountID,
+		TxnType:     "CREDIT",
+		Amount:      amount,
+		Description: &description,
+		Channel:     &channel,
+	}
+	if err := s.postEntryTx(ctx, tx, txn); err != nil {
+		return nil, err
+	}
+	err := postJournalTx(ctx, tx, &Journal{
+		DebitGL:   GLInterestExpense,
+		CreditGL:  GLCustomerDeposits,
+		Amount:    amount,
+		Narrative: description,
+		TxnID:     &txn.TxnID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return txn, nil
+}
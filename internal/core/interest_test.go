@@ -0,0 +1,40 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInterestPeriodEnding(t *testing.T) {
+	day := func(m time.Month, d int) time.Time { return time.Date(2026, m, d, 0, 0, 0, 0, time.UTC) }
+	monthly, quarterly := InterestPostingMonthly, InterestPostingQuarterly
+
+	tests := []struct {
+		name         string
+		posting      *string
+		businessDate time.Time
+		next         time.Time
+		wantFrom     time.Time
+		wantTo       time.Time
+		wantEnds     bool
+	}{
+		{"mid month", &monthly, day(time.January, 14), day(time.January, 15), day(time.January, 1), time.Time{}, false},
+		{"month ends on a weekend", &monthly, day(time.January, 30), day(time.February, 2), day(time.January, 1), day(time.January, 31), true},
+		{"last day of the month", &monthly, day(time.March, 31), day(time.April, 1), day(time.March, 1), day(time.March, 31), true},
+		{"no posting set is monthly", nil, day(time.April, 30), day(time.May, 1), day(time.April, 1), day(time.April, 30), true},
+		{"quarterly, month end", &quarterly, day(time.January, 30), day(time.February, 2), day(time.January, 1), time.Time{}, false},
+		{"quarterly, quarter end", &quarterly, day(time.March, 31), day(time.April, 1), day(time.January, 1), day(time.March, 31), true},
+		{"year end", &monthly, day(time.December, 31), time.Date(2027, time.January, 4, 0, 0, 0, 0, time.UTC), day(time.December, 1), day(time.December, 31), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, ends := interestPeriodEnding(tt.posting, tt.businessDate, tt.next)
+			if ends != tt.wantEnds || !from.Equal(tt.wantFrom) || (ends && !to.Equal(tt.wantTo)) {
+				t.Errorf("interestPeriodEnding = %s, %s, %v; want %s, %s, %v",
+					from.Format("2006-01-02"), to.Format("2006-01-02"), ends,
+					tt.wantFrom.Format("2006-01-02"), tt.wantTo.Format("2006-01-02"), tt.wantEnds)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// GL codes shared across services. Branch level cash GLs (tills and vaults)
+// are derived from the branch code, see TillGLCode and VaultGLCode.
+const (
+	GLCustomerDeposits = "CUSTOMER_DEPOSITS"
+)
+
+// GLAccount is an internal general ledger account. Balance is debit positive:
+// asset GLs such as tills carry a positive balance, liability GLs a negative one.
+type GLAccount struct {
+	GLCode    string    `json:"gl_code"`
+	Name      string    `json:"name"`
+	Balance   float64   `json:"balance"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type GLEntry struct {
+	EntryID   uuid.UUID  `json:"entry_id"`
+	JournalID uuid.UUID  `json:"journal_id"`
+	GLCode    string     `json:"gl_code"`
+	EntryType string     `json:"entry_type"`
+	Amount    float64    `json:"amount"`
+	Narrative string     `json:"narrative"`
+	TxnID     *uuid.UUID `json:"txn_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Journal is a balanced pair of GL entries moving Amount from CreditGL to DebitGL.
+type Journal struct {
+	DebitGL   string
+	CreditGL  string
+	Amount    float64
+	Narrative string
+	TxnID     *uuid.UUID
+}
+
+type LedgerService struct {
+	db *pgxpool.Pool
+}
+
+func NewLedgerService(db *pgxpool.Pool) *LedgerService {
+	return &LedgerService{db: db}
+}
+
+func (s *LedgerService) GetGLAccount(ctx context.Context, code string) (*GLAccount, error) {
+	query := `
+		SELECT gl_code, name, balance, created_at
+		FROM gl_accounts
+		WHERE gl_code = $1`
+
+	g := &GLAccount{}
+	err := s.db.QueryRow(ctx, query, code).Scan(&g.GLCode, &g.Name, &g.Balance, &g.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return g, nil
+}
+
+func (s *LedgerService) ListEntries(ctx context.Context, code string, limit, offset int) ([]*GLEntry, error) {
+	query := `
+		SELECT entry_id, journal_id, gl_code, entry_type, amount, narrative, txn_id, created_at
+		FROM gl_entries
+		WHERE gl_code = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := s.db.Query(ctx, query, code, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*GLEntry
+	for rows.Next() {
+		e := &GLEntry{}
+		err := rows.Scan(&e.EntryID, &e.JournalID, &e.GLCode, &e.EntryType,
+			&e.Amount, &e.Narrative, &e.TxnID, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// postJournalTx writes both legs of a journal inside an open database
+// transaction. GL accounts are created on first use.
+func postJournalTx(ctx context.Context, tx pgx.Tx, j *Journal) error {
+	if j.Amount <= 0 || j.DebitGL == j.CreditGL {
+		return ErrInvalidInput
+	}
+
+	journalID := uuid.New()
+	legs := []struct {
+		code      string
+		entryType string
+		change    float64
+	}{
+		{j.DebitGL, "DEBIT", j.Amount},
+		{j.CreditGL, "CREDIT", -j.Amount},
+	}
+
+	for _, leg := range legs {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO gl_accounts (gl_code, name) VALUES ($1, $1)
+			ON CONFLICT (gl_code) DO NOTHING`,
+			leg.code,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO gl_entries (journal_id, gl_code, entry_type, amount, narrative, txn_id)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			journalID, leg.code, leg.entryType, j.Amount, j.Narrative, j.TxnID,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE gl_accounts SET balance = balance + $1 WHERE gl_code = $2`,
+			leg.change, leg.code,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
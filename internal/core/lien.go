@@ -0,0 +1,399 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// GLGarnishmentPayable holds garnished funds until they are paid to the
+// authority that ordered them.
+const GLGarnishmentPayable = "GARNISHMENT_PAYABLE"
+
+// Lien types. A FIXED lien holds Amount of the balance and an ALL_FUNDS lien
+// holds all of it. A GARNISHMENT sweeps incoming credits until Amount has
+// been taken.
+const (
+	LienFixed       = "FIXED"
+	LienAllFunds    = "ALL_FUNDS"
+	LienGarnishment = "GARNISHMENT"
+)
+
+// Lien statuses. A lien keeps holding funds while its release waits for
+// approval.
+const (
+	LienActive    = "ACTIVE"
+	LienReleased  = "RELEASED"
+	LienExpired   = "EXPIRED"
+	LienSatisfied = "SATISFIED"
+)
+
+// Lien is a legal hold placed on an account by order of a court, tax
+// authority or similar, identified by the order's Reference.
+type Lien struct {
+	LienID             uuid.UUID  `json:"lien_id"`
+	AccountID          uuid.UUID  `json:"account_id"`
+	LienType           string     `json:"lien_type"`
+	Amount             *float64   `json:"amount,omitempty"`
+	SweptAmount        float64    `json:"swept_amount"`
+	Reference          string     `json:"reference"`
+	Authority          string     `json:"authority"`
+	ExpiresOn          *time.Time `json:"expires_on,omitempty"`
+	Status             string     `json:"status"`
+	CreatedBy          string     `json:"created_by"`
+	CreatedAt          time.Time  `json:"created_at"`
+	ReleaseReason      *string    `json:"release_reason,omitempty"`
+	ReleaseRequestedBy *string    `json:"release_requested_by,omitempty"`
+	ReleaseRequestedAt *time.Time `json:"release_requested_at,omitempty"`
+	ReleaseDecidedBy   *string    `json:"release_decided_by,omitempty"`
+	ReleaseDecidedAt   *time.Time `json:"release_decided_at,omitempty"`
+}
+
+// AccountBalance splits an account's balance into what liens hold and what
+// can be debited.
+type AccountBalance struct {
+	AccountID     uuid.UUID `json:"account_id"`
+	Balance       float64   `json:"balance"`
+	Liened        float64   `json:"liened"`
+	AllFundsLiens bool      `json:"all_funds_liens"`
+	Available     float64   `json:"available"`
+}
+
+const lienColumns = `lien_id, account_id, lien_type, amount, swept_amount, reference, authority,
+	expires_on, status, created_by, created_at, release_reason, release_requested_by,
+	release_requested_at, release_decided_by, release_decided_at`
+
+func scanLien(row pgx.Row) (*Lien, error) {
+	l := &Lien{}
+	err := row.Scan(&l.LienID, &l.AccountID, &l.LienType, &l.Amount, &l.SweptAmount, &l.Reference,
+		&l.Authority, &l.ExpiresOn, &l.Status, &l.CreatedBy, &l.CreatedAt, &l.ReleaseReason,
+		&l.ReleaseRequestedBy, &l.ReleaseRequestedAt, &l.ReleaseDecidedBy, &l.ReleaseDecidedAt)
+	return l, err
+}
+
+type LienService struct {
+	db *pgxpool.Pool
+}
+
+func NewLienService(db *pgxpool.Pool) *LienService {
+	return &LienService{db: db}
+}
+
+// PlaceLien records a lien or garnishment order against an account. It takes
+// effect immediately.
+func (s *LienService) PlaceLien(ctx context.Context, l *Lien, actor string) error {
+	if actor == "" {
+		return ErrInvalidInput
+	}
+	if l.Reference == "" || l.Authority == "" {
+		return fmt.Errorf("%w: reference and authority are required", ErrInvalidInput)
+	}
+	switch l.LienType {
+	case LienFixed, LienGarnishment:
+		if l.Amount == nil || *l.Amount <= 0 {
+			return fmt.Errorf("%w: a %s lien needs a positive amount", ErrInvalidInput, l.LienType)
+		}
+		rounded := roundCents(*l.Amount)
+		l.Amount = &rounded
+	case LienAllFunds:
+		l.Amount = nil
+	default:
+		return fmt.Errorf("%w: lien_type must be FIXED, ALL_FUNDS or GARNISHMENT", ErrInvalidInput)
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var status string
+	err = tx.QueryRow(ctx, `SELECT status FROM accounts WHERE account_id = $1 FOR UPDATE`, l.AccountID).Scan(&status)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+	if status == AccountClosed {
+		return ErrAccountClosed
+	}
+
+	businessDate, err := businessDateTx(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if l.ExpiresOn != nil && l.ExpiresOn.Before(businessDate) {
+		return fmt.Errorf("%w: expires_on is in the past", ErrInvalidInput)
+	}
+
+	l.Status = LienActive
+	l.CreatedBy = actor
+	err = tx.QueryRow(ctx, `
+		INSERT INTO liens (account_id, lien_type, amount, reference, authority, expires_on, status, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING lien_id, created_at`,
+		l.AccountID, l.LienType, l.Amount, l.Reference, l.Authority, l.ExpiresOn, l.Status, l.CreatedBy,
+	).Scan(&l.LienID, &l.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *LienService) GetLien(ctx context.Context, lienID uuid.UUID) (*Lien, error) {
+	l, err := scanLien(s.db.QueryRow(ctx, `SELECT `+lienColumns+` FROM liens WHERE lien_id = $1`, lienID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return l, nil
+}
+
+func (s *LienService) ListLiens(ctx context.Context, accountID uuid.UUID) ([]*Lien, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT `+lienColumns+`
+		FROM liens
+		WHERE account_id = $1
+		ORDER BY created_at DESC`,
+		accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var liens []*Lien
+	for rows.Next() {
+		l, err := scanLien(rows)
+		if err != nil {
+			return nil, err
+		}
+		liens = append(liens, l)
+	}
+	return liens, rows.Err()
+}
+
+// checkReleasable refuses the release of a lien that is not in force.
+func (s *LienService) checkReleasable(ctx context.Context, lienID uuid.UUID) error {
+	l, err := s.GetLien(ctx, lienID)
+	if err != nil {
+		return err
+	}
+	if l.Status != LienActive {
+		return fmt.Errorf("%w: lien is %s", ErrInvalidInput, l.Status)
+	}
+	return nil
+}
+
+// releaseLienTx releases an active lien once its release request has been
+// approved, recording who asked for it and who approved it.
+func (s *LienService) releaseLienTx(ctx context.Context, tx pgx.Tx, lienID uuid.UUID, reason string, a *Approval) (*Lien, error) {
+	l, err := scanLien(tx.QueryRow(ctx, `
+		UPDATE liens
+		SET status = 'RELEASED', release_reason = $1, release_requested_by = $2,
+		    release_requested_at = $3, release_decided_by = $4, release_decided_at = now()
+		WHERE lien_id = $5 AND status = 'ACTIVE'
+		RETURNING `+lienColumns,
+		reason, a.RequestedBy, a.CreatedAt, a.DecidedBy, lienID,
+	))
+	if err != nil {
+		if err != pgx.ErrNoRows {
+			return nil, err
+		}
+		if err := s.checkReleasable(ctx, lienID); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: lien is no longer active", ErrInvalidInput)
+	}
+	return l, nil
+}
+
+// ExpireDue ends liens whose expiry date is the business date or earlier. It
+// runs as an end-of-day step, so a lien holds funds through its last day.
+func (s *LienService) ExpireDue(ctx context.Context, businessDate time.Time, cp *Checkpoint) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE liens SET status = 'EXPIRED'
+		WHERE status = 'ACTIVE' AND expires_on <= $1`,
+		businessDate,
+	)
+	return err
+}
+
+// AvailableBalance returns what can be debited from an account after its
+// overdraft and liens.
+func (s *LienService) AvailableBalance(ctx context.Context, accountID uuid.UUID) (*AccountBalance, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	b := &AccountBalance{AccountID: accountID}
+	err = tx.QueryRow(ctx, `SELECT balance FROM accounts WHERE account_id = $1`, accountID).Scan(&b.Balance)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	terms, err := productTermsTx(ctx, tx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	b.Liened, b.AllFundsLiens, err = lienedTx(ctx, tx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	b.Available, err = availableTx(ctx, tx, accountID, terms, b.Balance)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// lienedTx returns the total of an account's fixed liens and whether an
+// all-funds lien is in force.
+func lienedTx(ctx context.Context, tx pgx.Tx, accountID uuid.UUID) (float64, bool, error) {
+	var amount float64
+	var allFunds bool
+	err := tx.QueryRow(ctx, `
+		SELECT COALESCE(SUM(amount) FILTER (WHERE lien_type = 'FIXED'), 0),
+		       COALESCE(bool_or(lien_type = 'ALL_FUNDS'), false)
+		FROM liens
+		WHERE account_id = $1 AND status = 'ACTIVE' AND lien_type <> 'GARNISHMENT'`,
+		accountID,
+	).Scan(&amount, &allFunds)
+	return amount, allFunds, err
+}
+
+// availableTx is the most that can be debited from balance: the overdraft
+// is added and liened funds are taken away. An all-funds lien leaves nothing.
+func availableTx(ctx context.Context, tx pgx.Tx, accountID uuid.UUID, terms *productTerms, balance float64) (float64, error) {
+	liened, allFunds, err := lienedTx(ctx, tx, accountID)
+	if err != nil {
+		return 0, err
+	}
+	if allFunds {
+		return 0, nil
+	}
+	return roundCents(terms.available(balance) - liened), nil
+}
+
+// correctionChannels are the channels of credits by which the bank corrects
+// its own postings rather than brings new money in: dispute credits,
+// reversals, refunded fees and returned garnishments.
+var correctionChannels = map[string]bool{
+	"DISPUTE":     true,
+	"REVERSAL":    true,
+	"FEE":         true,
+	"GARNISHMENT": true,
+}
+
+// sweepable reports whether garnishment orders take their share of an
+// entry: every new credit except the bank's corrections.
+func sweepable(txn *AccountTransaction) bool {
+	if txn.TxnType != "CREDIT" || txn.ReversalOf != nil {
+		return false
+	}
+	return txn.Channel == nil || !correctionChannels[*txn.Channel]
+}
+
+// sweepGarnishmentsTx takes a credit just posted to an account for its
+// garnishment orders, oldest first, up to what each still has to collect.
+// Only the part of the credit that left the account in credit is taken.
+func (s *TransactionService) sweepGarnishmentsTx(ctx context.Context, tx pgx.Tx, credit *AccountTransaction) error {
+	left := math.Min(credit.Amount, credit.BalanceAfter)
+	if left <= 0 {
+		return nil
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT lien_id, reference, amount - swept_amount
+		FROM liens
+		WHERE account_id = $1 AND lien_type = 'GARNISHMENT' AND status = 'ACTIVE'
+		ORDER BY created_at
+		FOR UPDATE`,
+		credit.AccountID,
+	)
+	if err != nil {
+		return err
+	}
+	type order struct {
+		lienID    uuid.UUID
+		reference string
+		remaining float64
+	}
+	var orders []order
+	for rows.Next() {
+		var o order
+		if err := rows.Scan(&o.lienID, &o.reference, &o.remaining); err != nil {
+			rows.Close()
+			return err
+		}
+		orders = append(orders, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, o := range orders {
+		take := roundCents(math.Min(left, o.remaining))
+		if take <= 0 {
+			break
+		}
+
+		channel := "GARNISHMENT"
+		description := "Garnishment " + o.reference
+		debit := &AccountTransaction{
+			AccountID:   credit.AccountID,
+			TxnType:     "DEBIT",
+			Amount:      take,
+			Description: &description,
+			Channel:     &channel,
+		}
+		if err := s.postEntryTx(ctx, tx, debit); err != nil {
+			return err
+		}
+		err := postJournalTx(ctx, tx, &Journal{
+			DebitGL:   GLCustomerDeposits,
+			CreditGL:  GLGarnishmentPayable,
+			Amount:    take,
+			Narrative: description,
+			TxnID:     &debit.TxnID,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO lien_sweeps (lien_id, credit_txn_id, txn_id, amount)
+			VALUES ($1, $2, $3, $4)`,
+			o.lienID, credit.TxnID, debit.TxnID, take,
+		)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `
+			UPDATE liens
+			SET swept_amount = swept_amount + $1,
+			    status = CASE WHEN swept_amount + $1 >= amount THEN 'SATISFIED' ELSE status END
+			WHERE lien_id = $2`,
+			take, o.lienID,
+		)
+		if err != nil {
+			return err
+		}
+
+		left = roundCents(left - take)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSweepable(t *testing.T) {
+	channel := func(c string) *string { return &c }
+	original := uuid.New()
+
+	tests := []struct {
+		name string
+		txn  *AccountTransaction
+		want bool
+	}{
+		{"transfer credit", &AccountTransaction{TxnType: "CREDIT", Channel: channel("TRANSFER")}, true},
+		{"cash deposit", &AccountTransaction{TxnType: "CREDIT", Channel: channel("BRANCH")}, true},
+		{"settled ACH debit", &AccountTransaction{TxnType: "CREDIT", Channel: channel("ACH")}, true},
+		{"interest", &AccountTransaction{TxnType: "CREDIT", Channel: channel("INTEREST")}, true},
+		{"no channel", &AccountTransaction{TxnType: "CREDIT"}, true},
+		{"debit", &AccountTransaction{TxnType: "DEBIT", Channel: channel("TRANSFER")}, false},
+		{"dispute provisional credit", &AccountTransaction{TxnType: "CREDIT", Channel: channel("DISPUTE")}, false},
+		{"reversal", &AccountTransaction{TxnType: "CREDIT", Channel: channel("REVERSAL"), ReversalOf: &original}, false},
+		{"refunded fee", &AccountTransaction{TxnType: "CREDIT", Channel: channel("FEE")}, false},
+		{"returned garnishment", &AccountTransaction{TxnType: "CREDIT", Channel: channel("GARNISHMENT")}, false},
+		{"correction on another channel", &AccountTransaction{TxnType: "CREDIT", Channel: channel("TRANSFER"), ReversalOf: &original}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sweepable(tt.txn); got != tt.want {
+				t.Errorf("sweepable = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package core
+
+import (
+	"context"
+	"log"
+
+	"github.com/google/uuid"
+)
+
+// Notifier delivers customer-facing messages such as one-time passcodes and
+// alerts.
+type Notifier interface {
+	Notify(ctx context.Context, customerID uuid.UUID, subject, message string) error
+}
+
+// LogNotifier writes notifications to the server log. It stands in for an SMS
+// or email gateway until one is configured.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, customerID uuid.UUID, subject, message string) error {
+	log.Printf("notify customer=%s subject=%q: %s", customerID, subject, message)
+	return nil
+}
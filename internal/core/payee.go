@@ -0,0 +1,213 @@
+package core
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Confirmation-of-payee outcomes.
+const (
+	PayeeMatch      = "MATCH"
+	PayeeCloseMatch = "CLOSE_MATCH"
+	PayeeNoMatch    = "NO_MATCH"
+)
+
+// Only names identical after normalisation are a MATCH; anything scoring at
+// least this much is a CLOSE_MATCH.
+const payeeCloseMatchThreshold = 0.85
+
+// Honorifics and legal suffixes that customers routinely add or drop.
+var payeeNoiseWords = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "miss": true, "dr": true, "shri": true, "smt": true,
+	"ltd": true, "limited": true, "pvt": true, "private": true, "inc": true, "llc": true,
+	"llp": true, "plc": true, "co": true, "corp": true,
+}
+
+type PayeeCheckResult struct {
+	Result     string  `json:"result"`
+	Score      float64 `json:"score"`
+	MaskedName *string `json:"masked_name,omitempty"`
+}
+
+type PayeeService struct {
+	db *pgxpool.Pool
+}
+
+func NewPayeeService(db *pgxpool.Pool) *PayeeService {
+	return &PayeeService{db: db}
+}
+
+// CheckByAccountNumber matches the name the customer typed against the
+// holder of the account number.
+func (s *PayeeService) CheckByAccountNumber(ctx context.Context, accountNumber, payeeName string) (*PayeeCheckResult, error) {
+	return s.check(ctx, `
+		SELECT c.name
+		FROM accounts a
+		JOIN customers c ON c.customer_id = a.customer_id
+		WHERE a.account_number = $1`, accountNumber, payeeName)
+}
+
+func (s *PayeeService) CheckByAccountID(ctx context.Context, accountID uuid.UUID, payeeName string) (*PayeeCheckResult, error) {
+	return s.check(ctx, `
+		SELECT c.name
+		FROM accounts a
+		JOIN customers c ON c.customer_id = a.customer_id
+		WHERE a.account_id = $1`, accountID, payeeName)
+}
+
+func (s *PayeeService) check(ctx context.Context, query string, key any, payeeName string) (*PayeeCheckResult, error) {
+	if strings.TrimSpace(payeeName) == "" {
+		return nil, ErrInvalidInput
+	}
+
+	var holder string
+	if err := s.db.QueryRow(ctx, query, key).Scan(&holder); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return MatchPayeeName(holder, payeeName), nil
+}
+
+// MatchPayeeName compares a typed payee name with the account holder's name.
+// Names are normalised first, and token order is ignored so "Smith John"
+// still matches "John Smith". The holder's name is only revealed, masked, on
+// a close match.
+func MatchPayeeName(holder, typed string) *PayeeCheckResult {
+	a, b := normalizePayeeName(holder), normalizePayeeName(typed)
+
+	score := 0.0
+	if len(a) > 0 && len(b) > 0 {
+		score = jaroWinkler(strings.Join(a, " "), strings.Join(b, " "))
+		if sorted := jaroWinkler(sortedJoin(a), sortedJoin(b)); sorted > score {
+			score = sorted
+		}
+	}
+
+	res := &PayeeCheckResult{Score: score}
+	switch {
+	case len(a) > 0 && sortedJoin(a) == sortedJoin(b):
+		res.Result = PayeeMatch
+	case score >= payeeCloseMatchThreshold:
+		res.Result = PayeeCloseMatch
+		masked := maskName(holder)
+		res.MaskedName = &masked
+	default:
+		res.Result = PayeeNoMatch
+	}
+
+	return res
+}
+
+func normalizePayeeName(name string) []string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(name) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// Drop combining accents so "José" compares equal to "Jose".
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			b.WriteRune(' ')
+		}
+	}
+
+	var tokens []string
+	for _, t := range strings.Fields(b.String()) {
+		if !payeeNoiseWords[t] {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
+
+func sortedJoin(tokens []string) string {
+	sorted := append([]string(nil), tokens...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, " ")
+}
+
+// maskName keeps the first letter of each word, and the second for longer
+// words, e.g. "Jonathan Smith" becomes "Jo****** Sm***".
+func maskName(name string) string {
+	words := strings.Fields(name)
+	for i, w := range words {
+		r := []rune(w)
+		keep := 1
+		if len(r) > 3 {
+			keep = 2
+		}
+		if keep > len(r) {
+			keep = len(r)
+		}
+		words[i] = string(r[:keep]) + strings.Repeat("*", len(r)-keep)
+	}
+	return strings.Join(words, " ")
+}
+
+// jaroWinkler returns the Jaro-Winkler similarity of a and b in [0, 1].
+func jaroWinkler(a, b string) float64 {
+	s1, s2 := []rune(a), []rune(b)
+	if len(s1) == 0 && len(s2) == 0 {
+		return 1
+	}
+	if len(s1) == 0 || len(s2) == 0 {
+		return 0
+	}
+
+	window := max(len(s1), len(s2))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+
+	m1 := make([]bool, len(s1))
+	m2 := make([]bool, len(s2))
+	matches := 0
+	for i := range s1 {
+		lo, hi := max(0, i-window), min(len(s2), i+window+1)
+		for j := lo; j < hi; j++ {
+			if !m2[j] && s1[i] == s2[j] {
+				m1[i], m2[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions := 0
+	k := 0
+	for i := range s1 {
+		if !m1[i] {
+			continue
+		}
+		for !m2[k] {
+			k++
+		}
+		if s1[i] != s2[k] {
+			transpositions++
+		}
+		k++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(s1)) + m/float64(len(s2)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < min(4, len(s1), len(s2)) && s1[prefix] == s2[prefix] {
+		prefix++
+	}
+
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
@@ -0,0 +1,98 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizePayeeName(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"lower case", "John SMITH", []string{"john", "smith"}},
+		{"extra spaces", "  John   Smith ", []string{"john", "smith"}},
+		{"punctuation", "O'Brien-Kelly, J.", []string{"o", "brien", "kelly", "j"}},
+		{"diacritics", "José Álvarez Müller", []string{"jose", "alvarez", "muller"}},
+		{"honorific", "Mr. John Smith", []string{"john", "smith"}},
+		{"legal suffixes", "Acme Widgets Pvt. Ltd.", []string{"acme", "widgets"}},
+		{"digits kept", "Unit 42 Traders", []string{"unit", "42", "traders"}},
+		{"only noise", "Mrs.", nil},
+		{"empty", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizePayeeName(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalizePayeeName(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchPayeeName(t *testing.T) {
+	tests := []struct {
+		name   string
+		holder string
+		typed  string
+		want   string
+	}{
+		{"identical", "John Smith", "John Smith", PayeeMatch},
+		{"case and spacing", "John Smith", "  john   SMITH", PayeeMatch},
+		{"diacritics", "José Álvarez", "Jose Alvarez", PayeeMatch},
+		{"umlaut dropped", "Ana Müller", "Ana Muller", PayeeMatch},
+		{"word order", "Suresh Kumar", "Kumar Suresh", PayeeMatch},
+		{"honorific added", "John Smith", "Mr John Smith", PayeeMatch},
+		{"legal suffix dropped", "Acme Widgets Pvt Ltd", "Acme Widgets", PayeeMatch},
+
+		{"umlaut spelled out", "Mueller GmbH", "Müller GmbH", PayeeCloseMatch},
+		{"initial for first name", "Suresh Kumar", "S. Kumar", PayeeCloseMatch},
+		{"middle initial added", "John Smith", "John R Smith", PayeeCloseMatch},
+		{"one letter missing", "John Smith", "Jon Smith", PayeeCloseMatch},
+		{"one letter wrong", "John Smith", "John Smyth", PayeeCloseMatch},
+		{"two typos", "John Smith", "Jon Smyth", PayeeCloseMatch},
+		{"different first name, same surname", "Jane Smith", "John Smith", PayeeCloseMatch},
+
+		{"initials only", "John Robert Smith", "J. R. Smith", PayeeNoMatch},
+		{"initial with short names", "John Smith", "J Smith", PayeeNoMatch},
+		{"nickname", "Bob Brown", "Robert Brown", PayeeNoMatch},
+		{"surname only", "John Smith", "Smith", PayeeNoMatch},
+		{"different person", "John Smith", "Jane Doe", PayeeNoMatch},
+		{"typed only noise", "John Smith", "Mr", PayeeNoMatch},
+		{"holder only noise", "Ltd", "Ltd", PayeeNoMatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MatchPayeeName(tt.holder, tt.typed)
+			if got.Result != tt.want {
+				t.Fatalf("MatchPayeeName(%q, %q) = %s (score %.4f), want %s",
+					tt.holder, tt.typed, got.Result, got.Score, tt.want)
+			}
+
+			switch got.Result {
+			case PayeeMatch:
+				if got.Score != 1 {
+					t.Errorf("score = %v, want 1 on a match", got.Score)
+				}
+			case PayeeCloseMatch:
+				if got.Score < payeeCloseMatchThreshold || got.Score >= 1 {
+					t.Errorf("score = %v, want in [%v, 1) on a close match", got.Score, payeeCloseMatchThreshold)
+				}
+			case PayeeNoMatch:
+				if got.Score >= payeeCloseMatchThreshold {
+					t.Errorf("score = %v, want below %v on no match", got.Score, payeeCloseMatchThreshold)
+				}
+			}
+
+			// Only a close match reveals the holder's name, and only masked.
+			if (got.MaskedName != nil) != (got.Result == PayeeCloseMatch) {
+				t.Errorf("masked name = %v on %s", got.MaskedName, got.Result)
+			}
+			if got.MaskedName != nil && *got.MaskedName != maskName(tt.holder) {
+				t.Errorf("masked name = %q, want %q", *got.MaskedName, maskName(tt.holder))
+			}
+		})
+	}
+}
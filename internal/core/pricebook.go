@@ -0,0 +1,257 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// DefaultFeeSchedule applies to accounts whose type has no schedule of its own.
+const DefaultFeeSchedule = "DEFAULT"
+
+// PriceBook is the bank's tariff: a fee schedule per account type and the
+// tax levied on every fee.
+type PriceBook struct {
+	Tax       FeeTax                  `json:"tax"`
+	Schedules map[string]*FeeSchedule `json:"schedules"`
+}
+
+// FeeTax is the tax on fees, such as GST or VAT. Rate is a percentage.
+type FeeTax struct {
+	Name string  `json:"name"`
+	Rate float64 `json:"rate"`
+}
+
+type FeeSchedule struct {
+	TransactionFees []*TransactionFee  `json:"transaction_fees,omitempty"`
+	MaintenanceFee  *PeriodicFee       `json:"maintenance_fee,omitempty"`
+	MinimumBalance  *MinimumBalanceFee `json:"minimum_balance,omitempty"`
+	ClosureFee      *ClosureFee        `json:"closure_fee,omitempty"`
+}
+
+// TransactionFee is charged on every entry posted through Channel. An empty
+// TxnType matches both credits and debits.
+type TransactionFee struct {
+	Code           string   `json:"code"`
+	Channel        string   `json:"channel"`
+	TxnType        string   `json:"txn_type,omitempty"`
+	Charge         Charge   `json:"charge"`
+	WaivedSegments []string `json:"waived_segments,omitempty"`
+}
+
+// PeriodicFee is charged once a month by the end-of-day run.
+type PeriodicFee struct {
+	Code           string   `json:"code"`
+	Charge         Charge   `json:"charge"`
+	WaivedSegments []string `json:"waived_segments,omitempty"`
+}
+
+// MinimumBalanceFee is charged for a month in which the average daily
+// balance fell below Minimum. The charge is calculated on the shortfall.
+type MinimumBalanceFee struct {
+	Code           string   `json:"code"`
+	Minimum        float64  `json:"minimum"`
+	Charge         Charge   `json:"charge"`
+	WaivedSegments []string `json:"waived_segments,omitempty"`
+}
+
+// ClosureFee is charged when an account is closed within WithinMonths of
+// being opened.
+type ClosureFee struct {
+	Code           string   `json:"code"`
+	WithinMonths   int      `json:"within_months"`
+	Charge         Charge   `json:"charge"`
+	WaivedSegments []string `json:"waived_segments,omitempty"`
+}
+
+// Charge prices a fee on an amount: a flat part plus a percentage, taken
+// from the first tier the amount falls in, then held between Min and Max.
+// Amounts above every tier use the charge's own Flat and Percent. A zero Max
+// means no cap.
+type Charge struct {
+	Flat    float64      `json:"flat,omitempty"`
+	Percent float64      `json:"percent,omitempty"`
+	Tiers   []ChargeTier `json:"tiers,omitempty"`
+	Min     float64      `json:"min,omitempty"`
+	Max     float64      `json:"max,omitempty"`
+}
+
+// ChargeTier covers amounts up to and including UpTo; a nil UpTo covers
+// every amount.
+type ChargeTier struct {
+	UpTo    *float64 `json:"up_to,omitempty"`
+	Flat    float64  `json:"flat,omitempty"`
+	Percent float64  `json:"percent,omitempty"`
+}
+
+// LoadPriceBook reads the price book from path. A missing file yields an
+// empty price book, which charges no fees.
+func LoadPriceBook(path string) (*PriceBook, error) {
+	pb := &PriceBook{Schedules: map[string]*FeeSchedule{}}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return pb, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, pb); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := pb.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return pb, nil
+}
+
+func (pb *PriceBook) validate() error {
+	if pb.Tax.Rate < 0 {
+		return fmt.Errorf("tax rate must not be negative")
+	}
+	if pb.Schedules == nil {
+		pb.Schedules = map[string]*FeeSchedule{}
+	}
+	for name, s := range pb.Schedules {
+		if s == nil {
+			return fmt.Errorf("schedule %s is empty", name)
+		}
+		for _, f := range s.TransactionFees {
+			if f.Code == "" || f.Channel == "" {
+				return fmt.Errorf("schedule %s: transaction fees need a code and a channel", name)
+			}
+			if f.TxnType != "" && f.TxnType != "CREDIT" && f.TxnType != "DEBIT" {
+				return fmt.Errorf("schedule %s: fee %s: txn_type must be CREDIT or DEBIT", name, f.Code)
+			}
+			if err := f.Charge.validate(); err != nil {
+				return fmt.Errorf("schedule %s: fee %s: %w", name, f.Code, err)
+			}
+		}
+		if f := s.MaintenanceFee; f != nil {
+			if f.Code == "" {
+				return fmt.Errorf("schedule %s: the maintenance fee needs a code", name)
+			}
+			if err := f.Charge.validate(); err != nil {
+				return fmt.Errorf("schedule %s: fee %s: %w", name, f.Code, err)
+			}
+		}
+		if f := s.MinimumBalance; f != nil {
+			if f.Code == "" || f.Minimum <= 0 {
+				return fmt.Errorf("schedule %s: the minimum balance fee needs a code and a positive minimum", name)
+			}
+			if err := f.Charge.validate(); err != nil {
+				return fmt.Errorf("schedule %s: fee %s: %w", name, f.Code, err)
+			}
+		}
+		if f := s.ClosureFee; f != nil {
+			if f.Code == "" || f.WithinMonths <= 0 {
+				return fmt.Errorf("schedule %s: the closure fee needs a code and a positive within_months", name)
+			}
+			if err := f.Charge.validate(); err != nil {
+				return fmt.Errorf("schedule %s: fee %s: %w", name, f.Code, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c Charge) validate() error {
+	if c.Flat < 0 || c.Percent < 0 || c.Min < 0 || c.Max < 0 {
+		return fmt.Errorf("charges must not be negative")
+	}
+	if c.Max > 0 && c.Min > c.Max {
+		return fmt.Errorf("min is above max")
+	}
+	for i, t := range c.Tiers {
+		if t.Flat < 0 || t.Percent < 0 {
+			return fmt.Errorf("charges must not be negative")
+		}
+		if t.UpTo == nil {
+			if i != len(c.Tiers)-1 {
+				return fmt.Errorf("only the last tier may be open-ended")
+			}
+			continue
+		}
+		if i > 0 && c.Tiers[i-1].UpTo != nil && *t.UpTo <= *c.Tiers[i-1].UpTo {
+			return fmt.Errorf("tiers must be in increasing order")
+		}
+	}
+	return nil
+}
+
+// Apply returns the fee on amount, rounded to cents.
+func (c Charge) Apply(amount float64) float64 {
+	flat, percent := c.Flat, c.Percent
+	for _, t := range c.Tiers {
+		if t.UpTo == nil || amount <= *t.UpTo {
+			flat, percent = t.Flat, t.Percent
+			break
+		}
+	}
+
+	fee := flat + amount*percent/100
+	if fee < c.Min {
+		fee = c.Min
+	}
+	if c.Max > 0 && fee > c.Max {
+		fee = c.Max
+	}
+	return roundCents(fee)
+}
+
+// Schedule returns the fee schedule of an account type, falling back to the
+// default schedule. It returns nil when neither exists.
+func (pb *PriceBook) Schedule(name string) *FeeSchedule {
+	if pb == nil {
+		return nil
+	}
+	if s, ok := pb.Schedules[name]; ok {
+		return s
+	}
+	return pb.Schedules[DefaultFeeSchedule]
+}
+
+// TaxOn returns the tax due on a fee.
+func (pb *PriceBook) TaxOn(fee float64) float64 {
+	if pb == nil {
+		return 0
+	}
+	return roundCents(fee * pb.Tax.Rate / 100)
+}
+
+// hasTransactionFees reports whether any schedule charges per transaction, so
+// postings can skip the fee lookup when none does.
+func (pb *PriceBook) hasTransactionFees() bool {
+	if pb == nil {
+		return false
+	}
+	for _, s := range pb.Schedules {
+		if len(s.TransactionFees) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// TransactionFee returns the fee for an entry posted through channel, or nil.
+func (s *FeeSchedule) TransactionFee(channel, txnType string) *TransactionFee {
+	if s == nil {
+		return nil
+	}
+	for _, f := range s.TransactionFees {
+		if f.Channel == channel && (f.TxnType == "" || f.TxnType == txnType) {
+			return f
+		}
+	}
+	return nil
+}
+
+func segmentWaived(waived []string, segment string) bool {
+	for _, s := range waived {
+		if s == segment {
+			return true
+		}
+	}
+	return false
+}
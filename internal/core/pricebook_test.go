@@ -0,0 +1,86 @@
+package core
+
+import "testing"
+
+func TestChargeApply(t *testing.T) {
+	upTo := func(v float64) *float64 { return &v }
+	tiered := []ChargeTier{
+		{UpTo: upTo(10000), Flat: 2.5},
+		{UpTo: upTo(100000), Flat: 5},
+	}
+
+	tests := []struct {
+		name   string
+		charge Charge
+		amount float64
+		want   float64
+	}{
+		{"flat", Charge{Flat: 25}, 1000, 25},
+		{"percent", Charge{Percent: 0.5}, 1000, 5},
+		{"flat plus percent", Charge{Flat: 10, Percent: 1}, 250, 12.5},
+		{"rounded to cents", Charge{Percent: 0.25}, 333.33, 0.83},
+
+		{"first tier", Charge{Tiers: tiered}, 5000, 2.5},
+		{"amount equal to first tier bound", Charge{Tiers: tiered}, 10000, 2.5},
+		{"just above first tier bound", Charge{Tiers: tiered}, 10000.01, 5},
+		{"amount equal to last tier bound", Charge{Tiers: tiered}, 100000, 5},
+		{"above every tier uses flat and percent", Charge{Tiers: tiered, Flat: 10, Percent: 0.1}, 200000, 210},
+		{"above every tier without flat or percent", Charge{Tiers: tiered}, 200000, 0},
+		{"open-ended last tier", Charge{Tiers: []ChargeTier{
+			{UpTo: upTo(1000), Flat: 1},
+			{Percent: 0.2},
+		}, Flat: 99}, 50000, 100},
+
+		{"raised to min", Charge{Percent: 0.1, Min: 5}, 1000, 5},
+		{"min not applied above it", Charge{Percent: 0.1, Min: 5}, 10000, 10},
+		{"capped at max", Charge{Percent: 1, Max: 250}, 100000, 250},
+		{"max not applied below it", Charge{Percent: 1, Max: 250}, 1000, 10},
+		{"min and max on a tier", Charge{Tiers: []ChargeTier{{Percent: 2}}, Min: 10, Max: 50}, 100, 10},
+		{"zero max means no cap", Charge{Percent: 1, Max: 0}, 1000000, 10000},
+		{"zero amount gets min", Charge{Percent: 1, Min: 2}, 0, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.charge.Apply(tt.amount); got != tt.want {
+				t.Errorf("Apply(%v) = %v, want %v", tt.amount, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChargeValidate(t *testing.T) {
+	upTo := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name    string
+		charge  Charge
+		wantErr bool
+	}{
+		{"empty", Charge{}, false},
+		{"flat and percent", Charge{Flat: 10, Percent: 0.5}, false},
+		{"min below max", Charge{Min: 5, Max: 50}, false},
+		{"min equal to max", Charge{Min: 50, Max: 50}, false},
+		{"min with zero max", Charge{Min: 50, Max: 0}, false},
+		{"increasing tiers", Charge{Tiers: []ChargeTier{{UpTo: upTo(100)}, {UpTo: upTo(1000)}, {}}}, false},
+
+		{"negative flat", Charge{Flat: -1}, true},
+		{"negative percent", Charge{Percent: -0.5}, true},
+		{"negative min", Charge{Min: -1}, true},
+		{"negative max", Charge{Max: -1}, true},
+		{"min above max", Charge{Min: 60, Max: 50}, true},
+		{"negative tier charge", Charge{Tiers: []ChargeTier{{UpTo: upTo(100), Flat: -1}}}, true},
+		{"open-ended tier not last", Charge{Tiers: []ChargeTier{{}, {UpTo: upTo(100)}}}, true},
+		{"equal tier bounds", Charge{Tiers: []ChargeTier{{UpTo: upTo(100)}, {UpTo: upTo(100)}}}, true},
+		{"decreasing tier bounds", Charge{Tiers: []ChargeTier{{UpTo: upTo(1000)}, {UpTo: upTo(100)}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.charge.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,437 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Product statuses. Accounts can only be opened on an ACTIVE product.
+const (
+	ProductActive  = "ACTIVE"
+	ProductRetired = "RETIRED"
+)
+
+// Interest calculation methods and posting frequencies.
+const (
+	InterestNone                  = "NONE"
+	InterestDailyBalance          = "DAILY_BALANCE"
+	InterestAverageMonthlyBalance = "AVERAGE_MONTHLY_BALANCE"
+
+	InterestPostingMonthly   = "MONTHLY"
+	InterestPostingQuarterly = "QUARTERLY"
+)
+
+var (
+	productCodePattern = regexp.MustCompile(`^[A-Z0-9_]{2,32}$`)
+	currencyPattern    = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// Product is one version of a product in the catalog, such as "Savings
+// Classic". Versions are immutable: changing a product publishes a new
+// version, and accounts keep the version they were opened on.
+type Product struct {
+	ProductCode     string             `json:"product_code"`
+	Version         int                `json:"version"`
+	Name            string             `json:"name"`
+	AccountType     string             `json:"account_type"`
+	Currency        string             `json:"currency"`
+	MinBalance      float64            `json:"min_balance"`
+	Interest        InterestScheme     `json:"interest"`
+	FeeSchedule     *string            `json:"fee_schedule,omitempty"`
+	Overdraft       OverdraftTerms     `json:"overdraft"`
+	AllowedChannels []string           `json:"allowed_channels"`
+	Limits          TransactionLimits  `json:"limits"`
+	Eligibility     ProductEligibility `json:"eligibility"`
+	Status          string             `json:"status"`
+	CreatedBy       *string            `json:"created_by,omitempty"`
+	CreatedAt       time.Time          `json:"created_at"`
+}
+
+type InterestScheme struct {
+	Method     string  `json:"method"`
+	AnnualRate float64 `json:"annual_rate"`
+	Posting    *string `json:"posting,omitempty"`
+}
+
+type OverdraftTerms struct {
+	Allowed bool    `json:"allowed"`
+	Limit   float64 `json:"limit"`
+}
+
+// TransactionLimits cap customer debits. Nil means no limit.
+type TransactionLimits struct {
+	PerTransaction *float64 `json:"per_transaction,omitempty"`
+	DailyDebit     *float64 `json:"daily_debit,omitempty"`
+}
+
+// ProductEligibility restricts who may open the product. Ages are in whole
+// years on the business date; nil means no restriction.
+type ProductEligibility struct {
+	MinAge            *int    `json:"min_age,omitempty"`
+	MaxAge            *int    `json:"max_age,omitempty"`
+	RequiredKYCStatus *string `json:"required_kyc_status,omitempty"`
+}
+
+const productColumns = `
+	product_code, version, name, account_type, currency, min_balance, interest_method, interest_rate,
+	interest_posting, fee_schedule, overdraft_allowed, overdraft_limit, allowed_channels,
+	per_transaction_limit, daily_debit_limit, min_age, max_age, required_kyc_status, status,
+	created_by, created_at`
+
+func scanProduct(row pgx.Row) (*Product, error) {
+	p := &Product{}
+	err := row.Scan(&p.ProductCode, &p.Version, &p.Name, &p.AccountType, &p.Currency, &p.MinBalance,
+		&p.Interest.Method, &p.Interest.AnnualRate, &p.Interest.Posting, &p.FeeSchedule,
+		&p.Overdraft.Allowed, &p.Overdraft.Limit, &p.AllowedChannels, &p.Limits.PerTransaction,
+		&p.Limits.DailyDebit, &p.Eligibility.MinAge, &p.Eligibility.MaxAge,
+		&p.Eligibility.RequiredKYCStatus, &p.Status, &p.CreatedBy, &p.CreatedAt)
+	return p, err
+}
+
+type ProductService struct {
+	db       *pgxpool.Pool
+	prices   *PriceBook
+	currency string
+}
+
+// NewProductService checks fee schedules named by products against prices.
+// Products are held in currency, the currency of account balances.
+func NewProductService(db *pgxpool.Pool, prices *PriceBook, currency string) *ProductService {
+	return &ProductService{db: db, prices: prices, currency: currency}
+}
+
+// CreateProduct adds a new product to the catalog as version 1.
+func (s *ProductService) CreateProduct(ctx context.Context, p *Product, actor string) error {
+	return s.publish(ctx, p, actor, true)
+}
+
+// PublishVersion replaces a product's terms with p as its next version.
+// Accounts already open stay on the version they were opened on.
+func (s *ProductService) PublishVersion(ctx context.Context, code string, p *Product, actor string) error {
+	p.ProductCode = code
+	return s.publish(ctx, p, actor, false)
+}
+
+// publish inserts p as the next version of its product: the first version
+// when isNew is set, otherwise the one after the latest.
+func (s *ProductService) publish(ctx context.Context, p *Product, actor string, isNew bool) error {
+	if err := s.validate(p); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Serialise publishers of the same product.
+	_, err = tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('product:' || $1))`, p.ProductCode)
+	if err != nil {
+		return err
+	}
+
+	var latest int
+	err = tx.QueryRow(ctx,
+		`SELECT COALESCE(MAX(version), 0) FROM products WHERE product_code = $1`, p.ProductCode,
+	).Scan(&latest)
+	if err != nil {
+		return err
+	}
+	switch {
+	case isNew && latest > 0:
+		return ErrDuplicateEntry
+	case !isNew && latest == 0:
+		return ErrNotFound
+	}
+	p.Version = latest + 1
+
+	if p.AllowedChannels == nil {
+		p.AllowedChannels = []string{}
+	}
+	p.CreatedBy = nil
+	if actor != "" {
+		p.CreatedBy = &actor
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO products (product_code, version, name, account_type, currency, min_balance,
+		                      interest_method, interest_rate, interest_posting, fee_schedule,
+		                      overdraft_allowed, overdraft_limit, allowed_channels, per_transaction_limit,
+		                      daily_debit_limit, min_age, max_age, required_kyc_status, status, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
+		RETURNING created_at`,
+		p.ProductCode, p.Version, p.Name, p.AccountType, p.Currency, p.MinBalance,
+		p.Interest.Method, p.Interest.AnnualRate, p.Interest.Posting, p.FeeSchedule,
+		p.Overdraft.Allowed, p.Overdraft.Limit, p.AllowedChannels, p.Limits.PerTransaction,
+		p.Limits.DailyDebit, p.Eligibility.MinAge, p.Eligibility.MaxAge,
+		p.Eligibility.RequiredKYCStatus, p.Status, p.CreatedBy,
+	).Scan(&p.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *ProductService) validate(p *Product) error {
+	if p.Status == "" {
+		p.Status = ProductActive
+	}
+	if p.Interest.Method == "" {
+		p.Interest.Method = InterestNone
+	}
+	if p.Currency == "" {
+		p.Currency = s.currency
+	}
+
+	switch {
+	case !productCodePattern.MatchString(p.ProductCode):
+		return fmt.Errorf("%w: product code must be 2 to 32 upper-case letters, digits or underscores", ErrInvalidInput)
+	case p.Name == "" || p.AccountType == "":
+		return fmt.Errorf("%w: name and account type are required", ErrInvalidInput)
+	case !currencyPattern.MatchString(p.Currency):
+		return fmt.Errorf("%w: currency must be an ISO 4217 code", ErrInvalidInput)
+	case p.Currency != s.currency:
+		return fmt.Errorf("%w: currency must be %s, the currency of account balances", ErrInvalidInput, s.currency)
+	case p.MinBalance < 0:
+		return fmt.Errorf("%w: minimum balance must not be negative", ErrInvalidInput)
+	case p.Status != ProductActive && p.Status != ProductRetired:
+		return fmt.Errorf("%w: status must be ACTIVE or RETIRED", ErrInvalidInput)
+	}
+
+	switch p.Interest.Method {
+	case InterestNone:
+		if p.Interest.AnnualRate != 0 || p.Interest.Posting != nil {
+			return fmt.Errorf("%w: a product without interest has no rate or posting frequency", ErrInvalidInput)
+		}
+	case InterestDailyBalance, InterestAverageMonthlyBalance:
+		if p.Interest.AnnualRate < 0 {
+			return fmt.Errorf("%w: interest rate must not be negative", ErrInvalidInput)
+		}
+		if p.Interest.Posting == nil ||
+			(*p.Interest.Posting != InterestPostingMonthly && *p.Interest.Posting != InterestPostingQuarterly) {
+			return fmt.Errorf("%w: interest posting must be MONTHLY or QUARTERLY", ErrInvalidInput)
+		}
+	default:
+		return fmt.Errorf("%w: interest method must be NONE, DAILY_BALANCE or AVERAGE_MONTHLY_BALANCE", ErrInvalidInput)
+	}
+
+	if p.FeeSchedule != nil {
+		if s.prices == nil || s.prices.Schedules[*p.FeeSchedule] == nil {
+			return fmt.Errorf("%w: fee schedule %s is not in the price book", ErrInvalidInput, *p.FeeSchedule)
+		}
+	}
+
+	if p.Overdraft.Limit < 0 || (!p.Overdraft.Allowed && p.Overdraft.Limit != 0) {
+		return fmt.Errorf("%w: an overdraft limit needs an overdraft-eligible product", ErrInvalidInput)
+	}
+
+	seen := map[string]bool{}
+	for _, c := range p.AllowedChannels {
+		if c == "" || seen[c] {
+			return fmt.Errorf("%w: allowed channels must be distinct and non-empty", ErrInvalidInput)
+		}
+		seen[c] = true
+	}
+
+	if l := p.Limits; (l.PerTransaction != nil && *l.PerTransaction <= 0) || (l.DailyDebit != nil && *l.DailyDebit <= 0) {
+		return fmt.Errorf("%w: transaction limits must be positive", ErrInvalidInput)
+	}
+
+	e := p.Eligibility
+	if (e.MinAge != nil && *e.MinAge < 0) || (e.MaxAge != nil && *e.MaxAge < 0) ||
+		(e.MinAge != nil && e.MaxAge != nil && *e.MinAge > *e.MaxAge) {
+		return fmt.Errorf("%w: invalid age range", ErrInvalidInput)
+	}
+
+	return nil
+}
+
+// GetProduct returns the latest version of a product.
+func (s *ProductService) GetProduct(ctx context.Context, code string) (*Product, error) {
+	p, err := scanProduct(s.db.QueryRow(ctx, `
+		SELECT `+productColumns+`
+		FROM products
+		WHERE product_code = $1
+		ORDER BY version DESC
+		LIMIT 1`,
+		code))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *ProductService) GetProductVersion(ctx context.Context, code string, version int) (*Product, error) {
+	p, err := scanProduct(s.db.QueryRow(ctx,
+		`SELECT `+productColumns+` FROM products WHERE product_code = $1 AND version = $2`, code, version))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// ListProducts returns the latest version of every product, leaving out
+// retired ones unless includeRetired is set.
+func (s *ProductService) ListProducts(ctx context.Context, includeRetired bool) ([]*Product, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT `+productColumns+`
+		FROM (
+			SELECT DISTINCT ON (product_code) *
+			FROM products
+			ORDER BY product_code, version DESC
+		) latest
+		WHERE $1 OR status = 'ACTIVE'
+		ORDER BY product_code`,
+		includeRetired)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []*Product
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+// checkEligibility applies the product's eligibility rules to a customer on
+// the given day.
+func (p *Product) checkEligibility(dateOfBirth *time.Time, kycStatus string, day time.Time) error {
+	e := p.Eligibility
+	if e.RequiredKYCStatus != nil && kycStatus != *e.RequiredKYCStatus {
+		return fmt.Errorf("%w: KYC status must be %s", ErrNotEligible, *e.RequiredKYCStatus)
+	}
+	if e.MinAge == nil && e.MaxAge == nil {
+		return nil
+	}
+	if dateOfBirth == nil {
+		return fmt.Errorf("%w: the customer's date of birth is required", ErrNotEligible)
+	}
+
+	age := ageOn(*dateOfBirth, day)
+	if e.MinAge != nil && age < *e.MinAge {
+		return fmt.Errorf("%w: customers must be at least %d", ErrNotEligible, *e.MinAge)
+	}
+	if e.MaxAge != nil && age > *e.MaxAge {
+		return fmt.Errorf("%w: customers must be at most %d", ErrNotEligible, *e.MaxAge)
+	}
+	return nil
+}
+
+// ageOn returns a person's age in whole years on day.
+func ageOn(dateOfBirth, day time.Time) int {
+	age := day.Year() - dateOfBirth.Year()
+	if day.Month() < dateOfBirth.Month() ||
+		(day.Month() == dateOfBirth.Month() && day.Day() < dateOfBirth.Day()) {
+		age--
+	}
+	return age
+}
+
+// productTerms are the parts of an account's product that postings enforce.
+type productTerms struct {
+	overdraftLimit float64
+	channels       []string
+	limits         TransactionLimits
+}
+
+// productTermsTx loads the posting terms of an account's product version. It
+// returns nil for accounts opened before the catalog, which have none.
+func productTermsTx(ctx context.Context, tx pgx.Tx, accountID uuid.UUID) (*productTerms, error) {
+	t := &productTerms{}
+	var overdraftAllowed bool
+	err := tx.QueryRow(ctx, `
+		SELECT p.overdraft_allowed, p.overdraft_limit, p.allowed_channels,
+		       p.per_transaction_limit, p.daily_debit_limit
+		FROM accounts a
+		JOIN products p ON p.product_code = a.product_code AND p.version = a.product_version
+		WHERE a.account_id = $1`,
+		accountID,
+	).Scan(&overdraftAllowed, &t.overdraftLimit, &t.channels, &t.limits.PerTransaction, &t.limits.DailyDebit)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !overdraftAllowed {
+		t.overdraftLimit = 0
+	}
+	return t, nil
+}
+
+// allows reports whether the product may be used through channel. A product
+// without a channel list allows every channel.
+func (t *productTerms) allows(channel string) bool {
+	if t == nil || len(t.channels) == 0 {
+		return true
+	}
+	for _, c := range t.channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// available is the most that can be debited from balance, overdraft included.
+func (t *productTerms) available(balance float64) float64 {
+	if t == nil {
+		return balance
+	}
+	return balance + t.overdraftLimit
+}
+
+// checkDebitTx applies the channel and limit terms of the product to a
+// customer debit. Fees are not counted towards the daily limit.
+func (t *productTerms) checkDebitTx(ctx context.Context, tx pgx.Tx, accountID uuid.UUID, channel string, amount float64) error {
+	if t == nil {
+		return nil
+	}
+	if !t.allows(channel) {
+		return fmt.Errorf("%w: %q", ErrChannelNotAllowed, channel)
+	}
+	if l := t.limits.PerTransaction; l != nil && amount > *l {
+		return fmt.Errorf("%w: per-transaction limit is %.2f", ErrLimitExceeded, *l)
+	}
+	if l := t.limits.DailyDebit; l != nil {
+		day, err := businessDateTx(ctx, tx)
+		if err != nil {
+			return err
+		}
+		var debited float64
+		err = tx.QueryRow(ctx, `
+			SELECT COALESCE(SUM(amount), 0)
+			FROM account_transactions
+			WHERE account_id = $1 AND posting_date = $2 AND txn_type = 'DEBIT'
+			  AND reversal_of IS NULL AND COALESCE(channel, '') <> 'FEE'`,
+			accountID, day,
+		).Scan(&debited)
+		if err != nil {
+			return err
+		}
+		if roundCents(debited+amount) > *l {
+			return fmt.Errorf("%w: daily debit limit is %.2f", ErrLimitExceeded, *l)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,363 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Reversal reason codes.
+const (
+	ReversalDuplicate        = "DUPLICATE"
+	ReversalIncorrectAmount  = "INCORRECT_AMOUNT"
+	ReversalIncorrectAccount = "INCORRECT_ACCOUNT"
+	ReversalUnauthorised     = "UNAUTHORISED"
+	ReversalCustomerRequest  = "CUSTOMER_REQUEST"
+)
+
+var reversalReasons = map[string]bool{
+	ReversalDuplicate:        true,
+	ReversalIncorrectAmount:  true,
+	ReversalIncorrectAccount: true,
+	ReversalUnauthorised:     true,
+	ReversalCustomerRequest:  true,
+}
+
+// serviceChannels are the channels of entries posted by a subsystem that
+// keeps its own state (cheques, tellers, ACH, interbank, disputes) and has
+// its own return flow. Only entries of CreateTransaction and Transfer are reversed
+// here.
+var serviceChannels = map[string]bool{
+	"CHEQUE":      true,
+	"BRANCH":      true,
+	"ACH":         true,
+	"INTERBANK":   true,
+	"DISPUTE":     true,
+	"GARNISHMENT": true,
+}
+
+// checkReversible refuses early a reversal with an unknown reason or of an
+// entry that is missing, already reversed or itself a reversal.
+func (s *TransactionService) checkReversible(ctx context.Context, txnID uuid.UUID, reason string) error {
+	if !reversalReasons[reason] {
+		return fmt.Errorf("%w: unknown reason code %s", ErrInvalidInput, reason)
+	}
+
+	var status string
+	var reversalOf *uuid.UUID
+	err := s.db.QueryRow(ctx,
+		`SELECT status, reversal_of FROM account_transactions WHERE txn_id = $1`, txnID,
+	).Scan(&status, &reversalOf)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+	if status == "REVERSED" {
+		return ErrAlreadyReversed
+	}
+	if reversalOf != nil {
+		return fmt.Errorf("%w: it is itself a reversal", ErrNotReversible)
+	}
+	return nil
+}
+
+// ReverseTransaction posts an equal and opposite entry for a transaction and
+// marks it REVERSED. Both legs of a transfer are reversed together, fees
+// charged on the entries and the tax on them are refunded, and any GL
+// journals posted with the original entries are reversed with them. The
+// reversal is refused if it would overdraw an account beyond its overdraft.
+func (s *TransactionService) ReverseTransaction(ctx context.Context, txnID uuid.UUID, reason, note, actor string) ([]*AccountTransaction, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	reversals, err := s.reverseTransactionTx(ctx, tx, txnID, reason, note, actor)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return reversals, nil
+}
+
+// reverseTransactionTx reverses a transaction inside an open database
+// transaction.
+func (s *TransactionService) reverseTransactionTx(ctx context.Context, tx pgx.Tx, txnID uuid.UUID, reason, note, actor string) ([]*AccountTransaction, error) {
+	if !reversalReasons[reason] {
+		return nil, ErrInvalidInput
+	}
+
+	// The entry is locked so a dispute cannot be opened alongside.
+	orig, err := scanTransaction(tx.QueryRow(ctx,
+		`SELECT `+transactionColumns+` FROM account_transactions WHERE txn_id = $1 FOR UPDATE`, txnID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	channel := ""
+	if orig.Channel != nil {
+		channel = *orig.Channel
+	}
+	if orig.ReversalOf != nil {
+		return nil, fmt.Errorf("%w: it is itself a reversal", ErrNotReversible)
+	}
+	if serviceChannels[channel] {
+		return nil, fmt.Errorf("%w: %s entries are reversed through their own service", ErrNotReversible, channel)
+	}
+
+	if channel == "FEE" && orig.FeeFor != nil {
+		var parentChannel *string
+		err := tx.QueryRow(ctx,
+			`SELECT channel FROM account_transactions WHERE txn_id = $1`, *orig.FeeFor,
+		).Scan(&parentChannel)
+		if err != nil {
+			return nil, err
+		}
+		if parentChannel != nil && *parentChannel == "FEE" {
+			return nil, fmt.Errorf("%w: tax is reversed with the fee it was levied on", ErrNotReversible)
+		}
+	}
+
+	legs := []*AccountTransaction{orig}
+	if orig.TransferID != nil {
+		legs, err = s.transferLegsTx(ctx, tx, *orig.TransferID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	legIDs := make([]uuid.UUID, len(legs))
+	for i, leg := range legs {
+		if leg.Status == "REVERSED" {
+			return nil, ErrAlreadyReversed
+		}
+		legIDs[i] = leg.TxnID
+	}
+
+	// A disputed entry is settled by the dispute; one refunded on a dispute
+	// must not be refunded again.
+	disputes, err := disputeStatusesTx(ctx, tx, legIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range disputes {
+		if disputeHolds(status) {
+			return nil, fmt.Errorf("%w: it is under dispute or was refunded on one", ErrNotReversible)
+		}
+	}
+
+	// Fees point at their entry and tax at its fee, so two rounds collect both.
+	charged := legs
+	for len(charged) > 0 {
+		ids := make([]uuid.UUID, len(charged))
+		for i, c := range charged {
+			ids[i] = c.TxnID
+		}
+		charged, err = feeEntriesTx(ctx, tx, ids)
+		if err != nil {
+			return nil, err
+		}
+		legs = append(legs, charged...)
+	}
+
+	// Lock every account in a fixed order and check what the reversal does
+	// to its balance.
+	change := map[uuid.UUID]float64{}
+	for _, leg := range legs {
+		if leg.TxnType == "CREDIT" {
+			change[leg.AccountID] -= leg.Amount
+		} else {
+			change[leg.AccountID] += leg.Amount
+		}
+	}
+	accounts := make([]uuid.UUID, 0, len(change))
+	for id := range change {
+		accounts = append(accounts, id)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return bytes.Compare(accounts[i][:], accounts[j][:]) < 0 })
+	for _, id := range accounts {
+		var balance float64
+		var status string
+		err := tx.QueryRow(ctx,
+			`SELECT balance, status FROM accounts WHERE account_id = $1 FOR UPDATE`, id,
+		).Scan(&balance, &status)
+		if err != nil {
+			return nil, err
+		}
+		direction := "CREDIT"
+		if change[id] < 0 {
+			direction = "DEBIT"
+		}
+		if err := postingBlocked(status, direction); err != nil {
+			return nil, err
+		}
+		terms, err := productTermsTx(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		if change[id] < 0 {
+			available, err := availableTx(ctx, tx, id, terms, balance)
+			if err != nil {
+				return nil, err
+			}
+			if roundCents(available+change[id]) < 0 {
+				return nil, ErrInsufficientFunds
+			}
+		}
+	}
+
+	var transferID *uuid.UUID
+	if orig.TransferID != nil {
+		id := uuid.New()
+		transferID = &id
+	}
+
+	reversalChannel := "REVERSAL"
+	reversals := make([]*AccountTransaction, 0, len(legs))
+	for _, leg := range legs {
+		description := fmt.Sprintf("Reversal of %s (%s)", leg.TxnID, reason)
+		if note != "" {
+			description += ": " + note
+		}
+		rev := &AccountTransaction{
+			AccountID:      leg.AccountID,
+			TxnType:        "DEBIT",
+			Amount:         leg.Amount,
+			Description:    &description,
+			Channel:        &reversalChannel,
+			ReversalOf:     &leg.TxnID,
+			ReversalReason: &reason,
+		}
+		if leg.TxnType == "DEBIT" {
+			rev.TxnType = "CREDIT"
+		}
+		if leg.TransferID != nil {
+			rev.TransferID = transferID
+		}
+		if err := s.postEntryTx(ctx, tx, rev); err != nil {
+			return nil, err
+		}
+		if err := reverseJournalsTx(ctx, tx, leg.TxnID, rev.TxnID); err != nil {
+			return nil, err
+		}
+
+		_, err := tx.Exec(ctx, `
+			UPDATE account_transactions SET status = 'REVERSED', reversed_by = $1 WHERE txn_id = $2`,
+			actor, leg.TxnID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		reversals = append(reversals, rev)
+	}
+	return reversals, nil
+}
+
+func (s *TransactionService) transferLegsTx(ctx context.Context, tx pgx.Tx, transferID uuid.UUID) ([]*AccountTransaction, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT `+transactionColumns+`
+		FROM account_transactions
+		WHERE transfer_id = $1 AND reversal_of IS NULL
+		ORDER BY entry_seq
+		FOR UPDATE`,
+		transferID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var legs []*AccountTransaction
+	for rows.Next() {
+		txn, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		legs = append(legs, txn)
+	}
+	return legs, rows.Err()
+}
+
+// feeEntriesTx returns the posted fee and tax entries charged on any of the
+// given entries.
+func feeEntriesTx(ctx context.Context, tx pgx.Tx, txnIDs []uuid.UUID) ([]*AccountTransaction, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT `+transactionColumns+`
+		FROM account_transactions
+		WHERE fee_for = ANY($1) AND status = 'POSTED'
+		ORDER BY entry_seq
+		FOR UPDATE`,
+		txnIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fees []*AccountTransaction
+	for rows.Next() {
+		txn, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		fees = append(fees, txn)
+	}
+	return fees, rows.Err()
+}
+
+// reverseJournalsTx posts the opposite of every GL journal recorded against
+// an entry, linked to the reversal entry.
+func reverseJournalsTx(ctx context.Context, tx pgx.Tx, txnID, reversalID uuid.UUID) error {
+	rows, err := tx.Query(ctx, `
+		SELECT journal_id, gl_code, entry_type, amount, narrative
+		FROM gl_entries
+		WHERE txn_id = $1
+		ORDER BY created_at, journal_id`,
+		txnID)
+	if err != nil {
+		return err
+	}
+
+	var order []uuid.UUID
+	journals := map[uuid.UUID]*Journal{}
+	for rows.Next() {
+		var journalID uuid.UUID
+		var code, entryType, narrative string
+		var amount float64
+		if err := rows.Scan(&journalID, &code, &entryType, &amount, &narrative); err != nil {
+			rows.Close()
+			return err
+		}
+		j, ok := journals[journalID]
+		if !ok {
+			j = &Journal{Amount: amount, Narrative: "Reversal: " + narrative, TxnID: &reversalID}
+			journals[journalID] = j
+			order = append(order, journalID)
+		}
+		// The reversal swaps the legs.
+		if entryType == "DEBIT" {
+			j.CreditGL = code
+		} else {
+			j.DebitGL = code
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range order {
+		if err := postJournalTx(ctx, tx, journals[id]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
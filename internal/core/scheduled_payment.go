@@ -0,0 +1,380 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ScheduledPayment is a one-off transfer that executes on ExecuteOn.
+type ScheduledPayment struct {
+	PaymentID     uuid.UUID  `json:"payment_id"`
+	FromAccountID uuid.UUID  `json:"from_account_id"`
+	ToAccountID   uuid.UUID  `json:"to_account_id"`
+	BeneficiaryID *uuid.UUID `json:"beneficiary_id,omitempty"`
+	Amount        float64    `json:"amount"`
+	Description   string     `json:"description"`
+	ExecuteOn     time.Time  `json:"execute_on"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	LastError     *string    `json:"last_error,omitempty"`
+	ExecutedAt    *time.Time `json:"executed_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+type ScheduledPaymentAttempt struct {
+	AttemptID   uuid.UUID `json:"attempt_id"`
+	PaymentID   uuid.UUID `json:"payment_id"`
+	Outcome     string    `json:"outcome"`
+	Error       *string   `json:"error,omitempty"`
+	AttemptedAt time.Time `json:"attempted_at"`
+}
+
+// RetryPolicy controls how often a payment that failed for insufficient funds,
+// or for an unexpected error such as a dropped database connection, is
+// retried. Any other failure is final.
+type RetryPolicy struct {
+	MaxRetries int
+	Interval   time.Duration
+}
+
+// retry returns the status of a payment after a retryable failure on its
+// given attempt, and when it is next attempted.
+func (r RetryPolicy) retry(attempts int, now time.Time) (string, time.Time) {
+	if attempts <= r.MaxRetries {
+		return "PENDING", now.Add(r.Interval)
+	}
+	return "FAILED", now
+}
+
+type ScheduledPaymentService struct {
+	db    *pgxpool.Pool
+	txns  *TransactionService
+	retry RetryPolicy
+}
+
+func NewScheduledPaymentService(db *pgxpool.Pool, txns *TransactionService, retry RetryPolicy) *ScheduledPaymentService {
+	return &ScheduledPaymentService{db: db, txns: txns, retry: retry}
+}
+
+func (s *ScheduledPaymentService) CreatePayment(ctx context.Context, p *ScheduledPayment) error {
+	if p.Amount <= 0 || p.FromAccountID == p.ToAccountID {
+		return ErrInvalidInput
+	}
+	if err := s.txns.checkApproval(p.Amount); err != nil {
+		return err
+	}
+	today, err := businessDate(ctx, s.db)
+	if err != nil {
+		return err
+	}
+	if p.ExecuteOn.Before(today) {
+		return ErrInvalidInput
+	}
+
+	p.Status = "PENDING"
+
+	query := `
+		INSERT INTO scheduled_payments (from_account_id, to_account_id, beneficiary_id, amount,
+		                                description, execute_on, status, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $6)
+		RETURNING payment_id, next_attempt_at, created_at, updated_at`
+
+	return s.db.QueryRow(ctx, query, p.FromAccountID, p.ToAccountID, p.BeneficiaryID, p.Amount,
+		p.Description, p.ExecuteOn, p.Status).Scan(&p.PaymentID, &p.NextAttemptAt, &p.CreatedAt, &p.UpdatedAt)
+}
+
+func (s *ScheduledPaymentService) GetPayment(ctx context.Context, id uuid.UUID) (*ScheduledPayment, error) {
+	query := `
+		SELECT payment_id, from_account_id, to_account_id, beneficiary_id, amount, description, execute_on,
+		       status, attempts, next_attempt_at, last_error, executed_at, created_at, updated_at
+		FROM scheduled_payments
+		WHERE payment_id = $1`
+
+	p := &ScheduledPayment{}
+	err := s.db.QueryRow(ctx, query, id).Scan(
+		&p.PaymentID, &p.FromAccountID, &p.ToAccountID, &p.BeneficiaryID, &p.Amount, &p.Description,
+		&p.ExecuteOn, &p.Status, &p.Attempts, &p.NextAttemptAt, &p.LastError, &p.ExecutedAt,
+		&p.CreatedAt, &p.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// ListPaymentsByAccount lists payments debiting the account, optionally
+// filtered by status.
+func (s *ScheduledPaymentService) ListPaymentsByAccount(ctx context.Context, accountID uuid.UUID, status string) ([]*ScheduledPayment, error) {
+	query := `
+		SELECT payment_id, from_account_id, to_account_id, beneficiary_id, amount, description, execute_on,
+		       status, attempts, next_attempt_at, last_error, executed_at, created_at, updated_at
+		FROM scheduled_payments
+		WHERE from_account_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY execute_on, created_at`
+
+	rows, err := s.db.Query(ctx, query, accountID, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []*ScheduledPayment
+	for rows.Next() {
+		p := &ScheduledPayment{}
+		err := rows.Scan(
+			&p.PaymentID, &p.FromAccountID, &p.ToAccountID, &p.BeneficiaryID, &p.Amount, &p.Description,
+			&p.ExecuteOn, &p.Status, &p.Attempts, &p.NextAttemptAt, &p.LastError, &p.ExecutedAt,
+			&p.CreatedAt, &p.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+
+	return payments, rows.Err()
+}
+
+// AmendPayment changes the amount, execution date or description of a payment
+// that has not been attempted yet. A payment to a beneficiary is held to its
+// cooling limit again, as when it was created.
+func (s *ScheduledPaymentService) AmendPayment(ctx context.Context, p *ScheduledPayment) error {
+	if p.Amount <= 0 {
+		return ErrInvalidInput
+	}
+	if err := s.txns.checkApproval(p.Amount); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	today, err := businessDateTx(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if p.ExecuteOn.Before(today) {
+		return ErrInvalidInput
+	}
+
+	err = tx.QueryRow(ctx, `
+		SELECT from_account_id, to_account_id, beneficiary_id
+		FROM scheduled_payments
+		WHERE payment_id = $1 AND status = 'PENDING' AND attempts = 0
+		FOR UPDATE`,
+		p.PaymentID,
+	).Scan(&p.FromAccountID, &p.ToAccountID, &p.BeneficiaryID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	if p.BeneficiaryID != nil {
+		payee := Payee{AccountID: &p.ToAccountID}
+		if err := checkCoolingTx(ctx, tx, s.txns.cooling, p.FromAccountID, payee, p.Amount); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE scheduled_payments
+		SET amount = $1, execute_on = $2, next_attempt_at = $2, description = $3, updated_at = now()
+		WHERE payment_id = $4`,
+		p.Amount, p.ExecuteOn, p.Description, p.PaymentID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *ScheduledPaymentService) CancelPayment(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE scheduled_payments
+		SET status = 'CANCELLED', updated_at = now()
+		WHERE payment_id = $1 AND status = 'PENDING'`
+
+	result, err := s.db.Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *ScheduledPaymentService) ListAttempts(ctx context.Context, paymentID uuid.UUID) ([]*ScheduledPaymentAttempt, error) {
+	query := `
+		SELECT attempt_id, payment_id, outcome, error, attempted_at
+		FROM scheduled_payment_attempts
+		WHERE payment_id = $1
+		ORDER BY attempted_at`
+
+	rows, err := s.db.Query(ctx, query, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []*ScheduledPaymentAttempt
+	for rows.Next() {
+		a := &ScheduledPaymentAttempt{}
+		if err := rows.Scan(&a.AttemptID, &a.PaymentID, &a.Outcome, &a.Error, &a.AttemptedAt); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, a)
+	}
+
+	return attempts, rows.Err()
+}
+
+// ExecuteDue runs every pending payment whose next attempt is due. Each
+// payment is claimed with SKIP LOCKED, so several server processes can run
+// the scheduler side by side.
+func (s *ScheduledPaymentService) ExecuteDue(ctx context.Context) error {
+	for ctx.Err() == nil {
+		done, err := s.executeNext(ctx)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
+
+func (s *ScheduledPaymentService) executeNext(ctx context.Context) (bool, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	p := &ScheduledPayment{}
+	err = tx.QueryRow(ctx, `
+		SELECT payment_id, from_account_id, to_account_id, amount, description, attempts
+		FROM scheduled_payments
+		WHERE status = 'PENDING' AND execute_on <= (SELECT business_date FROM system_state WHERE id = 1) AND next_attempt_at <= now()
+		ORDER BY next_attempt_at
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED`,
+	).Scan(&p.PaymentID, &p.FromAccountID, &p.ToAccountID, &p.Amount, &p.Description, &p.Attempts)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return true, nil
+		}
+		return false, err
+	}
+
+	p.Attempts++
+	_, transferErr := s.txns.transferTx(ctx, tx, p.FromAccountID, p.ToAccountID, p.Amount, p.Description)
+
+	if transferErr != nil && !isTransferRejection(transferErr) {
+		// The database transaction may be aborted; record the attempt on a
+		// fresh one.
+		tx.Rollback(ctx)
+		return false, s.recordError(ctx, p, transferErr)
+	}
+
+	outcome, status := "SUCCESS", "EXECUTED"
+	var lastError *string
+	nextAttempt := time.Now()
+	if transferErr != nil {
+		outcome, status = "FAILED", "FAILED"
+		msg := transferErr.Error()
+		lastError = &msg
+		if errors.Is(transferErr, ErrInsufficientFunds) {
+			status, nextAttempt = s.retry.retry(p.Attempts, nextAttempt)
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO scheduled_payment_attempts (payment_id, outcome, error)
+		VALUES ($1, $2, $3)`,
+		p.PaymentID, outcome, lastError,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE scheduled_payments
+		SET status = $1, attempts = $2, next_attempt_at = $3, last_error = $4, updated_at = now(),
+		    executed_at = CASE WHEN $1 = 'EXECUTED' THEN now() END
+		WHERE payment_id = $5`,
+		status, p.Attempts, nextAttempt, lastError, p.PaymentID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+
+	log.Printf("scheduled payment %s: %s (attempt %d)", p.PaymentID, status, p.Attempts)
+	return false, nil
+}
+
+// recordError records an attempt that failed for an unexpected error, such
+// as a dropped connection, a serialization failure or a deadlock. It counts
+// against the retry policy, so a passing fault is retried and only one that
+// persists fails the payment. The payment is updated only while it is still
+// pending, in case another process has moved it on since the claim was
+// rolled back.
+func (s *ScheduledPaymentService) recordError(ctx context.Context, p *ScheduledPayment, cause error) error {
+	msg := cause.Error()
+	status, nextAttempt := s.retry.retry(p.Attempts, time.Now())
+	tag, err := s.db.Exec(ctx, `
+		WITH attempt AS (
+			INSERT INTO scheduled_payment_attempts (payment_id, outcome, error)
+			SELECT payment_id, 'FAILED', $2 FROM scheduled_payments
+			WHERE payment_id = $1 AND status = 'PENDING'
+		)
+		UPDATE scheduled_payments
+		SET status = $4, attempts = $3, next_attempt_at = $5, last_error = $2, updated_at = now()
+		WHERE payment_id = $1 AND status = 'PENDING'`,
+		p.PaymentID, msg, p.Attempts, status, nextAttempt,
+	)
+	if err != nil {
+		return fmt.Errorf("recording failure of scheduled payment %s: %w", p.PaymentID, err)
+	}
+	if tag.RowsAffected() > 0 {
+		log.Printf("scheduled payment %s: %s after error (attempt %d): %v", p.PaymentID, status, p.Attempts, cause)
+	}
+	return nil
+}
+
+// isTransferRejection reports whether err is a business rule rejection from
+// transferTx, which leaves the database transaction usable.
+func isTransferRejection(err error) bool {
+	return errors.Is(err, ErrInsufficientFunds) ||
+		errors.Is(err, ErrNotFound) ||
+		isAccountStatusError(err) ||
+		errors.Is(err, ErrChannelNotAllowed) ||
+		errors.Is(err, ErrLimitExceeded) ||
+		errors.Is(err, ErrCoolingLimit) ||
+		errors.Is(err, ErrApprovalRequired)
+}
@@ -0,0 +1,33 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyRetry(t *testing.T) {
+	now := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	policy := RetryPolicy{MaxRetries: 3, Interval: 4 * time.Hour}
+
+	tests := []struct {
+		name       string
+		policy     RetryPolicy
+		attempts   int
+		wantStatus string
+		wantNext   time.Time
+	}{
+		{"first attempt", policy, 1, "PENDING", now.Add(4 * time.Hour)},
+		{"last retry left", policy, 3, "PENDING", now.Add(4 * time.Hour)},
+		{"retries used up", policy, 4, "FAILED", now},
+		{"retries off", RetryPolicy{}, 1, "FAILED", now},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, next := tt.policy.retry(tt.attempts, now)
+			if status != tt.wantStatus || !next.Equal(tt.wantNext) {
+				t.Errorf("retry(%d) = %s, %v; want %s, %v", tt.attempts, status, next, tt.wantStatus, tt.wantNext)
+			}
+		})
+	}
+}
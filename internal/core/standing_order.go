@@ -0,0 +1,532 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Standing order frequencies.
+const (
+	FrequencyWeekly          = "WEEKLY"
+	FrequencyMonthly         = "MONTHLY"
+	FrequencyLastBusinessDay = "LAST_BUSINESS_DAY"
+	FrequencyEveryNDays      = "EVERY_N_DAYS"
+)
+
+// StandingOrder is a recurring transfer from AccountID. Occurrences are
+// derived from StartDate so monthly orders anchored on the 31st still land on
+// the last day of shorter months.
+type StandingOrder struct {
+	StandingOrderID     uuid.UUID  `json:"standing_order_id"`
+	AccountID           uuid.UUID  `json:"account_id"`
+	ToAccountID         uuid.UUID  `json:"to_account_id"`
+	BeneficiaryID       *uuid.UUID `json:"beneficiary_id,omitempty"`
+	Amount              float64    `json:"amount"`
+	Description         string     `json:"description"`
+	Frequency           string     `json:"frequency"`
+	IntervalDays        *int       `json:"interval_days,omitempty"`
+	NonBusinessDay      string     `json:"non_business_day"`
+	StartDate           time.Time  `json:"start_date"`
+	EndDate             *time.Time `json:"end_date,omitempty"`
+	MaxCount            *int       `json:"max_count,omitempty"`
+	Status              string     `json:"status"`
+	Occurrences         int        `json:"occurrences"`
+	NextRunDate         *time.Time `json:"next_run_date,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+type StandingOrderExecution struct {
+	ExecutionID     uuid.UUID `json:"execution_id"`
+	StandingOrderID uuid.UUID `json:"standing_order_id"`
+	ScheduledDate   time.Time `json:"scheduled_date"`
+	Outcome         string    `json:"outcome"`
+	Error           *string   `json:"error,omitempty"`
+	ExecutedAt      time.Time `json:"executed_at"`
+}
+
+// nominalDate returns the unadjusted date of occurrence k, counting from zero.
+func (so *StandingOrder) nominalDate(k int) time.Time {
+	start := so.StartDate
+	switch so.Frequency {
+	case FrequencyWeekly:
+		return start.AddDate(0, 0, 7*k)
+	case FrequencyEveryNDays:
+		return start.AddDate(0, 0, *so.IntervalDays*k)
+	default:
+		// Monthly schedules clamp to the month end instead of overflowing.
+		first := time.Date(start.Year(), start.Month()+time.Month(k), 1, 0, 0, 0, 0, time.UTC)
+		lastDay := first.AddDate(0, 1, -1).Day()
+		return first.AddDate(0, 0, min(start.Day(), lastDay)-1)
+	}
+}
+
+// runDate returns the business-day adjusted execution date of occurrence k,
+// or nil when the order has no occurrence k.
+func (so *StandingOrder) runDate(cal BusinessDays, k int) (*time.Time, error) {
+	if so.MaxCount != nil && k >= *so.MaxCount {
+		return nil, nil
+	}
+
+	nominal := so.nominalDate(k)
+	if so.EndDate != nil && nominal.After(*so.EndDate) {
+		return nil, nil
+	}
+
+	var run time.Time
+	var err error
+	if so.Frequency == FrequencyLastBusinessDay {
+		run, err = LastBusinessDayOfMonth(cal, nominal)
+	} else {
+		run, err = AdjustToBusinessDay(cal, nominal, so.NonBusinessDay)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (so *StandingOrder) validate() error {
+	if so.Amount <= 0 || so.AccountID == so.ToAccountID {
+		return ErrInvalidInput
+	}
+	switch so.Frequency {
+	case FrequencyWeekly, FrequencyMonthly, FrequencyLastBusinessDay:
+		so.IntervalDays = nil
+	case FrequencyEveryNDays:
+		if so.IntervalDays == nil || *so.IntervalDays < 1 {
+			return fmt.Errorf("interval_days is required for EVERY_N_DAYS: %w", ErrInvalidInput)
+		}
+	default:
+		return fmt.Errorf("unknown frequency %q: %w", so.Frequency, ErrInvalidInput)
+	}
+	switch so.NonBusinessDay {
+	case "":
+		so.NonBusinessDay = NonBusinessDayNext
+	case NonBusinessDayNext, NonBusinessDayPrevious, NonBusinessDayNone:
+	default:
+		return fmt.Errorf("unknown non_business_day rule %q: %w", so.NonBusinessDay, ErrInvalidInput)
+	}
+	if so.EndDate != nil && so.EndDate.Before(so.StartDate) {
+		return fmt.Errorf("end_date before start_date: %w", ErrInvalidInput)
+	}
+	if so.MaxCount != nil && *so.MaxCount < 1 {
+		return fmt.Errorf("max_count must be positive: %w", ErrInvalidInput)
+	}
+	return nil
+}
+
+type StandingOrderService struct {
+	db          *pgxpool.Pool
+	txns        *TransactionService
+	calendar    BusinessDays
+	notifier    Notifier
+	maxFailures int
+}
+
+func NewStandingOrderService(db *pgxpool.Pool, txns *TransactionService, calendar BusinessDays,
+	notifier Notifier, maxFailures int) *StandingOrderService {
+	return &StandingOrderService{
+		db:          db,
+		txns:        txns,
+		calendar:    calendar,
+		notifier:    notifier,
+		maxFailures: maxFailures,
+	}
+}
+
+func (s *StandingOrderService) CreateStandingOrder(ctx context.Context, so *StandingOrder) error {
+	if err := so.validate(); err != nil {
+		return err
+	}
+	if err := s.txns.checkApproval(so.Amount); err != nil {
+		return err
+	}
+	today, err := businessDate(ctx, s.db)
+	if err != nil {
+		return err
+	}
+	if so.StartDate.Before(today) {
+		return fmt.Errorf("start_date in the past: %w", ErrInvalidInput)
+	}
+
+	so.Status = "ACTIVE"
+	if so.NextRunDate, err = so.runDate(s.calendar, 0); err != nil {
+		return err
+	}
+	if so.Frequency == FrequencyLastBusinessDay && so.NextRunDate != nil && so.NextRunDate.Before(so.StartDate) {
+		// This month's last business day has already gone; start next month.
+		so.StartDate = time.Date(so.StartDate.Year(), so.StartDate.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		if so.NextRunDate, err = so.runDate(s.calendar, 0); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		INSERT INTO standing_orders (account_id, to_account_id, beneficiary_id, amount, description, frequency,
+		                             interval_days, non_business_day, start_date, end_date, max_count,
+		                             status, next_run_date)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING standing_order_id, created_at, updated_at`
+
+	return s.db.QueryRow(ctx, query, so.AccountID, so.ToAccountID, so.BeneficiaryID, so.Amount, so.Description,
+		so.Frequency, so.IntervalDays, so.NonBusinessDay, so.StartDate, so.EndDate, so.MaxCount,
+		so.Status, so.NextRunDate).Scan(&so.StandingOrderID, &so.CreatedAt, &so.UpdatedAt)
+}
+
+const standingOrderColumns = `
+	standing_order_id, account_id, to_account_id, beneficiary_id, amount, description, frequency,
+	interval_days, non_business_day, start_date, end_date, max_count, status, occurrences,
+	next_run_date, consecutive_failures, created_at, updated_at`
+
+func scanStandingOrder(row pgx.Row) (*StandingOrder, error) {
+	so := &StandingOrder{}
+	err := row.Scan(
+		&so.StandingOrderID, &so.AccountID, &so.ToAccountID, &so.BeneficiaryID, &so.Amount, &so.Description,
+		&so.Frequency, &so.IntervalDays, &so.NonBusinessDay, &so.StartDate, &so.EndDate, &so.MaxCount,
+		&so.Status, &so.Occurrences, &so.NextRunDate, &so.ConsecutiveFailures, &so.CreatedAt, &so.UpdatedAt,
+	)
+	return so, err
+}
+
+func (s *StandingOrderService) GetStandingOrder(ctx context.Context, accountID, id uuid.UUID) (*StandingOrder, error) {
+	query := `SELECT ` + standingOrderColumns + `
+		FROM standing_orders
+		WHERE standing_order_id = $1 AND account_id = $2`
+
+	so, err := scanStandingOrder(s.db.QueryRow(ctx, query, id, accountID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return so, nil
+}
+
+func (s *StandingOrderService) ListStandingOrders(ctx context.Context, accountID uuid.UUID) ([]*StandingOrder, error) {
+	query := `SELECT ` + standingOrderColumns + `
+		FROM standing_orders
+		WHERE account_id = $1
+		ORDER BY created_at DESC`
+
+	rows, err := s.db.Query(ctx, query, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []*StandingOrder
+	for rows.Next() {
+		so, err := scanStandingOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, so)
+	}
+
+	return orders, rows.Err()
+}
+
+func (s *StandingOrderService) ListExecutions(ctx context.Context, id uuid.UUID) ([]*StandingOrderExecution, error) {
+	query := `
+		SELECT execution_id, standing_order_id, scheduled_date, outcome, error, executed_at
+		FROM standing_order_executions
+		WHERE standing_order_id = $1
+		ORDER BY executed_at DESC`
+
+	rows, err := s.db.Query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var executions []*StandingOrderExecution
+	for rows.Next() {
+		e := &StandingOrderExecution{}
+		err := rows.Scan(&e.ExecutionID, &e.StandingOrderID, &e.ScheduledDate, &e.Outcome, &e.Error, &e.ExecutedAt)
+		if err != nil {
+			return nil, err
+		}
+		executions = append(executions, e)
+	}
+
+	return executions, rows.Err()
+}
+
+// UpdateStandingOrder amends the amount, description and end conditions of
+// an active or suspended order. The schedule itself cannot change; cancel and
+// recreate the order instead.
+func (s *StandingOrderService) UpdateStandingOrder(ctx context.Context, update *StandingOrder) (*StandingOrder, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	so, err := scanStandingOrder(tx.QueryRow(ctx, `SELECT `+standingOrderColumns+`
+		FROM standing_orders
+		WHERE standing_order_id = $1 AND account_id = $2
+		FOR UPDATE`, update.StandingOrderID, update.AccountID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if so.Status != "ACTIVE" && so.Status != "SUSPENDED" {
+		return nil, fmt.Errorf("standing order is %s: %w", so.Status, ErrInvalidInput)
+	}
+
+	so.Amount = update.Amount
+	so.Description = update.Description
+	so.EndDate = update.EndDate
+	so.MaxCount = update.MaxCount
+	if err := so.validate(); err != nil {
+		return nil, err
+	}
+	if err := s.txns.checkApproval(so.Amount); err != nil {
+		return nil, err
+	}
+
+	if so.NextRunDate, err = so.runDate(s.calendar, so.Occurrences); err != nil {
+		return nil, err
+	}
+	if so.NextRunDate == nil {
+		so.Status = "COMPLETED"
+	}
+
+	err = tx.QueryRow(ctx, `
+		UPDATE standing_orders
+		SET amount = $1, description = $2, end_date = $3, max_count = $4, next_run_date = $5,
+		    status = $6, updated_at = now()
+		WHERE standing_order_id = $7
+		RETURNING updated_at`,
+		so.Amount, so.Description, so.EndDate, so.MaxCount, so.NextRunDate, so.Status, so.StandingOrderID,
+	).Scan(&so.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return so, tx.Commit(ctx)
+}
+
+// ResumeStandingOrder reactivates a suspended order from its next occurrence
+// on or after today, resetting the failure counter.
+func (s *StandingOrderService) ResumeStandingOrder(ctx context.Context, accountID, id uuid.UUID) (*StandingOrder, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	so, err := scanStandingOrder(tx.QueryRow(ctx, `SELECT `+standingOrderColumns+`
+		FROM standing_orders
+		WHERE standing_order_id = $1 AND account_id = $2
+		FOR UPDATE`, id, accountID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if so.Status != "SUSPENDED" {
+		return nil, fmt.Errorf("standing order is %s: %w", so.Status, ErrInvalidInput)
+	}
+
+	today, err := businessDateTx(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Skip occurrences missed while suspended rather than paying them late.
+	for so.NextRunDate != nil && so.NextRunDate.Before(today) {
+		so.Occurrences++
+		if so.NextRunDate, err = so.runDate(s.calendar, so.Occurrences); err != nil {
+			return nil, err
+		}
+	}
+
+	so.Status = "ACTIVE"
+	if so.NextRunDate == nil {
+		so.Status = "COMPLETED"
+	}
+	so.ConsecutiveFailures = 0
+
+	err = tx.QueryRow(ctx, `
+		UPDATE standing_orders
+		SET status = $1, occurrences = $2, next_run_date = $3, consecutive_failures = 0, updated_at = now()
+		WHERE standing_order_id = $4
+		RETURNING updated_at`,
+		so.Status, so.Occurrences, so.NextRunDate, so.StandingOrderID,
+	).Scan(&so.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return so, tx.Commit(ctx)
+}
+
+func (s *StandingOrderService) CancelStandingOrder(ctx context.Context, accountID, id uuid.UUID) error {
+	result, err := s.db.Exec(ctx, `
+		UPDATE standing_orders
+		SET status = 'CANCELLED', next_run_date = NULL, updated_at = now()
+		WHERE standing_order_id = $1 AND account_id = $2 AND status IN ('ACTIVE', 'SUSPENDED')`,
+		id, accountID,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// ExecuteDue runs every active standing order whose next run date has
+// arrived. Each order is claimed with SKIP LOCKED, mirroring the scheduled
+// payment runner.
+func (s *StandingOrderService) ExecuteDue(ctx context.Context) error {
+	for ctx.Err() == nil {
+		done, err := s.executeNext(ctx)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
+
+func (s *StandingOrderService) executeNext(ctx context.Context) (bool, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	so, err := scanStandingOrder(tx.QueryRow(ctx, `SELECT `+standingOrderColumns+`
+		FROM standing_orders
+		WHERE status = 'ACTIVE' AND next_run_date <= (SELECT business_date FROM system_state WHERE id = 1)
+		ORDER BY next_run_date
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED`))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return true, nil
+		}
+		return false, err
+	}
+
+	scheduled := *so.NextRunDate
+	_, transferErr := s.txns.transferTx(ctx, tx, so.AccountID, so.ToAccountID, so.Amount, so.Description)
+	if transferErr != nil && !isTransferRejection(transferErr) {
+		// Count unexpected errors as failures too, on a fresh transaction.
+		// The order is locked again, and left alone if another process has
+		// run it since the claim was rolled back.
+		tx.Rollback(ctx)
+		if tx, err = s.db.Begin(ctx); err != nil {
+			return false, err
+		}
+		defer tx.Rollback(ctx)
+
+		locked, err := tx.Exec(ctx, `
+			SELECT 1 FROM standing_orders
+			WHERE standing_order_id = $1 AND status = 'ACTIVE' AND next_run_date = $2
+			FOR UPDATE`,
+			so.StandingOrderID, scheduled,
+		)
+		if err != nil {
+			return false, err
+		}
+		if locked.RowsAffected() == 0 {
+			return false, nil
+		}
+	}
+
+	outcome := "SUCCESS"
+	var lastError *string
+	so.Occurrences++
+	if transferErr != nil {
+		outcome = "FAILED"
+		msg := transferErr.Error()
+		lastError = &msg
+		so.ConsecutiveFailures++
+	} else {
+		so.ConsecutiveFailures = 0
+	}
+
+	if so.NextRunDate, err = so.runDate(s.calendar, so.Occurrences); err != nil {
+		return false, err
+	}
+	switch {
+	case s.maxFailures > 0 && so.ConsecutiveFailures >= s.maxFailures:
+		so.Status = "SUSPENDED"
+	case so.NextRunDate == nil:
+		so.Status = "COMPLETED"
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO standing_order_executions (standing_order_id, scheduled_date, outcome, error)
+		VALUES ($1, $2, $3, $4)`,
+		so.StandingOrderID, scheduled, outcome, lastError,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE standing_orders
+		SET status = $1, occurrences = $2, next_run_date = $3, consecutive_failures = $4, updated_at = now()
+		WHERE standing_order_id = $5`,
+		so.Status, so.Occurrences, so.NextRunDate, so.ConsecutiveFailures, so.StandingOrderID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+
+	log.Printf("standing order %s: %s on %s", so.StandingOrderID, outcome, scheduled.Format("2006-01-02"))
+	if transferErr != nil {
+		s.notifyFailure(ctx, so, scheduled, transferErr)
+	}
+
+	return false, nil
+}
+
+func (s *StandingOrderService) notifyFailure(ctx context.Context, so *StandingOrder, scheduled time.Time, cause error) {
+	var customerID uuid.UUID
+	err := s.db.QueryRow(ctx, `SELECT customer_id FROM accounts WHERE account_id = $1`, so.AccountID).Scan(&customerID)
+	if err != nil {
+		log.Printf("standing order %s: cannot notify failure: %v", so.StandingOrderID, err)
+		return
+	}
+
+	reason := "it could not be processed"
+	if errors.Is(cause, ErrInsufficientFunds) {
+		reason = "there were insufficient funds"
+	}
+	msg := fmt.Sprintf("Your standing order of %.2f due on %s was not paid because %s.",
+		so.Amount, scheduled.Format("2006-01-02"), reason)
+	if so.Status == "SUSPENDED" {
+		msg += fmt.Sprintf(" It has been suspended after %d consecutive failures.", so.ConsecutiveFailures)
+	}
+
+	if err := s.notifier.Notify(ctx, customerID, "Standing order payment failed", msg); err != nil {
+		log.Printf("standing order %s: notification failed: %v", so.StandingOrderID, err)
+	}
+}
@@ -0,0 +1,202 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Statement is an account's activity over a range of posting dates with the
+// balances either side of it.
+type Statement struct {
+	AccountID      uuid.UUID             `json:"account_id"`
+	From           time.Time             `json:"from"`
+	To             time.Time             `json:"to"`
+	OpeningBalance float64               `json:"opening_balance"`
+	ClosingBalance float64               `json:"closing_balance"`
+	TotalCredits   float64               `json:"total_credits"`
+	TotalDebits    float64               `json:"total_debits"`
+	Transactions   []*AccountTransaction `json:"transactions"`
+}
+
+// StatementAccount identifies the account a statement or export is for.
+type StatementAccount struct {
+	AccountID     uuid.UUID
+	AccountNumber string
+	AccountType   string
+	BranchCode    string
+	CustomerName  string
+}
+
+// StatementVisitor receives a statement as it is read: the account and
+// opening balance, each transaction in posting order, then the closing
+// balance. Export formats implement it to stream without buffering.
+type StatementVisitor interface {
+	Begin(account *StatementAccount, from, to time.Time, openingBalance float64) error
+	Entry(txn *AccountTransaction) error
+	End(closingBalance float64) error
+}
+
+// Statement builds the statement for posting dates from..to inclusive. Every
+// transaction carries the balance_after recorded when it was posted.
+func (s *TransactionService) Statement(ctx context.Context, accountID uuid.UUID, from, to time.Time) (*Statement, error) {
+	b := &statementBuilder{st: &Statement{AccountID: accountID, Transactions: []*AccountTransaction{}}}
+	if err := s.StreamStatement(ctx, accountID, from, to, b); err != nil {
+		return nil, err
+	}
+	return b.st, nil
+}
+
+// StreamStatement walks the statement for posting dates from..to inclusive
+// through v, reading rows from the database cursor one at a time. A zero from
+// starts at the account opening date and a zero to ends at the current
+// business date. All reads share one snapshot so the balances agree with the
+// rows.
+func (s *TransactionService) StreamStatement(ctx context.Context, accountID uuid.UUID, from, to time.Time, v StatementVisitor) error {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	account := &StatementAccount{}
+	var openedAt time.Time
+	var businessDate time.Time
+	err = tx.QueryRow(ctx, `
+		SELECT a.account_id, a.account_number, a.account_type, b.branch_code, c.name, a.opened_at,
+		       (SELECT business_date FROM system_state WHERE id = 1)
+		FROM accounts a
+		JOIN branches b ON b.branch_id = a.branch_id
+		JOIN customers c ON c.customer_id = a.customer_id
+		WHERE a.account_id = $1`,
+		accountID,
+	).Scan(&account.AccountID, &account.AccountNumber, &account.AccountType, &account.BranchCode,
+		&account.CustomerName, &openedAt, &businessDate)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	if from.IsZero() {
+		from = civilDate(openedAt)
+	}
+	if to.IsZero() {
+		to = businessDate
+	}
+	if to.Before(from) {
+		return ErrInvalidInput
+	}
+
+	opening, err := openingBalanceTx(ctx, tx, accountID, from)
+	if err != nil {
+		return err
+	}
+	if err := v.Begin(account, from, to, opening); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT `+transactionColumns+`
+		FROM account_transactions
+		WHERE account_id = $1 AND posting_date BETWEEN $2 AND $3
+		ORDER BY entry_seq`,
+		accountID, from, to,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	closing := opening
+	for rows.Next() {
+		txn, err := scanTransaction(rows)
+		if err != nil {
+			return err
+		}
+		if err := v.Entry(txn); err != nil {
+			return err
+		}
+		closing = txn.BalanceAfter
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return v.End(closing)
+}
+
+// statementBuilder collects a streamed statement into a Statement.
+type statementBuilder struct {
+	st *Statement
+}
+
+func (b *statementBuilder) Begin(account *StatementAccount, from, to time.Time, openingBalance float64) error {
+	b.st.From, b.st.To = from, to
+	b.st.OpeningBalance = openingBalance
+	return nil
+}
+
+func (b *statementBuilder) Entry(txn *AccountTransaction) error {
+	if txn.TxnType == "DEBIT" {
+		b.st.TotalDebits += txn.Amount
+	} else {
+		b.st.TotalCredits += txn.Amount
+	}
+	b.st.Transactions = append(b.st.Transactions, txn)
+	return nil
+}
+
+func (b *statementBuilder) End(closingBalance float64) error {
+	b.st.ClosingBalance = closingBalance
+	b.st.TotalCredits = roundCents(b.st.TotalCredits)
+	b.st.TotalDebits = roundCents(b.st.TotalDebits)
+	return nil
+}
+
+// openingBalanceTx returns the balance at the start of day. It is the
+// balance after the last earlier entry or, when there is none, the balance
+// before the account's first entry, which covers accounts opened with an
+// initial balance.
+func openingBalanceTx(ctx context.Context, tx pgx.Tx, accountID uuid.UUID, day time.Time) (float64, error) {
+	var current float64
+	err := tx.QueryRow(ctx, `SELECT balance FROM accounts WHERE account_id = $1`, accountID).Scan(&current)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+
+	var opening float64
+	err = tx.QueryRow(ctx, `
+		SELECT balance_after
+		FROM account_transactions
+		WHERE account_id = $1 AND posting_date < $2
+		ORDER BY entry_seq DESC
+		LIMIT 1`,
+		accountID, day,
+	).Scan(&opening)
+	if err == nil {
+		return opening, nil
+	}
+	if err != pgx.ErrNoRows {
+		return 0, err
+	}
+
+	err = tx.QueryRow(ctx, `
+		SELECT balance_after - CASE WHEN txn_type = 'DEBIT' THEN -amount ELSE amount END
+		FROM account_transactions
+		WHERE account_id = $1
+		ORDER BY entry_seq
+		LIMIT 1`,
+		accountID,
+	).Scan(&opening)
+	if err == pgx.ErrNoRows {
+		return current, nil
+	}
+	return opening, err
+}
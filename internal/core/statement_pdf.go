@@ -0,0 +1,401 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shubhbham/BankingApi_Golang/internal/pdf"
+)
+
+// Statement cycles.
+const (
+	StatementCycleCalendarMonth = "CALENDAR_MONTH"
+	StatementCycleAnniversary   = "ANNIVERSARY"
+)
+
+// StatementDocument is a generated PDF statement. Period is the YYYY-MM month
+// in which the cycle ends.
+type StatementDocument struct {
+	AccountID   uuid.UUID `json:"account_id"`
+	Period      string    `json:"period"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	BlobKey     string    `json:"blob_key"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// StatementService produces monthly PDF statements and keeps them in a blob
+// store.
+type StatementService struct {
+	db    *pgxpool.Pool
+	txns  *TransactionService
+	blobs BlobStore
+}
+
+func NewStatementService(db *pgxpool.Pool, txns *TransactionService, blobs BlobStore) *StatementService {
+	return &StatementService{db: db, txns: txns, blobs: blobs}
+}
+
+// SetCycle switches an account between calendar-month and anniversary
+// statements. Anniversary cycles run from the day of the month the account
+// was opened.
+func (s *StatementService) SetCycle(ctx context.Context, accountID uuid.UUID, cycle string) error {
+	if cycle != StatementCycleCalendarMonth && cycle != StatementCycleAnniversary {
+		return ErrInvalidInput
+	}
+
+	result, err := s.db.Exec(ctx, `
+		UPDATE accounts SET statement_cycle = $1 WHERE account_id = $2`,
+		cycle, accountID,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// LastCompleteCycle returns the most recent statement cycle that ended before
+// businessDate.
+func LastCompleteCycle(cycle string, openedOn, businessDate time.Time) (period string, start, end time.Time) {
+	// Cycle M runs from boundary(M-1) to the day before boundary(M).
+	boundary := func(year int, month time.Month) time.Time {
+		if cycle != StatementCycleAnniversary {
+			return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+		}
+		last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		return time.Date(year, month, min(openedOn.Day(), last), 0, 0, 0, 0, time.UTC)
+	}
+
+	year, month := businessDate.Year(), businessDate.Month()
+	if boundary(year, month).After(businessDate) {
+		month--
+	}
+
+	start = boundary(year, month-1)
+	end = boundary(year, month).AddDate(0, 0, -1)
+	return end.Format("2006-01"), start, end
+}
+
+// GenerateDue renders the last complete statement of every open account that
+// does not have one yet. It runs as an end-of-day step and checkpoints the
+// last account processed.
+func (s *StatementService) GenerateDue(ctx context.Context, businessDate time.Time, cp *Checkpoint) error {
+	after := uuid.Nil
+	if cp.Value != "" {
+		id, err := uuid.Parse(cp.Value)
+		if err != nil {
+			return err
+		}
+		after = id
+	}
+
+	for {
+		var accountID uuid.UUID
+		var cycle string
+		var openedAt time.Time
+		err := s.db.QueryRow(ctx, `
+			SELECT account_id, statement_cycle, opened_at
+			FROM accounts
+			WHERE account_id > $1 AND status <> 'CLOSED'
+			ORDER BY account_id
+			LIMIT 1`,
+			after,
+		).Scan(&accountID, &cycle, &openedAt)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return nil
+			}
+			return err
+		}
+
+		opened := civilDate(openedAt)
+		period, start, end := LastCompleteCycle(cycle, opened, businessDate)
+		if !end.Before(opened) {
+			if start.Before(opened) {
+				start = opened
+			}
+			if _, err := s.generate(ctx, accountID, period, start, end); err != nil {
+				return fmt.Errorf("account %s: %w", accountID, err)
+			}
+		}
+
+		after = accountID
+		if err := cp.Save(ctx, after.String()); err != nil {
+			return err
+		}
+	}
+}
+
+// generate renders and stores one statement unless it already exists.
+func (s *StatementService) generate(ctx context.Context, accountID uuid.UUID, period string, start, end time.Time) (*StatementDocument, error) {
+	doc, err := s.getDocument(ctx, accountID, period)
+	if err == nil {
+		return doc, nil
+	}
+	if err != ErrNotFound {
+		return nil, err
+	}
+
+	data, err := s.render(ctx, accountID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	doc = &StatementDocument{
+		AccountID:   accountID,
+		Period:      period,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		BlobKey:     fmt.Sprintf("statements/%s/%s.pdf", accountID, period),
+	}
+	if err := s.blobs.Put(ctx, doc.BlobKey, data); err != nil {
+		return nil, err
+	}
+
+	err = s.db.QueryRow(ctx, `
+		INSERT INTO statement_documents (account_id, period, period_start, period_end, blob_key)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (account_id, period) DO UPDATE SET blob_key = EXCLUDED.blob_key
+		RETURNING generated_at`,
+		doc.AccountID, doc.Period, doc.PeriodStart, doc.PeriodEnd, doc.BlobKey,
+	).Scan(&doc.GeneratedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// GetPDF returns the stored statement for a period.
+func (s *StatementService) GetPDF(ctx context.Context, accountID uuid.UUID, period string) ([]byte, error) {
+	doc, err := s.getDocument(ctx, accountID, period)
+	if err != nil {
+		return nil, err
+	}
+	return s.blobs.Get(ctx, doc.BlobKey)
+}
+
+func (s *StatementService) getDocument(ctx context.Context, accountID uuid.UUID, period string) (*StatementDocument, error) {
+	doc := &StatementDocument{}
+	err := s.db.QueryRow(ctx, `
+		SELECT account_id, period, period_start, period_end, blob_key, generated_at
+		FROM statement_documents
+		WHERE account_id = $1 AND period = $2`,
+		accountID, period,
+	).Scan(&doc.AccountID, &doc.Period, &doc.PeriodStart, &doc.PeriodEnd, &doc.BlobKey, &doc.GeneratedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return doc, nil
+}
+
+// statementParty holds the header details printed on a statement.
+type statementParty struct {
+	accountNumber string
+	accountType   string
+	customerName  string
+	address       *string
+	branch        Branch
+}
+
+func (s *StatementService) render(ctx context.Context, accountID uuid.UUID, start, end time.Time) ([]byte, error) {
+	var p statementParty
+	err := s.db.QueryRow(ctx, `
+		SELECT a.account_number, a.account_type, c.name, c.address,
+		       b.branch_code, b.name, b.address, b.city, b.state, b.country
+		FROM accounts a
+		JOIN customers c ON c.customer_id = a.customer_id
+		JOIN branches b ON b.branch_id = a.branch_id
+		WHERE a.account_id = $1`,
+		accountID,
+	).Scan(&p.accountNumber, &p.accountType, &p.customerName, &p.address,
+		&p.branch.BranchCode, &p.branch.Name, &p.branch.Address, &p.branch.City, &p.branch.State, &p.branch.Country)
+	if err != nil {
+		return nil, err
+	}
+
+	st, err := s.txns.Statement(ctx, accountID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	return renderStatementPDF(p, st), nil
+}
+
+const (
+	pdfMargin     = 40.0
+	pdfRowHeight  = 14.0
+	pdfPageBottom = pdf.PageHeight - 60
+)
+
+func renderStatementPDF(p statementParty, st *Statement) []byte {
+	doc := pdf.New()
+	doc.AddPage()
+	right := pdf.PageWidth - pdfMargin
+
+	// Branch header
+	y := 50.0
+	doc.Text(pdfMargin, y, 16, true, p.branch.Name)
+	y += 16
+	doc.Text(pdfMargin, y, 9, false, "Branch code "+p.branch.BranchCode)
+	for _, line := range branchAddressLines(p.branch) {
+		y += 12
+		doc.Text(pdfMargin, y, 9, false, line)
+	}
+	doc.TextRight(right, 50, 14, true, "Account Statement")
+	doc.TextRight(right, 66, 9, false, fmt.Sprintf("%s to %s",
+		st.From.Format("02 Jan 2006"), st.To.Format("02 Jan 2006")))
+	y += 14
+	doc.Line(pdfMargin, y, right, y)
+
+	// Customer and account
+	y += 20
+	doc.Text(pdfMargin, y, 11, true, p.customerName)
+	doc.TextRight(right, y, 9, false, fmt.Sprintf("Account %s (%s)", p.accountNumber, p.accountType))
+	if p.address != nil {
+		for _, line := range strings.Split(*p.address, "\n") {
+			y += 12
+			doc.Text(pdfMargin, y, 9, false, strings.TrimSpace(line))
+		}
+	}
+
+	// Summary
+	y += 26
+	summary := []struct {
+		label  string
+		amount float64
+	}{
+		{"Opening balance", st.OpeningBalance},
+		{"Total credits", st.TotalCredits},
+		{"Total debits", st.TotalDebits},
+		{"Closing balance", st.ClosingBalance},
+	}
+	for i, item := range summary {
+		x := pdfMargin + float64(i)*(right-pdfMargin)/4
+		doc.Text(x, y, 8, false, item.label)
+		doc.Text(x, y+13, 11, true, formatAmount(item.amount))
+	}
+	y += 34
+
+	// Transaction table
+	columns := []struct {
+		title string
+		x     float64
+		right bool
+	}{
+		{"Date", pdfMargin, false},
+		{"Value date", pdfMargin + 62, false},
+		{"Description", pdfMargin + 124, false},
+		{"Debit", right - 160, true},
+		{"Credit", right - 80, true},
+		{"Balance", right, true},
+	}
+	header := func() {
+		for _, c := range columns {
+			if c.right {
+				doc.TextRight(c.x, y, 9, true, c.title)
+			} else {
+				doc.Text(c.x, y, 9, true, c.title)
+			}
+		}
+		y += 5
+		doc.Line(pdfMargin, y, right, y)
+		y += pdfRowHeight
+	}
+	header()
+
+	if len(st.Transactions) == 0 {
+		doc.Text(pdfMargin, y, 9, false, "No transactions in this period.")
+	}
+	for _, txn := range st.Transactions {
+		if y > pdfPageBottom {
+			pageFooter(doc)
+			doc.AddPage()
+			y = 50
+			header()
+		}
+
+		description := ""
+		if txn.Description != nil {
+			description = *txn.Description
+		}
+		description = ellipsize(description, 42)
+		debit, credit := "", formatAmount(txn.Amount)
+		if txn.TxnType == "DEBIT" {
+			debit, credit = credit, ""
+		}
+
+		doc.Text(columns[0].x, y, 8, false, txn.PostingDate.Format("02-01-2006"))
+		doc.Text(columns[1].x, y, 8, false, txn.ValueDate.Format("02-01-2006"))
+		doc.Text(columns[2].x, y, 8, false, description)
+		doc.TextRight(columns[3].x, y, 8, false, debit)
+		doc.TextRight(columns[4].x, y, 8, false, credit)
+		doc.TextRight(columns[5].x, y, 8, false, formatAmount(txn.BalanceAfter))
+		y += pdfRowHeight
+	}
+	pageFooter(doc)
+
+	return doc.Bytes()
+}
+
+func pageFooter(doc *pdf.Document) {
+	y := pdf.PageHeight - 30
+	doc.Line(pdfMargin, y-12, pdf.PageWidth-pdfMargin, y-12)
+	doc.Text(pdfMargin, y, 7, false, "This is a computer-generated statement and does not require a signature.")
+	doc.TextRight(pdf.PageWidth-pdfMargin, y, 7, false, fmt.Sprintf("Page %d", doc.PageCount()))
+}
+
+func branchAddressLines(b Branch) []string {
+	var lines []string
+	if b.Address != nil && *b.Address != "" {
+		lines = append(lines, *b.Address)
+	}
+	var cityLine []string
+	for _, part := range []*string{b.City, b.State} {
+		if part != nil && *part != "" {
+			cityLine = append(cityLine, *part)
+		}
+	}
+	cityLine = append(cityLine, b.Country)
+	return append(lines, strings.Join(cityLine, ", "))
+}
+
+// formatAmount renders an amount with two decimals and thousands separators.
+func formatAmount(v float64) string {
+	s := fmt.Sprintf("%.2f", v)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, frac := s[:len(s)-3], s[len(s)-3:]
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + "," + whole[i:]
+	}
+	return sign + whole + frac
+}
+
+// ellipsize shortens s to at most n characters, ending it with "..." when
+// anything was cut. It counts runes, so multi-byte characters stay whole.
+func ellipsize(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	if n < 3 {
+		// No room for the dots.
+		return string(r[:max(n, 0)])
+	}
+	return string(r[:n-3]) + "..."
+}
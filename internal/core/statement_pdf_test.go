@@ -0,0 +1,39 @@
+package core
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestEllipsize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		n    int
+		want string
+	}{
+		{"short", "ATM cash", 10, "ATM cash"},
+		{"exactly the limit", "0123456789", 10, "0123456789"},
+		{"ascii cut", "0123456789AB", 10, "0123456..."},
+		{"multi-byte cut", "Überweisung an Jürgen", 10, "Überwei..."},
+		{"cut after multi-byte", "€€€€€€€€€€€€", 10, "€€€€€€€..."},
+		{"room for the dots only", "0123456789", 3, "..."},
+		{"no room for the dots", "0123456789", 2, "01"},
+		{"zero width", "0123456789", 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ellipsize(tt.in, tt.n)
+			if got != tt.want {
+				t.Errorf("ellipsize(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+			}
+			if n := utf8.RuneCountInString(got); n > tt.n {
+				t.Errorf("ellipsize(%q, %d) = %q is %d runes long", tt.in, tt.n, got, n)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("ellipsize(%q) = %q is not valid UTF-8", tt.in, got)
+			}
+		})
+	}
+}
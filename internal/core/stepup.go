@@ -0,0 +1,143 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Step-up purposes. A challenge can only be redeemed for the purpose it was
+// issued for.
+const (
+	StepUpBeneficiaryAdd    = "BENEFICIARY_ADD"
+	StepUpBeneficiaryDelete = "BENEFICIARY_DELETE"
+)
+
+const (
+	stepUpTTL         = 5 * time.Minute
+	stepUpMaxAttempts = 3
+)
+
+type StepUpChallenge struct {
+	ChallengeID uuid.UUID `json:"challenge_id"`
+	CustomerID  uuid.UUID `json:"customer_id"`
+	Purpose     string    `json:"purpose"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// StepUpService issues and redeems one-time passcodes that confirm a
+// sensitive action was requested by the customer.
+type StepUpService struct {
+	db       *pgxpool.Pool
+	notifier Notifier
+}
+
+func NewStepUpService(db *pgxpool.Pool, notifier Notifier) *StepUpService {
+	return &StepUpService{db: db, notifier: notifier}
+}
+
+func (s *StepUpService) IssueChallenge(ctx context.Context, customerID uuid.UUID, purpose string) (*StepUpChallenge, error) {
+	if purpose != StepUpBeneficiaryAdd && purpose != StepUpBeneficiaryDelete {
+		return nil, ErrInvalidInput
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return nil, err
+	}
+	code := fmt.Sprintf("%06d", n.Int64())
+
+	c := &StepUpChallenge{
+		ChallengeID: uuid.New(),
+		CustomerID:  customerID,
+		Purpose:     purpose,
+		ExpiresAt:   time.Now().Add(stepUpTTL),
+	}
+
+	_, err = s.db.Exec(ctx, `
+		INSERT INTO step_up_challenges (challenge_id, customer_id, purpose, code_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		c.ChallengeID, c.CustomerID, c.Purpose, hashStepUpCode(c.ChallengeID, code), c.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := fmt.Sprintf("Your verification code is %s. It expires in %d minutes.", code, int(stepUpTTL.Minutes()))
+	if err := s.notifier.Notify(ctx, customerID, "Verification code", msg); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// verifyTx redeems a challenge inside the caller's transaction so the
+// challenge is only consumed if the protected action commits.
+func (s *StepUpService) verifyTx(ctx context.Context, tx pgx.Tx, challengeID, customerID uuid.UUID, purpose, code string) error {
+	var (
+		owner     uuid.UUID
+		chPurpose string
+		codeHash  string
+		expiresAt time.Time
+		usedAt    *time.Time
+		attempts  int
+	)
+	err := tx.QueryRow(ctx, `
+		SELECT customer_id, purpose, code_hash, expires_at, used_at, attempts
+		FROM step_up_challenges
+		WHERE challenge_id = $1`,
+		challengeID,
+	).Scan(&owner, &chPurpose, &codeHash, &expiresAt, &usedAt, &attempts)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrStepUpRequired
+		}
+		return err
+	}
+
+	if owner != customerID || chPurpose != purpose || usedAt != nil ||
+		attempts >= stepUpMaxAttempts || time.Now().After(expiresAt) {
+		return ErrStepUpRequired
+	}
+
+	if subtle.ConstantTimeCompare([]byte(codeHash), []byte(hashStepUpCode(challengeID, code))) != 1 {
+		// Count the failed attempt outside the caller's transaction, which
+		// will be rolled back.
+		_, err := s.db.Exec(ctx, `
+			UPDATE step_up_challenges SET attempts = attempts + 1 WHERE challenge_id = $1`,
+			challengeID,
+		)
+		if err != nil {
+			return err
+		}
+		return ErrStepUpRequired
+	}
+
+	// The used_at guard stops two concurrent requests redeeming the same code.
+	result, err := tx.Exec(ctx, `
+		UPDATE step_up_challenges SET used_at = now() WHERE challenge_id = $1 AND used_at IS NULL`,
+		challengeID,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrStepUpRequired
+	}
+
+	return nil
+}
+
+func hashStepUpCode(challengeID uuid.UUID, code string) string {
+	sum := sha256.Sum256([]byte(challengeID.String() + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
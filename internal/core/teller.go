@@ -0,0 +1,698 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Denominations counts notes and coins by face value, e.g. {"500": 20, "0.5": 10}.
+type Denominations map[string]int
+
+// Total returns the cash value of the denominations.
+func (d Denominations) Total() (float64, error) {
+	var total float64
+	for face, count := range d {
+		value, err := strconv.ParseFloat(face, 64)
+		if err != nil || value <= 0 || count < 0 {
+			return 0, fmt.Errorf("denomination %q: %w", face, ErrInvalidInput)
+		}
+		total += value * float64(count)
+	}
+	return roundCents(total), nil
+}
+
+type TellerSession struct {
+	SessionID            uuid.UUID     `json:"session_id"`
+	BranchID             uuid.UUID     `json:"branch_id"`
+	TellerID             string        `json:"teller_id"`
+	TillGLCode           string        `json:"till_gl_code"`
+	Status               string        `json:"status"`
+	OpeningDenominations Denominations `json:"opening_denominations"`
+	OpeningCash          float64       `json:"opening_cash"`
+	ClosingDenominations Denominations `json:"closing_denominations,omitempty"`
+	ClosingCash          *float64      `json:"closing_cash,omitempty"`
+	ExpectedCash         *float64      `json:"expected_cash,omitempty"`
+	Variance             *float64      `json:"variance,omitempty"`
+	OpenedAt             time.Time     `json:"opened_at"`
+	ClosedAt             *time.Time    `json:"closed_at,omitempty"`
+}
+
+type VaultTransfer struct {
+	TransferID    uuid.UUID     `json:"transfer_id"`
+	SessionID     uuid.UUID     `json:"session_id"`
+	Direction     string        `json:"direction"`
+	Amount        float64       `json:"amount"`
+	Denominations Denominations `json:"denominations"`
+	InitiatedBy   string        `json:"initiated_by"`
+	ApprovedBy    *string       `json:"approved_by,omitempty"`
+	Status        string        `json:"status"`
+	CreatedAt     time.Time     `json:"created_at"`
+	DecidedAt     *time.Time    `json:"decided_at,omitempty"`
+}
+
+// TellerBalance is one row of the end-of-day teller balancing report.
+type TellerBalance struct {
+	SessionID    uuid.UUID `json:"session_id"`
+	TellerID     string    `json:"teller_id"`
+	Status       string    `json:"status"`
+	OpeningCash  float64   `json:"opening_cash"`
+	Deposits     float64   `json:"deposits"`
+	Withdrawals  float64   `json:"withdrawals"`
+	VaultIn      float64   `json:"vault_in"`
+	VaultOut     float64   `json:"vault_out"`
+	ExpectedCash float64   `json:"expected_cash"`
+	ClosingCash  *float64  `json:"closing_cash,omitempty"`
+	Variance     *float64  `json:"variance,omitempty"`
+	Flag         string    `json:"flag"`
+}
+
+const (
+	VaultToTill = "VAULT_TO_TILL"
+	TillToVault = "TILL_TO_VAULT"
+)
+
+// GLCashVariance takes the overages and shortages found when tills are
+// counted at close.
+const GLCashVariance = "CASH_VARIANCE"
+
+func TillGLCode(branchCode, tellerID string) string {
+	return "TILL-" + branchCode + "-" + tellerID
+}
+
+func VaultGLCode(branchCode string) string {
+	return "VAULT-" + branchCode
+}
+
+type TellerService struct {
+	db   *pgxpool.Pool
+	txns *TransactionService
+
+	// supervisors may approve vault transfers; with none configured no
+	// vault transfer can be approved.
+	supervisors []string
+}
+
+func NewTellerService(db *pgxpool.Pool, txns *TransactionService, supervisors []string) *TellerService {
+	return &TellerService{db: db, txns: txns, supervisors: supervisors}
+}
+
+// OpenSession starts a teller's day on a branch till. A teller may only hold
+// one open session at a time. The opening cash is issued from the branch
+// vault, so it is journaled from the vault GL to the till GL.
+func (s *TellerService) OpenSession(ctx context.Context, ts *TellerSession) error {
+	if ts.TellerID == "" {
+		return ErrUnauthorized
+	}
+	if ts.OpeningDenominations == nil {
+		ts.OpeningDenominations = Denominations{}
+	}
+
+	total, err := ts.OpeningDenominations.Total()
+	if err != nil {
+		return err
+	}
+
+	var branchCode string
+	err = s.db.QueryRow(ctx, `SELECT branch_code FROM branches WHERE branch_id = $1`, ts.BranchID).
+		Scan(&branchCode)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	ts.TillGLCode = TillGLCode(branchCode, ts.TellerID)
+	ts.OpeningCash = total
+	ts.Status = "OPEN"
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO teller_sessions (branch_id, teller_id, till_gl_code, status, opening_denominations, opening_cash)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (teller_id) WHERE status = 'OPEN' DO NOTHING
+		RETURNING session_id, opened_at`
+
+	err = tx.QueryRow(ctx, query, ts.BranchID, ts.TellerID, ts.TillGLCode, ts.Status,
+		ts.OpeningDenominations, ts.OpeningCash).Scan(&ts.SessionID, &ts.OpenedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrDuplicateEntry
+		}
+		return err
+	}
+
+	if total > 0 {
+		err = postJournalTx(ctx, tx, &Journal{
+			DebitGL:   ts.TillGLCode,
+			CreditGL:  VaultGLCode(branchCode),
+			Amount:    total,
+			Narrative: "Till opening cash",
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *TellerService) GetSession(ctx context.Context, id uuid.UUID) (*TellerSession, error) {
+	query := `
+		SELECT session_id, branch_id, teller_id, till_gl_code, status, opening_denominations, opening_cash,
+		       closing_denominations, closing_cash, expected_cash, variance, opened_at, closed_at
+		FROM teller_sessions
+		WHERE session_id = $1`
+
+	ts := &TellerSession{}
+	err := s.db.QueryRow(ctx, query, id).Scan(
+		&ts.SessionID, &ts.BranchID, &ts.TellerID, &ts.TillGLCode, &ts.Status,
+		&ts.OpeningDenominations, &ts.OpeningCash, &ts.ClosingDenominations, &ts.ClosingCash,
+		&ts.ExpectedCash, &ts.Variance, &ts.OpenedAt, &ts.ClosedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return ts, nil
+}
+
+// PostCash posts a customer cash deposit (CREDIT) or withdrawal (DEBIT)
+// through the teller's till and mirrors it on the till GL. A withdrawal is
+// held to the approval threshold like any other payment out.
+func (s *TellerService) PostCash(ctx context.Context, sessionID uuid.UUID, tellerID string, txn *AccountTransaction) error {
+	if txn.Amount <= 0 || (txn.TxnType != "CREDIT" && txn.TxnType != "DEBIT") {
+		return ErrInvalidInput
+	}
+	if txn.TxnType == "DEBIT" {
+		if err := s.txns.checkApproval(txn.Amount); err != nil {
+			return err
+		}
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tillGL, err := lockOpenSession(ctx, tx, sessionID, tellerID)
+	if err != nil {
+		return err
+	}
+
+	channel := "BRANCH"
+	txn.Channel = &channel
+
+	journal := &Journal{Amount: txn.Amount}
+	movement := "DEPOSIT"
+	if txn.TxnType == "CREDIT" {
+		journal.DebitGL, journal.CreditGL = tillGL, GLCustomerDeposits
+		journal.Narrative = "Cash deposit"
+	} else {
+		till, err := sessionBalance(ctx, tx, sessionID)
+		if err != nil {
+			return err
+		}
+		if till.ExpectedCash < txn.Amount {
+			return fmt.Errorf("till cash: %w", ErrInsufficientFunds)
+		}
+		journal.DebitGL, journal.CreditGL = GLCustomerDeposits, tillGL
+		journal.Narrative = "Cash withdrawal"
+		movement = "WITHDRAWAL"
+	}
+
+	if err := s.txns.createTransactionTx(ctx, tx, txn); err != nil {
+		return err
+	}
+
+	journal.TxnID = &txn.TxnID
+	if err := postJournalTx(ctx, tx, journal); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO teller_cash_movements (session_id, movement_type, amount, txn_id)
+		VALUES ($1, $2, $3, $4)`,
+		sessionID, movement, txn.Amount, txn.TxnID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// payOutCashTx pays an account's money out as cash through the teller's till
+// without the checks of a customer withdrawal, for flows such as account
+// closure that have already made their own. Callers must already hold the
+// account lock.
+func (s *TellerService) payOutCashTx(ctx context.Context, tx pgx.Tx, sessionID uuid.UUID, tellerID string, txn *AccountTransaction) error {
+	tillGL, err := lockOpenSession(ctx, tx, sessionID, tellerID)
+	if err != nil {
+		return err
+	}
+	till, err := sessionBalance(ctx, tx, sessionID)
+	if err != nil {
+		return err
+	}
+	if till.ExpectedCash < txn.Amount {
+		return fmt.Errorf("till cash: %w", ErrInsufficientFunds)
+	}
+
+	channel := "BRANCH"
+	txn.TxnType = "DEBIT"
+	txn.Channel = &channel
+	if err := s.txns.postEntryTx(ctx, tx, txn); err != nil {
+		return err
+	}
+
+	err = postJournalTx(ctx, tx, &Journal{
+		DebitGL:   GLCustomerDeposits,
+		CreditGL:  tillGL,
+		Amount:    txn.Amount,
+		Narrative: "Cash withdrawal",
+		TxnID:     &txn.TxnID,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO teller_cash_movements (session_id, movement_type, amount, txn_id)
+		VALUES ($1, 'WITHDRAWAL', $2, $3)`,
+		sessionID, txn.Amount, txn.TxnID,
+	)
+	return err
+}
+
+// RequestVaultTransfer records a pending till/vault movement. It only moves
+// money once a second user approves it.
+func (s *TellerService) RequestVaultTransfer(ctx context.Context, vt *VaultTransfer) error {
+	if vt.Direction != VaultToTill && vt.Direction != TillToVault {
+		return ErrInvalidInput
+	}
+	if vt.InitiatedBy == "" {
+		return ErrUnauthorized
+	}
+
+	total, err := vt.Denominations.Total()
+	if err != nil {
+		return err
+	}
+	if total <= 0 {
+		return ErrInvalidInput
+	}
+	vt.Amount = total
+	vt.Status = "PENDING"
+
+	var status string
+	err = s.db.QueryRow(ctx, `SELECT status FROM teller_sessions WHERE session_id = $1`, vt.SessionID).
+		Scan(&status)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+	if status != "OPEN" {
+		return fmt.Errorf("teller session is %s: %w", status, ErrInvalidInput)
+	}
+
+	query := `
+		INSERT INTO vault_transfers (session_id, direction, amount, denominations, initiated_by, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING transfer_id, created_at`
+
+	return s.db.QueryRow(ctx, query, vt.SessionID, vt.Direction, vt.Amount, vt.Denominations,
+		vt.InitiatedBy, vt.Status).Scan(&vt.TransferID, &vt.CreatedAt)
+}
+
+// DecideVaultTransfer approves or rejects a pending vault transfer. Dual
+// control requires the approver to be neither the initiator nor the
+// session's teller, and to be one of the configured supervisors.
+func (s *TellerService) DecideVaultTransfer(ctx context.Context, transferID uuid.UUID, approverID string, approve bool) (*VaultTransfer, error) {
+	if approverID == "" {
+		return nil, ErrUnauthorized
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	vt := &VaultTransfer{}
+	err = tx.QueryRow(ctx, `
+		SELECT transfer_id, session_id, direction, amount, denominations, initiated_by, status, created_at
+		FROM vault_transfers
+		WHERE transfer_id = $1
+		FOR UPDATE`,
+		transferID,
+	).Scan(&vt.TransferID, &vt.SessionID, &vt.Direction, &vt.Amount, &vt.Denominations,
+		&vt.InitiatedBy, &vt.Status, &vt.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if vt.Status != "PENDING" {
+		return nil, fmt.Errorf("vault transfer is %s: %w", vt.Status, ErrInvalidInput)
+	}
+	if vt.InitiatedBy == approverID {
+		return nil, fmt.Errorf("initiator cannot approve own vault transfer: %w", ErrUnauthorized)
+	}
+	if !s.isSupervisor(approverID) {
+		return nil, fmt.Errorf("%s is not a supervisor: %w", approverID, ErrUnauthorized)
+	}
+
+	vt.Status = "REJECTED"
+	if approve {
+		vt.Status = "COMPLETED"
+
+		var tillGL, branchCode, sessionStatus, sessionTeller string
+		err = tx.QueryRow(ctx, `
+			SELECT s.till_gl_code, b.branch_code, s.status, s.teller_id
+			FROM teller_sessions s
+			JOIN branches b ON b.branch_id = s.branch_id
+			WHERE s.session_id = $1
+			FOR UPDATE OF s`,
+			vt.SessionID,
+		).Scan(&tillGL, &branchCode, &sessionStatus, &sessionTeller)
+		if err != nil {
+			return nil, err
+		}
+		if sessionTeller == approverID {
+			return nil, fmt.Errorf("teller cannot approve transfers of own till: %w", ErrUnauthorized)
+		}
+		if sessionStatus != "OPEN" {
+			return nil, fmt.Errorf("teller session is %s: %w", sessionStatus, ErrInvalidInput)
+		}
+
+		journal := &Journal{Amount: vt.Amount}
+		movement := "VAULT_IN"
+		if vt.Direction == VaultToTill {
+			journal.DebitGL, journal.CreditGL = tillGL, VaultGLCode(branchCode)
+			journal.Narrative = "Vault to till transfer"
+		} else {
+			till, err := sessionBalance(ctx, tx, vt.SessionID)
+			if err != nil {
+				return nil, err
+			}
+			if till.ExpectedCash < vt.Amount {
+				return nil, fmt.Errorf("till cash: %w", ErrInsufficientFunds)
+			}
+			journal.DebitGL, journal.CreditGL = VaultGLCode(branchCode), tillGL
+			journal.Narrative = "Till to vault transfer"
+			movement = "VAULT_OUT"
+		}
+
+		if err := postJournalTx(ctx, tx, journal); err != nil {
+			return nil, err
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO teller_cash_movements (session_id, movement_type, amount, vault_transfer_id)
+			VALUES ($1, $2, $3, $4)`,
+			vt.SessionID, movement, vt.Amount, vt.TransferID,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	vt.ApprovedBy = &approverID
+	err = tx.QueryRow(ctx, `
+		UPDATE vault_transfers
+		SET status = $1, approved_by = $2, decided_at = now()
+		WHERE transfer_id = $3
+		RETURNING decided_at`,
+		vt.Status, approverID, vt.TransferID,
+	).Scan(&vt.DecidedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return vt, tx.Commit(ctx)
+}
+
+// CheckTillsClosed fails while any teller session is still open, so the
+// business date cannot roll over with cash unbalanced.
+func (s *TellerService) CheckTillsClosed(ctx context.Context) error {
+	var open int
+	err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM teller_sessions WHERE status = 'OPEN'`).Scan(&open)
+	if err != nil {
+		return err
+	}
+	if open > 0 {
+		return fmt.Errorf("%d teller sessions still open: %w", open, ErrInvalidInput)
+	}
+	return nil
+}
+
+// CloseSession records the teller's counted cash and the variance against the
+// position expected from the session's cash movements.
+func (s *TellerService) CloseSession(ctx context.Context, sessionID uuid.UUID, tellerID string, counted Denominations) (*TellerSession, error) {
+	closingCash, err := counted.Total()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	tillGL, err := lockOpenSession(ctx, tx, sessionID, tellerID)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM vault_transfers WHERE session_id = $1 AND status = 'PENDING')`,
+		sessionID,
+	).Scan(&pending)
+	if err != nil {
+		return nil, err
+	}
+	if pending {
+		return nil, fmt.Errorf("session has pending vault transfers: %w", ErrInvalidInput)
+	}
+
+	b, err := sessionBalance(ctx, tx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	variance := roundCents(closingCash - b.ExpectedCash)
+
+	if err := postTillCloseTx(ctx, tx, sessionID, tillGL, closingCash, variance); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE teller_sessions
+		SET status = 'CLOSED', closing_denominations = $1, closing_cash = $2,
+		    expected_cash = $3, variance = $4, closed_at = now()
+		WHERE session_id = $5`,
+		counted, closingCash, b.ExpectedCash, variance, sessionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.GetSession(ctx, sessionID)
+}
+
+// BalancingReport lists every teller session opened at the branch on the
+// given day, flagging overages and shortages on closed sessions.
+func (s *TellerService) BalancingReport(ctx context.Context, branchID uuid.UUID, day time.Time) ([]*TellerBalance, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT session_id
+		FROM teller_sessions
+		WHERE branch_id = $1 AND opened_at >= $2 AND opened_at < $3
+		ORDER BY teller_id`,
+		branchID, day, day.AddDate(0, 0, 1),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var report []*TellerBalance
+	for _, id := range ids {
+		b, err := sessionBalance(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		report = append(report, b)
+	}
+
+	return report, nil
+}
+
+// postTillCloseTx empties the till GL at close: the counted cash goes back
+// to the branch vault, and an overage or shortage against the expected cash
+// is taken to the cash variance GL.
+func postTillCloseTx(ctx context.Context, tx pgx.Tx, sessionID uuid.UUID, tillGL string, closingCash, variance float64) error {
+	var branchCode string
+	err := tx.QueryRow(ctx, `
+		SELECT b.branch_code
+		FROM teller_sessions s
+		JOIN branches b ON b.branch_id = s.branch_id
+		WHERE s.session_id = $1`,
+		sessionID,
+	).Scan(&branchCode)
+	if err != nil {
+		return err
+	}
+
+	for _, j := range tillCloseJournals(VaultGLCode(branchCode), tillGL, closingCash, variance) {
+		if err := postJournalTx(ctx, tx, j); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tillCloseJournals returns the journals that bring a till GL holding the
+// expected cash back to zero when closingCash is counted.
+func tillCloseJournals(vaultGL, tillGL string, closingCash, variance float64) []*Journal {
+	var journals []*Journal
+	if closingCash > 0 {
+		journals = append(journals, &Journal{
+			DebitGL: vaultGL, CreditGL: tillGL, Amount: closingCash,
+			Narrative: "Till closing cash returned to vault",
+		})
+	}
+	switch {
+	case variance < 0:
+		journals = append(journals, &Journal{
+			DebitGL: GLCashVariance, CreditGL: tillGL, Amount: -variance, Narrative: "Till shortage",
+		})
+	case variance > 0:
+		journals = append(journals, &Journal{
+			DebitGL: tillGL, CreditGL: GLCashVariance, Amount: variance, Narrative: "Till overage",
+		})
+	}
+	return journals
+}
+
+// isSupervisor reports whether userID is one of the configured supervisors.
+// Nobody is a supervisor when none are configured.
+func (s *TellerService) isSupervisor(userID string) bool {
+	for _, id := range s.supervisors {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func lockOpenSession(ctx context.Context, tx pgx.Tx, sessionID uuid.UUID, tellerID string) (string, error) {
+	var owner, status, tillGL string
+	err := tx.QueryRow(ctx, `
+		SELECT teller_id, status, till_gl_code FROM teller_sessions WHERE session_id = $1 FOR UPDATE`,
+		sessionID,
+	).Scan(&owner, &status, &tillGL)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+
+	if owner != tellerID {
+		return "", ErrUnauthorized
+	}
+	if status != "OPEN" {
+		return "", fmt.Errorf("teller session is %s: %w", status, ErrInvalidInput)
+	}
+
+	return tillGL, nil
+}
+
+func sessionBalance(ctx context.Context, tx pgx.Tx, sessionID uuid.UUID) (*TellerBalance, error) {
+	b := &TellerBalance{SessionID: sessionID}
+	err := tx.QueryRow(ctx, `
+		SELECT s.teller_id, s.status, s.opening_cash, s.closing_cash,
+		       COALESCE(SUM(m.amount) FILTER (WHERE m.movement_type = 'DEPOSIT'), 0),
+		       COALESCE(SUM(m.amount) FILTER (WHERE m.movement_type = 'WITHDRAWAL'), 0),
+		       COALESCE(SUM(m.amount) FILTER (WHERE m.movement_type = 'VAULT_IN'), 0),
+		       COALESCE(SUM(m.amount) FILTER (WHERE m.movement_type = 'VAULT_OUT'), 0)
+		FROM teller_sessions s
+		LEFT JOIN teller_cash_movements m ON m.session_id = s.session_id
+		WHERE s.session_id = $1
+		GROUP BY s.session_id`,
+		sessionID,
+	).Scan(&b.TellerID, &b.Status, &b.OpeningCash, &b.ClosingCash,
+		&b.Deposits, &b.Withdrawals, &b.VaultIn, &b.VaultOut)
+	if err != nil {
+		return nil, err
+	}
+
+	b.ExpectedCash = roundCents(b.OpeningCash + b.Deposits - b.Withdrawals + b.VaultIn - b.VaultOut)
+
+	switch {
+	case b.ClosingCash == nil:
+		b.Flag = "OPEN"
+	default:
+		v := roundCents(*b.ClosingCash - b.ExpectedCash)
+		b.Variance = &v
+		switch {
+		case v > 0:
+			b.Flag = "OVERAGE"
+		case v < 0:
+			b.Flag = "SHORTAGE"
+		default:
+			b.Flag = "BALANCED"
+		}
+	}
+
+	return b, nil
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
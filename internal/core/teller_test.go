@@ -0,0 +1,70 @@
+package core
+
+import "testing"
+
+func TestTillCloseJournals(t *testing.T) {
+	const vault, till = "VAULT-B1", "TILL-B1-T1"
+
+	tests := []struct {
+		name     string
+		expected float64
+		counted  float64
+	}{
+		{"balanced", 5000, 5000},
+		{"shortage", 5000, 4800},
+		{"overage", 5000, 5150.5},
+		{"empty till", 0, 0},
+		{"all cash missing", 300, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variance := roundCents(tt.counted - tt.expected)
+			journals := tillCloseJournals(vault, till, tt.counted, variance)
+
+			// The till GL holds the expected cash before close and must be
+			// empty after it.
+			tillBalance := tt.expected
+			var toVault float64
+			for _, j := range journals {
+				if j.Amount <= 0 {
+					t.Fatalf("journal %q has amount %v", j.Narrative, j.Amount)
+				}
+				if j.DebitGL == till {
+					tillBalance += j.Amount
+				}
+				if j.CreditGL == till {
+					tillBalance -= j.Amount
+				}
+				if j.DebitGL == vault {
+					toVault += j.Amount
+				}
+			}
+			if roundCents(tillBalance) != 0 {
+				t.Errorf("till GL left at %v", tillBalance)
+			}
+			if toVault != tt.counted {
+				t.Errorf("vault received %v, want the counted %v", toVault, tt.counted)
+			}
+		})
+	}
+}
+
+func TestIsSupervisor(t *testing.T) {
+	tests := []struct {
+		supervisors []string
+		user        string
+		want        bool
+	}{
+		{nil, "anyone", false},
+		{[]string{"sup-1", "sup-2"}, "sup-2", true},
+		{[]string{"sup-1"}, "teller-9", false},
+	}
+
+	for _, tt := range tests {
+		s := &TellerService{supervisors: tt.supervisors}
+		if got := s.isSupervisor(tt.user); got != tt.want {
+			t.Errorf("isSupervisor(%q) with %v = %v, want %v", tt.user, tt.supervisors, got, tt.want)
+		}
+	}
+}
@@ -11,34 +11,99 @@ import (
 )
 
 type AccountTransaction struct {
-	TxnID       uuid.UUID `json:"txn_id"`
-	AccountID   uuid.UUID `json:"account_id"`
-	TxnType     string    `json:"txn_type"`
-	Amount      float64   `json:"amount"`
-	Description *string   `json:"description,omitempty"`
-	Channel     *string   `json:"channel,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
+	TxnID        uuid.UUID `json:"txn_id"`
+	AccountID    uuid.UUID `json:"account_id"`
+	TxnType      string    `json:"txn_type"`
+	Amount       float64   `json:"amount"`
+	Description  *string   `json:"description,omitempty"`
+	Channel      *string   `json:"channel,omitempty"`
+	PostingDate  time.Time `json:"posting_date"`
+	ValueDate    time.Time `json:"value_date"`
+	BalanceAfter float64   `json:"balance_after"`
+	// Status is POSTED, or REVERSED once a reversal entry has been posted
+	// against it. The amount of a reversed entry is never changed.
+	Status string `json:"status"`
+	// TransferID links the two legs of a transfer.
+	TransferID     *uuid.UUID `json:"transfer_id,omitempty"`
+	ReversalOf     *uuid.UUID `json:"reversal_of,omitempty"`
+	ReversalReason *string    `json:"reversal_reason,omitempty"`
+	ReversedBy     *string    `json:"reversed_by,omitempty"`
+	// FeeFor is the entry a fee was charged on, or the fee a tax was levied on.
+	FeeFor    *uuid.UUID `json:"fee_for,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+const transactionColumns = `
+	txn_id, account_id, txn_type, amount, description, channel, posting_date, value_date,
+	balance_after, status, transfer_id, reversal_of, reversal_reason, reversed_by, fee_for,
+	created_at`
+
+func scanTransaction(row pgx.Row) (*AccountTransaction, error) {
+	txn := &AccountTransaction{}
+	err := row.Scan(&txn.TxnID, &txn.AccountID, &txn.TxnType, &txn.Amount, &txn.Description,
+		&txn.Channel, &txn.PostingDate, &txn.ValueDate, &txn.BalanceAfter, &txn.Status,
+		&txn.TransferID, &txn.ReversalOf, &txn.ReversalReason, &txn.ReversedBy, &txn.FeeFor, &txn.CreatedAt)
+	return txn, err
 }
 
 type TransactionService struct {
-	db *pgxpool.Pool
+	db        *pgxpool.Pool
+	calendar  *Calendar
+	prices    *PriceBook
+	cooling   CoolingPolicy
+	approvals ApprovalPolicy
+}
+
+// NewTransactionService posts entries on the given calendar. Transaction fees
+// from prices are charged alongside the entries that trigger them, payments
+// to other customers are held to the cooling policy, and payments that need
+// a second user under the approval policy are refused unless approved.
+func NewTransactionService(db *pgxpool.Pool, calendar *Calendar, prices *PriceBook, cooling CoolingPolicy, approvals ApprovalPolicy) *TransactionService {
+	return &TransactionService{db: db, calendar: calendar, prices: prices, cooling: cooling, approvals: approvals}
 }
 
-func NewTransactionService(db *pgxpool.Pool) *TransactionService {
-	return &TransactionService{db: db}
+// checkApproval refuses a payment of amount that needs a second user's
+// approval. Every channel a customer pays out through checks it; only a
+// transfer carried out by the approval engine goes past it.
+func (s *TransactionService) checkApproval(amount float64) error {
+	if s.approvals.Required(OpTransfer, amount) {
+		return fmt.Errorf("%w: payments of %.2f or more must be requested as an approved transfer",
+			ErrApprovalRequired, s.approvals.TransferThreshold)
+	}
+	return nil
 }
 
 func (s *TransactionService) CreateTransaction(ctx context.Context, txn *AccountTransaction) error {
+	if txn.TxnType == "DEBIT" {
+		if err := s.checkApproval(txn.Amount); err != nil {
+			return err
+		}
+	}
+
 	tx, err := s.db.Begin(ctx)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback(ctx)
 
+	// Links between entries are set by the services that post them.
+	txn.TransferID, txn.ReversalOf, txn.ReversalReason, txn.FeeFor = nil, nil, nil, nil
+
+	if err := s.createTransactionTx(ctx, tx, txn); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// createTransactionTx posts a single credit or debit, and any fee it
+// attracts, inside an open database transaction so other services can
+// combine it with their own writes.
+func (s *TransactionService) createTransactionTx(ctx context.Context, tx pgx.Tx, txn *AccountTransaction) error {
 	// Check account status and balance
 	var balance float64
 	var status string
-	err = tx.QueryRow(ctx, `
+	err := tx.QueryRow(ctx, `
 		SELECT balance, status FROM accounts WHERE account_id = $1 FOR UPDATE`,
 		txn.AccountID,
 	).Scan(&balance, &status)
@@ -50,24 +115,79 @@ func (s *TransactionService) CreateTransaction(ctx context.Context, txn *Account
 		return err
 	}
 
-	if status != "ACTIVE" {
-		return ErrAccountClosed
+	if err := postingBlocked(status, txn.TxnType); err != nil {
+		return err
+	}
+
+	channel := ""
+	if txn.Channel != nil {
+		channel = *txn.Channel
+	}
+	terms, err := productTermsTx(ctx, tx, txn.AccountID)
+	if err != nil {
+		return err
+	}
+	if txn.TxnType == "DEBIT" {
+		if err := terms.checkDebitTx(ctx, tx, txn.AccountID, channel, txn.Amount); err != nil {
+			return err
+		}
+	}
+	fee, err := s.transactionFeeTx(ctx, tx, txn.AccountID, channel, txn.TxnType, txn.Amount)
+	if err != nil {
+		return err
 	}
 
-	// For DEBIT, check sufficient funds
-	if txn.TxnType == "DEBIT" && balance < txn.Amount {
+	// For DEBIT, check sufficient funds, overdraft included and liened funds
+	// excluded. A fee must be covered as well.
+	available, err := availableTx(ctx, tx, txn.AccountID, terms, balance)
+	if err != nil {
+		return err
+	}
+	if txn.TxnType == "DEBIT" && available < txn.Amount+fee.total() {
+		return ErrInsufficientFunds
+	}
+	if txn.TxnType == "CREDIT" && fee != nil && available+txn.Amount < fee.total() {
 		return ErrInsufficientFunds
 	}
 
-	// Insert transaction
-	query := `
-		INSERT INTO account_transactions (account_id, txn_type, amount, description, channel)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING txn_id, created_at`
+	if err := s.postEntryTx(ctx, tx, txn); err != nil {
+		return err
+	}
+	if fee == nil {
+		return nil
+	}
+	_, _, err = s.postFeeTx(ctx, tx, txn.AccountID, fee.code, fee.fee, fee.tax,
+		fmt.Sprintf("Fee %s on %s", fee.code, channel), &txn.TxnID)
+	return err
+}
 
-	err = tx.QueryRow(ctx, query, txn.AccountID, txn.TxnType, txn.Amount,
-		txn.Description, txn.Channel).Scan(&txn.TxnID, &txn.CreatedAt)
+// postEntryTx inserts a ledger row and applies it to the account balance. The
+// posting date is the system business date; the value date follows the channel
+// cut-off and the holidays of the account's branch. Callers must already hold
+// the account lock.
+func (s *TransactionService) postEntryTx(ctx context.Context, tx pgx.Tx, txn *AccountTransaction) error {
+	var country, branchCode string
+	err := tx.QueryRow(ctx, `
+		SELECT b.country, b.branch_code
+		FROM accounts a
+		JOIN branches b ON b.branch_id = a.branch_id
+		WHERE a.account_id = $1`,
+		txn.AccountID,
+	).Scan(&country, &branchCode)
+	if err != nil {
+		return err
+	}
 
+	txn.PostingDate, err = businessDateTx(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	channel := ""
+	if txn.Channel != nil {
+		channel = *txn.Channel
+	}
+	txn.ValueDate, err = s.calendar.ValueDate(time.Now(), txn.PostingDate, channel, country, branchCode)
 	if err != nil {
 		return err
 	}
@@ -78,30 +198,38 @@ func (s *TransactionService) CreateTransaction(ctx context.Context, txn *Account
 		balanceChange = -txn.Amount
 	}
 
-	_, err = tx.Exec(ctx, `
-		UPDATE accounts SET balance = balance + $1 WHERE account_id = $2`,
+	err = tx.QueryRow(ctx, `
+		UPDATE accounts SET balance = balance + $1 WHERE account_id = $2
+		RETURNING balance`,
 		balanceChange, txn.AccountID,
-	)
+	).Scan(&txn.BalanceAfter)
+	if err != nil {
+		return err
+	}
 
+	query := `
+		INSERT INTO account_transactions (account_id, txn_type, amount, description, channel,
+		                                  posting_date, value_date, balance_after, transfer_id,
+		                                  reversal_of, reversal_reason, fee_for)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING txn_id, status, created_at`
+
+	err = tx.QueryRow(ctx, query, txn.AccountID, txn.TxnType, txn.Amount, txn.Description,
+		txn.Channel, txn.PostingDate, txn.ValueDate, txn.BalanceAfter, txn.TransferID,
+		txn.ReversalOf, txn.ReversalReason, txn.FeeFor).Scan(&txn.TxnID, &txn.Status, &txn.CreatedAt)
 	if err != nil {
 		return err
 	}
 
-	return tx.Commit(ctx)
+	if sweepable(txn) {
+		return s.sweepGarnishmentsTx(ctx, tx, txn)
+	}
+	return nil
 }
 
 func (s *TransactionService) GetTransaction(ctx context.Context, id uuid.UUID) (*AccountTransaction, error) {
-	query := `
-		SELECT txn_id, account_id, txn_type, amount, description, channel, created_at
-		FROM account_transactions
-		WHERE txn_id = $1`
-
-	txn := &AccountTransaction{}
-	err := s.db.QueryRow(ctx, query, id).Scan(
-		&txn.TxnID, &txn.AccountID, &txn.TxnType, &txn.Amount,
-		&txn.Description, &txn.Channel, &txn.CreatedAt,
-	)
-
+	txn, err := scanTransaction(s.db.QueryRow(ctx,
+		`SELECT `+transactionColumns+` FROM account_transactions WHERE txn_id = $1`, id))
 	if err != nil {
 		return nil, err
 	}
@@ -111,7 +239,7 @@ func (s *TransactionService) GetTransaction(ctx context.Context, id uuid.UUID) (
 
 func (s *TransactionService) ListTransactionsByAccount(ctx context.Context, accountID uuid.UUID, limit, offset int) ([]*AccountTransaction, error) {
 	query := `
-		SELECT txn_id, account_id, txn_type, amount, description, channel, created_at
+		SELECT ` + transactionColumns + `
 		FROM account_transactions
 		WHERE account_id = $1
 		ORDER BY created_at DESC
@@ -125,11 +253,7 @@ func (s *TransactionService) ListTransactionsByAccount(ctx context.Context, acco
 
 	var transactions []*AccountTransaction
 	for rows.Next() {
-		txn := &AccountTransaction{}
-		err := rows.Scan(
-			&txn.TxnID, &txn.AccountID, &txn.TxnType, &txn.Amount,
-			&txn.Description, &txn.Channel, &txn.CreatedAt,
-		)
+		txn, err := scanTransaction(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -146,27 +270,128 @@ func (s *TransactionService) Transfer(ctx context.Context, fromAccountID, toAcco
 	}
 	defer tx.Rollback(ctx)
 
+	if _, err := s.transferTx(ctx, tx, fromAccountID, toAccountID, amount, description); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// transferTx moves money between two accounts inside an open database
+// transaction and returns the ID of the debit entry; any fee is posted as
+// separate entries after it. Validation failures are returned before
+// anything is written, so the caller's transaction stays usable.
+func (s *TransactionService) transferTx(ctx context.Context, tx pgx.Tx, fromAccountID, toAccountID uuid.UUID, amount float64, description string) (uuid.UUID, error) {
+	if err := s.checkApproval(amount); err != nil {
+		return uuid.Nil, err
+	}
+	return s.approvedTransferTx(ctx, tx, fromAccountID, toAccountID, amount, description)
+}
+
+// approvedTransferTx is transferTx for a transfer a second user has already
+// approved, so the approval threshold is not applied again.
+func (s *TransactionService) approvedTransferTx(ctx context.Context, tx pgx.Tx, fromAccountID, toAccountID uuid.UUID, amount float64, description string) (uuid.UUID, error) {
+	fee, err := s.checkTransferTx(ctx, tx, fromAccountID, toAccountID, amount)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	transferID := uuid.New()
+	channel := "TRANSFER"
+
+	// Create debit transaction
+	debit := &AccountTransaction{
+		AccountID:   fromAccountID,
+		TxnType:     "DEBIT",
+		Amount:      amount,
+		Description: &description,
+		Channel:     &channel,
+		TransferID:  &transferID,
+	}
+	if err := s.postEntryTx(ctx, tx, debit); err != nil {
+		return uuid.Nil, err
+	}
+	if fee != nil {
+		_, _, err := s.postFeeTx(ctx, tx, fromAccountID, fee.code, fee.fee, fee.tax,
+			fmt.Sprintf("Fee %s on %s", fee.code, channel), &debit.TxnID)
+		if err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	// Create credit transaction
+	err = s.postEntryTx(ctx, tx, &AccountTransaction{
+		AccountID:   toAccountID,
+		TxnType:     "CREDIT",
+		Amount:      amount,
+		Description: &description,
+		Channel:     &channel,
+		TransferID:  &transferID,
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return debit.TxnID, nil
+}
+
+// checkTransferable refuses early a transfer that approvedTransferTx would
+// refuse now: a blocked or missing account, a payee still cooling, a product
+// limit or too little available to cover it and its fee. Nothing is written.
+func (s *TransactionService) checkTransferable(ctx context.Context, fromAccountID, toAccountID uuid.UUID, amount float64) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = s.checkTransferTx(ctx, tx, fromAccountID, toAccountID, amount)
+	return err
+}
+
+// checkTransferTx locks both accounts of a transfer, runs its checks and
+// returns the fee it attracts.
+func (s *TransactionService) checkTransferTx(ctx context.Context, tx pgx.Tx, fromAccountID, toAccountID uuid.UUID, amount float64) (*feeQuote, error) {
 	// Lock and check source account
 	var fromBalance float64
 	var fromStatus string
-	err = tx.QueryRow(ctx, `
+	err := tx.QueryRow(ctx, `
 		SELECT balance, status FROM accounts WHERE account_id = $1 FOR UPDATE`,
 		fromAccountID,
 	).Scan(&fromBalance, &fromStatus)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return fmt.Errorf("source account: %w", ErrNotFound)
+			return nil, fmt.Errorf("source account: %w", ErrNotFound)
 		}
-		return err
+		return nil, err
 	}
 
-	if fromStatus != "ACTIVE" {
-		return fmt.Errorf("source account: %w", ErrAccountClosed)
+	if err := postingBlocked(fromStatus, "DEBIT"); err != nil {
+		return nil, fmt.Errorf("source account: %w", err)
+	}
+	if err := checkCoolingTx(ctx, tx, s.cooling, fromAccountID, Payee{AccountID: &toAccountID}, amount); err != nil {
+		return nil, err
 	}
 
-	if fromBalance < amount {
-		return ErrInsufficientFunds
+	channel := "TRANSFER"
+	terms, err := productTermsTx(ctx, tx, fromAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := terms.checkDebitTx(ctx, tx, fromAccountID, channel, amount); err != nil {
+		return nil, err
+	}
+	fee, err := s.transactionFeeTx(ctx, tx, fromAccountID, channel, "DEBIT", amount)
+	if err != nil {
+		return nil, err
+	}
+
+	available, err := availableTx(ctx, tx, fromAccountID, terms, fromBalance)
+	if err != nil {
+		return nil, err
+	}
+	if available < amount+fee.total() {
+		return nil, ErrInsufficientFunds
 	}
 
 	// Lock and check destination account
@@ -178,51 +403,86 @@ func (s *TransactionService) Transfer(ctx context.Context, fromAccountID, toAcco
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return fmt.Errorf("destination account: %w", ErrNotFound)
+			return nil, fmt.Errorf("destination account: %w", ErrNotFound)
 		}
-		return err
+		return nil, err
 	}
 
-	if toStatus != "ACTIVE" {
-		return fmt.Errorf("destination account: %w", ErrAccountClosed)
+	if err := postingBlocked(toStatus, "CREDIT"); err != nil {
+		return nil, fmt.Errorf("destination account: %w", err)
 	}
+	return fee, nil
+}
 
-	// Create debit transaction
-	_, err = tx.Exec(ctx, `
-		INSERT INTO account_transactions (account_id, txn_type, amount, description, channel)
-		VALUES ($1, 'DEBIT', $2, $3, 'TRANSFER')`,
-		fromAccountID, amount, description,
-	)
+// balanceAsOfValueDateTx returns the account balance counting only entries
+// value-dated on or before day. Interest is calculated on this balance so
+// that late postings earn or cost interest from their value date.
+func balanceAsOfValueDateTx(ctx context.Context, tx pgx.Tx, accountID uuid.UUID, day time.Time) (float64, error) {
+	var balance float64
+	err := tx.QueryRow(ctx, `
+		SELECT a.balance - COALESCE((
+			SELECT SUM(CASE WHEN t.txn_type = 'DEBIT' THEN -t.amount ELSE t.amount END)
+			FROM account_transactions t
+			WHERE t.account_id = a.account_id AND t.value_date > $2
+		), 0)
+		FROM accounts a
+		WHERE a.account_id = $1`,
+		accountID, day,
+	).Scan(&balance)
 	if err != nil {
-		return err
+		if err == pgx.ErrNoRows {
+			return 0, ErrNotFound
+		}
+		return 0, err
 	}
 
-	// Create credit transaction
-	_, err = tx.Exec(ctx, `
-		INSERT INTO account_transactions (account_id, txn_type, amount, description, channel)
-		VALUES ($1, 'CREDIT', $2, $3, 'TRANSFER')`,
-		toAccountID, amount, description,
-	)
+	return balance, nil
+}
+
+// valueDatedBalancesTx returns the closing balance of every day from one date
+// to another, both inclusive, counting each entry from its value date.
+func valueDatedBalancesTx(ctx context.Context, tx pgx.Tx, accountID uuid.UUID, from, to time.Time) ([]float64, error) {
+	opening, err := balanceAsOfValueDateTx(ctx, tx, accountID, from.AddDate(0, 0, -1))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Update balances
-	_, err = tx.Exec(ctx, `
-		UPDATE accounts SET balance = balance - $1 WHERE account_id = $2`,
-		amount, fromAccountID,
+	rows, err := tx.Query(ctx, `
+		SELECT value_date, SUM(CASE WHEN txn_type = 'DEBIT' THEN -amount ELSE amount END)
+		FROM account_transactions
+		WHERE account_id = $1 AND value_date BETWEEN $2 AND $3
+		GROUP BY value_date`,
+		accountID, from, to,
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer rows.Close()
 
-	_, err = tx.Exec(ctx, `
-		UPDATE accounts SET balance = balance + $1 WHERE account_id = $2`,
-		amount, toAccountID,
-	)
-	if err != nil {
-		return err
+	movements := map[time.Time]float64{}
+	for rows.Next() {
+		var day time.Time
+		var net float64
+		if err := rows.Scan(&day, &net); err != nil {
+			return nil, err
+		}
+		movements[civilDate(day)] = net
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return tx.Commit(ctx)
-}
\ No newline at end of file
+	return dailyBalances(opening, movements, from, to), nil
+}
+
+// dailyBalances rolls an opening balance forward by each day's net movement
+// and returns the closing balance of every day from one date to another.
+func dailyBalances(opening float64, movements map[time.Time]float64, from, to time.Time) []float64 {
+	var balances []float64
+	balance := opening
+	for day := civilDate(from); !day.After(civilDate(to)); day = day.AddDate(0, 0, 1) {
+		balance = roundCents(balance + movements[day])
+		balances = append(balances, balance)
+	}
+	return balances
+}
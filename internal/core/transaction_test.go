@@ -0,0 +1,35 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDailyBalances(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name      string
+		opening   float64
+		movements map[time.Time]float64
+		from, to  time.Time
+		want      []float64
+	}{
+		{"no movements", 100, nil, day(1), day(3), []float64{100, 100, 100}},
+		{"movement carries forward", 100, map[time.Time]float64{day(2): 50}, day(1), day(3), []float64{100, 150, 150}},
+		{"debit below zero", 10, map[time.Time]float64{day(1): -25.5}, day(1), day(2), []float64{-15.5, -15.5}},
+		{"movements outside the range ignored", 0, map[time.Time]float64{day(5): 99}, day(1), day(2), []float64{0, 0}},
+		{"single day", 0.1, map[time.Time]float64{day(4): 0.2}, day(4), day(4), []float64{0.3}},
+		{"to before from", 100, nil, day(3), day(2), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := dailyBalances(tt.opening, tt.movements, tt.from, tt.to)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dailyBalances = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"time"
+
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+// csvFlushEvery bounds how many rows are buffered before they are sent.
+const csvFlushEvery = 500
+
+// CSV writes one row per transaction with separate debit and credit columns
+// and the running balance.
+type CSV struct {
+	w    *csv.Writer
+	rows int
+}
+
+func NewCSV(w io.Writer) *CSV {
+	return &CSV{w: csv.NewWriter(w)}
+}
+
+func (c *CSV) Begin(account *core.StatementAccount, from, to time.Time, openingBalance float64) error {
+	return c.w.Write([]string{"Posting Date", "Value Date", "Transaction ID", "Type", "Description",
+		"Channel", "Debit", "Credit", "Balance"})
+}
+
+func (c *CSV) Entry(txn *core.AccountTransaction) error {
+	debit, credit := "", amount(txn.Amount)
+	if txn.TxnType == "DEBIT" {
+		debit, credit = credit, ""
+	}
+	channel := ""
+	if txn.Channel != nil {
+		channel = *txn.Channel
+	}
+
+	err := c.w.Write([]string{
+		txn.PostingDate.Format("2006-01-02"),
+		txn.ValueDate.Format("2006-01-02"),
+		txn.TxnID.String(),
+		txn.TxnType,
+		description(txn),
+		channel,
+		debit,
+		credit,
+		amount(txn.BalanceAfter),
+	})
+	if err != nil {
+		return err
+	}
+
+	c.rows++
+	if c.rows%csvFlushEvery == 0 {
+		c.w.Flush()
+	}
+	return c.w.Error()
+}
+
+func (c *CSV) End(closingBalance float64) error {
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,86 @@
+// Package export renders account activity in the file formats accounting
+// packages import. Every format is a core.StatementVisitor, so rows are
+// written as they are read from the database.
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+// Supported formats.
+const (
+	FormatCSV   = "csv"
+	FormatOFX   = "ofx"
+	FormatQIF   = "qif"
+	FormatMT940 = "mt940"
+)
+
+// Format describes an export format and its media type.
+type Format struct {
+	Name        string
+	ContentType string
+	Extension   string
+	New         func(w io.Writer, currency string) core.StatementVisitor
+}
+
+var formats = []Format{
+	{FormatCSV, "text/csv", "csv", func(w io.Writer, currency string) core.StatementVisitor { return NewCSV(w) }},
+	{FormatOFX, "application/x-ofx", "ofx", func(w io.Writer, currency string) core.StatementVisitor { return NewOFX(w, currency) }},
+	{FormatQIF, "application/qif", "qif", func(w io.Writer, currency string) core.StatementVisitor { return NewQIF(w) }},
+	{FormatMT940, "application/x-mt940", "sta", func(w io.Writer, currency string) core.StatementVisitor { return NewMT940(w, currency) }},
+}
+
+// mediaTypes maps Accept header values, including common aliases, to formats.
+var mediaTypes = map[string]string{
+	"text/csv":            FormatCSV,
+	"application/csv":     FormatCSV,
+	"application/x-ofx":   FormatOFX,
+	"application/ofx":     FormatOFX,
+	"application/qif":     FormatQIF,
+	"application/x-qif":   FormatQIF,
+	"application/x-mt940": FormatMT940,
+	"text/x-mt940":        FormatMT940,
+}
+
+// Lookup returns the format named by a format= parameter.
+func Lookup(name string) (Format, bool) {
+	for _, f := range formats {
+		if strings.EqualFold(f.Name, name) {
+			return f, true
+		}
+	}
+	return Format{}, false
+}
+
+// Negotiate picks the first export format listed in an Accept header.
+func Negotiate(accept string) (Format, bool) {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		if name, ok := mediaTypes[strings.ToLower(mediaType)]; ok {
+			return Lookup(name)
+		}
+	}
+	return Format{}, false
+}
+
+func signedAmount(txn *core.AccountTransaction) float64 {
+	if txn.TxnType == "DEBIT" {
+		return -txn.Amount
+	}
+	return txn.Amount
+}
+
+func description(txn *core.AccountTransaction) string {
+	if txn.Description == nil {
+		return ""
+	}
+	return *txn.Description
+}
+
+func amount(v float64) string {
+	return fmt.Sprintf("%.2f", v)
+}
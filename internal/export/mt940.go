@@ -0,0 +1,230 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+// mt940MaxLength is the SWIFT limit on the text block of an MT940 message.
+// Longer statements continue in further messages with the same statement
+// number and the next sequence number.
+const mt940MaxLength = 2000
+
+// MT940 writes a SWIFT MT940 customer statement. Each message is buffered
+// until it is complete, which bounds memory by the message size limit.
+type MT940 struct {
+	w        *bufio.Writer
+	currency string
+
+	reference string
+	account   string
+	to        time.Time
+	statement int
+	sequence  int
+
+	balance  float64
+	lastDate time.Time
+	lines    []string
+	entries  int
+	length   int
+}
+
+func NewMT940(w io.Writer, currency string) *MT940 {
+	return &MT940{w: bufio.NewWriter(w), currency: currency}
+}
+
+func (m *MT940) Begin(account *core.StatementAccount, from, to time.Time, openingBalance float64) error {
+	m.reference = "STM" + to.Format("060102") + mt940Text(account.AccountNumber)
+	if len(m.reference) > 16 {
+		m.reference = m.reference[:16]
+	}
+	m.account = truncateText(mt940Text(account.AccountNumber), 35)
+	m.to = to
+	m.statement = to.YearDay()
+	m.balance = openingBalance
+	m.lastDate = from
+
+	return m.open("60F", openingBalance, from)
+}
+
+func (m *MT940) Entry(txn *core.AccountTransaction) error {
+	mark := "C"
+	if txn.TxnType == "DEBIT" {
+		mark = "D"
+	}
+	amt, err := mt940Amount(txn.Amount)
+	if err != nil {
+		return err
+	}
+
+	channel := ""
+	if txn.Channel != nil {
+		channel = *txn.Channel
+	}
+	ref := strings.ToUpper(strings.ReplaceAll(txn.TxnID.String(), "-", ""))[:16]
+
+	// Value date, entry (posting) date, mark, amount, type code, the
+	// customer reference (none) and the bank's reference.
+	lines := []string{fmt.Sprintf(":61:%s%s%s%s%sNONREF//%s",
+		txn.ValueDate.Format("060102"), txn.PostingDate.Format("0102"), mark,
+		amt, mt940TypeCode(channel), ref)}
+	if info := mt940Lines(description(txn), 6, 65); len(info) > 0 {
+		lines = append(lines, ":86:"+info[0])
+		lines = append(lines, info[1:]...)
+	}
+
+	// Leave room for the intermediate closing balance that ends the page.
+	size := mt940Size(lines)
+	if m.entries > 0 && m.length+size+m.balanceSize() > mt940MaxLength {
+		if err := m.close("62M", m.balance, m.lastDate); err != nil {
+			return err
+		}
+		if err := m.open("60M", m.balance, m.lastDate); err != nil {
+			return err
+		}
+	}
+
+	m.lines = append(m.lines, lines...)
+	m.entries++
+	m.length += size
+	m.balance = roundCents(m.balance + signedAmount(txn))
+	m.lastDate = txn.PostingDate
+	return nil
+}
+
+func (m *MT940) End(closingBalance float64) error {
+	// The closing balance is dated by the end of the period, not by the last
+	// entry, matching the other statement formats.
+	if err := m.close("62F", closingBalance, m.to); err != nil {
+		return err
+	}
+	return m.w.Flush()
+}
+
+// open starts a message with its header and opening balance.
+func (m *MT940) open(tag string, balance float64, day time.Time) error {
+	m.sequence++
+	m.lines = []string{
+		":20:" + m.reference,
+		":25:" + m.account,
+		fmt.Sprintf(":28C:%d/%d", m.statement, m.sequence),
+	}
+	line, err := m.balanceLine(tag, balance, day)
+	if err != nil {
+		return err
+	}
+	m.lines = append(m.lines, line)
+	m.entries = 0
+	m.length = mt940Size(m.lines)
+	return nil
+}
+
+// close ends the current message with a closing balance and writes it out.
+func (m *MT940) close(tag string, balance float64, day time.Time) error {
+	line, err := m.balanceLine(tag, balance, day)
+	if err != nil {
+		return err
+	}
+	m.lines = append(m.lines, line, "-")
+	for _, l := range m.lines {
+		if _, err := m.w.WriteString(l + "\r\n"); err != nil {
+			return err
+		}
+	}
+	m.lines = nil
+	m.length = 0
+	return nil
+}
+
+func (m *MT940) balanceLine(tag string, balance float64, day time.Time) (string, error) {
+	mark := "C"
+	if balance < 0 {
+		mark = "D"
+	}
+	amt, err := mt940Amount(math.Abs(balance))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(":%s:%s%s%s%s", tag, mark, day.Format("060102"), m.currency, amt), nil
+}
+
+// balanceSize is the room reserved for a closing balance line and the
+// message terminator.
+func (m *MT940) balanceSize() int {
+	return mt940Size([]string{":62M:C000000XXX" + strings.Repeat("9", 15), "-"})
+}
+
+// mt940Amount formats an amount as SWIFT 15d: digits with a comma as the
+// decimal separator, the comma included in the 15 characters.
+func mt940Amount(v float64) (string, error) {
+	s := strings.Replace(strconv.FormatFloat(roundCents(v), 'f', 2, 64), ".", ",", 1)
+	if len(s) > 15 {
+		return "", fmt.Errorf("amount %s exceeds the MT940 field length", s)
+	}
+	return s, nil
+}
+
+// mt940TypeCode maps a posting channel to a SWIFT transaction type
+// identification code.
+func mt940TypeCode(channel string) string {
+	switch channel {
+	case "TRANSFER":
+		return "NTRF"
+	case "CHEQUE":
+		return "NCHK"
+	}
+	return "NMSC"
+}
+
+// mt940Lines wraps text into at most maxLines lines of width characters.
+// Continuation lines must not start with ':' or '-', which would read as a
+// new field or the end of the message, so those are indented by a space.
+func mt940Lines(s string, maxLines, width int) []string {
+	s = strings.Join(strings.Fields(mt940Text(s)), " ")
+	var lines []string
+	for s != "" && len(lines) < maxLines {
+		prefix := ""
+		if len(lines) > 0 && (s[0] == ':' || s[0] == '-') {
+			prefix = " "
+		}
+		n := width - len(prefix)
+		if len(s) < n {
+			n = len(s)
+		}
+		lines = append(lines, prefix+s[:n])
+		s = strings.TrimLeft(s[n:], " ")
+	}
+	return lines
+}
+
+// mt940Text replaces characters outside the SWIFT X character set.
+func mt940Text(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case strings.ContainsRune("/-?:().,'+ ", r):
+			return r
+		}
+		return ' '
+	}, s)
+}
+
+func mt940Size(lines []string) int {
+	n := 0
+	for _, l := range lines {
+		n += len(l) + 2
+	}
+	return n
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
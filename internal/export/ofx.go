@@ -0,0 +1,85 @@
+package export
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+// OFX writes an OFX 2.2 bank statement response.
+type OFX struct {
+	w        *bufio.Writer
+	currency string
+	to       time.Time
+}
+
+func NewOFX(w io.Writer, currency string) *OFX {
+	return &OFX{w: bufio.NewWriter(w), currency: currency}
+}
+
+func (o *OFX) Begin(account *core.StatementAccount, from, to time.Time, openingBalance float64) error {
+	o.to = to
+
+	acctType := "SAVINGS"
+	if account.AccountType == "CURRENT" {
+		acctType = "CHECKING"
+	}
+
+	fmt.Fprint(o.w, `<?xml version="1.0" encoding="UTF-8" standalone="no"?>`+"\n")
+	fmt.Fprint(o.w, `<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>`+"\n")
+	fmt.Fprint(o.w, "<OFX>\n<SIGNONMSGSRSV1><SONRS>\n")
+	fmt.Fprint(o.w, "<STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>\n")
+	fmt.Fprintf(o.w, "<DTSERVER>%s</DTSERVER><LANGUAGE>ENG</LANGUAGE>\n", time.Now().UTC().Format("20060102150405"))
+	fmt.Fprint(o.w, "</SONRS></SIGNONMSGSRSV1>\n<BANKMSGSRSV1><STMTTRNRS>\n")
+	fmt.Fprint(o.w, "<TRNUID>0</TRNUID><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>\n")
+	fmt.Fprintf(o.w, "<STMTRS><CURDEF>%s</CURDEF>\n", ofxText(o.currency))
+	fmt.Fprintf(o.w, "<BANKACCTFROM><BANKID>%s</BANKID><ACCTID>%s</ACCTID><ACCTTYPE>%s</ACCTTYPE></BANKACCTFROM>\n",
+		ofxText(account.BranchCode), ofxText(account.AccountNumber), acctType)
+	fmt.Fprintf(o.w, "<BANKTRANLIST><DTSTART>%s</DTSTART><DTEND>%s</DTEND>\n",
+		from.Format("20060102"), to.Format("20060102"))
+
+	return nil
+}
+
+func (o *OFX) Entry(txn *core.AccountTransaction) error {
+	name := truncateText(description(txn), 32)
+
+	fmt.Fprintf(o.w, "<STMTTRN><TRNTYPE>%s</TRNTYPE><DTPOSTED>%s</DTPOSTED><DTAVAIL>%s</DTAVAIL>"+
+		"<TRNAMT>%s</TRNAMT><FITID>%s</FITID>",
+		txn.TxnType, txn.PostingDate.Format("20060102"), txn.ValueDate.Format("20060102"),
+		amount(signedAmount(txn)), txn.TxnID)
+	if name != "" {
+		fmt.Fprintf(o.w, "<NAME>%s</NAME><MEMO>%s</MEMO>", ofxText(name), ofxText(description(txn)))
+	}
+	_, err := fmt.Fprint(o.w, "</STMTTRN>\n")
+	return err
+}
+
+func (o *OFX) End(closingBalance float64) error {
+	fmt.Fprint(o.w, "</BANKTRANLIST>\n")
+	fmt.Fprintf(o.w, "<LEDGERBAL><BALAMT>%s</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL>\n",
+		amount(closingBalance), o.to.Format("20060102"))
+	fmt.Fprint(o.w, "</STMTRS></STMTTRNRS></BANKMSGSRSV1>\n</OFX>\n")
+	return o.w.Flush()
+}
+
+func ofxText(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// truncateText keeps the first n characters of s, counting runes so that a
+// multi-byte character is never split.
+func truncateText(s string, n int) string {
+	r := []rune(s)
+	if len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
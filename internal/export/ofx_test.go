@@ -0,0 +1,26 @@
+package export
+
+import "testing"
+
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		n    int
+		want string
+	}{
+		{"short", "Rent", 32, "Rent"},
+		{"ascii cut", "Salary March", 6, "Salary"},
+		{"multi-byte kept whole", "Café Zürich", 4, "Café"},
+		{"cut inside multi-byte run", "日本語のテキスト", 3, "日本語"},
+		{"empty", "", 5, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateText(tt.in, tt.n); got != tt.want {
+				t.Errorf("truncateText(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+			}
+		})
+	}
+}
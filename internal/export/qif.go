@@ -0,0 +1,45 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+// QIF writes a Quicken Interchange Format bank register.
+type QIF struct {
+	w *bufio.Writer
+}
+
+func NewQIF(w io.Writer) *QIF {
+	return &QIF{w: bufio.NewWriter(w)}
+}
+
+func (q *QIF) Begin(account *core.StatementAccount, from, to time.Time, openingBalance float64) error {
+	_, err := fmt.Fprint(q.w, "!Type:Bank\n")
+	return err
+}
+
+func (q *QIF) Entry(txn *core.AccountTransaction) error {
+	fmt.Fprintf(q.w, "D%s\n", txn.PostingDate.Format("01/02/2006"))
+	fmt.Fprintf(q.w, "T%s\n", amount(signedAmount(txn)))
+	if d := qifLine(description(txn)); d != "" {
+		fmt.Fprintf(q.w, "P%s\n", d)
+	}
+	fmt.Fprintf(q.w, "M%s\n", txn.TxnID)
+	_, err := fmt.Fprint(q.w, "^\n")
+	return err
+}
+
+func (q *QIF) End(closingBalance float64) error {
+	return q.w.Flush()
+}
+
+// qifLine keeps a value on one line, since QIF fields are line-delimited.
+func qifLine(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
@@ -0,0 +1,364 @@
+// Package iso20022 produces ISO 20022 cash management messages from account
+// history, reads payment initiation files and checks every generated message
+// against the schemas in schemas/.
+package iso20022
+
+import (
+	"context"
+	"embed"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+//go:embed schemas/*.xsd
+var schemaFiles embed.FS
+
+// Message kinds.
+const (
+	Camt053 = "camt.053.001.08"
+	Camt052 = "camt.052.001.08"
+)
+
+// LoadSchema returns the embedded schema of a message kind.
+func LoadSchema(kind string) (*Schema, error) {
+	data, err := schemaFiles.ReadFile("schemas/" + kind + ".xsd")
+	if err != nil {
+		return nil, err
+	}
+	return ParseSchema(data)
+}
+
+// schemas parses each embedded schema the first time a message of its kind
+// is validated. A schema that fails to parse fails every message of its kind
+// rather than the process.
+var schemas = map[string]func() (*Schema, error){
+	Camt053: sync.OnceValues(func() (*Schema, error) { return LoadSchema(Camt053) }),
+	Camt052: sync.OnceValues(func() (*Schema, error) { return LoadSchema(Camt052) }),
+	Pain002: sync.OnceValues(func() (*Schema, error) { return LoadSchema(Pain002) }),
+}
+
+// validate checks a generated message against the schema of its kind.
+func validate(kind string, doc []byte) error {
+	load, ok := schemas[kind]
+	if !ok {
+		return fmt.Errorf("no schema for %s", kind)
+	}
+	schema, err := load()
+	if err != nil {
+		return fmt.Errorf("%s schema: %w", kind, err)
+	}
+	if err := schema.Validate(doc); err != nil {
+		return fmt.Errorf("generated %s failed schema validation: %w", kind, err)
+	}
+	return nil
+}
+
+// Reporter renders camt.053 end-of-day statements and camt.052 intraday
+// reports. Every message is validated before it is returned.
+type Reporter struct {
+	txns     *core.TransactionService
+	currency string
+}
+
+func NewReporter(txns *core.TransactionService, currency string) *Reporter {
+	return &Reporter{txns: txns, currency: currency}
+}
+
+// Statement renders a camt.053 statement with OPBD and CLBD balances.
+func (r *Reporter) Statement(ctx context.Context, accountID uuid.UUID, from, to time.Time) ([]byte, error) {
+	return r.render(ctx, Camt053, accountID, from, to)
+}
+
+// IntradayReport renders a camt.052 report. Its closing balance is interim
+// (ITBD) because the business day may still be open.
+func (r *Reporter) IntradayReport(ctx context.Context, accountID uuid.UUID, from, to time.Time) ([]byte, error) {
+	return r.render(ctx, Camt052, accountID, from, to)
+}
+
+func (r *Reporter) render(ctx context.Context, kind string, accountID uuid.UUID, from, to time.Time) ([]byte, error) {
+	b := &camtBuilder{kind: kind, currency: r.currency, now: time.Now().UTC()}
+	if err := r.txns.StreamStatement(ctx, accountID, from, to, b); err != nil {
+		return nil, err
+	}
+	return b.document()
+}
+
+type camtDocument struct {
+	XMLName   xml.Name     `xml:"Document"`
+	Xmlns     string       `xml:"xmlns,attr"`
+	Statement *camtMessage `xml:"BkToCstmrStmt,omitempty"`
+	Report    *camtMessage `xml:"BkToCstmrAcctRpt,omitempty"`
+}
+
+type camtMessage struct {
+	GrpHdr camtGroupHeader `xml:"GrpHdr"`
+	Stmt   *camtReport     `xml:"Stmt,omitempty"`
+	Rpt    *camtReport     `xml:"Rpt,omitempty"`
+}
+
+type camtGroupHeader struct {
+	MsgID   string `xml:"MsgId"`
+	CreDtTm string `xml:"CreDtTm"`
+}
+
+type camtReport struct {
+	ID        string          `xml:"Id"`
+	CreDtTm   string          `xml:"CreDtTm"`
+	FrToDt    camtPeriod      `xml:"FrToDt"`
+	Acct      camtAccount     `xml:"Acct"`
+	Bal       []camtBalance   `xml:"Bal"`
+	TxsSummry *camtTxsSummary `xml:"TxsSummry,omitempty"`
+	Ntry      []camtEntry     `xml:"Ntry"`
+}
+
+type camtPeriod struct {
+	FrDtTm string `xml:"FrDtTm"`
+	ToDtTm string `xml:"ToDtTm"`
+}
+
+type camtAccount struct {
+	ID   string `xml:"Id>Othr>Id"`
+	Ccy  string `xml:"Ccy"`
+	Ownr string `xml:"Ownr>Nm,omitempty"`
+	Svcr string `xml:"Svcr>FinInstnId>Othr>Id"`
+}
+
+type camtAmount struct {
+	Ccy   string `xml:"Ccy,attr"`
+	Value string `xml:",chardata"`
+}
+
+type camtBalance struct {
+	Type      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amt       camtAmount `xml:"Amt"`
+	CdtDbtInd string     `xml:"CdtDbtInd"`
+	Date      string     `xml:"Dt>Dt"`
+}
+
+type camtTxsSummary struct {
+	TtlNtries    camtCount `xml:"TtlNtries"`
+	TtlCdtNtries camtCount `xml:"TtlCdtNtries"`
+	TtlDbtNtries camtCount `xml:"TtlDbtNtries"`
+}
+
+type camtCount struct {
+	NbOfNtries string `xml:"NbOfNtries"`
+	Sum        string `xml:"Sum"`
+}
+
+type camtEntry struct {
+	NtryRef     string         `xml:"NtryRef"`
+	Amt         camtAmount     `xml:"Amt"`
+	CdtDbtInd   string         `xml:"CdtDbtInd"`
+	Status      string         `xml:"Sts>Cd"`
+	BookgDt     string         `xml:"BookgDt>Dt"`
+	ValDt       string         `xml:"ValDt>Dt"`
+	AcctSvcrRef string         `xml:"AcctSvcrRef"`
+	BkTxCd      camtBankTxCode `xml:"BkTxCd"`
+	NtryDtls    *camtEntryDtls `xml:"NtryDtls,omitempty"`
+}
+
+type camtBankTxCode struct {
+	Domain      string `xml:"Domn>Cd"`
+	Family      string `xml:"Domn>Fmly>Cd"`
+	SubFamily   string `xml:"Domn>Fmly>SubFmlyCd"`
+	Proprietary string `xml:"Prtry>Cd"`
+}
+
+type camtEntryDtls struct {
+	AcctSvcrRef string `xml:"TxDtls>Refs>AcctSvcrRef"`
+	EndToEndID  string `xml:"TxDtls>Refs>EndToEndId"`
+	Ustrd       string `xml:"TxDtls>RmtInf>Ustrd,omitempty"`
+}
+
+// camtBuilder collects a streamed statement into a camt report.
+type camtBuilder struct {
+	kind     string
+	currency string
+	now      time.Time
+
+	report              *camtReport
+	credits, debits     int
+	creditSum, debitSum float64
+}
+
+// document marshals the collected report as a message of the builder's kind
+// and validates it.
+func (b *camtBuilder) document() ([]byte, error) {
+	doc := camtDocument{Xmlns: "urn:iso:std:iso:20022:tech:xsd:" + b.kind}
+	if b.kind == Camt053 {
+		doc.Statement = &camtMessage{GrpHdr: b.header(), Stmt: b.report}
+	} else {
+		doc.Report = &camtMessage{GrpHdr: b.header(), Rpt: b.report}
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	out = append([]byte(xml.Header), out...)
+
+	if err := validate(b.kind, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (b *camtBuilder) header() camtGroupHeader {
+	prefix := "C053"
+	if b.kind == Camt052 {
+		prefix = "C052"
+	}
+	id := strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+	return camtGroupHeader{
+		MsgID:   prefix + b.now.Format("20060102150405") + strings.ToUpper(id),
+		CreDtTm: b.now.Format(time.RFC3339),
+	}
+}
+
+func (b *camtBuilder) Begin(account *core.StatementAccount, from, to time.Time, openingBalance float64) error {
+	b.report = &camtReport{
+		ID:      truncate(account.AccountNumber+"-"+to.Format("20060102"), 35),
+		CreDtTm: b.now.Format(time.RFC3339),
+		FrToDt: camtPeriod{
+			FrDtTm: from.Format("2006-01-02") + "T00:00:00",
+			ToDtTm: to.Format("2006-01-02") + "T23:59:59",
+		},
+		Acct: camtAccount{
+			ID:   truncate(account.AccountNumber, 34),
+			Ccy:  b.currency,
+			Ownr: truncate(account.CustomerName, 140),
+			Svcr: truncate(account.BranchCode, 35),
+		},
+		Bal: []camtBalance{b.balance("OPBD", openingBalance, from)},
+	}
+	return nil
+}
+
+func (b *camtBuilder) Entry(txn *core.AccountTransaction) error {
+	indicator := "CRDT"
+	if txn.TxnType == "DEBIT" {
+		indicator = "DBIT"
+		b.debits++
+		b.debitSum += txn.Amount
+	} else {
+		b.credits++
+		b.creditSum += txn.Amount
+	}
+
+	channel := ""
+	if txn.Channel != nil {
+		channel = *txn.Channel
+	}
+	ref := strings.ReplaceAll(txn.TxnID.String(), "-", "")
+
+	entry := camtEntry{
+		NtryRef:     strconv.Itoa(len(b.report.Ntry) + 1),
+		Amt:         camtAmount{Ccy: b.currency, Value: amount(txn.Amount)},
+		CdtDbtInd:   indicator,
+		Status:      "BOOK",
+		BookgDt:     txn.PostingDate.Format("2006-01-02"),
+		ValDt:       txn.ValueDate.Format("2006-01-02"),
+		AcctSvcrRef: ref,
+		BkTxCd:      bankTransactionCode(txn.TxnType, channel),
+		NtryDtls: &camtEntryDtls{
+			AcctSvcrRef: ref,
+			EndToEndID:  "NOTPROVIDED",
+		},
+	}
+	if txn.Description != nil && *txn.Description != "" {
+		entry.NtryDtls.Ustrd = truncate(*txn.Description, 140)
+	}
+
+	b.report.Ntry = append(b.report.Ntry, entry)
+	return nil
+}
+
+func (b *camtBuilder) End(closingBalance float64) error {
+	closing := "CLBD"
+	if b.kind == Camt052 {
+		closing = "ITBD"
+	}
+
+	// The closing balance is dated by the end of the period, not by the
+	// last entry, so empty periods still report both balances.
+	to, _ := time.Parse("2006-01-02", b.report.FrToDt.ToDtTm[:10])
+	b.report.Bal = append(b.report.Bal, b.balance(closing, closingBalance, to))
+
+	b.report.TxsSummry = &camtTxsSummary{
+		TtlNtries:    camtCount{strconv.Itoa(b.credits + b.debits), amount(b.creditSum + b.debitSum)},
+		TtlCdtNtries: camtCount{strconv.Itoa(b.credits), amount(b.creditSum)},
+		TtlDbtNtries: camtCount{strconv.Itoa(b.debits), amount(b.debitSum)},
+	}
+	return nil
+}
+
+func (b *camtBuilder) balance(code string, value float64, day time.Time) camtBalance {
+	indicator := "CRDT"
+	if value < 0 {
+		indicator = "DBIT"
+	}
+	return camtBalance{
+		Type:      code,
+		Amt:       camtAmount{Ccy: b.currency, Value: amount(math.Abs(value))},
+		CdtDbtInd: indicator,
+		Date:      day.Format("2006-01-02"),
+	}
+}
+
+// bankTransactionCode maps a posting's type and channel to the ISO 20022
+// domain, family and sub-family codes. The proprietary code keeps the
+// original type and channel.
+func bankTransactionCode(txnType, channel string) camtBankTxCode {
+	debit := txnType == "DEBIT"
+	family, sub := "RCDT", "OTHR"
+	if debit {
+		family = "ICDT"
+	}
+
+	switch channel {
+	case "TRANSFER":
+		sub = "BOOK"
+	case "BRANCH":
+		family, sub = "CNTR", "CDPT"
+		if debit {
+			sub = "CWDL"
+		}
+	case "CHEQUE":
+		family, sub = "RCHQ", "CCHQ"
+		if debit {
+			family = "ICHQ"
+		}
+	case "ATM":
+		family, sub = "CCRD", "CWDL"
+		if !debit {
+			sub = "CDPT"
+		}
+	}
+
+	proprietary := txnType
+	if channel != "" {
+		proprietary += "/" + channel
+	}
+	return camtBankTxCode{Domain: "PMNT", Family: family, SubFamily: sub, Proprietary: truncate(proprietary, 35)}
+}
+
+func amount(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', 2, 64)
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
@@ -0,0 +1,161 @@
+package iso20022
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+// statement builds a message of kind through the same visitor calls the
+// transaction service makes when it streams a statement.
+func statement(t *testing.T, kind string) []byte {
+	t.Helper()
+
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+	text := func(s string) *string { return &s }
+
+	b := &camtBuilder{kind: kind, currency: "INR", now: time.Date(2026, 4, 1, 6, 30, 0, 0, time.UTC)}
+	err := b.Begin(&core.StatementAccount{
+		AccountID:     uuid.New(),
+		AccountNumber: "ACC0001234567",
+		BranchCode:    "B001",
+		CustomerName:  "Jürgen Müller",
+	}, from, to, -120.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := []*core.AccountTransaction{
+		{TxnType: "CREDIT", Amount: 50000, Channel: text("TRANSFER"), Description: text("Salary March")},
+		{TxnType: "DEBIT", Amount: 2000, Channel: text("ATM")},
+		{TxnType: "DEBIT", Amount: 1234.567, Channel: text("CHEQUE"), Description: text(strings.Repeat("Überweisung ", 20))},
+		{TxnType: "CREDIT", Amount: 0.1, Description: text("")},
+	}
+	for _, txn := range entries {
+		txn.TxnID = uuid.New()
+		txn.PostingDate = from.AddDate(0, 0, 4)
+		txn.ValueDate = from.AddDate(0, 0, 6)
+		if err := b.Entry(txn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.End(46645.83); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := b.document()
+	if err != nil {
+		t.Fatalf("%s: %v", kind, err)
+	}
+	return doc
+}
+
+func TestSchemasParse(t *testing.T) {
+	for _, kind := range []string{Camt053, Camt052, Pain002} {
+		if _, err := LoadSchema(kind); err != nil {
+			t.Errorf("LoadSchema(%s): %v", kind, err)
+		}
+	}
+}
+
+func TestGeneratedStatementsValidate(t *testing.T) {
+	for _, kind := range []string{Camt053, Camt052} {
+		t.Run(kind, func(t *testing.T) {
+			doc := statement(t, kind)
+			if err := validate(kind, doc); err != nil {
+				t.Errorf("validate: %v\n%s", err, doc)
+			}
+		})
+	}
+}
+
+func TestGeneratedStatusReportValidates(t *testing.T) {
+	sum, count := 700.0, 2
+	instructionID, name, reason, reasonText := "INSTR-1", "Asha Rao", "AM04", "Insufficient funds"
+	txnID := uuid.New()
+
+	f := &core.BulkPaymentFile{
+		MessageID:     "MSG-2026-0001",
+		MessageName:   "pain.001.001.09",
+		DeclaredCount: 2,
+		DeclaredSum:   &sum,
+		Status:        core.PaymentStatusPartial,
+		Batches: []*core.BulkPaymentBatch{{
+			BatchID:       "PMT-1",
+			DeclaredCount: &count,
+			DeclaredSum:   &sum,
+			Status:        core.PaymentStatusPartial,
+			Instructions: []*core.BulkPaymentInstruction{
+				{InstructionID: &instructionID, EndToEndID: "E2E-1", CreditorAccount: "ACC0007654321",
+					CreditorName: &name, Amount: 500, Currency: "INR", Status: core.PaymentStatusSettled, TxnID: &txnID},
+				{EndToEndID: "E2E-2", CreditorAccount: "ACC0001111111", Amount: 200, Currency: "INR",
+					Status: core.PaymentStatusRejected, Reason: &reason, ReasonText: &reasonText},
+			},
+		}},
+	}
+
+	if _, err := StatusReport(f); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateRefusesInvalidDocuments(t *testing.T) {
+	valid := string(statement(t, Camt053))
+
+	tests := []struct {
+		name   string
+		mutate func(string) string
+	}{
+		{"missing account", func(s string) string {
+			return regexp.MustCompile(`(?s)<Acct>.*?</Acct>\s*`).ReplaceAllString(s, "")
+		}},
+		{"unexpected element", func(s string) string {
+			return strings.Replace(s, "<Bal>", "<Note>unexpected</Note><Bal>", 1)
+		}},
+		{"elements out of order", func(s string) string {
+			return regexp.MustCompile(`(?s)(<Id>ACC[^<]*</Id>)(\s*)(<CreDtTm>[^<]*</CreDtTm>)`).
+				ReplaceAllString(s, "$3$2$1")
+		}},
+		{"lower case currency", func(s string) string {
+			return strings.Replace(s, `Ccy="INR"`, `Ccy="inr"`, 1)
+		}},
+		{"negative amount", func(s string) string {
+			return strings.Replace(s, ">50000.00<", ">-50000.00<", 1)
+		}},
+		{"too many decimals", func(s string) string {
+			return strings.Replace(s, ">50000.00<", ">50000.000001<", 1)
+		}},
+		{"unknown credit debit indicator", func(s string) string {
+			return strings.Replace(s, "<CdtDbtInd>CRDT</CdtDbtInd>", "<CdtDbtInd>CRED</CdtDbtInd>", 1)
+		}},
+		{"invalid date", func(s string) string {
+			return strings.Replace(s, "<Dt>2026-03-05</Dt>", "<Dt>05/03/2026</Dt>", 1)
+		}},
+		{"text too long", func(s string) string {
+			return strings.Replace(s, "<Id>ACC0001234567</Id>", "<Id>"+strings.Repeat("A", 40)+"</Id>", 1)
+		}},
+		{"wrong namespace", func(s string) string {
+			return strings.Replace(s, "camt.053.001.08", "camt.052.001.08", 1)
+		}},
+		{"not well formed", func(s string) string {
+			return s[:len(s)/2]
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := tt.mutate(valid)
+			if doc == valid {
+				t.Fatal("mutation left the document unchanged")
+			}
+			if err := validate(Camt053, []byte(doc)); err == nil {
+				t.Errorf("validate accepted an invalid document:\n%s", doc)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package iso20022
+
+import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+// Pain001Namespace is the namespace prefix shared by pain.001 versions.
+const Pain001Namespace = "urn:iso:std:iso:20022:tech:xsd:pain.001.001."
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+type pain001Document struct {
+	XMLName xml.Name `xml:"Document"`
+	Initn   *struct {
+		GrpHdr struct {
+			MsgID   string `xml:"MsgId"`
+			NbOfTxs string `xml:"NbOfTxs"`
+			CtrlSum string `xml:"CtrlSum"`
+		} `xml:"GrpHdr"`
+		PmtInf []pain001PaymentInfo `xml:"PmtInf"`
+	} `xml:"CstmrCdtTrfInitn"`
+}
+
+type pain001PaymentInfo struct {
+	PmtInfID string         `xml:"PmtInfId"`
+	NbOfTxs  string         `xml:"NbOfTxs"`
+	CtrlSum  string         `xml:"CtrlSum"`
+	DbtrAcct pain001Account `xml:"DbtrAcct"`
+	CdtTrf   []struct {
+		InstrID    string `xml:"PmtId>InstrId"`
+		EndToEndID string `xml:"PmtId>EndToEndId"`
+		Amt        struct {
+			Ccy   string `xml:"Ccy,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"Amt>InstdAmt"`
+		CdtrNm   string         `xml:"Cdtr>Nm"`
+		CdtrAcct pain001Account `xml:"CdtrAcct"`
+		Ustrd    []string       `xml:"RmtInf>Ustrd"`
+	} `xml:"CdtTrfTxInf"`
+}
+
+// pain001Account accepts the IBAN and the generic (Othr) identification;
+// account numbers in this bank are generic identifiers.
+type pain001Account struct {
+	IBAN  string `xml:"Id>IBAN"`
+	Other string `xml:"Id>Othr>Id"`
+}
+
+func (a pain001Account) number() string {
+	if a.Other != "" {
+		return strings.TrimSpace(a.Other)
+	}
+	return strings.TrimSpace(a.IBAN)
+}
+
+// ParsePain001 reads a customer credit transfer initiation. Elements the
+// bank does not act on are ignored, so files from any pain.001 version
+// produced by standard tooling are accepted. Declared counts and control sums
+// are returned as stated; checking them is up to the caller.
+func ParsePain001(data []byte) (*core.BulkPaymentFile, error) {
+	var doc pain001Document
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("malformed XML: %w", err)
+	}
+	if !strings.HasPrefix(doc.XMLName.Space, Pain001Namespace) || doc.Initn == nil {
+		return nil, fmt.Errorf("not a pain.001 customer credit transfer initiation")
+	}
+
+	hdr := doc.Initn.GrpHdr
+	f := &core.BulkPaymentFile{
+		MessageID:   strings.TrimSpace(hdr.MsgID),
+		MessageName: strings.TrimPrefix(doc.XMLName.Space, "urn:iso:std:iso:20022:tech:xsd:"),
+	}
+	if f.MessageID == "" {
+		return nil, fmt.Errorf("GrpHdr/MsgId is required")
+	}
+
+	var err error
+	if f.DeclaredCount, err = strconv.Atoi(strings.TrimSpace(hdr.NbOfTxs)); err != nil {
+		return nil, fmt.Errorf("GrpHdr/NbOfTxs: %q is not a number", hdr.NbOfTxs)
+	}
+	if f.DeclaredSum, err = optionalDecimal(hdr.CtrlSum, "GrpHdr/CtrlSum"); err != nil {
+		return nil, err
+	}
+	if len(doc.Initn.PmtInf) == 0 {
+		return nil, fmt.Errorf("file contains no PmtInf")
+	}
+
+	for i, p := range doc.Initn.PmtInf {
+		path := fmt.Sprintf("PmtInf[%d]", i+1)
+		b := &core.BulkPaymentBatch{
+			BatchID:       strings.TrimSpace(p.PmtInfID),
+			DebtorAccount: p.DbtrAcct.number(),
+		}
+		if b.BatchID == "" {
+			return nil, fmt.Errorf("%s/PmtInfId is required", path)
+		}
+		if b.DebtorAccount == "" {
+			return nil, fmt.Errorf("%s/DbtrAcct is required", path)
+		}
+		if p.NbOfTxs != "" {
+			n, err := strconv.Atoi(strings.TrimSpace(p.NbOfTxs))
+			if err != nil {
+				return nil, fmt.Errorf("%s/NbOfTxs: %q is not a number", path, p.NbOfTxs)
+			}
+			b.DeclaredCount = &n
+		}
+		if b.DeclaredSum, err = optionalDecimal(p.CtrlSum, path+"/CtrlSum"); err != nil {
+			return nil, err
+		}
+		if len(p.CdtTrf) == 0 {
+			return nil, fmt.Errorf("%s contains no CdtTrfTxInf", path)
+		}
+
+		for j, t := range p.CdtTrf {
+			txPath := fmt.Sprintf("%s/CdtTrfTxInf[%d]", path, j+1)
+			in := &core.BulkPaymentInstruction{
+				EndToEndID:      strings.TrimSpace(t.EndToEndID),
+				CreditorAccount: t.CdtrAcct.number(),
+				Currency:        strings.TrimSpace(t.Amt.Ccy),
+			}
+			if in.EndToEndID == "" {
+				return nil, fmt.Errorf("%s/PmtId/EndToEndId is required", txPath)
+			}
+			if in.CreditorAccount == "" {
+				return nil, fmt.Errorf("%s/CdtrAcct is required", txPath)
+			}
+			amt, err := optionalDecimal(t.Amt.Value, txPath+"/Amt/InstdAmt")
+			if err != nil {
+				return nil, err
+			}
+			if amt == nil || !currencyCode.MatchString(in.Currency) {
+				return nil, fmt.Errorf("%s/Amt/InstdAmt with a Ccy is required", txPath)
+			}
+			if *amt < 0 {
+				return nil, fmt.Errorf("%s/Amt/InstdAmt must not be negative", txPath)
+			}
+			in.Amount = *amt
+			if v := strings.TrimSpace(t.InstrID); v != "" {
+				in.InstructionID = &v
+			}
+			if v := strings.TrimSpace(t.CdtrNm); v != "" {
+				in.CreditorName = &v
+			}
+			if v := strings.TrimSpace(strings.Join(t.Ustrd, " ")); v != "" {
+				in.Remittance = &v
+			}
+			b.Instructions = append(b.Instructions, in)
+		}
+
+		f.Batches = append(f.Batches, b)
+	}
+
+	return f, nil
+}
+
+// optionalDecimal parses an ISO 20022 decimal amount; empty means absent.
+func optionalDecimal(value, path string) (*float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if strings.ContainsAny(value, "eE") {
+		return nil, fmt.Errorf("%s: %q is not a decimal", path, value)
+	}
+	if _, frac, ok := strings.Cut(value, "."); ok && len(frac) > 2 {
+		return nil, fmt.Errorf("%s: %q has more than 2 fraction digits", path, value)
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %q is not a decimal", path, value)
+	}
+	return &v, nil
+}
@@ -0,0 +1,174 @@
+package iso20022
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+)
+
+const Pain002 = "pain.002.001.10"
+
+// StatusReport renders the pain.002 status report of a processed payment
+// file, with the group status, the status of every batch and the result of
+// every instruction. The document is validated before it is returned.
+func StatusReport(f *core.BulkPaymentFile) ([]byte, error) {
+	now := time.Now().UTC()
+	id := strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+
+	grp := pain002GroupInfo{
+		OrgnlMsgID:   truncate(f.MessageID, 35),
+		OrgnlMsgNmID: truncate(f.MessageName, 35),
+		OrgnlNbOfTxs: strconv.Itoa(f.DeclaredCount),
+		GrpSts:       f.Status,
+	}
+	if f.DeclaredSum != nil {
+		grp.OrgnlCtrlSum = amount(*f.DeclaredSum)
+	}
+	if f.Reason != nil {
+		grp.StsRsnInf = &pain002Reason{Cd: *f.Reason}
+	}
+
+	counts := map[string]int{}
+	sums := map[string]float64{}
+	var batches []pain002PaymentInfo
+	for _, b := range f.Batches {
+		pi := pain002PaymentInfo{
+			OrgnlPmtInfID: truncate(b.BatchID, 35),
+			PmtInfSts:     b.Status,
+		}
+		if b.DeclaredCount != nil {
+			pi.OrgnlNbOfTxs = strconv.Itoa(*b.DeclaredCount)
+		}
+		if b.DeclaredSum != nil {
+			pi.OrgnlCtrlSum = amount(*b.DeclaredSum)
+		}
+
+		for _, in := range b.Instructions {
+			counts[in.Status]++
+			sums[in.Status] += in.Amount
+
+			tx := pain002Transaction{
+				OrgnlEndToEndID: truncate(in.EndToEndID, 35),
+				TxSts:           in.Status,
+				OrgnlTxRef: pain002TxRef{
+					Amt:      camtAmount{Ccy: in.Currency, Value: amount(in.Amount)},
+					CdtrAcct: truncate(in.CreditorAccount, 34),
+				},
+			}
+			if in.InstructionID != nil {
+				tx.OrgnlInstrID = truncate(*in.InstructionID, 35)
+			}
+			if in.Reason != nil {
+				tx.StsRsnInf = &pain002Reason{Cd: *in.Reason}
+				if in.ReasonText != nil {
+					tx.StsRsnInf.AddtlInf = truncate(*in.ReasonText, 105)
+				}
+			}
+			if in.TxnID != nil {
+				tx.AcctSvcrRef = strings.ReplaceAll(in.TxnID.String(), "-", "")
+			}
+			if in.CreditorName != nil {
+				tx.OrgnlTxRef.Cdtr = &pain002Creditor{Nm: truncate(*in.CreditorName, 140)}
+			}
+			pi.TxInfAndSts = append(pi.TxInfAndSts, tx)
+		}
+		batches = append(batches, pi)
+	}
+
+	for _, status := range []string{core.PaymentStatusSettled, core.PaymentStatusRejected, core.PaymentStatusPending} {
+		if counts[status] > 0 {
+			grp.NbOfTxsPerSts = append(grp.NbOfTxsPerSts, pain002StatusCount{
+				DtldNbOfTxs: strconv.Itoa(counts[status]),
+				DtldSts:     status,
+				DtldCtrlSum: amount(sums[status]),
+			})
+		}
+	}
+
+	doc := pain002Document{
+		Xmlns: "urn:iso:std:iso:20022:tech:xsd:" + Pain002,
+		Report: pain002Report{
+			GrpHdr: camtGroupHeader{
+				MsgID:   "P002" + now.Format("20060102150405") + strings.ToUpper(id),
+				CreDtTm: now.Format(time.RFC3339),
+			},
+			OrgnlGrpInfAndSts: grp,
+			OrgnlPmtInfAndSts: batches,
+		},
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	out = append([]byte(xml.Header), out...)
+
+	if err := validate(Pain002, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+type pain002Document struct {
+	XMLName xml.Name      `xml:"Document"`
+	Xmlns   string        `xml:"xmlns,attr"`
+	Report  pain002Report `xml:"CstmrPmtStsRpt"`
+}
+
+type pain002Report struct {
+	GrpHdr            camtGroupHeader      `xml:"GrpHdr"`
+	OrgnlGrpInfAndSts pain002GroupInfo     `xml:"OrgnlGrpInfAndSts"`
+	OrgnlPmtInfAndSts []pain002PaymentInfo `xml:"OrgnlPmtInfAndSts"`
+}
+
+type pain002GroupInfo struct {
+	OrgnlMsgID    string               `xml:"OrgnlMsgId"`
+	OrgnlMsgNmID  string               `xml:"OrgnlMsgNmId"`
+	OrgnlNbOfTxs  string               `xml:"OrgnlNbOfTxs"`
+	OrgnlCtrlSum  string               `xml:"OrgnlCtrlSum,omitempty"`
+	GrpSts        string               `xml:"GrpSts"`
+	StsRsnInf     *pain002Reason       `xml:"StsRsnInf,omitempty"`
+	NbOfTxsPerSts []pain002StatusCount `xml:"NbOfTxsPerSts"`
+}
+
+type pain002PaymentInfo struct {
+	OrgnlPmtInfID string               `xml:"OrgnlPmtInfId"`
+	OrgnlNbOfTxs  string               `xml:"OrgnlNbOfTxs,omitempty"`
+	OrgnlCtrlSum  string               `xml:"OrgnlCtrlSum,omitempty"`
+	PmtInfSts     string               `xml:"PmtInfSts"`
+	TxInfAndSts   []pain002Transaction `xml:"TxInfAndSts"`
+}
+
+type pain002Transaction struct {
+	OrgnlInstrID    string         `xml:"OrgnlInstrId,omitempty"`
+	OrgnlEndToEndID string         `xml:"OrgnlEndToEndId"`
+	TxSts           string         `xml:"TxSts"`
+	StsRsnInf       *pain002Reason `xml:"StsRsnInf,omitempty"`
+	AcctSvcrRef     string         `xml:"AcctSvcrRef,omitempty"`
+	OrgnlTxRef      pain002TxRef   `xml:"OrgnlTxRef"`
+}
+
+type pain002Reason struct {
+	Cd       string `xml:"Rsn>Cd"`
+	AddtlInf string `xml:"AddtlInf,omitempty"`
+}
+
+type pain002StatusCount struct {
+	DtldNbOfTxs string `xml:"DtldNbOfTxs"`
+	DtldSts     string `xml:"DtldSts"`
+	DtldCtrlSum string `xml:"DtldCtrlSum"`
+}
+
+type pain002TxRef struct {
+	Amt      camtAmount       `xml:"Amt>InstdAmt"`
+	Cdtr     *pain002Creditor `xml:"Cdtr,omitempty"`
+	CdtrAcct string           `xml:"CdtrAcct>Id>Othr>Id"`
+}
+
+type pain002Creditor struct {
+	Nm string `xml:"Pty>Nm"`
+}
@@ -0,0 +1,369 @@
+package iso20022
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is a parsed XML schema restricted to the constructs ISO 20022
+// message definitions use: named complex types with a sequence or choice of
+// elements, simple content with attributes, and simple types restricting a
+// built-in type with enumeration, pattern, length and digit facets.
+type Schema struct {
+	Namespace string
+	root      xsdElement
+	complex   map[string]*xsdComplexType
+	simple    map[string]*xsdSimpleType
+}
+
+type xsdSchema struct {
+	TargetNamespace string           `xml:"targetNamespace,attr"`
+	Elements        []xsdElement     `xml:"element"`
+	ComplexTypes    []xsdComplexType `xml:"complexType"`
+	SimpleTypes     []xsdSimpleType  `xml:"simpleType"`
+}
+
+type xsdElement struct {
+	Name      string `xml:"name,attr"`
+	Type      string `xml:"type,attr"`
+	MinOccurs string `xml:"minOccurs,attr"`
+	MaxOccurs string `xml:"maxOccurs,attr"`
+}
+
+type xsdComplexType struct {
+	Name          string        `xml:"name,attr"`
+	Sequence      *xsdGroup     `xml:"sequence"`
+	Choice        *xsdGroup     `xml:"choice"`
+	SimpleContent *xsdExtension `xml:"simpleContent>extension"`
+}
+
+type xsdGroup struct {
+	Elements []xsdElement `xml:"element"`
+}
+
+type xsdExtension struct {
+	Base       string         `xml:"base,attr"`
+	Attributes []xsdAttribute `xml:"attribute"`
+}
+
+type xsdAttribute struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+	Use  string `xml:"use,attr"`
+}
+
+type xsdFacet struct {
+	Value string `xml:"value,attr"`
+}
+
+type xsdSimpleType struct {
+	Name        string `xml:"name,attr"`
+	Restriction struct {
+		Base           string     `xml:"base,attr"`
+		Enumerations   []xsdFacet `xml:"enumeration"`
+		Patterns       []xsdFacet `xml:"pattern"`
+		MinLength      *xsdFacet  `xml:"minLength"`
+		MaxLength      *xsdFacet  `xml:"maxLength"`
+		TotalDigits    *xsdFacet  `xml:"totalDigits"`
+		FractionDigits *xsdFacet  `xml:"fractionDigits"`
+		MinInclusive   *xsdFacet  `xml:"minInclusive"`
+	} `xml:"restriction"`
+
+	patterns []*regexp.Regexp
+}
+
+// ParseSchema reads an XSD document.
+func ParseSchema(data []byte) (*Schema, error) {
+	var x xsdSchema
+	if err := xml.Unmarshal(data, &x); err != nil {
+		return nil, err
+	}
+	if len(x.Elements) != 1 {
+		return nil, fmt.Errorf("schema must declare exactly one root element")
+	}
+
+	s := &Schema{
+		Namespace: x.TargetNamespace,
+		root:      x.Elements[0],
+		complex:   map[string]*xsdComplexType{},
+		simple:    map[string]*xsdSimpleType{},
+	}
+	for i := range x.ComplexTypes {
+		ct := &x.ComplexTypes[i]
+		s.complex[ct.Name] = ct
+	}
+	for i := range x.SimpleTypes {
+		st := &x.SimpleTypes[i]
+		for _, p := range st.Restriction.Patterns {
+			re, err := regexp.Compile("^(?:" + p.Value + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("simple type %s: %w", st.Name, err)
+			}
+			st.patterns = append(st.patterns, re)
+		}
+		s.simple[st.Name] = st
+	}
+
+	return s, nil
+}
+
+// node is an element of the document being validated.
+type node struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	text     string
+	children []*node
+}
+
+// Validate checks a document against the schema and reports the first
+// violation with its element path.
+func (s *Schema) Validate(doc []byte) error {
+	root, err := parseNodes(doc)
+	if err != nil {
+		return err
+	}
+	if root.name.Local != s.root.Name || root.name.Space != s.Namespace {
+		return fmt.Errorf("root element {%s}%s, want {%s}%s", root.name.Space, root.name.Local, s.Namespace, s.root.Name)
+	}
+	return s.validateElement(root, s.root.Type, "/"+root.name.Local)
+}
+
+func parseNodes(doc []byte) (*node, error) {
+	dec := xml.NewDecoder(bytes.NewReader(doc))
+	var stack []*node
+	var root *node
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &node{name: t.Name, attrs: t.Attr}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			} else if root == nil {
+				root = n
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("empty document")
+	}
+	return root, nil
+}
+
+func (s *Schema) validateElement(n *node, typeName, path string) error {
+	if ct, ok := s.complex[typeName]; ok {
+		return s.validateComplex(n, ct, path)
+	}
+	if len(n.children) > 0 {
+		return fmt.Errorf("%s: unexpected child element %s", path, n.children[0].name.Local)
+	}
+	return s.validateSimple(strings.TrimSpace(n.text), typeName, path)
+}
+
+func (s *Schema) validateComplex(n *node, ct *xsdComplexType, path string) error {
+	if ct.SimpleContent != nil {
+		if len(n.children) > 0 {
+			return fmt.Errorf("%s: unexpected child element %s", path, n.children[0].name.Local)
+		}
+		for _, a := range ct.SimpleContent.Attributes {
+			value, ok := attr(n, a.Name)
+			if !ok {
+				if a.Use == "required" {
+					return fmt.Errorf("%s: missing attribute %s", path, a.Name)
+				}
+				continue
+			}
+			if err := s.validateSimple(value, a.Type, path+"/@"+a.Name); err != nil {
+				return err
+			}
+		}
+		return s.validateSimple(strings.TrimSpace(n.text), ct.SimpleContent.Base, path)
+	}
+
+	for _, c := range n.children {
+		if c.name.Space != s.Namespace {
+			return fmt.Errorf("%s: element %s in namespace %q", path, c.name.Local, c.name.Space)
+		}
+	}
+
+	if ct.Choice != nil {
+		if len(n.children) != 1 {
+			return fmt.Errorf("%s: choice needs exactly one element, got %d", path, len(n.children))
+		}
+		c := n.children[0]
+		for _, e := range ct.Choice.Elements {
+			if e.Name == c.name.Local {
+				return s.validateElement(c, e.Type, path+"/"+c.name.Local)
+			}
+		}
+		return fmt.Errorf("%s: unexpected element %s", path, c.name.Local)
+	}
+
+	var particles []xsdElement
+	if ct.Sequence != nil {
+		particles = ct.Sequence.Elements
+	}
+
+	i := 0
+	for _, e := range particles {
+		count := 0
+		for i < len(n.children) && n.children[i].name.Local == e.Name {
+			if err := s.validateElement(n.children[i], e.Type, path+"/"+e.Name); err != nil {
+				return err
+			}
+			count++
+			i++
+		}
+		if min := occurs(e.MinOccurs); count < min {
+			return fmt.Errorf("%s: expected element %s", path, e.Name)
+		}
+		if max := occurs(e.MaxOccurs); max >= 0 && count > max {
+			return fmt.Errorf("%s: element %s occurs %d times, at most %d allowed", path, e.Name, count, max)
+		}
+	}
+	if i < len(n.children) {
+		return fmt.Errorf("%s: unexpected element %s", path, n.children[i].name.Local)
+	}
+
+	return nil
+}
+
+func (s *Schema) validateSimple(value, typeName, path string) error {
+	st, ok := s.simple[typeName]
+	if !ok {
+		return validateBuiltin(value, typeName, path)
+	}
+	r := st.Restriction
+
+	if err := validateBuiltin(value, r.Base, path); err != nil {
+		return err
+	}
+
+	if len(r.Enumerations) > 0 {
+		found := false
+		for _, e := range r.Enumerations {
+			if e.Value == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: %q is not a valid %s", path, value, typeName)
+		}
+	}
+
+	for _, re := range st.patterns {
+		if !re.MatchString(value) {
+			return fmt.Errorf("%s: %q does not match the %s pattern", path, value, typeName)
+		}
+	}
+
+	length := len([]rune(value))
+	if r.MinLength != nil && length < atoi(r.MinLength.Value) {
+		return fmt.Errorf("%s: %q is shorter than %s characters", path, value, r.MinLength.Value)
+	}
+	if r.MaxLength != nil && length > atoi(r.MaxLength.Value) {
+		return fmt.Errorf("%s: value is longer than %s characters", path, r.MaxLength.Value)
+	}
+
+	if r.TotalDigits != nil || r.FractionDigits != nil {
+		whole, frac, _ := strings.Cut(strings.TrimLeft(value, "+-"), ".")
+		whole = strings.TrimLeft(whole, "0")
+		frac = strings.TrimRight(frac, "0")
+		if r.TotalDigits != nil && len(whole)+len(frac) > atoi(r.TotalDigits.Value) {
+			return fmt.Errorf("%s: %s has more than %s digits", path, value, r.TotalDigits.Value)
+		}
+		if r.FractionDigits != nil && len(frac) > atoi(r.FractionDigits.Value) {
+			return fmt.Errorf("%s: %s has more than %s fraction digits", path, value, r.FractionDigits.Value)
+		}
+	}
+
+	if r.MinInclusive != nil {
+		v, _ := strconv.ParseFloat(value, 64)
+		min, _ := strconv.ParseFloat(r.MinInclusive.Value, 64)
+		if v < min {
+			return fmt.Errorf("%s: %s is below %s", path, value, r.MinInclusive.Value)
+		}
+	}
+
+	return nil
+}
+
+func validateBuiltin(value, typeName, path string) error {
+	var err error
+	switch typeName {
+	case "xs:string":
+	case "xs:decimal":
+		if strings.ContainsAny(value, "eE") {
+			err = fmt.Errorf("exponent not allowed")
+		} else {
+			_, err = strconv.ParseFloat(value, 64)
+		}
+	case "xs:boolean":
+		switch value {
+		case "true", "false", "1", "0":
+		default:
+			err = fmt.Errorf("not a boolean")
+		}
+	case "xs:date":
+		_, err = time.Parse("2006-01-02", value)
+	case "xs:dateTime":
+		if _, err = time.Parse(time.RFC3339Nano, value); err != nil {
+			_, err = time.Parse("2006-01-02T15:04:05.999999999", value)
+		}
+	default:
+		return fmt.Errorf("%s: unknown type %s", path, typeName)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %q is not a valid %s", path, value, typeName)
+	}
+	return nil
+}
+
+func attr(n *node, name string) (string, bool) {
+	for _, a := range n.attrs {
+		if a.Name.Local == name && a.Name.Space == "" {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// occurs parses minOccurs/maxOccurs; absent means 1 and unbounded is -1.
+func occurs(v string) int {
+	switch v {
+	case "":
+		return 1
+	case "unbounded":
+		return -1
+	}
+	return atoi(v)
+}
+
+func atoi(v string) int {
+	n, _ := strconv.Atoi(v)
+	return n
+}
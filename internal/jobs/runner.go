@@ -0,0 +1,88 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Job is a unit of background work the runner invokes on every tick.
+// Implementations must be safe to run repeatedly and must pick up only the
+// work that is due.
+type Job interface {
+	Name() string
+	Run(ctx context.Context) error
+}
+
+// Runner executes registered jobs on a fixed interval inside the server
+// process.
+type Runner struct {
+	interval time.Duration
+	jobs     []Job
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewRunner(interval time.Duration, jobs ...Job) *Runner {
+	return &Runner{interval: interval, jobs: jobs}
+}
+
+func (r *Runner) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		log.Printf("Background jobs started (interval %s)", r.interval)
+		for {
+			r.runAll(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop cancels the running tick and waits for in-flight jobs to return.
+func (r *Runner) Stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	r.wg.Wait()
+	log.Println("Background jobs stopped")
+}
+
+func (r *Runner) runAll(ctx context.Context) {
+	for _, job := range r.jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := job.Run(ctx); err != nil {
+			log.Printf("job %s failed: %v", job.Name(), err)
+		}
+	}
+}
+
+type funcJob struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Func adapts a plain function to the Job interface.
+func Func(name string, fn func(ctx context.Context) error) Job {
+	return funcJob{name: name, fn: fn}
+}
+
+func (j funcJob) Name() string                  { return j.name }
+func (j funcJob) Run(ctx context.Context) error { return j.fn(ctx) }
@@ -0,0 +1,471 @@
+// Package nacha reads and writes ACH files in the NACHA fixed-width format:
+// 94-character records grouped in blocks of ten, with a file header, batches
+// of entries and their addenda, batch controls and a file control.
+package nacha
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	recordLength   = 94
+	blockingFactor = 10
+)
+
+// Standard entry class codes.
+const (
+	SECPPD = "PPD" // prearranged payment or deposit to a consumer account
+	SECCCD = "CCD" // corporate credit or debit
+)
+
+// Service class codes.
+const (
+	ServiceMixed   = 200
+	ServiceCredits = 220
+	ServiceDebits  = 225
+)
+
+// Transaction codes of forward entries and of their returns.
+const (
+	CheckingReturnCredit = 21
+	CheckingCredit       = 22
+	CheckingReturnDebit  = 26
+	CheckingDebit        = 27
+	SavingsReturnCredit  = 31
+	SavingsCredit        = 32
+	SavingsReturnDebit   = 36
+	SavingsDebit         = 37
+)
+
+// Addenda type codes.
+const (
+	AddendaPayment = "05"
+	AddendaReturn  = "99"
+)
+
+type File struct {
+	// ImmediateDestination is the routing number of the ACH operator or
+	// receiving point; ImmediateOrigin identifies the sender.
+	ImmediateDestination string
+	ImmediateOrigin      string
+	DestinationName      string
+	OriginName           string
+	CreatedAt            time.Time
+	IDModifier           string
+	ReferenceCode        string
+	Batches              []*Batch
+}
+
+type Batch struct {
+	CompanyName      string
+	CompanyID        string
+	SECCode          string
+	EntryDescription string
+	EffectiveDate    time.Time
+	// ODFI is the first eight digits of the originating bank's routing number.
+	ODFI    string
+	Number  int
+	Entries []*Entry
+}
+
+type Entry struct {
+	TransactionCode int
+	// RDFI is the receiving bank's full nine-digit routing number.
+	RDFI          string
+	AccountNumber string
+	// Amount is in cents.
+	Amount       int64
+	IndividualID string
+	Name         string
+	TraceNumber  string
+	Addenda      []*Addenda
+}
+
+// Addenda carries payment information (type 05) or, in return files, the
+// return reason and the trace number of the original entry (type 99).
+type Addenda struct {
+	TypeCode      string
+	PaymentInfo   string
+	ReturnCode    string
+	OriginalTrace string
+	OriginalRDFI  string
+}
+
+// IsCredit reports whether a transaction code credits the receiver's account.
+func IsCredit(code int) bool {
+	return code%10 >= 1 && code%10 <= 4
+}
+
+// ServiceClass returns the service class code matching the batch's entries.
+func (b *Batch) ServiceClass() int {
+	credits, debits := false, false
+	for _, e := range b.Entries {
+		if IsCredit(e.TransactionCode) {
+			credits = true
+		} else {
+			debits = true
+		}
+	}
+	switch {
+	case credits && !debits:
+		return ServiceCredits
+	case debits && !credits:
+		return ServiceDebits
+	}
+	return ServiceMixed
+}
+
+// CheckDigit computes the ninth digit of a routing number from its first
+// eight digits.
+func CheckDigit(routing string) (int, error) {
+	if len(routing) < 8 || !digits(routing[:8]) {
+		return 0, fmt.Errorf("routing number %q must start with 8 digits", routing)
+	}
+	weights := []int{3, 7, 1, 3, 7, 1, 3, 7}
+	sum := 0
+	for i, w := range weights {
+		sum += int(routing[i]-'0') * w
+	}
+	return (10 - sum%10) % 10, nil
+}
+
+// ValidRouting reports whether routing is a nine-digit ABA routing number
+// with a correct check digit.
+func ValidRouting(routing string) bool {
+	if len(routing) != 9 || !digits(routing) {
+		return false
+	}
+	d, err := CheckDigit(routing)
+	return err == nil && int(routing[8]-'0') == d
+}
+
+// totals are the control counts of a batch or file.
+type totals struct {
+	entries int
+	hash    int64
+	debits  int64
+	credits int64
+}
+
+func (t *totals) add(e *Entry) {
+	t.entries += 1 + len(e.Addenda)
+	rdfi, _ := strconv.ParseInt(e.RDFI[:8], 10, 64)
+	t.hash += rdfi
+	if IsCredit(e.TransactionCode) {
+		t.credits += e.Amount
+	} else {
+		t.debits += e.Amount
+	}
+}
+
+// entryHash is the sum of the RDFI identifications, keeping the low ten digits.
+func (t *totals) entryHash() int64 {
+	return t.hash % 10_000_000_000
+}
+
+// Write renders f, computing control records, and pads the last block.
+func Write(w io.Writer, f *File) error {
+	bw := bufio.NewWriter(w)
+	lines := 0
+	emit := func(fields ...string) error {
+		rec := strings.Join(fields, "")
+		if len(rec) != recordLength {
+			return fmt.Errorf("nacha: record %q is %d characters, want %d", rec, len(rec), recordLength)
+		}
+		lines++
+		_, err := bw.WriteString(rec + "\n")
+		return err
+	}
+
+	err := emit("1", "01",
+		" "+num(f.ImmediateDestination, 9), text(f.ImmediateOrigin, 10, true),
+		f.CreatedAt.Format("060102"), f.CreatedAt.Format("1504"), text(f.IDModifier, 1, false),
+		"094", "10", "1",
+		text(f.DestinationName, 23, false), text(f.OriginName, 23, false), text(f.ReferenceCode, 8, false))
+	if err != nil {
+		return err
+	}
+
+	var file totals
+	for _, b := range f.Batches {
+		if len(b.Entries) == 0 {
+			return fmt.Errorf("nacha: batch %d has no entries", b.Number)
+		}
+		class := fmt.Sprintf("%03d", b.ServiceClass())
+		err := emit("5", class,
+			text(b.CompanyName, 16, false), text("", 20, false), text(b.CompanyID, 10, false),
+			text(b.SECCode, 3, false), text(b.EntryDescription, 10, false), text("", 6, false),
+			b.EffectiveDate.Format("060102"), "   ", "1", num(b.ODFI, 8), pad(b.Number, 7))
+		if err != nil {
+			return err
+		}
+
+		var batch totals
+		for _, e := range b.Entries {
+			if !ValidRouting(e.RDFI) {
+				return fmt.Errorf("nacha: invalid RDFI routing number %q", e.RDFI)
+			}
+			indicator := "0"
+			if len(e.Addenda) > 0 {
+				indicator = "1"
+			}
+			err := emit("6", pad(e.TransactionCode, 2), e.RDFI,
+				text(e.AccountNumber, 17, false), pad64(e.Amount, 10),
+				text(e.IndividualID, 15, false), text(e.Name, 22, false), "  ", indicator,
+				num(e.TraceNumber, 15))
+			if err != nil {
+				return err
+			}
+			for i, a := range e.Addenda {
+				if err := emit(addendaRecord(e, a, i+1)); err != nil {
+					return err
+				}
+			}
+			batch.add(e)
+			file.add(e)
+		}
+
+		err = emit("8", class, pad(batch.entries, 6), pad64(batch.entryHash(), 10),
+			pad64(batch.debits, 12), pad64(batch.credits, 12), text(b.CompanyID, 10, false),
+			text("", 19, false), text("", 6, false), num(b.ODFI, 8), pad(b.Number, 7))
+		if err != nil {
+			return err
+		}
+	}
+
+	blocks := (lines + 1 + blockingFactor - 1) / blockingFactor
+	err = emit("9", pad(len(f.Batches), 6), pad(blocks, 6), pad(file.entries, 8),
+		pad64(file.entryHash(), 10), pad64(file.debits, 12), pad64(file.credits, 12),
+		text("", 39, false))
+	if err != nil {
+		return err
+	}
+	for lines%blockingFactor != 0 {
+		if err := emit(strings.Repeat("9", recordLength)); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+func addendaRecord(e *Entry, a *Addenda, seq int) string {
+	if a.TypeCode == AddendaReturn {
+		return "7" + AddendaReturn + text(a.ReturnCode, 3, false) + num(a.OriginalTrace, 15) +
+			text("", 6, false) + num(a.OriginalRDFI, 8) + text(a.PaymentInfo, 44, false) + num(e.TraceNumber, 15)
+	}
+	return "7" + AddendaPayment + text(a.PaymentInfo, 80, false) + pad(seq, 4) + num(e.TraceNumber, 15)[8:]
+}
+
+// Parse reads an ACH file and checks its batch and file control totals.
+func Parse(r io.Reader) (*File, error) {
+	sc := bufio.NewScanner(r)
+	f := &File{}
+	var batch *Batch
+	var entry *Entry
+	var batchTotals, fileTotals totals
+	line, batches := 0, 0
+	seenHeader, seenControl := false, false
+
+	for sc.Scan() {
+		line++
+		rec := strings.TrimRight(sc.Text(), "\r")
+		if rec == "" {
+			continue
+		}
+		if len(rec) != recordLength {
+			return nil, fmt.Errorf("nacha: line %d is %d characters, want %d", line, len(rec), recordLength)
+		}
+		if seenControl {
+			if rec != strings.Repeat("9", recordLength) {
+				return nil, fmt.Errorf("nacha: line %d: data after file control", line)
+			}
+			continue
+		}
+
+		switch rec[0] {
+		case '1':
+			if seenHeader {
+				return nil, fmt.Errorf("nacha: line %d: second file header", line)
+			}
+			seenHeader = true
+			created, err := time.Parse("0601021504", rec[23:33])
+			if err != nil {
+				return nil, fmt.Errorf("nacha: line %d: invalid creation date", line)
+			}
+			f.ImmediateDestination = strings.TrimSpace(rec[3:13])
+			f.ImmediateOrigin = strings.TrimSpace(rec[13:23])
+			f.CreatedAt = created
+			f.IDModifier = rec[33:34]
+			f.DestinationName = strings.TrimSpace(rec[40:63])
+			f.OriginName = strings.TrimSpace(rec[63:86])
+			f.ReferenceCode = strings.TrimSpace(rec[86:94])
+
+		case '5':
+			if !seenHeader || batch != nil {
+				return nil, fmt.Errorf("nacha: line %d: unexpected batch header", line)
+			}
+			effective, err := time.Parse("060102", rec[69:75])
+			if err != nil {
+				return nil, fmt.Errorf("nacha: line %d: invalid effective entry date", line)
+			}
+			number, err := strconv.Atoi(rec[87:94])
+			if err != nil {
+				return nil, fmt.Errorf("nacha: line %d: invalid batch number", line)
+			}
+			batch = &Batch{
+				CompanyName:      strings.TrimSpace(rec[4:20]),
+				CompanyID:        strings.TrimSpace(rec[40:50]),
+				SECCode:          rec[50:53],
+				EntryDescription: strings.TrimSpace(rec[53:63]),
+				EffectiveDate:    effective,
+				ODFI:             rec[79:87],
+				Number:           number,
+			}
+			batchTotals = totals{}
+			entry = nil
+
+		case '6':
+			if batch == nil {
+				return nil, fmt.Errorf("nacha: line %d: entry outside a batch", line)
+			}
+			code, err1 := strconv.Atoi(rec[1:3])
+			amount, err2 := strconv.ParseInt(rec[29:39], 10, 64)
+			if err1 != nil || err2 != nil || !digits(rec[3:12]) {
+				return nil, fmt.Errorf("nacha: line %d: malformed entry detail", line)
+			}
+			entry = &Entry{
+				TransactionCode: code,
+				RDFI:            rec[3:12],
+				AccountNumber:   strings.TrimSpace(rec[12:29]),
+				Amount:          amount,
+				IndividualID:    strings.TrimSpace(rec[39:54]),
+				Name:            strings.TrimSpace(rec[54:76]),
+				TraceNumber:     rec[79:94],
+			}
+			batch.Entries = append(batch.Entries, entry)
+			batchTotals.add(entry)
+			fileTotals.add(entry)
+
+		case '7':
+			if entry == nil {
+				return nil, fmt.Errorf("nacha: line %d: addenda without an entry", line)
+			}
+			a := &Addenda{TypeCode: rec[1:3]}
+			if a.TypeCode == AddendaReturn {
+				a.ReturnCode = rec[3:6]
+				a.OriginalTrace = rec[6:21]
+				a.OriginalRDFI = rec[27:35]
+				a.PaymentInfo = strings.TrimSpace(rec[35:79])
+			} else {
+				a.PaymentInfo = strings.TrimSpace(rec[3:83])
+			}
+			entry.Addenda = append(entry.Addenda, a)
+			batchTotals.entries++
+			fileTotals.entries++
+
+		case '8':
+			if batch == nil {
+				return nil, fmt.Errorf("nacha: line %d: batch control without a batch", line)
+			}
+			if err := checkControl(rec[4:10], rec[10:20], rec[20:32], rec[32:44], batchTotals); err != nil {
+				return nil, fmt.Errorf("nacha: batch %d: %w", batch.Number, err)
+			}
+			f.Batches = append(f.Batches, batch)
+			batches++
+			batch, entry = nil, nil
+
+		case '9':
+			if batch != nil {
+				return nil, fmt.Errorf("nacha: line %d: file control inside a batch", line)
+			}
+			if n, err := strconv.Atoi(rec[1:7]); err != nil || n != batches {
+				return nil, fmt.Errorf("nacha: file control batch count %q, counted %d", rec[1:7], batches)
+			}
+			if err := checkControl(rec[13:21], rec[21:31], rec[31:43], rec[43:55], fileTotals); err != nil {
+				return nil, fmt.Errorf("nacha: file control: %w", err)
+			}
+			seenControl = true
+
+		default:
+			return nil, fmt.Errorf("nacha: line %d: unknown record type %q", line, rec[0])
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if !seenHeader || !seenControl {
+		return nil, fmt.Errorf("nacha: file header or control missing")
+	}
+
+	return f, nil
+}
+
+func checkControl(count, hash, debits, credits string, t totals) error {
+	checks := []struct {
+		name  string
+		field string
+		want  int64
+	}{
+		{"entry/addenda count", count, int64(t.entries)},
+		{"entry hash", hash, t.entryHash()},
+		{"total debits", debits, t.debits},
+		{"total credits", credits, t.credits},
+	}
+	for _, c := range checks {
+		got, err := strconv.ParseInt(c.field, 10, 64)
+		if err != nil || got != c.want {
+			return fmt.Errorf("%s is %q, computed %d", c.name, c.field, c.want)
+		}
+	}
+	return nil
+}
+
+// text left-justifies s in an alphanumeric field of width n. Leading pads
+// with a space first, as immediate origins are written.
+func text(s string, n int, leading bool) string {
+	s = strings.ToUpper(s)
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return ' '
+		}
+		return r
+	}, s)
+	if leading && len(s) < n {
+		s = " " + s
+	}
+	if len(s) > n {
+		return s[:n]
+	}
+	return s + strings.Repeat(" ", n-len(s))
+}
+
+// num right-justifies a numeric string in a zero-filled field of width n.
+func num(s string, n int) string {
+	if len(s) > n {
+		return s[len(s)-n:]
+	}
+	return strings.Repeat("0", n-len(s)) + s
+}
+
+func pad(v, n int) string {
+	return pad64(int64(v), n)
+}
+
+func pad64(v int64, n int) string {
+	return num(strconv.FormatInt(v, 10), n)
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/shubhbham/BankingApi_Golang/internal/batch"
+	"github.com/shubhbham/BankingApi_Golang/internal/config"
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
+	"github.com/shubhbham/BankingApi_Golang/internal/db"
+)
+
+// RunEOD runs, or resumes, the end-of-day batch once and prints the run
+// report. It is the entry point of the `eod` subcommand.
+func RunEOD(cfg *config.Config) error {
+	database, err := db.New(cfg.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer database.Close()
+
+	calendar, err := core.LoadCalendar(cfg.CalendarDir)
+	if err != nil {
+		return fmt.Errorf("failed to load business calendar: %w", err)
+	}
+
+	prices, err := core.LoadPriceBook(cfg.PriceBookFile)
+	if err != nil {
+		return fmt.Errorf("failed to load price book: %w", err)
+	}
+
+	run, runErr := batch.NewEOD(database.Pool, cfg, calendar, prices).Run(context.Background())
+	if run != nil {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(run); err != nil {
+			return err
+		}
+	}
+
+	return runErr
+}
@@ -11,14 +11,18 @@ import (
 	"time"
 
 	"github.com/shubhbham/BankingApi_Golang/internal/api"
+	"github.com/shubhbham/BankingApi_Golang/internal/batch"
 	"github.com/shubhbham/BankingApi_Golang/internal/config"
+	"github.com/shubhbham/BankingApi_Golang/internal/core"
 	"github.com/shubhbham/BankingApi_Golang/internal/db"
+	"github.com/shubhbham/BankingApi_Golang/internal/jobs"
 )
 
 type Server struct {
 	config *config.Config
 	db     *db.DB
 	http   *http.Server
+	jobs   *jobs.Runner
 }
 
 func New(cfg *config.Config) (*Server, error) {
@@ -28,8 +32,20 @@ func New(cfg *config.Config) (*Server, error) {
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
 
+	// Load business calendar
+	calendar, err := core.LoadCalendar(cfg.CalendarDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load business calendar: %w", err)
+	}
+
+	// Load fee price book
+	prices, err := core.LoadPriceBook(cfg.PriceBookFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load price book: %w", err)
+	}
+
 	// Initialize router
-	router := api.NewRouter(database)
+	router := api.NewRouter(database, cfg, calendar, prices)
 
 	// Create HTTP server
 	httpServer := &http.Server{
@@ -40,10 +56,33 @@ func New(cfg *config.Config) (*Server, error) {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Initialize background jobs
+	transactionService := batch.NewTransactionService(database.Pool, cfg, calendar, prices)
+	scheduledPayments := core.NewScheduledPaymentService(database.Pool, transactionService, core.RetryPolicy{
+		MaxRetries: cfg.ScheduledPaymentMaxRetries,
+		Interval:   cfg.ScheduledPaymentRetryInterval,
+	})
+
+	standingOrders := core.NewStandingOrderService(database.Pool, transactionService,
+		calendar, core.LogNotifier{}, cfg.StandingOrderMaxFailures)
+
+	interbank := batch.NewInterbankService(database.Pool, cfg, transactionService)
+
+	// Expiring requests needs no actions.
+	approvals := core.NewApprovalService(database.Pool, batch.NewApprovalPolicy(cfg), nil)
+
+	runner := jobs.NewRunner(cfg.SchedulerInterval,
+		jobs.Func("scheduled-payments", scheduledPayments.ExecuteDue),
+		jobs.Func("standing-orders", standingOrders.ExecuteDue),
+		jobs.Func("interbank-settlement", interbank.SettleDue),
+		jobs.Func("approval-expiry", approvals.ExpireDue),
+	)
+
 	return &Server{
 		config: cfg,
 		db:     database,
 		http:   httpServer,
+		jobs:   runner,
 	}, nil
 }
 
@@ -56,6 +95,8 @@ func (s *Server) Start() error {
 		}
 	}()
 
+	s.jobs.Start()
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -72,6 +113,7 @@ func (s *Server) Start() error {
 		return err
 	}
 
+	s.jobs.Stop()
 	s.db.Close()
 	log.Println("Server stopped")
 
@@ -0,0 +1,40 @@
+-- Cheque book issuance, stop-payment instructions and inward clearing.
+
+CREATE TABLE IF NOT EXISTS cheque_books (
+    cheque_book_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id     UUID NOT NULL REFERENCES accounts (account_id),
+    start_leaf     BIGINT NOT NULL,
+    end_leaf       BIGINT NOT NULL,
+    status         TEXT NOT NULL DEFAULT 'ISSUED',
+    issued_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (end_leaf >= start_leaf)
+);
+
+CREATE INDEX IF NOT EXISTS idx_cheque_books_account ON cheque_books (account_id, start_leaf);
+
+CREATE TABLE IF NOT EXISTS cheque_stop_payments (
+    stop_id       UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id    UUID NOT NULL REFERENCES accounts (account_id),
+    cheque_number BIGINT NOT NULL,
+    reason        TEXT,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (account_id, cheque_number)
+);
+
+CREATE TABLE IF NOT EXISTS cheque_presentments (
+    presentment_id     UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id         UUID NOT NULL REFERENCES accounts (account_id),
+    cheque_number      BIGINT NOT NULL,
+    amount             NUMERIC(18, 2) NOT NULL CHECK (amount > 0),
+    payee_name         TEXT NOT NULL,
+    cheque_date        DATE NOT NULL,
+    signature_verified BOOLEAN NOT NULL,
+    status             TEXT NOT NULL,
+    return_reason      TEXT,
+    txn_id             UUID REFERENCES account_transactions (txn_id),
+    fee_txn_id         UUID REFERENCES account_transactions (txn_id),
+    presented_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cheque_presentments_paid
+    ON cheque_presentments (account_id, cheque_number) WHERE status = 'PAID';
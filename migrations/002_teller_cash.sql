@@ -0,0 +1,67 @@
+-- General ledger, teller sessions and branch vault management.
+
+CREATE TABLE IF NOT EXISTS gl_accounts (
+    gl_code    TEXT PRIMARY KEY,
+    name       TEXT NOT NULL,
+    balance    NUMERIC(18, 2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS gl_entries (
+    entry_id   UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    journal_id UUID NOT NULL,
+    gl_code    TEXT NOT NULL REFERENCES gl_accounts (gl_code),
+    entry_type TEXT NOT NULL CHECK (entry_type IN ('DEBIT', 'CREDIT')),
+    amount     NUMERIC(18, 2) NOT NULL CHECK (amount > 0),
+    narrative  TEXT NOT NULL,
+    txn_id     UUID REFERENCES account_transactions (txn_id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_gl_entries_code ON gl_entries (gl_code, created_at);
+
+CREATE TABLE IF NOT EXISTS teller_sessions (
+    session_id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    branch_id             UUID NOT NULL REFERENCES branches (branch_id),
+    teller_id             TEXT NOT NULL,
+    till_gl_code          TEXT NOT NULL,
+    status                TEXT NOT NULL,
+    opening_denominations JSONB NOT NULL,
+    opening_cash          NUMERIC(18, 2) NOT NULL,
+    closing_denominations JSONB,
+    closing_cash          NUMERIC(18, 2),
+    expected_cash         NUMERIC(18, 2),
+    variance              NUMERIC(18, 2),
+    opened_at             TIMESTAMPTZ NOT NULL DEFAULT now(),
+    closed_at             TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_teller_sessions_open
+    ON teller_sessions (teller_id) WHERE status = 'OPEN';
+CREATE INDEX IF NOT EXISTS idx_teller_sessions_branch ON teller_sessions (branch_id, opened_at);
+
+CREATE TABLE IF NOT EXISTS vault_transfers (
+    transfer_id   UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    session_id    UUID NOT NULL REFERENCES teller_sessions (session_id),
+    direction     TEXT NOT NULL CHECK (direction IN ('VAULT_TO_TILL', 'TILL_TO_VAULT')),
+    amount        NUMERIC(18, 2) NOT NULL CHECK (amount > 0),
+    denominations JSONB NOT NULL,
+    initiated_by  TEXT NOT NULL,
+    approved_by   TEXT,
+    status        TEXT NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    decided_at    TIMESTAMPTZ,
+    CHECK (approved_by IS NULL OR approved_by <> initiated_by)
+);
+
+CREATE TABLE IF NOT EXISTS teller_cash_movements (
+    movement_id       UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    session_id        UUID NOT NULL REFERENCES teller_sessions (session_id),
+    movement_type     TEXT NOT NULL CHECK (movement_type IN ('DEPOSIT', 'WITHDRAWAL', 'VAULT_IN', 'VAULT_OUT')),
+    amount            NUMERIC(18, 2) NOT NULL,
+    txn_id            UUID REFERENCES account_transactions (txn_id),
+    vault_transfer_id UUID REFERENCES vault_transfers (transfer_id),
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_teller_cash_movements_session ON teller_cash_movements (session_id);
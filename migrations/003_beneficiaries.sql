@@ -0,0 +1,31 @@
+-- Beneficiary (payee) registration with cooling period and step-up verification.
+
+CREATE TABLE IF NOT EXISTS step_up_challenges (
+    challenge_id UUID PRIMARY KEY,
+    customer_id  UUID NOT NULL REFERENCES customers (customer_id),
+    purpose      TEXT NOT NULL,
+    code_hash    TEXT NOT NULL,
+    attempts     INT NOT NULL DEFAULT 0,
+    expires_at   TIMESTAMPTZ NOT NULL,
+    used_at      TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS beneficiaries (
+    beneficiary_id   UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    customer_id      UUID NOT NULL REFERENCES customers (customer_id),
+    nickname         TEXT NOT NULL,
+    beneficiary_type TEXT NOT NULL CHECK (beneficiary_type IN ('INTERNAL', 'EXTERNAL')),
+    account_number   TEXT NOT NULL,
+    bank_code        TEXT,
+    account_id       UUID REFERENCES accounts (account_id),
+    payee_name       TEXT NOT NULL,
+    status           TEXT NOT NULL,
+    cooling_ends_at  TIMESTAMPTZ NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    deleted_at       TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_beneficiaries_active
+    ON beneficiaries (customer_id, account_number, COALESCE(bank_code, ''))
+    WHERE status = 'ACTIVE';
@@ -0,0 +1,32 @@
+-- Scheduled and future-dated transfers.
+
+CREATE TABLE IF NOT EXISTS scheduled_payments (
+    payment_id      UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    from_account_id UUID NOT NULL REFERENCES accounts (account_id),
+    to_account_id   UUID NOT NULL REFERENCES accounts (account_id),
+    beneficiary_id  UUID REFERENCES beneficiaries (beneficiary_id),
+    amount          NUMERIC(18, 2) NOT NULL CHECK (amount > 0),
+    description     TEXT NOT NULL DEFAULT '',
+    execute_on      DATE NOT NULL,
+    status          TEXT NOT NULL,
+    attempts        INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    last_error      TEXT,
+    executed_at     TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_payments_due
+    ON scheduled_payments (next_attempt_at) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS idx_scheduled_payments_from ON scheduled_payments (from_account_id);
+
+CREATE TABLE IF NOT EXISTS scheduled_payment_attempts (
+    attempt_id   UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    payment_id   UUID NOT NULL REFERENCES scheduled_payments (payment_id),
+    outcome      TEXT NOT NULL CHECK (outcome IN ('SUCCESS', 'FAILED')),
+    error        TEXT,
+    attempted_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_payment_attempts_payment ON scheduled_payment_attempts (payment_id);
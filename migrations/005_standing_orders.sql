@@ -0,0 +1,37 @@
+-- Recurring standing orders.
+
+CREATE TABLE IF NOT EXISTS standing_orders (
+    standing_order_id    UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id           UUID NOT NULL REFERENCES accounts (account_id),
+    to_account_id        UUID NOT NULL REFERENCES accounts (account_id),
+    beneficiary_id       UUID REFERENCES beneficiaries (beneficiary_id),
+    amount               NUMERIC(18, 2) NOT NULL CHECK (amount > 0),
+    description          TEXT NOT NULL DEFAULT '',
+    frequency            TEXT NOT NULL CHECK (frequency IN ('WEEKLY', 'MONTHLY', 'LAST_BUSINESS_DAY', 'EVERY_N_DAYS')),
+    interval_days        INT CHECK (interval_days > 0),
+    non_business_day     TEXT NOT NULL DEFAULT 'NEXT',
+    start_date           DATE NOT NULL,
+    end_date             DATE,
+    max_count            INT CHECK (max_count > 0),
+    status               TEXT NOT NULL,
+    occurrences          INT NOT NULL DEFAULT 0,
+    next_run_date        DATE,
+    consecutive_failures INT NOT NULL DEFAULT 0,
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at           TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_standing_orders_account ON standing_orders (account_id);
+CREATE INDEX IF NOT EXISTS idx_standing_orders_due
+    ON standing_orders (next_run_date) WHERE status = 'ACTIVE';
+
+CREATE TABLE IF NOT EXISTS standing_order_executions (
+    execution_id      UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    standing_order_id UUID NOT NULL REFERENCES standing_orders (standing_order_id),
+    scheduled_date    DATE NOT NULL,
+    outcome           TEXT NOT NULL CHECK (outcome IN ('SUCCESS', 'FAILED')),
+    error             TEXT,
+    executed_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_standing_order_executions_order ON standing_order_executions (standing_order_id);
@@ -0,0 +1,16 @@
+-- Separate posting and value dates on account transactions.
+
+ALTER TABLE account_transactions
+    ADD COLUMN IF NOT EXISTS posting_date DATE,
+    ADD COLUMN IF NOT EXISTS value_date   DATE;
+
+UPDATE account_transactions
+SET posting_date = created_at::date, value_date = created_at::date
+WHERE posting_date IS NULL;
+
+ALTER TABLE account_transactions
+    ALTER COLUMN posting_date SET NOT NULL,
+    ALTER COLUMN value_date   SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_account_transactions_value_date
+    ON account_transactions (account_id, value_date);
@@ -0,0 +1,32 @@
+-- System business date and end-of-day batch checkpoints.
+
+CREATE TABLE IF NOT EXISTS system_state (
+    id            INT PRIMARY KEY CHECK (id = 1),
+    business_date DATE NOT NULL,
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO system_state (id, business_date)
+VALUES (1, CURRENT_DATE)
+ON CONFLICT (id) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS eod_runs (
+    run_id        UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    business_date DATE NOT NULL UNIQUE,
+    status        TEXT NOT NULL CHECK (status IN ('RUNNING', 'COMPLETED', 'FAILED')),
+    started_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at   TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS eod_step_runs (
+    run_id      UUID NOT NULL REFERENCES eod_runs (run_id),
+    step_name   TEXT NOT NULL,
+    seq         INT NOT NULL,
+    status      TEXT NOT NULL CHECK (status IN ('PENDING', 'RUNNING', 'COMPLETED', 'FAILED')),
+    checkpoint  TEXT,
+    error       TEXT,
+    started_at  TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (run_id, step_name)
+);
@@ -0,0 +1,42 @@
+-- Running balance on account transactions.
+
+ALTER TABLE account_transactions
+    ADD COLUMN IF NOT EXISTS entry_seq     BIGINT,
+    ADD COLUMN IF NOT EXISTS balance_after NUMERIC(18, 2);
+
+-- Number existing entries in the order they were made, which the running
+-- balance below relies on. A BIGSERIAL column would number them in whatever
+-- order the rows happen to be stored.
+UPDATE account_transactions t
+SET entry_seq = o.seq
+FROM (
+    SELECT txn_id, row_number() OVER (ORDER BY created_at, txn_id) AS seq
+    FROM account_transactions
+) o
+WHERE o.txn_id = t.txn_id AND t.entry_seq IS NULL;
+
+CREATE SEQUENCE IF NOT EXISTS account_transactions_entry_seq_seq
+    OWNED BY account_transactions.entry_seq;
+SELECT setval('account_transactions_entry_seq_seq',
+              COALESCE((SELECT max(entry_seq) FROM account_transactions), 0) + 1, false);
+
+ALTER TABLE account_transactions
+    ALTER COLUMN entry_seq SET DEFAULT nextval('account_transactions_entry_seq_seq'),
+    ALTER COLUMN entry_seq SET NOT NULL;
+
+-- Work backwards from the current balance so accounts opened with an initial
+-- balance come out right.
+UPDATE account_transactions t
+SET balance_after = a.balance - COALESCE((
+        SELECT SUM(CASE WHEN l.txn_type = 'DEBIT' THEN -l.amount ELSE l.amount END)
+        FROM account_transactions l
+        WHERE l.account_id = t.account_id AND l.entry_seq > t.entry_seq
+    ), 0)
+FROM accounts a
+WHERE a.account_id = t.account_id AND t.balance_after IS NULL;
+
+ALTER TABLE account_transactions
+    ALTER COLUMN balance_after SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_account_transactions_statement
+    ON account_transactions (account_id, posting_date, entry_seq);
@@ -0,0 +1,18 @@
+-- Monthly PDF statements.
+
+ALTER TABLE customers
+    ADD COLUMN IF NOT EXISTS address TEXT;
+
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS statement_cycle TEXT NOT NULL DEFAULT 'CALENDAR_MONTH'
+        CHECK (statement_cycle IN ('CALENDAR_MONTH', 'ANNIVERSARY'));
+
+CREATE TABLE IF NOT EXISTS statement_documents (
+    account_id   UUID NOT NULL REFERENCES accounts (account_id),
+    period       TEXT NOT NULL,
+    period_start DATE NOT NULL,
+    period_end   DATE NOT NULL,
+    blob_key     TEXT NOT NULL,
+    generated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (account_id, period)
+);
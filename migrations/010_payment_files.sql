@@ -0,0 +1,37 @@
+-- Bulk payment files (pain.001) and the outcome of each instruction.
+
+CREATE TABLE IF NOT EXISTS payment_files (
+    file_id        UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    customer_id    UUID NOT NULL REFERENCES customers (customer_id),
+    message_id     TEXT NOT NULL,
+    message_name   TEXT NOT NULL,
+    mode           TEXT NOT NULL CHECK (mode IN ('ATOMIC', 'BEST_EFFORT')),
+    declared_count INTEGER NOT NULL,
+    declared_sum   NUMERIC(18,2),
+    status         TEXT NOT NULL CHECK (status IN ('PDNG', 'ACSC', 'PART', 'RJCT')),
+    reason         TEXT,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (customer_id, message_id)
+);
+
+CREATE TABLE IF NOT EXISTS payment_file_instructions (
+    file_id              UUID NOT NULL REFERENCES payment_files (file_id),
+    batch_seq            INTEGER NOT NULL,
+    batch_id             TEXT NOT NULL,
+    debtor_account       TEXT NOT NULL,
+    batch_declared_count INTEGER,
+    batch_declared_sum   NUMERIC(18,2),
+    seq                  INTEGER NOT NULL,
+    instruction_id       TEXT,
+    end_to_end_id        TEXT NOT NULL,
+    creditor_account     TEXT NOT NULL,
+    creditor_name        TEXT,
+    amount               NUMERIC(18,2) NOT NULL,
+    currency             TEXT NOT NULL,
+    remittance           TEXT,
+    status               TEXT NOT NULL CHECK (status IN ('PDNG', 'ACSC', 'RJCT')),
+    reason               TEXT,
+    reason_text          TEXT,
+    txn_id               UUID REFERENCES account_transactions (txn_id),
+    PRIMARY KEY (file_id, batch_seq, seq)
+);
@@ -0,0 +1,48 @@
+-- Outbound ACH transfers, the NACHA files they are sent in, and returns.
+
+CREATE SEQUENCE IF NOT EXISTS ach_trace_seq;
+
+CREATE TABLE IF NOT EXISTS ach_files (
+    file_id      UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    file_name    TEXT NOT NULL UNIQUE,
+    direction    TEXT NOT NULL CHECK (direction IN ('OUTBOUND', 'RETURN')),
+    file_date    DATE NOT NULL,
+    id_modifier  TEXT,
+    entry_count  INTEGER NOT NULL,
+    total_debit  NUMERIC(18,2) NOT NULL,
+    total_credit NUMERIC(18,2) NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ach_files_modifier
+    ON ach_files (file_date, id_modifier) WHERE direction = 'OUTBOUND';
+
+CREATE TABLE IF NOT EXISTS ach_transfers (
+    transfer_id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id            UUID NOT NULL REFERENCES accounts (account_id),
+    beneficiary_id        UUID REFERENCES beneficiaries (beneficiary_id),
+    entry_type            TEXT NOT NULL CHECK (entry_type IN ('CREDIT', 'DEBIT')),
+    sec_code              TEXT NOT NULL CHECK (sec_code IN ('PPD', 'CCD')),
+    routing_number        TEXT NOT NULL,
+    receiver_account      TEXT NOT NULL,
+    receiver_account_type TEXT NOT NULL CHECK (receiver_account_type IN ('CHECKING', 'SAVINGS')),
+    receiver_name         TEXT NOT NULL,
+    receiver_id           TEXT,
+    amount                NUMERIC(18,2) NOT NULL CHECK (amount > 0),
+    description           TEXT,
+    effective_date        DATE NOT NULL,
+    status                TEXT NOT NULL CHECK (status IN ('PENDING', 'SENT', 'RETURNED')),
+    txn_id                UUID NOT NULL REFERENCES account_transactions (txn_id),
+    file_id               UUID REFERENCES ach_files (file_id),
+    trace_number          TEXT UNIQUE,
+    return_code           TEXT,
+    return_file_id        UUID REFERENCES ach_files (file_id),
+    return_txn_id         UUID REFERENCES account_transactions (txn_id),
+    created_at            TIMESTAMPTZ NOT NULL DEFAULT now(),
+    sent_at               TIMESTAMPTZ,
+    returned_at           TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_ach_transfers_pending
+    ON ach_transfers (effective_date) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS idx_ach_transfers_account ON ach_transfers (account_id, created_at);
@@ -0,0 +1,40 @@
+-- Outbound interbank payments and the deferred net settlement batches they
+-- are cleared in.
+
+CREATE TABLE IF NOT EXISTS interbank_batches (
+    batch_id       UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    rail           TEXT NOT NULL CHECK (rail IN ('DNS')),
+    cutoff         TIMESTAMPTZ NOT NULL,
+    payment_count  INTEGER NOT NULL,
+    settled_count  INTEGER NOT NULL,
+    returned_count INTEGER NOT NULL,
+    net_amount     NUMERIC(18,2) NOT NULL,
+    reference      TEXT,
+    settled_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS interbank_payments (
+    payment_id     UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id     UUID NOT NULL REFERENCES accounts (account_id),
+    beneficiary_id UUID REFERENCES beneficiaries (beneficiary_id),
+    rail           TEXT NOT NULL CHECK (rail IN ('DNS', 'RTGS')),
+    urgency        TEXT NOT NULL CHECK (urgency IN ('NORMAL', 'URGENT')),
+    bank_code      TEXT NOT NULL,
+    account_number TEXT NOT NULL,
+    payee_name     TEXT NOT NULL,
+    amount         NUMERIC(18,2) NOT NULL CHECK (amount > 0),
+    description    TEXT,
+    status         TEXT NOT NULL CHECK (status IN ('SUBMITTED', 'SETTLED', 'RETURNED')),
+    txn_id         UUID NOT NULL REFERENCES account_transactions (txn_id),
+    batch_id       UUID REFERENCES interbank_batches (batch_id),
+    clearing_ref   TEXT,
+    return_reason  TEXT,
+    return_txn_id  UUID REFERENCES account_transactions (txn_id),
+    submitted_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    settled_at     TIMESTAMPTZ,
+    returned_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_interbank_payments_submitted
+    ON interbank_payments (rail, submitted_at) WHERE status = 'SUBMITTED';
+CREATE INDEX IF NOT EXISTS idx_interbank_payments_account ON interbank_payments (account_id, submitted_at);
@@ -0,0 +1,33 @@
+-- Transaction reversals. Both legs of a transfer share a transfer_id so they
+-- are reversed together; a reversal entry points at the entry it reverses.
+
+ALTER TABLE account_transactions
+    ADD COLUMN IF NOT EXISTS status          TEXT NOT NULL DEFAULT 'POSTED'
+        CHECK (status IN ('POSTED', 'REVERSED')),
+    ADD COLUMN IF NOT EXISTS transfer_id     UUID,
+    ADD COLUMN IF NOT EXISTS reversal_of     UUID REFERENCES account_transactions (txn_id),
+    ADD COLUMN IF NOT EXISTS reversal_reason TEXT,
+    ADD COLUMN IF NOT EXISTS reversed_by     TEXT;
+
+-- An entry can only be reversed once.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_account_transactions_reversal_of
+    ON account_transactions (reversal_of) WHERE reversal_of IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_account_transactions_transfer
+    ON account_transactions (transfer_id) WHERE transfer_id IS NOT NULL;
+
+-- Link the legs of earlier transfers: both were written in the same database
+-- transaction, so they share created_at, amount and description. Only
+-- unambiguous pairs are linked.
+WITH legs AS (
+    SELECT txn_id, txn_type,
+           count(*) OVER w AS n,
+           count(*) FILTER (WHERE txn_type = 'DEBIT') OVER w AS debits,
+           first_value(txn_id) OVER (PARTITION BY created_at, amount, description ORDER BY txn_type DESC) AS debit_id
+    FROM account_transactions
+    WHERE channel = 'TRANSFER' AND transfer_id IS NULL
+    WINDOW w AS (PARTITION BY created_at, amount, description)
+)
+UPDATE account_transactions t
+SET transfer_id = legs.debit_id
+FROM legs
+WHERE t.txn_id = legs.txn_id AND legs.n = 2 AND legs.debits = 1;
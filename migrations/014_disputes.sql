@@ -0,0 +1,51 @@
+-- Customer disputes of account transactions, with their notes and attached
+-- evidence.
+
+CREATE TABLE IF NOT EXISTS disputes (
+    dispute_id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id              UUID NOT NULL REFERENCES accounts (account_id),
+    txn_id                  UUID NOT NULL REFERENCES account_transactions (txn_id),
+    reason_code             TEXT NOT NULL,
+    description             TEXT,
+    amount                  NUMERIC(18,2) NOT NULL CHECK (amount > 0),
+    status                  TEXT NOT NULL CHECK (status IN ('OPENED', 'PROVISIONAL_CREDIT', 'UNDER_INVESTIGATION',
+                                                            'RESOLVED_CUSTOMER', 'RESOLVED_BANK')),
+    provisional_credit_due  DATE NOT NULL,
+    resolution_due          DATE NOT NULL,
+    credit_txn_id           UUID REFERENCES account_transactions (txn_id),
+    credit_reversal_txn_id  UUID REFERENCES account_transactions (txn_id),
+    resolution              TEXT,
+    resolved_by             TEXT,
+    opened_at               TIMESTAMPTZ NOT NULL DEFAULT now(),
+    resolved_at             TIMESTAMPTZ
+);
+
+-- A transaction can only be under one open dispute at a time.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_disputes_open_txn
+    ON disputes (txn_id) WHERE status NOT IN ('RESOLVED_CUSTOMER', 'RESOLVED_BANK');
+CREATE INDEX IF NOT EXISTS idx_disputes_account ON disputes (account_id, opened_at);
+CREATE INDEX IF NOT EXISTS idx_disputes_due
+    ON disputes (resolution_due) WHERE status NOT IN ('RESOLVED_CUSTOMER', 'RESOLVED_BANK');
+
+CREATE TABLE IF NOT EXISTS dispute_notes (
+    note_id    UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    dispute_id UUID NOT NULL REFERENCES disputes (dispute_id),
+    author     TEXT NOT NULL,
+    body       TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_dispute_notes_dispute ON dispute_notes (dispute_id, created_at);
+
+CREATE TABLE IF NOT EXISTS dispute_attachments (
+    attachment_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    dispute_id    UUID NOT NULL REFERENCES disputes (dispute_id),
+    file_name     TEXT NOT NULL,
+    content_type  TEXT NOT NULL,
+    size_bytes    INTEGER NOT NULL,
+    blob_key      TEXT NOT NULL,
+    uploaded_by   TEXT NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_dispute_attachments_dispute ON dispute_attachments (dispute_id, created_at);
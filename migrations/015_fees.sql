@@ -0,0 +1,25 @@
+-- Fees and charges. Customer segments drive fee waivers; fee and tax entries
+-- point at the transaction that triggered them; periodic fees are charged
+-- once per account and period.
+
+ALTER TABLE customers
+    ADD COLUMN IF NOT EXISTS segment TEXT NOT NULL DEFAULT 'STANDARD';
+
+ALTER TABLE account_transactions
+    ADD COLUMN IF NOT EXISTS fee_for UUID REFERENCES account_transactions (txn_id);
+
+CREATE INDEX IF NOT EXISTS idx_account_transactions_fee_for
+    ON account_transactions (fee_for) WHERE fee_for IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS fee_charges (
+    charge_id  UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id UUID NOT NULL REFERENCES accounts (account_id),
+    fee_code   TEXT NOT NULL,
+    period     TEXT NOT NULL,
+    amount     NUMERIC(18,2) NOT NULL,
+    tax        NUMERIC(18,2) NOT NULL,
+    txn_id     UUID REFERENCES account_transactions (txn_id),
+    tax_txn_id UUID REFERENCES account_transactions (txn_id),
+    charged_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (account_id, fee_code, period)
+);
@@ -0,0 +1,40 @@
+-- Product catalog. Every change to a product publishes a new version; a
+-- version is never updated, so the product_code and product_version held on
+-- an account are a snapshot of the terms it was opened on.
+
+CREATE TABLE IF NOT EXISTS products (
+    product_code          TEXT NOT NULL,
+    version               INTEGER NOT NULL CHECK (version > 0),
+    name                  TEXT NOT NULL,
+    account_type          TEXT NOT NULL,
+    currency              CHAR(3) NOT NULL,
+    min_balance           NUMERIC(18,2) NOT NULL DEFAULT 0 CHECK (min_balance >= 0),
+    interest_method       TEXT NOT NULL CHECK (interest_method IN ('NONE', 'DAILY_BALANCE', 'AVERAGE_MONTHLY_BALANCE')),
+    interest_rate         NUMERIC(9,4) NOT NULL DEFAULT 0 CHECK (interest_rate >= 0),
+    interest_posting      TEXT CHECK (interest_posting IN ('MONTHLY', 'QUARTERLY')),
+    fee_schedule          TEXT,
+    overdraft_allowed     BOOLEAN NOT NULL DEFAULT false,
+    overdraft_limit       NUMERIC(18,2) NOT NULL DEFAULT 0 CHECK (overdraft_limit >= 0),
+    allowed_channels      TEXT[] NOT NULL DEFAULT '{}',
+    per_transaction_limit NUMERIC(18,2) CHECK (per_transaction_limit > 0),
+    daily_debit_limit     NUMERIC(18,2) CHECK (daily_debit_limit > 0),
+    min_age               INTEGER CHECK (min_age >= 0),
+    max_age               INTEGER CHECK (max_age >= 0),
+    required_kyc_status   TEXT,
+    status                TEXT NOT NULL CHECK (status IN ('ACTIVE', 'RETIRED')),
+    created_by            TEXT,
+    created_at            TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (product_code, version)
+);
+
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS product_code TEXT,
+    ADD COLUMN IF NOT EXISTS product_version INTEGER;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'accounts_product_fkey') THEN
+        ALTER TABLE accounts ADD CONSTRAINT accounts_product_fkey
+            FOREIGN KEY (product_code, product_version) REFERENCES products (product_code, version);
+    END IF;
+END $$;
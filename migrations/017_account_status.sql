@@ -0,0 +1,20 @@
+-- Account status history. Every status change records who made it and why;
+-- accounts opened before the history was kept get a starting row.
+
+CREATE TABLE IF NOT EXISTS account_status_history (
+    change_id   UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id  UUID NOT NULL REFERENCES accounts (account_id),
+    from_status TEXT,
+    to_status   TEXT NOT NULL,
+    reason      TEXT NOT NULL,
+    actor       TEXT,
+    changed_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_status_history_account
+    ON account_status_history (account_id, changed_at);
+
+INSERT INTO account_status_history (account_id, from_status, to_status, reason, changed_at)
+SELECT a.account_id, NULL, a.status, 'Status before history was kept', a.opened_at
+FROM accounts a
+WHERE NOT EXISTS (SELECT 1 FROM account_status_history h WHERE h.account_id = a.account_id);
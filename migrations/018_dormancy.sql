@@ -0,0 +1,17 @@
+-- Dormancy. A dormant account is reactivated only after a KYC verification
+-- newer than the date it went dormant, so customers record when and by whom
+-- they were last verified. Notices sent before an account goes dormant are
+-- kept per expected dormancy date, so fresh activity starts a new series.
+
+ALTER TABLE customers
+    ADD COLUMN IF NOT EXISTS kyc_verified_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS kyc_verified_by TEXT;
+
+CREATE TABLE IF NOT EXISTS dormancy_notices (
+    notice_id   UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id  UUID NOT NULL REFERENCES accounts (account_id),
+    dormant_on  DATE NOT NULL,
+    days_before INT NOT NULL,
+    sent_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (account_id, dormant_on, days_before)
+);
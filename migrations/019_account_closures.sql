@@ -0,0 +1,19 @@
+-- Account closures. Each closed account keeps the record of its final
+-- interest, closure fee and balance payout, and the certificate issued for it.
+
+CREATE TABLE IF NOT EXISTS account_closures (
+    closure_id        UUID PRIMARY KEY,
+    account_id        UUID NOT NULL UNIQUE REFERENCES accounts (account_id),
+    closed_on         DATE NOT NULL,
+    reason            TEXT NOT NULL,
+    actor             TEXT NOT NULL,
+    final_interest    NUMERIC(18,2) NOT NULL DEFAULT 0,
+    closure_fee       NUMERIC(18,2) NOT NULL DEFAULT 0,
+    closure_fee_tax   NUMERIC(18,2) NOT NULL DEFAULT 0,
+    payout_method     TEXT CHECK (payout_method IN ('TRANSFER', 'CASH')),
+    payout_amount     NUMERIC(18,2) NOT NULL DEFAULT 0,
+    payout_account_id UUID REFERENCES accounts (account_id),
+    payout_txn_id     UUID REFERENCES account_transactions (txn_id),
+    certificate_key   TEXT NOT NULL,
+    closed_at         TIMESTAMPTZ NOT NULL DEFAULT now()
+);
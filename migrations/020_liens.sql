@@ -0,0 +1,38 @@
+-- Liens and garnishment orders. Liens hold part or all of an account's
+-- balance; garnishments sweep incoming credits to the GARNISHMENT_PAYABLE GL
+-- until the ordered amount is collected. Releasing either needs a second
+-- user's approval.
+
+CREATE TABLE IF NOT EXISTS liens (
+    lien_id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id           UUID NOT NULL REFERENCES accounts (account_id),
+    lien_type            TEXT NOT NULL CHECK (lien_type IN ('FIXED', 'ALL_FUNDS', 'GARNISHMENT')),
+    amount               NUMERIC(18,2) CHECK (amount > 0),
+    swept_amount         NUMERIC(18,2) NOT NULL DEFAULT 0,
+    reference            TEXT NOT NULL,
+    authority            TEXT NOT NULL,
+    expires_on           DATE,
+    status               TEXT NOT NULL CHECK (status IN ('ACTIVE', 'RELEASE_PENDING', 'RELEASED', 'EXPIRED', 'SATISFIED')),
+    created_by           TEXT NOT NULL,
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT now(),
+    release_reason       TEXT,
+    release_requested_by TEXT,
+    release_requested_at TIMESTAMPTZ,
+    release_decided_by   TEXT,
+    release_decided_at   TIMESTAMPTZ,
+    CHECK ((lien_type = 'ALL_FUNDS') = (amount IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_liens_account
+    ON liens (account_id) WHERE status IN ('ACTIVE', 'RELEASE_PENDING');
+
+CREATE TABLE IF NOT EXISTS lien_sweeps (
+    sweep_id      UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    lien_id       UUID NOT NULL REFERENCES liens (lien_id),
+    credit_txn_id UUID NOT NULL REFERENCES account_transactions (txn_id),
+    txn_id        UUID NOT NULL REFERENCES account_transactions (txn_id),
+    amount        NUMERIC(18,2) NOT NULL,
+    swept_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_lien_sweeps_lien ON lien_sweeps (lien_id);
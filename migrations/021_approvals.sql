@@ -0,0 +1,62 @@
+-- Maker-checker approvals. Configured operations are recorded as pending
+-- requests and only carried out once a different user approves them.
+-- approval_events keeps every step of each request's decision trail.
+
+CREATE TABLE IF NOT EXISTS approvals (
+    approval_id  UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    operation    TEXT NOT NULL CHECK (operation IN ('ACCOUNT_CLOSURE', 'TRANSFER', 'KYC_OVERRIDE', 'LIEN_RELEASE', 'REVERSAL')),
+    subject_id   UUID NOT NULL,
+    amount       NUMERIC(18,2),
+    payload      JSONB NOT NULL,
+    status       TEXT NOT NULL CHECK (status IN ('PENDING', 'APPROVED', 'REJECTED', 'EXPIRED', 'EXECUTED', 'FAILED')),
+    requested_by TEXT NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMPTZ NOT NULL,
+    decided_by   TEXT,
+    decided_at   TIMESTAMPTZ,
+    result       JSONB,
+    error        TEXT,
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_approvals_status ON approvals (status, created_at);
+
+-- Only one request at a time may be pending for an account closure, KYC
+-- override, lien release or reversal. Transfers from the same account may
+-- wait side by side.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_approvals_pending_subject
+    ON approvals (operation, subject_id) WHERE status = 'PENDING' AND operation <> 'TRANSFER';
+
+CREATE TABLE IF NOT EXISTS approval_events (
+    event_id    UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    approval_id UUID NOT NULL REFERENCES approvals (approval_id),
+    event       TEXT NOT NULL CHECK (event IN ('REQUESTED', 'APPROVED', 'REJECTED', 'EXPIRED', 'EXECUTED', 'FAILED')),
+    actor       TEXT,
+    comment     TEXT,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_approval_events_approval ON approval_events (approval_id, created_at);
+
+-- Lien releases move to the approval engine. Releases already waiting for a
+-- second user become pending approvals, and the lien goes back to ACTIVE.
+INSERT INTO approvals (operation, subject_id, payload, status, requested_by, created_at, expires_at)
+SELECT 'LIEN_RELEASE', lien_id, jsonb_build_object('reason', release_reason), 'PENDING',
+       release_requested_by, release_requested_at, now() + interval '1 day'
+FROM liens
+WHERE status = 'RELEASE_PENDING';
+
+INSERT INTO approval_events (approval_id, event, actor, comment, created_at)
+SELECT approval_id, 'REQUESTED', requested_by, payload ->> 'reason', created_at
+FROM approvals
+WHERE operation = 'LIEN_RELEASE' AND status = 'PENDING'
+  AND NOT EXISTS (SELECT 1 FROM approval_events e WHERE e.approval_id = approvals.approval_id);
+
+UPDATE liens SET status = 'ACTIVE' WHERE status = 'RELEASE_PENDING';
+
+ALTER TABLE liens DROP CONSTRAINT IF EXISTS liens_status_check;
+ALTER TABLE liens ADD CONSTRAINT liens_status_check
+    CHECK (status IN ('ACTIVE', 'RELEASED', 'EXPIRED', 'SATISFIED'));
+
+DROP INDEX IF EXISTS idx_liens_account;
+CREATE INDEX IF NOT EXISTS idx_liens_account ON liens (account_id) WHERE status = 'ACTIVE';
@@ -0,0 +1,14 @@
+-- ACH debits are credited to the customer when they settle, once their
+-- return window has passed, rather than when they are originated. Until
+-- then they carry no account entry.
+
+ALTER TABLE ach_transfers
+    ALTER COLUMN txn_id DROP NOT NULL,
+    ADD COLUMN IF NOT EXISTS settled_at TIMESTAMPTZ;
+
+ALTER TABLE ach_transfers DROP CONSTRAINT IF EXISTS ach_transfers_status_check;
+ALTER TABLE ach_transfers ADD CONSTRAINT ach_transfers_status_check
+    CHECK (status IN ('PENDING', 'SENT', 'SETTLED', 'RETURNED'));
+
+CREATE INDEX IF NOT EXISTS idx_ach_transfers_sent
+    ON ach_transfers (effective_date) WHERE status = 'SENT';
@@ -0,0 +1,5 @@
+-- An RTGS payment is claimed before it is sent to the clearing house, so the
+-- retry job and the submitting request never send the same payment twice.
+
+ALTER TABLE interbank_payments
+    ADD COLUMN IF NOT EXISTS clearing_attempted_at TIMESTAMPTZ;
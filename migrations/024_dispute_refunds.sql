@@ -0,0 +1,6 @@
+-- A transaction refunded on a dispute cannot be disputed again: only a
+-- dispute resolved in the bank's favour frees it for a new one.
+
+DROP INDEX IF EXISTS idx_disputes_open_txn;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_disputes_held_txn
+    ON disputes (txn_id) WHERE status <> 'RESOLVED_BANK';
@@ -0,0 +1,14 @@
+-- Approved requests are now carried out in the transaction that approves
+-- them. Requests left APPROVED by an interrupted execution before this change
+-- never recorded an outcome; they end FAILED so operations can check the
+-- subject and request again if needed.
+
+WITH stranded AS (
+    UPDATE approvals
+    SET status = 'FAILED', completed_at = now(),
+        error = 'interrupted before its outcome was recorded; check the subject before requesting again'
+    WHERE status = 'APPROVED'
+    RETURNING approval_id, error
+)
+INSERT INTO approval_events (approval_id, event, comment)
+SELECT approval_id, 'FAILED', error FROM stranded;